@@ -11,6 +11,10 @@ import (
 	"crypto/tls"
 	"fmt"
 	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"go.uber.org/fx"
@@ -19,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core"
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
 	"github.com/DataDog/datadog-agent/pkg/api/security"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
@@ -43,6 +48,7 @@ type cliParams struct {
 	dsdCaptureDuration   time.Duration
 	dsdCaptureFilePath   string
 	dsdCaptureCompressed bool
+	dsdInspectFilePath   string
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -56,6 +62,13 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 		Short: "Start a dogstatsd UDS traffic capture",
 		Long:  ``,
 		RunE: func(_ *cobra.Command, _ []string) error {
+			if cliParams.dsdInspectFilePath != "" {
+				return fxutil.OneShot(dogstatsdInspect,
+					fx.Supply(cliParams),
+					fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+					core.Bundle(),
+				)
+			}
 			return fxutil.OneShot(dogstatsdCapture,
 				fx.Supply(cliParams),
 				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
@@ -67,6 +80,7 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdCaptureCmd.Flags().DurationVarP(&cliParams.dsdCaptureDuration, "duration", "d", defaultCaptureDuration, "Duration traffic capture should span.")
 	dogstatsdCaptureCmd.Flags().StringVarP(&cliParams.dsdCaptureFilePath, "path", "p", "", "Directory path to write the capture to.")
 	dogstatsdCaptureCmd.Flags().BoolVarP(&cliParams.dsdCaptureCompressed, "compressed", "z", true, "Should capture be zstd compressed.")
+	dogstatsdCaptureCmd.Flags().StringVarP(&cliParams.dsdInspectFilePath, "inspect", "i", "", "Print the statistics of the metrics of this capture file instead of starting a capture.")
 
 	// shut up grpc client!
 	grpclog.SetLoggerV2(grpclog.NewLoggerV2(io.Discard, io.Discard, io.Discard))
@@ -124,3 +138,45 @@ func dogstatsdCapture(_ log.Component, config config.Component, cliParams *cliPa
 
 	return nil
 }
+
+//nolint:revive // TODO(AML) Fix revive linter
+func dogstatsdInspect(_ log.Component, _ config.Component, cliParams *cliParams) error {
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdInspectFilePath, 1, true)
+	if reader != nil {
+		defer reader.Close()
+	}
+	if err != nil {
+		fmt.Printf("could not open: %s\n", cliParams.dsdInspectFilePath)
+		return err
+	}
+
+	stats, err := reader.Inspect()
+	if err != nil {
+		return err
+	}
+	printCaptureStats(os.Stdout, stats)
+	return nil
+}
+
+// printCaptureStats prints the totals of the capture and a table of the
+// statistics of its metrics.
+func printCaptureStats(w io.Writer, stats *replay.CaptureStats) {
+	fmt.Fprintf(w, "Packets: %d\n", stats.Packets)
+	fmt.Fprintf(w, "Bytes: %d\n", stats.Bytes)
+	fmt.Fprintf(w, "Duration: %s\n", stats.Duration)
+	fmt.Fprintf(w, "Metrics: %d\n", len(stats.Metrics))
+	fmt.Fprintf(w, "Events: %d\n", stats.Events)
+	fmt.Fprintf(w, "Service checks: %d\n\n", stats.ServiceChecks)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tMESSAGES\tTYPES\tCONTEXTS")
+	for _, m := range stats.SortedMetrics() {
+		types := make([]string, 0, len(m.Types))
+		for t, count := range m.Types {
+			types = append(types, fmt.Sprintf("%s:%d", t, count))
+		}
+		sort.Strings(types)
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\n", m.Name, m.Messages, strings.Join(types, ","), m.Contexts)
+	}
+	tw.Flush()
+}
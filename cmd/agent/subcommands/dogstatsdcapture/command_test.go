@@ -28,3 +28,13 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestInspectCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-capture", "-i", "capture.dog"},
+		dogstatsdInspect,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, "capture.dog", cliParams.dsdInspectFilePath)
+		})
+}
@@ -7,12 +7,18 @@
 package dogstatsdreplay
 
 import (
+	"bufio"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"go.uber.org/fx"
@@ -35,6 +41,7 @@ import (
 
 const (
 	defaultIterations = 1
+	defaultSpeed      = 1
 )
 
 // cliParams are the command-line arguments for this subcommand
@@ -47,12 +54,18 @@ type cliParams struct {
 	dsdVerboseReplay    bool
 	dsdMmapReplay       bool
 	dsdReplayIterations int
+	dsdReplaySpeed      float64
+	dsdReplayFilter     string
+	dsdReplayTagRewrite []string
+	dsdReplayPidRewrite []string
+	dsdReplayExportPath string
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
 func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	cliParams := &cliParams{
-		GlobalParams: globalParams,
+		GlobalParams:   globalParams,
+		dsdReplaySpeed: defaultSpeed,
 	}
 
 	dogstatsdReplayCmd := &cobra.Command{
@@ -71,12 +84,49 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdVerboseReplay, "verbose", "v", false, "Verbose replay.")
 	dogstatsdReplayCmd.Flags().BoolVarP(&cliParams.dsdMmapReplay, "mmap", "m", true, "Mmap file for replay. Set to false to load the entire file into memory instead")
 	dogstatsdReplayCmd.Flags().IntVarP(&cliParams.dsdReplayIterations, "loops", "l", defaultIterations, "Number of iterations to replay.")
+	dogstatsdReplayCmd.Flags().VarP((*speedValue)(&cliParams.dsdReplaySpeed), "speed", "s", "Replay speed factor: 2 replays twice as fast as captured, 0.5 twice as slow, and 0 as fast as possible.")
+	dogstatsdReplayCmd.Flags().StringVar(&cliParams.dsdReplayFilter, "filter", "", "Only replay the metrics whose name matches this regular expression, dropping events and service checks.")
+	dogstatsdReplayCmd.Flags().StringArrayVar(&cliParams.dsdReplayTagRewrite, "rewrite-tag", nil, "Rewrite a tag, or all the tags with a name, as FROM=TO. An empty TO removes the tag. Can be repeated.")
+	dogstatsdReplayCmd.Flags().StringArrayVar(&cliParams.dsdReplayPidRewrite, "rewrite-pid", nil, "Rewrite an origin PID as FROM=TO, FROM being * to rewrite all the other PIDs. Can be repeated.")
+	dogstatsdReplayCmd.Flags().StringVarP(&cliParams.dsdReplayExportPath, "export", "e", "", "Export the traffic as plain-text statsd lines to this file, - for stdout, instead of replaying it.")
 
 	return []*cobra.Command{dogstatsdReplayCmd}
 }
 
+// speedValue is the value of the --speed flag, validated when it is parsed.
+type speedValue float64
+
+func (v *speedValue) String() string {
+	return strconv.FormatFloat(float64(*v), 'g', -1, 64)
+}
+
+func (v *speedValue) Set(s string) error {
+	speed, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	if err := replay.ValidateSpeed(speed); err != nil {
+		return err
+	}
+	*v = speedValue(speed)
+	return nil
+}
+
+func (v *speedValue) Type() string {
+	return "float"
+}
+
 //nolint:revive // TODO(AML) Fix revive linter
 func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliParams) error {
+	transform, err := newTrafficTransform(cliParams)
+	if err != nil {
+		return err
+	}
+
+	if cliParams.dsdReplayExportPath != "" {
+		return dogstatsdExport(cliParams, transform)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -132,6 +182,9 @@ func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliPar
 		fmt.Printf("could not open: %s\n", cliParams.dsdReplayFilePath)
 		return err
 	}
+	if err := reader.SetSpeed(cliParams.dsdReplaySpeed); err != nil {
+		return err
+	}
 
 	s := pkgconfigsetup.Datadog().GetString("dogstatsd_socket")
 	if s == "" {
@@ -191,6 +244,9 @@ func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliPar
 			case msg := <-reader.Traffic:
 				// The cadence is enforced by the reader. The reader will only write to
 				// the traffic channel when it estimates the payload should be submitted.
+				if !transform.Apply(msg) {
+					continue
+				}
 				n, oobn, err := conn.(*net.UnixConn).WriteMsgUnix(
 					msg.Payload[:msg.PayloadSize], replay.GetUcredsForPid(msg.Pid), addr)
 				if err != nil {
@@ -225,3 +281,108 @@ func dogstatsdReplay(_ log.Component, config config.Component, cliParams *cliPar
 	fmt.Println("replay done")
 	return err
 }
+
+// dogstatsdExport writes the packets of the capture as plain-text statsd
+// lines, once, without replaying them.
+func dogstatsdExport(cliParams *cliParams, transform *replay.TrafficTransform) error {
+	reader, err := replay.NewTrafficCaptureReader(cliParams.dsdReplayFilePath, 1, cliParams.dsdMmapReplay)
+	if reader != nil {
+		defer reader.Close()
+	}
+	if err != nil {
+		fmt.Printf("could not open: %s\n", cliParams.dsdReplayFilePath)
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if cliParams.dsdReplayExportPath != "-" {
+		f, err := os.Create(cliParams.dsdReplayExportPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	w := bufio.NewWriter(out)
+
+	reader.Seek(0)
+	for {
+		msg, err := reader.ReadNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if !transform.Apply(msg) {
+			continue
+		}
+		if err := replay.WriteStatsdLines(w, msg.Payload[:msg.PayloadSize]); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// newTrafficTransform returns the transformation of the replayed traffic
+// described by the flags, or nil if there is none.
+func newTrafficTransform(cliParams *cliParams) (*replay.TrafficTransform, error) {
+	if cliParams.dsdReplayFilter == "" && len(cliParams.dsdReplayTagRewrite) == 0 && len(cliParams.dsdReplayPidRewrite) == 0 {
+		return nil, nil
+	}
+
+	transform := &replay.TrafficTransform{}
+	if cliParams.dsdReplayFilter != "" {
+		filter, err := regexp.Compile(cliParams.dsdReplayFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --filter: %w", err)
+		}
+		transform.NameFilter = filter
+	}
+
+	for _, rewrite := range cliParams.dsdReplayTagRewrite {
+		from, to, ok := strings.Cut(rewrite, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid --rewrite-tag %q, must be FROM=TO", rewrite)
+		}
+		if transform.TagRewrites == nil {
+			transform.TagRewrites = make(map[string]string)
+		}
+		transform.TagRewrites[from] = to
+	}
+
+	for _, rewrite := range cliParams.dsdReplayPidRewrite {
+		from, to, ok := strings.Cut(rewrite, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --rewrite-pid %q, must be FROM=TO", rewrite)
+		}
+		fromPid := replay.AnyPid
+		if from != "*" {
+			pid, err := parsePid(from)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --rewrite-pid %q: %w", rewrite, err)
+			}
+			fromPid = pid
+		}
+		toPid, err := parsePid(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --rewrite-pid %q: %w", rewrite, err)
+		}
+		if transform.PidRewrites == nil {
+			transform.PidRewrites = make(map[int32]int32)
+		}
+		transform.PidRewrites[fromPid] = toPid
+	}
+
+	return transform, nil
+}
+
+func parsePid(s string) (int32, error) {
+	pid, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if pid < 0 {
+		return 0, errors.New("PIDs can't be negative")
+	}
+	return int32(pid), nil
+}
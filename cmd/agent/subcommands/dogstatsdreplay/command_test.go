@@ -6,13 +6,18 @@
 package dogstatsdreplay
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/cmd/agent/command"
 	"github.com/DataDog/datadog-agent/comp/core"
 	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -26,3 +31,76 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestCommandTransform(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-replay", "-s", "2.5", "--filter", "^app\\.", "--rewrite-tag", "env=env:test", "--rewrite-tag", "host=", "--rewrite-pid", "*=1", "-e", "-"},
+		dogstatsdReplay,
+		func(cliParams *cliParams, _ core.BundleParams) {
+			require.Equal(t, 2.5, cliParams.dsdReplaySpeed)
+			require.Equal(t, "^app\\.", cliParams.dsdReplayFilter)
+			require.Equal(t, []string{"env=env:test", "host="}, cliParams.dsdReplayTagRewrite)
+			require.Equal(t, []string{"*=1"}, cliParams.dsdReplayPidRewrite)
+			require.Equal(t, "-", cliParams.dsdReplayExportPath)
+		})
+}
+
+func TestCommandInvalidSpeed(t *testing.T) {
+	for _, speed := range []string{"-1", "NaN", "+Inf", "fast"} {
+		cmd := Commands(&command.GlobalParams{})[0]
+		assert.Error(t, cmd.ParseFlags([]string{"--speed", speed}), speed)
+	}
+
+	cmd := Commands(&command.GlobalParams{})[0]
+	require.NoError(t, cmd.ParseFlags([]string{"--speed", "0"}))
+	assert.Equal(t, "0", cmd.Flag("speed").Value.String())
+	cmd = Commands(&command.GlobalParams{})[0]
+	require.NoError(t, cmd.ParseFlags(nil))
+	assert.Equal(t, "1", cmd.Flag("speed").Value.String())
+}
+
+func TestNewTrafficTransform(t *testing.T) {
+	transform, err := newTrafficTransform(&cliParams{})
+	require.NoError(t, err)
+	assert.Nil(t, transform)
+
+	transform, err = newTrafficTransform(&cliParams{
+		dsdReplayFilter:     "^app\\.",
+		dsdReplayTagRewrite: []string{"env=env:test", "host="},
+		dsdReplayPidRewrite: []string{"10=20", "*=1"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "^app\\.", transform.NameFilter.String())
+	assert.Equal(t, map[string]string{"env": "env:test", "host": ""}, transform.TagRewrites)
+	assert.Equal(t, map[int32]int32{10: 20, replay.AnyPid: 1}, transform.PidRewrites)
+
+	for _, params := range []*cliParams{
+		{dsdReplayFilter: "("},
+		{dsdReplayTagRewrite: []string{"env"}},
+		{dsdReplayTagRewrite: []string{"=env:test"}},
+		{dsdReplayPidRewrite: []string{"10"}},
+		{dsdReplayPidRewrite: []string{"a=1"}},
+		{dsdReplayPidRewrite: []string{"1=*"}},
+		{dsdReplayPidRewrite: []string{"-2=1"}},
+	} {
+		_, err := newTrafficTransform(params)
+		assert.Error(t, err)
+	}
+}
+
+func TestDogstatsdExport(t *testing.T) {
+	params := &cliParams{
+		dsdReplayFilePath:   "../../../../comp/dogstatsd/replay/impl/resources/test/datadog-capture.dog",
+		dsdReplayExportPath: filepath.Join(t.TempDir(), "export.txt"),
+	}
+	transform, err := newTrafficTransform(&cliParams{dsdReplayTagRewrite: []string{"shell=shell:replay"}})
+	require.NoError(t, err)
+	require.NoError(t, dogstatsdExport(params, transform))
+
+	content, err := os.ReadFile(params.dsdReplayExportPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	require.Len(t, lines, 21)
+	assert.Equal(t, "jaime.uds.test:8|g|#shell:replay", lines[0])
+}
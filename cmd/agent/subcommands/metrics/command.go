@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2025-present Datadog, Inc.
+
+// Package metrics implements 'agent metrics'.
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/api/util"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+
+	"github.com/spf13/cobra"
+)
+
+// cliParams are the command-line arguments for this subcommand
+type cliParams struct {
+	*command.GlobalParams
+
+	// subcommand-specific flags
+
+	name            string
+	tags            []string
+	jsonOutput      bool
+	prettyPrintJSON bool
+}
+
+// Commands returns a slice of subcommands for the 'agent' command.
+func Commands(globalParams *command.GlobalParams) []*cobra.Command {
+	cliParams := &cliParams{
+		GlobalParams: globalParams,
+	}
+
+	metricsCmd := &cobra.Command{
+		Use:   "metrics [name pattern]",
+		Short: "Print the metrics recently flushed by the agent",
+		Long: `Print the series and sketches recently flushed by the agent whose name matches
+the pattern and whose tags match all the --tag patterns. The patterns accept the
+* and ? wildcards, e.g. 'agent metrics "system.cpu.*" --tag "env:*"'.
+
+The agent only keeps the flushed metrics when aggregator_flushed_metrics_buffer_size
+is set to the number of metrics to keep, e.g. 10000.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				cliParams.name = args[0]
+			}
+			return fxutil.OneShot(requestFlushedMetrics,
+				fx.Supply(cliParams),
+				fx.Supply(command.GetDefaultCoreBundleParams(cliParams.GlobalParams)),
+				core.Bundle(),
+			)
+		},
+	}
+
+	metricsCmd.Flags().StringArrayVarP(&cliParams.tags, "tag", "t", nil, "only print the metrics with a tag matching this pattern, can be repeated")
+	metricsCmd.Flags().BoolVarP(&cliParams.jsonOutput, "json", "j", false, "print out raw json")
+	metricsCmd.Flags().BoolVarP(&cliParams.prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
+
+	return []*cobra.Command{metricsCmd}
+}
+
+//nolint:revive // TODO(AML) Fix revive linter
+func requestFlushedMetrics(_ log.Component, config config.Component, cliParams *cliParams) error {
+	c := util.GetClient(false) // FIX: get certificates right then make this true
+	ipcAddress, err := pkgconfigsetup.GetIPCAddress(pkgconfigsetup.Datadog())
+	if err != nil {
+		return err
+	}
+	query := url.Values{}
+	if cliParams.name != "" {
+		query.Set("name", cliParams.name)
+	}
+	for _, tag := range cliParams.tags {
+		query.Add("tag", tag)
+	}
+	urlstr := fmt.Sprintf("https://%v:%v/agent/flushed-metrics?%s", ipcAddress, pkgconfigsetup.Datadog().GetInt("cmd_port"), query.Encode())
+
+	// Set session token
+	if err := util.SetAuthToken(config); err != nil {
+		return err
+	}
+
+	r, err := util.DoGet(c, urlstr, util.LeaveConnectionOpen)
+	if err != nil {
+		var errMap = make(map[string]string)
+		json.Unmarshal(r, &errMap) //nolint:errcheck
+		// If the error has been marshalled into a json object, check it and return it properly
+		if e, found := errMap["error"]; found {
+			err = errors.New(e)
+		}
+
+		fmt.Printf("Could not reach agent: %v \nMake sure the agent is running before requesting the flushed metrics and contact support if you continue having issues. \n", err)
+		return err
+	}
+
+	if cliParams.prettyPrintJSON {
+		var prettyJSON bytes.Buffer
+		json.Indent(&prettyJSON, r, "", "  ") //nolint:errcheck
+		fmt.Println(prettyJSON.String())
+		return nil
+	} else if cliParams.jsonOutput {
+		fmt.Println(string(r))
+		return nil
+	}
+
+	var flushed []aggregator.FlushedMetric
+	if err := json.Unmarshal(r, &flushed); err != nil {
+		return fmt.Errorf("unable to parse the flushed metrics: %w", err)
+	}
+	if len(flushed) == 0 {
+		fmt.Println("No metric matching the query was flushed recently.")
+		if config.GetInt("aggregator_flushed_metrics_buffer_size") <= 0 {
+			fmt.Println("Keeping the flushed metrics is disabled, set aggregator_flushed_metrics_buffer_size to enable it.")
+		}
+		return nil
+	}
+	printFlushedMetrics(os.Stdout, flushed)
+	return nil
+}
+
+// printFlushedMetrics prints a table of the points of the flushed metrics.
+func printFlushedMetrics(w io.Writer, flushed []aggregator.FlushedMetric) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTYPE\tTAGS\tTIMESTAMP\tVALUE")
+	for _, m := range flushed {
+		tags := strings.Join(m.Tags, ",")
+		for _, p := range m.Points {
+			ts := time.Unix(p.Timestamp, 0).UTC().Format(time.RFC3339)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Name, m.Type, tags, ts, formatFlushedPoint(p))
+		}
+	}
+	tw.Flush()
+}
+
+func formatFlushedPoint(p aggregator.FlushedPoint) string {
+	if s := p.Sketch; s != nil {
+		return fmt.Sprintf("count=%d sum=%s min=%s max=%s avg=%s", s.Count,
+			formatFloat(s.Sum), formatFloat(s.Min), formatFloat(s.Max), formatFloat(s.Avg))
+	}
+	return formatFloat(p.Value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2025-present Datadog, Inc.
+
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/command"
+	"github.com/DataDog/datadog-agent/comp/core"
+	"github.com/DataDog/datadog-agent/comp/core/secrets"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+func TestCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"metrics", "system.*", "--tag", "env:*", "-t", "host:a", "--json"},
+		requestFlushedMetrics,
+		func(cliParams *cliParams, _ core.BundleParams, secretParams secrets.Params) {
+			require.Equal(t, "system.*", cliParams.name)
+			require.Equal(t, []string{"env:*", "host:a"}, cliParams.tags)
+			require.True(t, cliParams.jsonOutput)
+			require.Equal(t, false, secretParams.Enabled)
+		})
+}
+
+func TestPrintFlushedMetrics(t *testing.T) {
+	var b bytes.Buffer
+	printFlushedMetrics(&b, []aggregator.FlushedMetric{
+		{
+			Name:   "system.load.1",
+			Type:   "gauge",
+			Tags:   []string{"env:prod", "role:db"},
+			Points: []aggregator.FlushedPoint{{Timestamp: 1700000000, Value: 0.5}},
+		},
+		{
+			Name: "request.latency",
+			Type: "distribution",
+			Points: []aggregator.FlushedPoint{{
+				Timestamp: 1700000010,
+				Value:     2,
+				Sketch:    &aggregator.FlushedSketch{Count: 3, Sum: 6, Min: 1, Max: 3, Avg: 2},
+			}},
+		},
+	})
+
+	assert.Equal(t, `NAME             TYPE          TAGS              TIMESTAMP             VALUE
+system.load.1    gauge         env:prod,role:db  2023-11-14T22:13:20Z  0.5
+request.latency  distribution                    2023-11-14T22:13:30Z  count=3 sum=6 min=1 max=3 avg=2
+`, b.String())
+}
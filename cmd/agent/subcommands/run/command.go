@@ -51,6 +51,7 @@ import (
 	internalAPI "github.com/DataDog/datadog-agent/comp/api/api/def"
 	authtokenimpl "github.com/DataDog/datadog-agent/comp/api/authtoken/createandfetchimpl"
 	commonendpoints "github.com/DataDog/datadog-agent/comp/api/commonendpoints/fx"
+	metricsendpointfx "github.com/DataDog/datadog-agent/comp/api/metricsendpoint/fx"
 	"github.com/DataDog/datadog-agent/comp/collector/collector"
 	"github.com/DataDog/datadog-agent/comp/collector/collector/collectorimpl"
 	"github.com/DataDog/datadog-agent/comp/core"
@@ -388,8 +389,12 @@ func getSharedFxOption() fx.Option {
 		authtokenimpl.Module(),
 		apiimpl.Module(),
 		commonendpoints.Module(),
-		demultiplexerimpl.Module(demultiplexerimpl.NewDefaultParams(demultiplexerimpl.WithDogstatsdNoAggregationPipelineConfig())),
+		demultiplexerimpl.Module(demultiplexerimpl.NewDefaultParams(
+			demultiplexerimpl.WithDogstatsdNoAggregationPipelineConfig(),
+			demultiplexerimpl.WithOpenMetricsExporterConfig(),
+		)),
 		demultiplexerendpointfx.Module(),
+		metricsendpointfx.Module(),
 		dogstatsd.Bundle(dogstatsdServer.Params{Serverless: false}),
 		fx.Provide(func(logsagent option.Option[logsAgent.Component]) option.Option[logsagentpipeline.Component] {
 			if la, ok := logsagent.Get(); ok {
@@ -469,6 +474,8 @@ func getSharedFxOption() fx.Option {
 					"runtime_block_profile_rate":             commonsettings.NewRuntimeBlockProfileRate(),
 					"dogstatsd_stats":                        internalsettings.NewDsdStatsRuntimeSetting(serverDebug),
 					"dogstatsd_capture_duration":             internalsettings.NewDsdCaptureDurationRuntimeSetting("dogstatsd_capture_duration"),
+					"dogstatsd_tag_filterlist":               internalsettings.NewDsdTagFilterListRuntimeSetting(),
+					"aggregator_rollup_rules":                internalsettings.NewAggregatorRollupRulesRuntimeSetting(),
 					"log_payloads":                           commonsettings.NewLogPayloadsRuntimeSetting(),
 					"internal_profiling_goroutines":          commonsettings.NewProfilingGoroutines(),
 					"multi_region_failover.enabled":          internalsettings.NewMultiRegionFailoverRuntimeSetting("multi_region_failover.enabled", "Enable/disable Multi-Region Failover support."),
@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// AggregatorRollupRulesRuntimeSetting wraps operations to change the rollup rules of the metrics at runtime.
+type AggregatorRollupRulesRuntimeSetting struct{}
+
+// NewAggregatorRollupRulesRuntimeSetting creates a new instance of AggregatorRollupRulesRuntimeSetting
+func NewAggregatorRollupRulesRuntimeSetting() *AggregatorRollupRulesRuntimeSetting {
+	return &AggregatorRollupRulesRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *AggregatorRollupRulesRuntimeSetting) Description() string {
+	return `Set the rollup rules of the metrics, as a JSON list of {"metric_name", "keep_tags", "full_cardinality_hosts"} objects`
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *AggregatorRollupRulesRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *AggregatorRollupRulesRuntimeSetting) Name() string {
+	return aggregator.RollupRulesKey
+}
+
+// Get returns the current value of the runtime setting
+func (s *AggregatorRollupRulesRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	return config.Get(aggregator.RollupRulesKey), nil
+}
+
+// Set changes the value of the runtime setting; expected to be a JSON list of rules
+func (s *AggregatorRollupRulesRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	var raw []byte
+	if str, ok := v.(string); ok {
+		raw = []byte(str)
+	} else {
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("AggregatorRollupRulesRuntimeSetting: %v", err)
+		}
+	}
+
+	var rules []aggregator.RollupRule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return fmt.Errorf("AggregatorRollupRulesRuntimeSetting: bad parameter value provided: %v", err)
+	}
+	if err := aggregator.ValidateRollupRules(rules); err != nil {
+		return fmt.Errorf("AggregatorRollupRulesRuntimeSetting: %v", err)
+	}
+
+	// store the rules as they would be read from the configuration file
+	var value []interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("AggregatorRollupRulesRuntimeSetting: %v", err)
+	}
+	config.Set(aggregator.RollupRulesKey, value, source)
+	return nil
+}
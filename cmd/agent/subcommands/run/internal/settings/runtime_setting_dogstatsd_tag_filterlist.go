@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// DsdTagFilterListRuntimeSetting wraps operations to change the tag filter rules of the dogstatsd metrics at runtime.
+type DsdTagFilterListRuntimeSetting struct{}
+
+// NewDsdTagFilterListRuntimeSetting creates a new instance of DsdTagFilterListRuntimeSetting
+func NewDsdTagFilterListRuntimeSetting() *DsdTagFilterListRuntimeSetting {
+	return &DsdTagFilterListRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *DsdTagFilterListRuntimeSetting) Description() string {
+	return `Set the tag filter rules of the dogstatsd metrics, as a JSON list of {"metric_name", "action", "tags"} objects`
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *DsdTagFilterListRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *DsdTagFilterListRuntimeSetting) Name() string {
+	return aggregator.TagFilterListKey
+}
+
+// Get returns the current value of the runtime setting
+func (s *DsdTagFilterListRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	return config.Get(aggregator.TagFilterListKey), nil
+}
+
+// Set changes the value of the runtime setting; expected to be a JSON list of rules
+func (s *DsdTagFilterListRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	var raw []byte
+	if str, ok := v.(string); ok {
+		raw = []byte(str)
+	} else {
+		var err error
+		if raw, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("DsdTagFilterListRuntimeSetting: %v", err)
+		}
+	}
+
+	var rules []aggregator.TagFilterRule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return fmt.Errorf("DsdTagFilterListRuntimeSetting: bad parameter value provided: %v", err)
+	}
+	if err := aggregator.ValidateTagFilterRules(rules); err != nil {
+		return fmt.Errorf("DsdTagFilterListRuntimeSetting: %v", err)
+	}
+
+	// store the rules as they would be read from the configuration file
+	var value []interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("DsdTagFilterListRuntimeSetting: %v", err)
+	}
+	config.Set(aggregator.TagFilterListKey, value, source)
+	return nil
+}
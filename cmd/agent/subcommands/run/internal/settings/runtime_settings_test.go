@@ -103,3 +103,66 @@ func TestDogstatsdMetricsStats(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(v, true)
 }
+
+func TestDogstatsdTagFilterList(t *testing.T) {
+	assert := assert.New(t)
+	cfg := config.NewMock(t)
+	s := NewDsdTagFilterListRuntimeSetting()
+
+	// JSON string, as provided by `agent config set`
+
+	err := s.Set(cfg, `[{"metric_name":"http.*","action":"exclude","tags":["request_id"]}]`, model.SourceCLI)
+	assert.Nil(err)
+	v, err := s.Get(cfg)
+	assert.Nil(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"metric_name": "http.*", "action": "exclude", "tags": []interface{}{"request_id"}},
+	}, v)
+
+	// decoded value
+
+	err = s.Set(cfg, []interface{}{
+		map[string]interface{}{"metric_name": "db.queries", "action": "include", "tags": []interface{}{"env"}},
+	}, model.SourceCLI)
+	assert.Nil(err)
+	v, err = s.Get(cfg)
+	assert.Nil(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{"metric_name": "db.queries", "action": "include", "tags": []interface{}{"env"}},
+	}, v)
+
+	// invalid rules don't change the setting
+
+	assert.NotNil(s.Set(cfg, `[{"metric_name":"http.*","action":"drop"}]`, model.SourceCLI))
+	assert.NotNil(s.Set(cfg, `not json`, model.SourceCLI))
+	v, err = s.Get(cfg)
+	assert.Nil(err)
+	assert.Len(v, 1)
+}
+
+func TestAggregatorRollupRules(t *testing.T) {
+	assert := assert.New(t)
+	cfg := config.NewMock(t)
+	s := NewAggregatorRollupRulesRuntimeSetting()
+
+	err := s.Set(cfg, `[{"metric_name":"http.requests","keep_tags":["service","status_code"],"full_cardinality_hosts":["canary-1"]}]`, model.SourceRC)
+	assert.Nil(err)
+	v, err := s.Get(cfg)
+	assert.Nil(err)
+	assert.Equal([]interface{}{
+		map[string]interface{}{
+			"metric_name":            "http.requests",
+			"keep_tags":              []interface{}{"service", "status_code"},
+			"full_cardinality_hosts": []interface{}{"canary-1"},
+		},
+	}, v)
+	assert.Equal(model.SourceRC, cfg.GetSource(s.Name()))
+
+	// invalid rules don't change the setting
+
+	assert.NotNil(s.Set(cfg, `[{"keep_tags":["service"]}]`, model.SourceCLI))
+	assert.NotNil(s.Set(cfg, `[{"metric_name":"http.[","keep_tags":["service"]}]`, model.SourceCLI))
+	v, err = s.Get(cfg)
+	assert.Nil(err)
+	assert.Len(v, 1)
+}
@@ -117,6 +117,9 @@ func createAgentDemultiplexerOptions(config config.Component, params Params) agg
 	if params.useDogstatsdNoAggregationPipelineConfig {
 		options.EnableNoAggregationPipeline = config.GetBool("dogstatsd_no_aggregation_pipeline")
 	}
+	if params.useOpenMetricsExporterConfig {
+		options.OpenMetricsExporterPort = config.GetInt("openmetrics_exporter_port")
+	}
 
 	// Override FlushInterval only if flushInterval is set by the user
 	if v, ok := params.flushInterval.Get(); ok {
@@ -0,0 +1,27 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2023-present Datadog, Inc.
+
+//go:build test
+
+package demultiplexerimpl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+)
+
+func TestCreateAgentDemultiplexerOptionsOpenMetricsExporter(t *testing.T) {
+	cfg := config.NewMockFromYAML(t, "openmetrics_exporter_port: 9100")
+
+	// only the long-running agent starts the exporter
+	options := createAgentDemultiplexerOptions(cfg, NewDefaultParams())
+	assert.Zero(t, options.OpenMetricsExporterPort)
+
+	options = createAgentDemultiplexerOptions(cfg, NewDefaultParams(WithOpenMetricsExporterConfig()))
+	assert.Equal(t, 9100, options.OpenMetricsExporterPort)
+}
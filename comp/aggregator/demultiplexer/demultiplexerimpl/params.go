@@ -19,6 +19,8 @@ type Params struct {
 	flushInterval option.Option[time.Duration]
 
 	useDogstatsdNoAggregationPipelineConfig bool
+
+	useOpenMetricsExporterConfig bool
 }
 
 // Option is a function that sets a parameter in the Params struct
@@ -53,3 +55,12 @@ func WithDogstatsdNoAggregationPipelineConfig() Option {
 		p.useDogstatsdNoAggregationPipelineConfig = true
 	}
 }
+
+// WithOpenMetricsExporterConfig uses the config openmetrics_exporter_port. It
+// must only be used by the long-running agent, as the exporter listens on the
+// port.
+func WithOpenMetricsExporterConfig() Option {
+	return func(p *Params) {
+		p.useOpenMetricsExporterConfig = true
+	}
+}
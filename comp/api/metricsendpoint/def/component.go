@@ -0,0 +1,13 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2025-present Datadog, Inc.
+
+// Package metricsendpoint component provides the /flushed-metrics API endpoint, querying the metrics recently flushed by the aggregator.
+package metricsendpoint
+
+// team: agent-metric-pipelines
+
+// Component is the component type.
+type Component interface {
+}
@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2025-present Datadog, Inc.
+
+// Package metricsendpointimpl component provides the /flushed-metrics API endpoint, querying the metrics recently flushed by the aggregator.
+package metricsendpointimpl
+
+import (
+	"encoding/json"
+	"net/http"
+
+	demultiplexerComp "github.com/DataDog/datadog-agent/comp/aggregator/demultiplexer"
+	api "github.com/DataDog/datadog-agent/comp/api/api/def"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+// Requires defines the dependencies for the metricsendpoint component
+type Requires struct {
+	Log           log.Component
+	Demultiplexer demultiplexerComp.Component
+}
+
+type metricsEndpoint struct {
+	demux demultiplexerComp.Component
+	log   log.Component
+}
+
+// Provides defines the output of the metricsendpoint component
+type Provides struct {
+	Endpoint api.AgentEndpointProvider
+}
+
+// NewComponent creates a new metricsendpoint component
+func NewComponent(reqs Requires) Provides {
+	endpoint := metricsEndpoint{
+		demux: reqs.Demultiplexer,
+		log:   reqs.Log,
+	}
+
+	return Provides{
+		Endpoint: api.NewAgentEndpointProvider(endpoint.writeFlushedMetrics, "/flushed-metrics", "GET"),
+	}
+}
+
+// writeFlushedMetrics writes the metrics recently flushed whose name matches
+// the name parameter and whose tags match all the tag parameters.
+func (endpoint metricsEndpoint) writeFlushedMetrics(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	query := aggregator.FlushedMetricsQuery{
+		Name: params.Get("name"),
+		Tags: params["tag"],
+	}
+	if err := query.Validate(); err != nil {
+		httputils.SetJSONError(w, endpoint.log.Errorf("Invalid flushed metrics query: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	resp, err := json.Marshal(endpoint.demux.FlushedMetrics(query))
+	if err != nil {
+		httputils.SetJSONError(w, endpoint.log.Errorf("Failed to serialize response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
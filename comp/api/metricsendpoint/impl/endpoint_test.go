@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2025-present Datadog, Inc.
+
+package metricsendpointimpl
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	demultiplexerComp "github.com/DataDog/datadog-agent/comp/aggregator/demultiplexer"
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/pkg/aggregator"
+)
+
+type fakeDemultiplexer struct {
+	demultiplexerComp.Component
+	query aggregator.FlushedMetricsQuery
+}
+
+func (d *fakeDemultiplexer) FlushedMetrics(query aggregator.FlushedMetricsQuery) []aggregator.FlushedMetric {
+	d.query = query
+	return []aggregator.FlushedMetric{{Name: "my.metric", Type: "gauge", Tags: []string{"env:prod"}}}
+}
+
+func TestWriteFlushedMetrics(t *testing.T) {
+	demux := &fakeDemultiplexer{}
+	endpoint := metricsEndpoint{demux: demux, log: logmock.New(t)}
+
+	rr := httptest.NewRecorder()
+	endpoint.writeFlushedMetrics(rr, httptest.NewRequest("GET", "/flushed-metrics?name=my.*&tag=env:*&tag=host:a", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, aggregator.FlushedMetricsQuery{Name: "my.*", Tags: []string{"env:*", "host:a"}}, demux.query)
+
+	var flushed []aggregator.FlushedMetric
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &flushed))
+	assert.Equal(t, []aggregator.FlushedMetric{{Name: "my.metric", Type: "gauge", Tags: []string{"env:prod"}}}, flushed)
+}
+
+func TestWriteFlushedMetricsInvalidQuery(t *testing.T) {
+	endpoint := metricsEndpoint{demux: &fakeDemultiplexer{}, log: logmock.New(t)}
+
+	rr := httptest.NewRecorder()
+	endpoint.writeFlushedMetrics(rr, httptest.NewRequest("GET", "/flushed-metrics?name=my.%5B", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
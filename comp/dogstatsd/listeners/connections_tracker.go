@@ -7,6 +7,7 @@
 package listeners
 
 import (
+	"crypto/tls"
 	"net"
 	"time"
 
@@ -98,6 +99,8 @@ func (t *ConnectionTracker) HandleConnections() {
 					err = c.CloseWrite()
 				case *net.UnixConn:
 					err = c.CloseWrite()
+				case *tls.Conn:
+					err = c.CloseWrite()
 				}
 
 				if err != nil {
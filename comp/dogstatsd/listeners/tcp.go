@@ -0,0 +1,350 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tcpExpvars             = expvar.NewMap("dogstatsd-tcp")
+	tcpPacketReadingErrors = expvar.Int{}
+	tcpPackets             = expvar.Int{}
+	tcpBytes               = expvar.Int{}
+)
+
+func init() {
+	tcpExpvars.Set("PacketReadingErrors", &tcpPacketReadingErrors)
+	tcpExpvars.Set("Packets", &tcpPackets)
+	tcpExpvars.Set("Bytes", &tcpBytes)
+}
+
+const (
+	// TCPFramingLengthPrefixed prefixes each packet with its length, as a
+	// 32 bits little-endian integer, as on the UDS stream socket.
+	TCPFramingLengthPrefixed = "length_prefixed"
+	// TCPFramingNewline separates the messages with newlines.
+	TCPFramingNewline = "newline"
+)
+
+// tcpTLSHandshakeTimeout bounds the time a client has to complete the TLS
+// handshake.
+var tcpTLSHandshakeTimeout = 10 * time.Second
+
+// TCPListener implements the StatsdListener interface for TCP protocol.
+// It accepts connections on a given TCP address, optionally over TLS, and
+// sends back the packets read on each of them.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	listener                net.Listener
+	connTracker             *ConnectionTracker
+	packetOut               chan packets.Packets
+	sharedPacketPoolManager *packets.PoolManager[packets.Packet]
+	newlineFraming          bool
+	idleTimeout             time.Duration
+
+	packetBufferSize         uint
+	packetBufferFlushTimeout time.Duration
+	telemetryWithListenerID  bool
+
+	listenWg sync.WaitGroup
+
+	// telemetry
+	telemetryStore        *TelemetryStore
+	packetsTelemetryStore *packets.TelemetryStore
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	var url string
+
+	port := cfg.GetString("dogstatsd_tcp_port")
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	var newlineFraming bool
+	switch framing := cfg.GetString("dogstatsd_tcp_framing"); framing {
+	case TCPFramingLengthPrefixed, "":
+	case TCPFramingNewline:
+		newlineFraming = true
+	default:
+		return nil, fmt.Errorf("invalid dogstatsd_tcp_framing %q, must be %q or %q", framing, TCPFramingLengthPrefixed, TCPFramingNewline)
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	certFile := cfg.GetString("dogstatsd_tcp_tls_cert_file")
+	keyFile := cfg.GetString("dogstatsd_tcp_tls_key_file")
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("can't load the TLS certificate: %s", err)
+		}
+		listener = tls.NewListener(listener, &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		})
+	}
+
+	l := &TCPListener{
+		listener:                 listener,
+		connTracker:              NewConnectionTracker("tcp", 1*time.Second),
+		packetOut:                packetOut,
+		sharedPacketPoolManager:  sharedPacketPoolManager,
+		newlineFraming:           newlineFraming,
+		idleTimeout:              cfg.GetDuration("dogstatsd_tcp_idle_timeout"),
+		packetBufferSize:         uint(cfg.GetInt("dogstatsd_packet_buffer_size")),
+		packetBufferFlushTimeout: cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout"),
+		telemetryWithListenerID:  cfg.GetBool("dogstatsd_telemetry_enabled_listener_id"),
+		telemetryStore:           telemetryStore,
+		packetsTelemetryStore:    packetsTelemetryStore,
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), " use of closed network connection") {
+				log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			}
+			break
+		}
+		go func() {
+			l.connTracker.Track(conn)
+			l.handleConnection(conn)
+			l.connTracker.Close(conn)
+		}()
+	}
+}
+
+// handleConnection reads the packets of a connection until it is closed.
+func (l *TCPListener) handleConnection(conn net.Conn) {
+	listenerID := "tcp"
+	if l.telemetryWithListenerID {
+		listenerID = "tcp-" + conn.RemoteAddr().String()
+	}
+
+	packetsBuffer := packets.NewBuffer(
+		l.packetBufferSize,
+		l.packetBufferFlushTimeout,
+		l.packetOut,
+		listenerID,
+		l.packetsTelemetryStore,
+	)
+	l.telemetryStore.tlmTCPConnections.Inc(listenerID)
+	defer func() {
+		packetsBuffer.Flush()
+		packetsBuffer.Close()
+		l.telemetryStore.tlmTCPConnections.Dec(listenerID)
+		if l.telemetryWithListenerID {
+			l.clearTelemetry(listenerID)
+		}
+	}()
+
+	log.Debugf("dogstatsd-tcp: starting to handle %s", conn.RemoteAddr())
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		// The handshake would otherwise happen on the first read, without
+		// any deadline if the idle timeout is disabled.
+		_ = conn.SetDeadline(time.Now().Add(tcpTLSHandshakeTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			log.Debugf("dogstatsd-tcp: dropping connection %s: TLS handshake failed: %v", conn.RemoteAddr(), err)
+			return
+		}
+		_ = conn.SetDeadline(time.Time{})
+	}
+
+	read := l.readLengthPrefixed
+	if l.newlineFraming {
+		read = l.readNewlineDelimited
+	}
+	if err := read(conn, listenerID, packetsBuffer); err != nil {
+		log.Debugf("dogstatsd-tcp: dropping connection %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// readLengthPrefixed reads packets preceded by their length.
+func (l *TCPListener) readLengthPrefixed(conn net.Conn, listenerID string, packetsBuffer *packets.Buffer) error {
+	header := []byte{0, 0, 0, 0}
+	t1 := time.Now()
+	for {
+		l.setReadDeadline(conn)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return l.readError(err, listenerID)
+		}
+		packetLength := binary.LittleEndian.Uint32(header)
+
+		// retrieve an available packet from the packet pool,
+		// which will be pushed back by the server when processed.
+		packet := l.sharedPacketPoolManager.Get()
+		if packetLength > uint32(len(packet.Buffer)) {
+			l.sharedPacketPoolManager.Put(packet)
+			return fmt.Errorf("packet length %d too large", packetLength)
+		}
+
+		l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), listenerID, "tcp", "tcp")
+		n, err := io.ReadFull(conn, packet.Buffer[:packetLength])
+		t1 = time.Now()
+		if err != nil {
+			l.sharedPacketPoolManager.Put(packet)
+			return l.readError(err, listenerID)
+		}
+		l.appendPacket(packet, packet.Buffer[:n], listenerID, packetsBuffer)
+	}
+}
+
+// readNewlineDelimited reads messages separated by newlines, and sends the
+// complete ones read so far in a single packet.
+func (l *TCPListener) readNewlineDelimited(conn net.Conn, listenerID string, packetsBuffer *packets.Buffer) error {
+	packet := l.sharedPacketPoolManager.Get()
+	defer func() { l.sharedPacketPoolManager.Put(packet) }()
+
+	buffer := make([]byte, len(packet.Buffer))
+	startWriteIndex := 0
+	// discarding is set while skipping the rest of a message bigger than the
+	// buffer, up to its '\n'.
+	discarding := false
+	t1 := time.Now()
+	for {
+		l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), listenerID, "tcp", "tcp")
+		l.setReadDeadline(conn)
+		n, err := conn.Read(buffer[startWriteIndex:])
+		t1 = time.Now()
+		if err != nil {
+			return l.readError(err, listenerID)
+		}
+		data := buffer[:startWriteIndex+n]
+
+		if discarding {
+			end := bytes.IndexByte(data, '\n')
+			if end < 0 {
+				startWriteIndex = 0
+				continue
+			}
+			data = data[end+1:]
+			discarding = false
+		}
+
+		// When there is no '\n', the message is partial and messageSize is 0.
+		messageSize := bytes.LastIndexByte(data, '\n') + 1
+		if messageSize > 0 {
+			l.appendPacket(packet, data[:messageSize], listenerID, packetsBuffer)
+			packet = l.sharedPacketPoolManager.Get()
+		}
+
+		remaining := len(data) - messageSize
+		if remaining >= len(buffer) {
+			// The message is bigger than the buffer, drop it up to its end
+			// and continue reading the next ones.
+			log.Debugf("dogstatsd-tcp: message from %s too large, dropping it", conn.RemoteAddr())
+			discarding = true
+			startWriteIndex = 0
+		} else {
+			copy(buffer, data[messageSize:])
+			startWriteIndex = remaining
+		}
+	}
+}
+
+// setReadDeadline closes the connection if the next read doesn't receive
+// anything within the idle timeout, so that idle or half-open connections
+// don't stay open forever.
+func (l *TCPListener) setReadDeadline(conn net.Conn) {
+	if l.idleTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(l.idleTimeout))
+	}
+}
+
+// appendPacket sends the contents in packet to the server.
+func (l *TCPListener) appendPacket(packet *packets.Packet, contents []byte, listenerID string, packetsBuffer *packets.Buffer) {
+	tcpPackets.Add(1)
+	tcpBytes.Add(int64(len(contents)))
+	l.telemetryStore.tlmTCPPackets.Inc(listenerID, "ok")
+	l.telemetryStore.tlmTCPPacketsBytes.Add(float64(len(contents)), listenerID)
+
+	packet.Contents = packet.Buffer[:copy(packet.Buffer, contents)]
+	packet.Origin = ""
+	packet.ProcessID = 0
+	packet.Source = packets.TCP
+
+	// packetsBuffer handles the forwarding of the packets to the dogstatsd server intake channel
+	packetsBuffer.Append(packet)
+}
+
+// readError returns err unless it signals that the connection was closed.
+func (l *TCPListener) readError(err error, listenerID string) error {
+	if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("idle for more than %s", l.idleTimeout)
+	}
+	tcpPacketReadingErrors.Add(1)
+	l.telemetryStore.tlmTCPPackets.Inc(listenerID, "error")
+	return err
+}
+
+func (l *TCPListener) clearTelemetry(id string) {
+	// Since the listener id is volatile we need to make sure we clear the telemetry.
+	l.telemetryStore.tlmListener.Delete(id, "tcp", "tcp")
+	l.telemetryStore.tlmTCPConnections.Delete(id)
+	l.telemetryStore.tlmTCPPackets.Delete(id, "error")
+	l.telemetryStore.tlmTCPPackets.Delete(id, "ok")
+	l.telemetryStore.tlmTCPPacketsBytes.Delete(id)
+}
+
+// Stop closes the TCP listener and its connections, and stops listening
+func (l *TCPListener) Stop() {
+	_ = l.listener.Close()
+	l.connTracker.Stop()
+	l.listenWg.Wait()
+}
@@ -0,0 +1,252 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, cfg map[string]interface{}) (*TCPListener, chan packets.Packets) {
+	cfg["dogstatsd_tcp_port"] = RandomPortName
+	cfg["dogstatsd_packet_buffer_size"] = 1
+	packetsChannel := make(chan packets.Packets, 10)
+	deps := fulfillDepsWithConfig(t, cfg)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	l, err := NewTCPListener(packetsChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	return l, packetsChannel
+}
+
+func receivePacket(t *testing.T, packetsChannel chan packets.Packets) *packets.Packet {
+	select {
+	case pkts := <-packetsChannel:
+		require.Len(t, pkts, 1)
+		return pkts[0]
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func writeLengthPrefixed(t *testing.T, conn net.Conn, contents []byte) {
+	require.NoError(t, binary.Write(conn, binary.LittleEndian, int32(len(contents))))
+	_, err := conn.Write(contents)
+	require.NoError(t, err)
+}
+
+func TestTCPListenerInvalidFraming(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{"dogstatsd_tcp_port": RandomPortName, "dogstatsd_tcp_framing": "json"})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	assert.Error(t, err)
+}
+
+func TestTCPListenerLengthPrefixed(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	contents0 := []byte("daemon:666|g|#sometag1:somevalue1,sometag2:somevalue2")
+	contents1 := []byte("daemon:999|g|#sometag1:somevalue1\ndaemon:1|c")
+	writeLengthPrefixed(t, conn, contents0)
+	writeLengthPrefixed(t, conn, contents1)
+
+	packet := receivePacket(t, packetsChannel)
+	assert.Equal(t, contents0, packet.Contents)
+	assert.Equal(t, packets.TCP, packet.Source)
+	assert.Equal(t, "tcp", packet.ListenerID)
+	assert.Equal(t, contents1, receivePacket(t, packetsChannel).Contents)
+}
+
+func TestTCPListenerDropsConnectionOnLargePacket(t *testing.T) {
+	l, _ := newTestTCPListener(t, map[string]interface{}{"dogstatsd_buffer_size": 16})
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	writeLengthPrefixed(t, conn, []byte("daemon:666|g|#sometag1:somevalue1"))
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	// the connection is either closed or reset, as the packet wasn't read
+	var netErr net.Error
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func TestTCPListenerNewlineDelimited(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{"dogstatsd_tcp_framing": TCPFramingNewline})
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("daemon:666|g\ndaemon:999|g\ndaemon:"))
+	require.NoError(t, err)
+	packet := receivePacket(t, packetsChannel)
+	assert.Equal(t, "daemon:666|g\ndaemon:999|g\n", string(packet.Contents))
+	assert.Equal(t, packets.TCP, packet.Source)
+
+	// the partial message is completed by the next read
+	_, err = conn.Write([]byte("1|c\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "daemon:1|c\n", string(receivePacket(t, packetsChannel).Contents))
+}
+
+func TestTCPListenerNewlineDelimitedDropsLargeMessage(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_framing": TCPFramingNewline,
+		"dogstatsd_buffer_size": 16,
+	})
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the rest of the large message isn't parsed as a new message
+	_, err = conn.Write([]byte("daemon:666|g|#sometag1:somevalue1,sometag2:somevalue2\n"))
+	require.NoError(t, err)
+	_, err = conn.Write([]byte("daemon:999|g\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "daemon:999|g\n", string(receivePacket(t, packetsChannel).Contents))
+}
+
+func TestTCPListenerClosesIdleConnection(t *testing.T) {
+	l, _ := newTestTCPListener(t, map[string]interface{}{"dogstatsd_tcp_idle_timeout": "100ms"})
+	defer l.Stop()
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func TestTCPListenerTLSHandshakeTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { tcpTLSHandshakeTimeout = timeout }(tcpTLSHandshakeTimeout)
+	tcpTLSHandshakeTimeout = 100 * time.Millisecond
+
+	certFile, keyFile := writeTestCertificate(t)
+	l, _ := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_tls_cert_file": certFile,
+		"dogstatsd_tcp_tls_key_file":  keyFile,
+		"dogstatsd_tcp_idle_timeout":  0,
+	})
+	defer l.Stop()
+
+	// the handshake isn't started, the connection is closed after the
+	// handshake timeout
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	var netErr net.Error
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &netErr) && netErr.Timeout())
+}
+
+func TestTCPListenerTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_tls_cert_file": certFile,
+		"dogstatsd_tcp_tls_key_file":  keyFile,
+	})
+	defer l.Stop()
+
+	conn, err := tls.Dial("tcp", l.LocalAddr(), &tls.Config{InsecureSkipVerify: true})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	writeLengthPrefixed(t, conn, []byte("daemon:666|g"))
+	assert.Equal(t, "daemon:666|g", string(receivePacket(t, packetsChannel).Contents))
+}
+
+func TestTCPListenerInvalidCertificate(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_tcp_port":          RandomPortName,
+		"dogstatsd_tcp_tls_cert_file": filepath.Join(t.TempDir(), "missing.pem"),
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	assert.Error(t, err)
+}
+
+func TestTCPListenerStopClosesConnections(t *testing.T) {
+	l, packetsChannel := newTestTCPListener(t, map[string]interface{}{})
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+	writeLengthPrefixed(t, conn, []byte("daemon:666|g"))
+	receivePacket(t, packetsChannel)
+
+	l.Stop()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+// writeTestCertificate writes a self-signed certificate and its key, and
+// returns their paths.
+func writeTestCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
@@ -11,6 +11,8 @@ package mapper
 import (
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -21,8 +23,14 @@ var (
 const (
 	matchTypeWildcard = "wildcard"
 	matchTypeRegex    = "regex"
+
+	actionMap  = "map"
+	actionDrop = "drop"
 )
 
+// metricTypes are the metric types a mapping can convert the metrics to
+var metricTypes = []string{"gauge", "count", "histogram", "distribution", "timing"}
+
 //
 // Those two structs are used to pull data from the configuration into typed struct. We currently load the data from the
 // configuration into MappingProfileConfig and then convert it to MappingProfile.
@@ -40,16 +48,31 @@ type MappingProfileConfig struct {
 
 // MetricMapping represent one mapping rule
 type MetricMappingConfig struct {
-	Match     string            `mapstructure:"match" json:"match" yaml:"match"`
-	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
-	Name      string            `mapstructure:"name" json:"name" yaml:"name"`
-	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Match       string             `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType   string             `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	MatchTags   map[string]string  `mapstructure:"match_tags" json:"match_tags" yaml:"match_tags"`
+	Action      string             `mapstructure:"action" json:"action" yaml:"action"`
+	Name        string             `mapstructure:"name" json:"name" yaml:"name"`
+	Tags        map[string]string  `mapstructure:"tags" json:"tags" yaml:"tags"`
+	RemoveTags  []string           `mapstructure:"remove_tags" json:"remove_tags" yaml:"remove_tags"`
+	RewriteTags []TagRewriteConfig `mapstructure:"rewrite_tags" json:"rewrite_tags" yaml:"rewrite_tags"`
+	MetricType  string             `mapstructure:"metric_type" json:"metric_type" yaml:"metric_type"`
+}
+
+// TagRewriteConfig represent the rewriting of the value of a tag
+type TagRewriteConfig struct {
+	Tag   string `mapstructure:"tag" json:"tag" yaml:"tag"`
+	Match string `mapstructure:"match" json:"match" yaml:"match"`
+	Value string `mapstructure:"value" json:"value" yaml:"value"`
 }
 
 // MetricMapper contains mappings and cache instance
 type MetricMapper struct {
 	Profiles []MappingProfile
 	cache    *mapperCache
+	// matchTagKeys are the keys of the tags used to match the metrics, which
+	// are part of the cache key
+	matchTagKeys []string
 }
 
 // MappingProfile represent a group of mappings
@@ -61,21 +84,41 @@ type MappingProfile struct {
 
 // MetricMapping represent one mapping rule
 type MetricMapping struct {
-	name  string
-	tags  map[string]string
+	name        string
+	tags        map[string]string
+	regex       *regexp.Regexp
+	matchTags   map[string]*regexp.Regexp
+	drop        bool
+	removeTags  map[string]struct{}
+	rewriteTags []tagRewrite
+	metricType  string
+}
+
+type tagRewrite struct {
+	key   string
 	regex *regexp.Regexp
+	value string
 }
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
+	Name string
+	// Tags are the tags to add to the metric
+	Tags []string
+	// MetricType is the type the metric is converted to, if not empty
+	MetricType string
+	// Drop is true if the metric must be dropped
+	Drop    bool
 	matched bool
+
+	removeTags  map[string]struct{}
+	rewriteTags []tagRewrite
 }
 
 // NewMetricMapper creates, validates, prepares a new MetricMapper
 func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*MetricMapper, error) {
 	profiles := make([]MappingProfile, 0, len(configProfiles))
+	matchTagKeys := make(map[string]struct{})
 	for profileIndex, configProfile := range configProfiles {
 		if configProfile.Name == "" {
 			return nil, fmt.Errorf("missing profile name %d", profileIndex)
@@ -89,24 +132,14 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			Mappings: make([]*MetricMapping, 0, len(configProfile.Mappings)),
 		}
 		for i, currentMapping := range configProfile.Mappings {
-			matchType := currentMapping.MatchType
-			if matchType == "" {
-				matchType = matchTypeWildcard
-			}
-			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match type, must be `wildcard` or `regex`", profile.Name, i)
-			}
-			if currentMapping.Name == "" {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: name is required", profile.Name, i)
-			}
-			if currentMapping.Match == "" {
-				return nil, fmt.Errorf("profile: %s, mapping num %d: match is required", profile.Name, i)
-			}
-			regex, err := buildRegex(currentMapping.Match, matchType)
+			mapping, err := newMetricMapping(currentMapping)
 			if err != nil {
-				return nil, err
+				return nil, fmt.Errorf("profile: %s, mapping num %d: %v", profile.Name, i, err)
 			}
-			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
+			for key := range mapping.matchTags {
+				matchTagKeys[key] = struct{}{}
+			}
+			profile.Mappings = append(profile.Mappings, mapping)
 		}
 		profiles = append(profiles, profile)
 	}
@@ -114,7 +147,79 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 	if err != nil {
 		return nil, err
 	}
-	return &MetricMapper{Profiles: profiles, cache: cache}, nil
+	mapper := &MetricMapper{Profiles: profiles, cache: cache}
+	for key := range matchTagKeys {
+		mapper.matchTagKeys = append(mapper.matchTagKeys, key)
+	}
+	sort.Strings(mapper.matchTagKeys)
+	return mapper, nil
+}
+
+func newMetricMapping(config MetricMappingConfig) (*MetricMapping, error) {
+	matchType := config.MatchType
+	if matchType == "" {
+		matchType = matchTypeWildcard
+	}
+	if matchType != matchTypeWildcard && matchType != matchTypeRegex {
+		return nil, fmt.Errorf("invalid match type, must be `wildcard` or `regex`")
+	}
+	action := config.Action
+	if action == "" {
+		action = actionMap
+	}
+	if action != actionMap && action != actionDrop {
+		return nil, fmt.Errorf("invalid action, must be `map` or `drop`")
+	}
+	if config.Name == "" && action != actionDrop {
+		return nil, fmt.Errorf("name is required")
+	}
+	if config.Match == "" {
+		return nil, fmt.Errorf("match is required")
+	}
+	if config.MetricType != "" && !slices.Contains(metricTypes, config.MetricType) {
+		return nil, fmt.Errorf("invalid metric type `%s`, must be one of %v", config.MetricType, metricTypes)
+	}
+	regex, err := buildRegex(config.Match, matchType)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &MetricMapping{
+		name:       config.Name,
+		tags:       config.Tags,
+		regex:      regex,
+		drop:       action == actionDrop,
+		metricType: config.MetricType,
+	}
+	for key, match := range config.MatchTags {
+		if mapping.matchTags == nil {
+			mapping.matchTags = make(map[string]*regexp.Regexp, len(config.MatchTags))
+		}
+		if mapping.matchTags[key], err = buildRegex(match, matchTypeRegex); err != nil {
+			return nil, fmt.Errorf("tag `%s`: %v", key, err)
+		}
+	}
+	for _, key := range config.RemoveTags {
+		if mapping.removeTags == nil {
+			mapping.removeTags = make(map[string]struct{}, len(config.RemoveTags))
+		}
+		mapping.removeTags[key] = struct{}{}
+	}
+	for _, rewrite := range config.RewriteTags {
+		if rewrite.Tag == "" {
+			return nil, fmt.Errorf("the tag of the tags to rewrite is required")
+		}
+		match := rewrite.Match
+		if match == "" {
+			match = ".*"
+		}
+		regex, err := buildRegex(match, matchTypeRegex)
+		if err != nil {
+			return nil, fmt.Errorf("tag `%s`: %v", rewrite.Tag, err)
+		}
+		mapping.rewriteTags = append(mapping.rewriteTags, tagRewrite{key: rewrite.Tag, regex: regex, value: rewrite.Value})
+	}
+	return mapping, nil
 }
 
 func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
@@ -135,13 +240,20 @@ func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 	return regex, nil
 }
 
-// Map returns a MapResult
+// Map returns a MapResult, or nil if the metric isn't mapped
 func (m *MetricMapper) Map(metricName string) *MapResult {
+	return m.MapWithTags(metricName, nil)
+}
+
+// MapWithTags returns the MapResult of a metric with the given tags, or nil
+// if the metric isn't mapped
+func (m *MetricMapper) MapWithTags(metricName string, tags []string) *MapResult {
 	for _, profile := range m.Profiles {
 		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
 			continue
 		}
-		result, cached := m.cache.get(metricName)
+		cacheKey := m.cacheKey(metricName, tags)
+		result, cached := m.cache.get(cacheKey)
 		if cached {
 			if result.matched {
 				return result
@@ -150,10 +262,16 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 		}
 		for _, mapping := range profile.Mappings {
 			matches := mapping.regex.FindStringSubmatchIndex(metricName)
-			if len(matches) == 0 {
+			if len(matches) == 0 || !mapping.matchesTags(tags) {
 				continue
 			}
 
+			if mapping.drop {
+				mapResult := &MapResult{Drop: true, matched: true}
+				m.cache.add(cacheKey, mapResult)
+				return mapResult
+			}
+
 			name := string(mapping.regex.ExpandString(
 				[]byte{},
 				mapping.name,
@@ -161,19 +279,99 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 				matches,
 			))
 
-			tags := make([]string, 0, len(mapping.tags))
+			mappedTags := make([]string, 0, len(mapping.tags))
 			for tagKey, tagValueExpr := range mapping.tags {
 				tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
-				tags = append(tags, tagKey+":"+tagValue)
+				mappedTags = append(mappedTags, tagKey+":"+tagValue)
 			}
 
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
-			m.cache.add(metricName, mapResult)
+			mapResult := &MapResult{
+				Name:        name,
+				matched:     true,
+				Tags:        mappedTags,
+				MetricType:  mapping.metricType,
+				removeTags:  mapping.removeTags,
+				rewriteTags: mapping.rewriteTags,
+			}
+			m.cache.add(cacheKey, mapResult)
 			return mapResult
 		}
 		mapResult := &MapResult{matched: false}
-		m.cache.add(metricName, mapResult)
+		m.cache.add(cacheKey, mapResult)
 		return nil
 	}
 	return nil
 }
+
+// cacheKey returns the key of the result of the mapping in the cache: the
+// metric name, followed by the values of the tags used to match the metrics.
+func (m *MetricMapper) cacheKey(metricName string, tags []string) string {
+	if len(m.matchTagKeys) == 0 {
+		return metricName
+	}
+	var b strings.Builder
+	b.WriteString(metricName)
+	for _, key := range m.matchTagKeys {
+		b.WriteByte(0)
+		if value, ok := tagValue(tags, key); ok {
+			b.WriteByte(1)
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+// matchesTags returns whether the tags match the tags patterns of the mapping.
+func (mapping *MetricMapping) matchesTags(tags []string) bool {
+	for key, regex := range mapping.matchTags {
+		value, ok := tagValue(tags, key)
+		if !ok || !regex.MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// TransformTags removes and rewrites the tags of the metric as configured by
+// the mapping, modifying tags in place, and returns the resulting tags.
+func (r *MapResult) TransformTags(tags []string) []string {
+	if len(r.removeTags) == 0 && len(r.rewriteTags) == 0 {
+		return tags
+	}
+	n := 0
+	for _, tag := range tags {
+		key, value := splitTag(tag)
+		if _, removed := r.removeTags[key]; removed {
+			continue
+		}
+		for _, rewrite := range r.rewriteTags {
+			if rewrite.key != key {
+				continue
+			}
+			if matches := rewrite.regex.FindStringSubmatchIndex(value); matches != nil {
+				tag = key + ":" + string(rewrite.regex.ExpandString([]byte{}, rewrite.value, value, matches))
+				break
+			}
+		}
+		tags[n] = tag
+		n++
+	}
+	return tags[:n]
+}
+
+// tagValue returns the value of the first tag with the given key.
+func tagValue(tags []string, key string) (string, bool) {
+	for _, tag := range tags {
+		if k, v := splitTag(tag); k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func splitTag(tag string) (string, string) {
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		return tag[:i], tag[i+1:]
+	}
+	return tag, ""
+}
@@ -329,6 +329,30 @@ dogstatsd_mapper_profiles:
 				{Name: "foo.bar1.duration", Tags: []string{"bar:bar", "foo:foo_name"}, matched: true},
 			},
 		},
+		{
+			name: "Drop and metric type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.job.duration.*"
+        name: "test.job.duration"
+        metric_type: distribution
+        tags:
+          job_name: "$1"
+`,
+			packets: []string{
+				"test.debug.queue",
+				"test.job.duration.my_job_name",
+			},
+			expectedResults: []MapResult{
+				{Drop: true, matched: true},
+				{Name: "test.job.duration", Tags: []string{"job_name:my_job_name"}, MetricType: "distribution", matched: true},
+			},
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -489,6 +513,59 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "missing profile name",
 		},
+		{
+			name: "Invalid action",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration"
+        action: ignore
+`,
+			expectedError: "invalid action",
+		},
+		{
+			name: "Invalid metric type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration"
+        name: "test.job.duration"
+        metric_type: set
+`,
+			expectedError: "invalid metric type",
+		},
+		{
+			name: "Invalid tag match regex",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration"
+        name: "test.job.duration"
+        match_tags:
+          env: "prod("
+`,
+			expectedError: "cannot compile regex",
+		},
+		{
+			name: "Missing tag to rewrite",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.job.duration"
+        name: "test.job.duration"
+        rewrite_tags:
+          - value: "foo"
+`,
+			expectedError: "the tag of the tags to rewrite is required",
+		},
 		{
 			name: "Missing profile prefix",
 			config: `
@@ -515,6 +592,56 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestMapWithTags(t *testing.T) {
+	mapper, err := getMapper(t, `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: 'test\.request\.(\w+)'
+        match_type: regex
+        match_tags:
+          env: "prod|staging"
+        name: "test.request"
+        tags:
+          endpoint: "$1"
+        remove_tags: ["request_id"]
+        rewrite_tags:
+          - tag: status
+            match: '(\d)\d\d'
+            value: '${1}xx'
+          - tag: host
+            value: "redacted"
+      - match: "test.request.*"
+        action: drop
+`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"env"}, mapper.matchTagKeys)
+
+	tags := []string{"env:prod", "request_id:1234", "status:404", "status:ok", "host:a", "other"}
+	result := mapper.MapWithTags("test.request.login", tags)
+	require.NotNil(t, result)
+	assert.False(t, result.Drop)
+	assert.Equal(t, "test.request", result.Name)
+	assert.Equal(t, []string{"endpoint:login"}, result.Tags)
+	assert.Equal(t, []string{"env:prod", "status:4xx", "status:ok", "host:redacted", "other"}, result.TransformTags(tags))
+
+	// the result depends on the tags matched
+	result = mapper.MapWithTags("test.request.login", []string{"env:dev"})
+	require.NotNil(t, result)
+	assert.True(t, result.Drop)
+	result = mapper.MapWithTags("test.request.login", nil)
+	require.NotNil(t, result)
+	assert.True(t, result.Drop)
+	result = mapper.MapWithTags("test.request.login", []string{"env:staging", "request_id:5678"})
+	require.NotNil(t, result)
+	assert.Equal(t, "test.request", result.Name)
+	assert.Equal(t, []string{"env:staging"}, result.TransformTags([]string{"env:staging", "request_id:5678"}))
+
+	// one result per value of the env tag
+	assert.Equal(t, 4, mapper.cache.cache.Len())
+}
+
 func getMapper(t *testing.T, configString string) (*MetricMapper, error) {
 	var profiles []MappingProfileConfig
 
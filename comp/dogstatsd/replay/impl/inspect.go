@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CaptureStats holds the statistics of the messages of a capture.
+type CaptureStats struct {
+	Packets       int
+	Bytes         int
+	Events        int
+	ServiceChecks int
+	// Duration is the time between the first and the last packets.
+	Duration time.Duration
+	Metrics  map[string]*MetricStats
+}
+
+// MetricStats holds the statistics of the messages of a metric.
+type MetricStats struct {
+	Name     string
+	Messages int
+	// Types holds the number of messages of each metric type.
+	Types map[string]int
+	// Contexts is the number of distinct sets of tags of the metric.
+	Contexts int
+
+	tagsets map[string]struct{}
+}
+
+// SortedMetrics returns the statistics of the metrics, the ones with the most
+// messages first.
+func (s *CaptureStats) SortedMetrics() []*MetricStats {
+	metrics := make([]*MetricStats, 0, len(s.Metrics))
+	for _, m := range s.Metrics {
+		metrics = append(metrics, m)
+	}
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Messages != metrics[j].Messages {
+			return metrics[i].Messages > metrics[j].Messages
+		}
+		return metrics[i].Name < metrics[j].Name
+	})
+	return metrics
+}
+
+// Inspect reads all the packets of the capture, from its start, and returns
+// the statistics of their messages.
+func (tc *TrafficCaptureReader) Inspect() (*CaptureStats, error) {
+	tsResolution := time.Nanosecond
+	if tc.Version < minNanoVersion {
+		tsResolution = time.Second
+	}
+	tc.Seek(0)
+
+	stats := &CaptureStats{Metrics: make(map[string]*MetricStats)}
+	var first, last int64
+	for {
+		msg, err := tc.ReadNext()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if stats.Packets == 0 {
+			first = msg.Timestamp
+		}
+		last = msg.Timestamp
+		stats.Packets++
+		stats.Bytes += int(msg.PayloadSize)
+		forEachMessage(msg.Payload[:msg.PayloadSize], stats.add)
+	}
+
+	for _, m := range stats.Metrics {
+		m.Contexts = len(m.tagsets)
+		m.tagsets = nil
+	}
+	stats.Duration = time.Duration(last-first) * tsResolution
+	return stats, nil
+}
+
+// add counts a message.
+func (s *CaptureStats) add(message []byte) {
+	switch {
+	case bytes.HasPrefix(message, eventPrefix):
+		s.Events++
+		return
+	case bytes.HasPrefix(message, serviceCheckPrefix):
+		s.ServiceChecks++
+		return
+	}
+	name, ok := metricName(message)
+	if !ok {
+		return
+	}
+
+	m, ok := s.Metrics[string(name)]
+	if !ok {
+		m = &MetricStats{
+			Name:    string(name),
+			Types:   make(map[string]int),
+			tagsets: make(map[string]struct{}),
+		}
+		s.Metrics[m.Name] = m
+	}
+	m.Messages++
+
+	fields := bytes.Split(message, []byte("|"))
+	if len(fields) > 1 {
+		m.Types[string(fields[1])]++
+	}
+	var tags []string
+	for _, field := range fields[min(len(fields), 2):] {
+		if len(field) > 0 && field[0] == '#' {
+			tags = strings.Split(string(field[1:]), ",")
+		}
+	}
+	sort.Strings(tags)
+	m.tagsets[strings.Join(tags, ",")] = struct{}{}
+}
@@ -9,6 +9,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"sync" // might be unnecessary
 	"time"
 
@@ -28,6 +29,7 @@ type TrafficCaptureReader struct {
 	fuse        chan struct{}
 	offset      uint32
 	mmap        bool
+	speed       float64
 
 	sync.Mutex
 }
@@ -50,6 +52,7 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	} else {
 		tsResolution = time.Nanosecond
 	}
+	speed := tc.speed
 	tc.Unlock()
 
 	first := int64(0)
@@ -75,8 +78,10 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 			first = msg.Timestamp
 		}
 
-		t := time.Duration(msg.Timestamp-first) * tsResolution
-		time.Sleep(t - time.Since(start))
+		if speed > 0 {
+			t := time.Duration(float64(time.Duration(msg.Timestamp-first)*tsResolution) / speed)
+			time.Sleep(t - time.Since(start))
+		}
 
 		tc.Traffic <- msg
 
@@ -89,6 +94,30 @@ func (tc *TrafficCaptureReader) Read(ready chan struct{}) {
 	}
 }
 
+// SetSpeed sets the factor by which the packets are read faster than they were
+// captured: 2 reads them twice as fast, 0.5 twice as slow, and 0 as fast as
+// possible. It is 1 by default. The speed is left unchanged if it isn't valid.
+func (tc *TrafficCaptureReader) SetSpeed(speed float64) error {
+	if err := ValidateSpeed(speed); err != nil {
+		return err
+	}
+
+	tc.Lock()
+	defer tc.Unlock()
+
+	tc.speed = speed
+	return nil
+}
+
+// ValidateSpeed returns an error if speed isn't a positive number, or 0 to
+// read the packets as fast as possible.
+func ValidateSpeed(speed float64) error {
+	if math.IsNaN(speed) || math.IsInf(speed, 0) || speed < 0 {
+		return fmt.Errorf("invalid speed %v, it must be a positive number, or 0 to replay as fast as possible", speed)
+	}
+	return nil
+}
+
 // Close cleans up any resources used by the TrafficCaptureReader, should not normally
 // be called directly.
 func (tc *TrafficCaptureReader) Close() error {
@@ -56,5 +56,6 @@ func NewTrafficCaptureReader(path string, depth int, mmap bool) (*TrafficCapture
 		Version:     ver,
 		Traffic:     make(chan *pb.UnixDogstatsdMsg, depth),
 		mmap:        mmap,
+		speed:       1,
 	}, nil
 }
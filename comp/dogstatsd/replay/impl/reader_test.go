@@ -7,6 +7,7 @@ package replayimpl
 
 import (
 	"io"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -91,3 +92,16 @@ func TestSeek(t *testing.T) {
 	assert.Equal(t, cnt*i, total)
 
 }
+
+func TestSetSpeed(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	assert.Nil(t, err)
+
+	assert.Nil(t, tc.SetSpeed(0))
+	assert.Nil(t, tc.SetSpeed(2.5))
+	for _, speed := range []float64{-1, math.NaN(), math.Inf(1)} {
+		assert.Error(t, tc.SetSpeed(speed))
+	}
+	// invalid speeds are ignored
+	assert.Equal(t, 2.5, tc.speed)
+}
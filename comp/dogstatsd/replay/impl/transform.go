@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+// AnyPid is the key of TrafficTransform.PidRewrites matching all the PIDs.
+const AnyPid = int32(-1)
+
+var (
+	eventPrefix        = []byte("_e{")
+	serviceCheckPrefix = []byte("_sc")
+)
+
+// TrafficTransform selects and rewrites the messages of the captured packets
+// before they are replayed or exported.
+type TrafficTransform struct {
+	// NameFilter keeps only the metrics whose name matches if it isn't nil,
+	// dropping the events and service checks.
+	NameFilter *regexp.Regexp
+	// TagRewrites replaces the tags equal to a key, or whose name is the key
+	// if it has no value, by the value. Empty values remove the tags.
+	TagRewrites map[string]string
+	// PidRewrites replaces the PIDs of the packets, used by the origin
+	// detection. AnyPid matches all the PIDs without their own rewrite.
+	PidRewrites map[int32]int32
+}
+
+// Apply rewrites the packet in msg, and returns false if none of its
+// messages is left.
+func (t *TrafficTransform) Apply(msg *pb.UnixDogstatsdMsg) bool {
+	if t == nil {
+		return true
+	}
+
+	if pid, ok := t.PidRewrites[msg.Pid]; ok {
+		msg.Pid = pid
+	} else if pid, ok := t.PidRewrites[AnyPid]; ok {
+		msg.Pid = pid
+	}
+
+	if t.NameFilter == nil && len(t.TagRewrites) == 0 {
+		return true
+	}
+
+	var payload []byte
+	forEachMessage(msg.Payload[:msg.PayloadSize], func(message []byte) {
+		if t.NameFilter != nil {
+			name, ok := metricName(message)
+			if !ok || !t.NameFilter.Match(name) {
+				return
+			}
+		}
+		if len(payload) > 0 {
+			payload = append(payload, '\n')
+		}
+		payload = t.appendRewritten(payload, message)
+	})
+
+	msg.Payload = payload
+	msg.PayloadSize = int32(len(payload))
+	return len(payload) > 0
+}
+
+// appendRewritten appends the message with its tags rewritten to dst.
+func (t *TrafficTransform) appendRewritten(dst []byte, message []byte) []byte {
+	if len(t.TagRewrites) == 0 {
+		return append(dst, message...)
+	}
+
+	for i, field := range bytes.Split(message, []byte("|")) {
+		if i > 0 {
+			dst = append(dst, '|')
+		}
+		if i == 0 || len(field) == 0 || field[0] != '#' {
+			dst = append(dst, field...)
+			continue
+		}
+
+		dst = append(dst, '#')
+		first := true
+		for _, tag := range bytes.Split(field[1:], []byte(",")) {
+			rewritten, ok := t.rewriteTag(string(tag))
+			if ok && rewritten == "" {
+				continue
+			}
+			if !first {
+				dst = append(dst, ',')
+			}
+			first = false
+			if ok {
+				dst = append(dst, rewritten...)
+			} else {
+				dst = append(dst, tag...)
+			}
+		}
+	}
+	return dst
+}
+
+// rewriteTag returns the rewrite of the tag, if any.
+func (t *TrafficTransform) rewriteTag(tag string) (string, bool) {
+	if rewritten, ok := t.TagRewrites[tag]; ok {
+		return rewritten, true
+	}
+	if i := strings.IndexByte(tag, ':'); i > 0 {
+		rewritten, ok := t.TagRewrites[tag[:i]]
+		return rewritten, ok
+	}
+	return "", false
+}
+
+// WriteStatsdLines writes the messages of the packet in payload to w, one
+// per line.
+func WriteStatsdLines(w io.Writer, payload []byte) error {
+	var err error
+	forEachMessage(payload, func(message []byte) {
+		if err == nil {
+			_, err = w.Write(message)
+		}
+		if err == nil {
+			_, err = w.Write([]byte{'\n'})
+		}
+	})
+	return err
+}
+
+// forEachMessage calls f for each non-empty message of the packet.
+func forEachMessage(payload []byte, f func(message []byte)) {
+	for len(payload) > 0 {
+		message := payload
+		if i := bytes.IndexByte(payload, '\n'); i >= 0 {
+			message, payload = payload[:i], payload[i+1:]
+		} else {
+			payload = nil
+		}
+		message = bytes.TrimSuffix(message, []byte("\r"))
+		if len(message) > 0 {
+			f(message)
+		}
+	}
+}
+
+// metricName returns the name of the metric message, or false for events and
+// service checks.
+func metricName(message []byte) ([]byte, bool) {
+	if bytes.HasPrefix(message, eventPrefix) || bytes.HasPrefix(message, serviceCheckPrefix) {
+		return nil, false
+	}
+	i := bytes.IndexByte(message, ':')
+	if i <= 0 {
+		return nil, false
+	}
+	return message[:i], true
+}
@@ -0,0 +1,92 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+func newTestMsg(payload string, pid int32) *pb.UnixDogstatsdMsg {
+	return &pb.UnixDogstatsdMsg{Payload: []byte(payload), PayloadSize: int32(len(payload)), Pid: pid}
+}
+
+func TestTrafficTransformFilter(t *testing.T) {
+	transform := &TrafficTransform{NameFilter: regexp.MustCompile(`^app\.`)}
+
+	msg := newTestMsg("app.requests:1|c|#env:prod\nother:1|g\n_e{1,1}:a|b\napp.latency:2|d\n", 10)
+	require.True(t, transform.Apply(msg))
+	assert.Equal(t, "app.requests:1|c|#env:prod\napp.latency:2|d", string(msg.Payload[:msg.PayloadSize]))
+	assert.Equal(t, int32(10), msg.Pid)
+
+	assert.False(t, transform.Apply(newTestMsg("other:1|g", 10)))
+}
+
+func TestTrafficTransformRewrite(t *testing.T) {
+	transform := &TrafficTransform{
+		TagRewrites: map[string]string{"env:prod": "env:staging", "host": "", "team": "team:replay"},
+		PidRewrites: map[int32]int32{10: 20, AnyPid: 30},
+	}
+
+	msg := newTestMsg("app:1|c|@0.5|#env:prod,host:a,team:x,shell\n_sc|app.check|0|#host:a,env:dev", 10)
+	require.True(t, transform.Apply(msg))
+	assert.Equal(t, "app:1|c|@0.5|#env:staging,team:replay,shell\n_sc|app.check|0|#env:dev", string(msg.Payload[:msg.PayloadSize]))
+	assert.Equal(t, int32(20), msg.Pid)
+
+	msg = newTestMsg("app:1|c", 11)
+	require.True(t, transform.Apply(msg))
+	assert.Equal(t, "app:1|c", string(msg.Payload[:msg.PayloadSize]))
+	assert.Equal(t, int32(30), msg.Pid)
+
+	// a nil transform keeps the packets as is
+	var noop *TrafficTransform
+	assert.True(t, noop.Apply(msg))
+}
+
+func TestWriteStatsdLines(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, WriteStatsdLines(&b, []byte("a:1|c\n\nb:2|g\r\nc:3|d")))
+	assert.Equal(t, "a:1|c\nb:2|g\nc:3|d\n", b.String())
+}
+
+func TestInspect(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+	defer tc.Close()
+
+	stats, err := tc.Inspect()
+	require.NoError(t, err)
+	assert.Equal(t, 21, stats.Packets)
+	assert.Equal(t, 630, stats.Bytes)
+	assert.Equal(t, 13*time.Second, stats.Duration)
+
+	metrics := stats.SortedMetrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, "jaime.uds.test", metrics[0].Name)
+	assert.Equal(t, 21, metrics[0].Messages)
+	assert.Equal(t, map[string]int{"g": 21}, metrics[0].Types)
+	assert.Equal(t, 1, metrics[0].Contexts)
+}
+
+func TestCaptureStatsContexts(t *testing.T) {
+	stats := &CaptureStats{Metrics: make(map[string]*MetricStats)}
+	forEachMessage([]byte("a:1|c|#x,y\na:1|c|#y,x\na:1|g|@1|#z\na:1|c\n_e{1,1}:a|b\n_sc|a|0"), stats.add)
+
+	a := stats.Metrics["a"]
+	require.NotNil(t, a)
+	assert.Equal(t, 4, a.Messages)
+	assert.Equal(t, map[string]int{"c": 3, "g": 1}, a.Types)
+	assert.Len(t, a.tagsets, 3)
+	assert.Equal(t, 1, stats.Events)
+	assert.Equal(t, 1, stats.ServiceChecks)
+}
@@ -204,7 +204,10 @@ func (b *batcher) appendServiceCheck(serviceCheck *servicecheck.ServiceCheck) {
 func (b *batcher) appendLateSample(sample metrics.MetricSample) {
 	// if the no aggregation pipeline is not enabled, we fallback on the
 	// main pipeline eventually distributing the samples on multiple samplers.
-	if !b.noAggPipelineEnabled {
+	// The summaries aggregated by the clients are always merged into the
+	// sketches of the main pipeline, as the no aggregation pipeline doesn't
+	// support distributions.
+	if !b.noAggPipelineEnabled || sample.Summary != nil {
 		b.appendSample(sample)
 		return
 	}
@@ -168,6 +168,7 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 		OriginInfo: extractedOrigin,
 		ListenerID: listenerID,
 		Source:     metricSource,
+		Summary:    ddSample.summary,
 	})
 }
 
@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
 	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/util/containers/metrics/provider"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/util/option"
@@ -180,6 +181,7 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 	var cardinality string
 	var optionalField []byte
 	var timestamp time.Time
+	var rawSummary, rawBuckets []byte
 	for message != nil {
 		optionalField, message = nextField(message)
 		switch {
@@ -205,6 +207,11 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 				return dogstatsdMetricSample{}, fmt.Errorf("dogstatsd timestamp should be > 0")
 			}
 			timestamp = time.Unix(ts, 0)
+		// distribution aggregated by the client
+		case bytes.HasPrefix(optionalField, summaryFieldPrefix):
+			rawSummary = optionalField[len(summaryFieldPrefix):]
+		case bytes.HasPrefix(optionalField, bucketsFieldPrefix):
+			rawBuckets = optionalField[len(bucketsFieldPrefix):]
 		// local data
 		case p.dsdOriginEnabled && bytes.HasPrefix(optionalField, localDataPrefix):
 			localData = p.parseLocalData(optionalField[len(localDataPrefix):])
@@ -217,6 +224,28 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 		}
 	}
 
+	// the value of a message carrying a summary is only used by the agents
+	// not supporting them
+	var summary *metrics.DistributionSummary
+	if rawSummary != nil {
+		// histograms and timings are not supported: their median and
+		// percentiles are computed by the Agent from every value received,
+		// which the summaries don't carry.
+		if metricType != distributionType {
+			return dogstatsdMetricSample{}, fmt.Errorf("summaries are only supported for distributions, send the metric as a distribution")
+		}
+		if values != nil {
+			p.float64List.put(values)
+			values = nil
+		}
+		summary, err = parseMetricSampleSummary(rawSummary, rawBuckets)
+		if err != nil {
+			return dogstatsdMetricSample{}, fmt.Errorf("could not parse dogstatsd summary: %v", err)
+		}
+	} else if rawBuckets != nil {
+		return dogstatsdMetricSample{}, fmt.Errorf("buckets require a summary field")
+	}
+
 	return dogstatsdMetricSample{
 		name:         p.interner.LoadOrStore(name),
 		value:        value,
@@ -229,6 +258,7 @@ func (p *parser) parseMetricSample(message []byte) (dogstatsdMetricSample, error
 		externalData: externalData,
 		cardinality:  cardinality,
 		ts:           timestamp,
+		summary:      summary,
 	}, nil
 }
 
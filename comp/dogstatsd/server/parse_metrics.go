@@ -8,8 +8,11 @@ package server
 import (
 	"bytes"
 	"fmt"
-	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
+	"strconv"
 	"time"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 type metricType int
@@ -34,6 +37,12 @@ var (
 	tagsFieldPrefix       = []byte("#")
 	sampleRateFieldPrefix = []byte("@")
 	timestampFieldPrefix  = []byte("T")
+	// summaryFieldPrefix and bucketsFieldPrefix carry a distribution
+	// aggregated by the client: `S:<count>:<sum>:<min>:<max>` and
+	// `B:<value>:<count>,<value>:<count>,...`
+	summaryFieldPrefix = []byte("S:")
+	bucketsFieldPrefix = []byte("B:")
+	bucketsSeparator   = []byte(",")
 )
 
 type dogstatsdMetricSample struct {
@@ -55,6 +64,8 @@ type dogstatsdMetricSample struct {
 	cardinality string
 	// timestamp read in the message if any
 	ts time.Time
+	// summary of a distribution aggregated by the client if any
+	summary *metrics.DistributionSummary
 }
 
 // sanity checks a given message against the metric sample format
@@ -97,6 +108,76 @@ func parseMetricSampleMetricType(rawMetricType []byte) (metricType, error) {
 	return 0, fmt.Errorf("invalid metric type: %q", rawMetricType)
 }
 
+// mapperMetricType returns the metric type named by a mapping of the metric
+// mapper, which validates the names.
+func mapperMetricType(name string) metricType {
+	switch name {
+	case "count":
+		return countType
+	case "histogram":
+		return histogramType
+	case "distribution":
+		return distributionType
+	case "timing":
+		return timingType
+	}
+	return gaugeType
+}
+
 func parseMetricSampleSampleRate(rawSampleRate []byte) (float64, error) {
 	return parseFloat64(rawSampleRate)
 }
+
+// parseMetricSampleSummary parses the `S:` and `B:` fields of a distribution
+// aggregated by the client. rawBuckets is nil when there is no `B:` field.
+func parseMetricSampleSummary(rawSummary []byte, rawBuckets []byte) (*metrics.DistributionSummary, error) {
+	fields := bytes.Split(rawSummary, colonSeparator)
+	if len(fields) != 4 {
+		return nil, fmt.Errorf("invalid summary %q, expected count:sum:min:max", rawSummary)
+	}
+	count, err := strconv.ParseUint(string(fields[0]), 10, 64)
+	if err != nil || count == 0 || count > metrics.MaxDistributionSummaryCount {
+		return nil, fmt.Errorf("invalid summary count %q, expected a count between 1 and %d", fields[0], uint64(metrics.MaxDistributionSummaryCount))
+	}
+	summary := &metrics.DistributionSummary{Count: count}
+	for i, dest := range []*float64{&summary.Sum, &summary.Min, &summary.Max} {
+		if *dest, err = parseFloat64(fields[i+1]); err != nil {
+			return nil, fmt.Errorf("invalid summary value %q: %v", fields[i+1], err)
+		}
+	}
+	if summary.Min > summary.Max {
+		return nil, fmt.Errorf("invalid summary, min %v is greater than max %v", summary.Min, summary.Max)
+	}
+
+	if rawBuckets == nil {
+		return summary, nil
+	}
+	var total uint64
+	for _, rawBucket := range bytes.Split(rawBuckets, bucketsSeparator) {
+		rawValue, rawCount, err := parseMetricSampleNameAndRawValue(rawBucket)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket %q, expected value:count", rawBucket)
+		}
+		value, err := parseFloat64(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket value %q: %v", rawValue, err)
+		}
+		count, err := strconv.ParseUint(string(rawCount), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket count %q: %v", rawCount, err)
+		}
+		// checked before adding it to the total so that it can't overflow
+		if count > summary.Count {
+			return nil, fmt.Errorf("bucket count %d greater than the summary count %d", count, summary.Count)
+		}
+		if value < summary.Min || value > summary.Max {
+			return nil, fmt.Errorf("bucket value %v out of the summary range", value)
+		}
+		summary.Buckets = append(summary.Buckets, metrics.DistributionBucket{Value: value, Count: count})
+		total += count
+	}
+	if total != summary.Count {
+		return nil, fmt.Errorf("bucket counts add up to %d instead of the summary count %d", total, summary.Count)
+	}
+	return summary, nil
+}
@@ -14,6 +14,7 @@ import (
 	"go.uber.org/fx"
 
 	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
 func parseMetricSample(t *testing.T, overrides map[string]any, rawSample []byte) (dogstatsdMetricSample, error) {
@@ -494,6 +495,67 @@ func TestParseGaugeTimestampMalformed(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestParseDistributionSummary(t *testing.T) {
+	sample, err := parseMetricSample(t, make(map[string]any), []byte("latency:2.5|d|#atag|S:4:10:1:4|B:1:1,2:1,3.5:2"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "latency", sample.name)
+	assert.Equal(t, distributionType, sample.metricType)
+	assert.Equal(t, []string{"atag"}, sample.tags)
+	require.NotNil(t, sample.summary)
+	assert.Equal(t, uint64(4), sample.summary.Count)
+	assert.Equal(t, 10.0, sample.summary.Sum)
+	assert.Equal(t, 1.0, sample.summary.Min)
+	assert.Equal(t, 4.0, sample.summary.Max)
+	assert.Equal(t, []metrics.DistributionBucket{{Value: 1, Count: 1}, {Value: 2, Count: 1}, {Value: 3.5, Count: 2}}, sample.summary.Buckets)
+
+	// the buckets are optional, and the fields can come in any order
+	sample, err = parseMetricSample(t, make(map[string]any), []byte("latency:2.5|d|S:4:10:1:4|#atag"))
+	require.NoError(t, err)
+	require.NotNil(t, sample.summary)
+	assert.Equal(t, uint64(4), sample.summary.Count)
+	assert.Empty(t, sample.summary.Buckets)
+
+	sample, err = parseMetricSample(t, make(map[string]any), []byte("latency:2.5|d|#atag"))
+	require.NoError(t, err)
+	assert.Nil(t, sample.summary)
+
+	sample, err = parseMetricSample(t, make(map[string]any), []byte("latency:2.5|d|S:4294967295:10:1:4"))
+	require.NoError(t, err)
+	require.NotNil(t, sample.summary)
+	assert.Equal(t, uint64(metrics.MaxDistributionSummaryCount), sample.summary.Count)
+}
+
+func TestParseDistributionSummaryError(t *testing.T) {
+	for _, message := range []string{
+		// only supported for distributions
+		"latency:2.5|h|S:4:10:1:4",
+		// missing fields
+		"latency:2.5|d|S:4:10:1",
+		// zero count
+		"latency:0|d|S:0:0:0:0",
+		// min greater than max
+		"latency:2.5|d|S:4:10:4:1",
+		// bad value
+		"latency:2.5|d|S:4:abc:1:4",
+		// buckets without summary
+		"latency:2.5|d|B:1:4",
+		// bucket counts not adding up to the count
+		"latency:2.5|d|S:4:10:1:4|B:1:1,4:2",
+		// bucket out of range
+		"latency:2.5|d|S:4:10:1:4|B:1:1,5:3",
+		// malformed bucket
+		"latency:2.5|d|S:4:10:1:4|B:1:1,4",
+		// huge counts
+		"latency:2.5|d|S:18446744073709551615:10:1:4",
+		"latency:2.5|d|S:4294967296:10:1:4",
+		"latency:2.5|d|S:4:10:1:4|B:1:18446744073709551615,4:5",
+	} {
+		_, err := parseMetricSample(t, make(map[string]any), []byte(message))
+		assert.Error(t, err, message)
+	}
+}
+
 func TestParseManyPipes(t *testing.T) {
 	t.Run("Sample rate and container ID (4 pipes)", func(t *testing.T) {
 		sample, err := parseMetricSample(t, make(map[string]any), []byte("example.metric:2.39283|d|@1.000000|#environment:dev|c:2a25f7fc8fbf573d62053d7263dd2d440c07b6ab4d2b107e50b0d4df1f2ee15f"))
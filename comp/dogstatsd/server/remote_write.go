@@ -0,0 +1,482 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	// remoteWritePath is the path of the remote-write endpoint, as used by
+	// the Prometheus remote-write receivers.
+	remoteWritePath = "/api/v1/write"
+	// remoteWriteMaxRequestSize is the maximum size of the payload of a
+	// request, compressed or not.
+	remoteWriteMaxRequestSize = 32 << 20
+	// remoteWriteSeriesExpiry is the time after which the last value of a
+	// counter which wasn't received again is forgotten.
+	remoteWriteSeriesExpiry = 15 * time.Minute
+
+	// headers used for Origin Detection, as on the trace-agent intake
+	remoteWriteLocalDataHeader    = "Datadog-Entity-ID"
+	remoteWriteExternalDataHeader = "Datadog-External-Env"
+)
+
+// remoteWriteReceiver receives the metrics pushed with the Prometheus
+// remote-write protocol, and sends them to the aggregator:
+//   - the counters, as counts of their increase since their previous value,
+//   - the histogram buckets, as distributions where each observation is
+//     approximated by the middle of its bucket,
+//   - the other series, as gauges.
+//
+// Native histograms are not supported.
+type remoteWriteReceiver struct {
+	server     *server
+	listener   net.Listener
+	httpServer *http.Server
+
+	// mu serializes the processing of the requests, which share the batcher
+	// and the state of the series.
+	mu      sync.Mutex
+	batcher dogstatsdBatcher
+	samples []metrics.MetricSample
+	// familyTypes are the types of the metric families sent in the metadata.
+	familyTypes map[string]prompb.MetricMetadata_MetricType
+	// cumulative are the last values of the counters and histogram buckets.
+	cumulative map[string]remoteWriteCumulative
+	lastExpiry time.Time
+
+	tlmRequests telemetry.Counter
+	tlmSamples  telemetry.Counter
+}
+
+type remoteWriteCumulative struct {
+	value    float64
+	lastSeen time.Time
+}
+
+// remoteWriteOrigin holds the Origin Detection data sent in the headers of a
+// request.
+type remoteWriteOrigin struct {
+	localData    origindetection.LocalData
+	externalData origindetection.ExternalData
+}
+
+// remoteWriteHistogram holds the increase of the buckets of a histogram.
+type remoteWriteHistogram struct {
+	name    string
+	tags    []string
+	buckets []remoteWriteBucket
+	// incomplete is true when the increase of some buckets is unknown.
+	incomplete bool
+}
+
+type remoteWriteBucket struct {
+	upperBound float64
+	count      float64
+}
+
+func newRemoteWriteReceiver(s *server, batcher dogstatsdBatcher) *remoteWriteReceiver {
+	return &remoteWriteReceiver{
+		server:      s,
+		batcher:     batcher,
+		familyTypes: make(map[string]prompb.MetricMetadata_MetricType),
+		cumulative:  make(map[string]remoteWriteCumulative),
+		lastExpiry:  time.Now(),
+		tlmRequests: s.telemetry.NewCounter("dogstatsd", "remote_write_requests",
+			[]string{"state"}, "Count of Prometheus remote-write requests received by dogstatsd"),
+		tlmSamples: s.telemetry.NewCounter("dogstatsd", "remote_write_samples",
+			[]string{"state"}, "Count of Prometheus remote-write samples received by dogstatsd"),
+	}
+}
+
+// remoteWriteAddr returns the address the remote-write receiver listens on.
+func remoteWriteAddr(cfg model.Reader) string {
+	port := cfg.GetString("dogstatsd_remote_write_port")
+	if port == listeners.RandomPortName {
+		port = "0"
+	}
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		return ":" + port
+	}
+	return net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+}
+
+// listen starts serving the remote-write requests on addr.
+func (r *remoteWriteReceiver) listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("can't listen: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(remoteWritePath, r)
+	r.listener = listener
+	r.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := r.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.server.log.Errorf("dogstatsd-remote-write: error serving the requests: %v", err)
+		}
+	}()
+	r.server.log.Infof("dogstatsd-remote-write: starting to listen on %s", listener.Addr())
+	return nil
+}
+
+// stop closes the listener and the connections.
+func (r *remoteWriteReceiver) stop() {
+	if r.httpServer != nil {
+		_ = r.httpServer.Close()
+	}
+}
+
+// ServeHTTP decodes a remote-write request and sends its samples to the aggregator.
+func (r *remoteWriteReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		r.tlmRequests.Inc("error")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "" && encoding != "snappy" {
+		r.tlmRequests.Inc("error")
+		http.Error(w, fmt.Sprintf("unsupported content encoding %q", encoding), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	wr, err := readRemoteWriteRequest(w, req)
+	if err != nil {
+		r.tlmRequests.Inc("error")
+		r.server.errLog("dogstatsd-remote-write: invalid request from %s: %v", req.RemoteAddr, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var origin remoteWriteOrigin
+	if localData := req.Header.Get(remoteWriteLocalDataHeader); localData != "" {
+		if origin.localData, err = origindetection.ParseLocalData(localData); err != nil {
+			r.server.log.Debugf("dogstatsd-remote-write: invalid %s header: %v", remoteWriteLocalDataHeader, err)
+		}
+	}
+	if externalData := req.Header.Get(remoteWriteExternalDataHeader); externalData != "" {
+		if origin.externalData, err = origindetection.ParseExternalData(externalData); err != nil {
+			r.server.log.Debugf("dogstatsd-remote-write: invalid %s header: %v", remoteWriteExternalDataHeader, err)
+		}
+	}
+
+	r.process(wr, origin)
+	r.tlmRequests.Inc("ok")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readRemoteWriteRequest reads and decodes the snappy compressed protobuf
+// payload of the request.
+func readRemoteWriteRequest(w http.ResponseWriter, req *http.Request) (*prompb.WriteRequest, error) {
+	compressed, err := io.ReadAll(http.MaxBytesReader(w, req.Body, remoteWriteMaxRequestSize))
+	if err != nil {
+		return nil, fmt.Errorf("can't read the payload: %v", err)
+	}
+	length, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("can't decompress the payload: %v", err)
+	}
+	if length > remoteWriteMaxRequestSize {
+		return nil, fmt.Errorf("uncompressed payload too large: %d bytes", length)
+	}
+	payload, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return nil, fmt.Errorf("can't decompress the payload: %v", err)
+	}
+	var wr prompb.WriteRequest
+	if err := wr.Unmarshal(payload); err != nil {
+		return nil, fmt.Errorf("can't decode the payload: %v", err)
+	}
+	return &wr, nil
+}
+
+// process sends the samples of the request to the aggregator.
+func (r *remoteWriteReceiver) process(wr *prompb.WriteRequest, origin remoteWriteOrigin) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, metadata := range wr.Metadata {
+		r.familyTypes[metadata.MetricFamilyName] = metadata.Type
+	}
+
+	// the _sum and _count series of the histograms and summaries are counters,
+	// whether or not the metadata of the family was received.
+	aggregatedFamilies := make(map[string]struct{})
+	for i := range wr.Timeseries {
+		name, le, quantile := remoteWriteSeriesInfo(&wr.Timeseries[i])
+		if le != "" && strings.HasSuffix(name, "_bucket") {
+			aggregatedFamilies[strings.TrimSuffix(name, "_bucket")] = struct{}{}
+		} else if quantile != "" {
+			aggregatedFamilies[name] = struct{}{}
+		}
+	}
+
+	histograms := make(map[string]*remoteWriteHistogram)
+	for i := range wr.Timeseries {
+		r.processSeries(&wr.Timeseries[i], aggregatedFamilies, histograms, origin, now)
+	}
+	for _, histogram := range histograms {
+		r.sendHistogram(histogram, origin)
+	}
+	r.batcher.flush()
+	r.expire(now)
+}
+
+// remoteWriteSeriesInfo returns the name of the series and the values of its
+// le and quantile labels.
+func remoteWriteSeriesInfo(series *prompb.TimeSeries) (name, le, quantile string) {
+	for _, label := range series.Labels {
+		switch label.Name {
+		case "__name__":
+			name = label.Value
+		case "le":
+			le = label.Value
+		case "quantile":
+			quantile = label.Value
+		}
+	}
+	return name, le, quantile
+}
+
+func (r *remoteWriteReceiver) processSeries(series *prompb.TimeSeries, aggregatedFamilies map[string]struct{}, histograms map[string]*remoteWriteHistogram, origin remoteWriteOrigin, now time.Time) {
+	if len(series.Histograms) > 0 {
+		r.tlmSamples.Add(float64(len(series.Histograms)), "unsupported")
+	}
+	if len(series.Samples) == 0 {
+		return
+	}
+
+	name, le, _ := remoteWriteSeriesInfo(series)
+	if name == "" {
+		r.tlmSamples.Add(float64(len(series.Samples)), "error")
+		return
+	}
+
+	family := strings.TrimSuffix(name, "_bucket")
+	isBucket := le != "" && family != name && r.familyTypes[family] != prompb.MetricMetadata_GAUGEHISTOGRAM
+
+	tags := make([]string, 0, len(series.Labels))
+	for _, label := range series.Labels {
+		if label.Name == "__name__" || label.Value == "" || (isBucket && label.Name == "le") {
+			continue
+		}
+		tags = append(tags, label.Name+":"+label.Value)
+	}
+
+	switch {
+	case isBucket:
+		upperBound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			r.tlmSamples.Add(float64(len(series.Samples)), "error")
+			return
+		}
+		key := family + "\xff" + strings.Join(tags, "\xff")
+		histogram, ok := histograms[key]
+		if !ok {
+			histogram = &remoteWriteHistogram{name: family, tags: tags}
+			histograms[key] = histogram
+		}
+		increase, known := r.increase(series, name, now)
+		histogram.buckets = append(histogram.buckets, remoteWriteBucket{upperBound: upperBound, count: increase})
+		histogram.incomplete = histogram.incomplete || !known
+	case r.isCounter(name, aggregatedFamilies):
+		key := seriesKey(name, series.Labels)
+		for _, sample := range series.Samples {
+			if !isFinite(sample.Value) {
+				r.tlmSamples.Inc("dropped")
+				continue
+			}
+			r.tlmSamples.Inc("ok")
+			if delta, ok := r.delta(key, sample.Value, now); ok {
+				r.send(name, tags, countType, delta, 1, sample.Timestamp, origin)
+			}
+		}
+	default:
+		for _, sample := range series.Samples {
+			// stale markers are NaN values
+			if !isFinite(sample.Value) {
+				r.tlmSamples.Inc("dropped")
+				continue
+			}
+			r.tlmSamples.Inc("ok")
+			r.send(name, tags, gaugeType, sample.Value, 1, sample.Timestamp, origin)
+		}
+	}
+}
+
+// isCounter returns whether the series is a counter, using the metadata of its
+// family if any, and its name otherwise.
+func (r *remoteWriteReceiver) isCounter(name string, aggregatedFamilies map[string]struct{}) bool {
+	for _, suffix := range []string{"_sum", "_count"} {
+		if family, found := strings.CutSuffix(name, suffix); found {
+			if _, ok := aggregatedFamilies[family]; ok {
+				return true
+			}
+			switch r.familyTypes[family] {
+			case prompb.MetricMetadata_HISTOGRAM, prompb.MetricMetadata_SUMMARY:
+				return true
+			}
+		}
+	}
+
+	if metricType, ok := r.familyTypes[name]; ok {
+		return metricType == prompb.MetricMetadata_COUNTER
+	}
+	// the family of the counters doesn't have the _total suffix in OpenMetrics
+	if metricType, ok := r.familyTypes[strings.TrimSuffix(name, "_total")]; ok {
+		return metricType == prompb.MetricMetadata_COUNTER
+	}
+	return strings.HasSuffix(name, "_total")
+}
+
+// increase returns the increase of a cumulative series over its samples, and
+// false if it is unknown as the series wasn't received before.
+func (r *remoteWriteReceiver) increase(series *prompb.TimeSeries, name string, now time.Time) (float64, bool) {
+	key := seriesKey(name, series.Labels)
+	var increase float64
+	known := true
+	for _, sample := range series.Samples {
+		if !isFinite(sample.Value) {
+			r.tlmSamples.Inc("dropped")
+			continue
+		}
+		r.tlmSamples.Inc("ok")
+		delta, ok := r.delta(key, sample.Value, now)
+		increase += delta
+		known = known && ok
+	}
+	return increase, known
+}
+
+// delta returns the increase of a counter since its previous value, and false
+// if it is the first value received.
+func (r *remoteWriteReceiver) delta(key string, value float64, now time.Time) (float64, bool) {
+	previous, ok := r.cumulative[key]
+	r.cumulative[key] = remoteWriteCumulative{value: value, lastSeen: now}
+	if !ok {
+		return 0, false
+	}
+	if value < previous.value {
+		// the counter was reset
+		return value, true
+	}
+	return value - previous.value, true
+}
+
+// sendHistogram sends the observations of each bucket of the histogram to a
+// distribution.
+func (r *remoteWriteReceiver) sendHistogram(histogram *remoteWriteHistogram, origin remoteWriteOrigin) {
+	if histogram.incomplete {
+		return
+	}
+	sort.Slice(histogram.buckets, func(i, j int) bool {
+		return histogram.buckets[i].upperBound < histogram.buckets[j].upperBound
+	})
+
+	var previousCount, lowerBound float64
+	for i, bucket := range histogram.buckets {
+		count := math.Round(bucket.count - previousCount)
+		previousCount = bucket.count
+
+		value := (lowerBound + bucket.upperBound) / 2
+		if i == 0 && bucket.upperBound <= 0 {
+			value = bucket.upperBound
+		} else if math.IsInf(bucket.upperBound, 1) {
+			value = lowerBound
+		}
+		lowerBound = bucket.upperBound
+
+		if count >= 1 {
+			r.send(histogram.name, histogram.tags, distributionType, value, 1/count, 0, origin)
+		}
+	}
+}
+
+// send sends a sample to the aggregator.
+func (r *remoteWriteReceiver) send(name string, tags []string, mtype metricType, value float64, sampleRate float64, timestamp int64, origin remoteWriteOrigin) {
+	sample := dogstatsdMetricSample{
+		name:       name,
+		value:      value,
+		metricType: mtype,
+		sampleRate: sampleRate,
+		// the tags are modified when enriched
+		tags:         append(make([]string, 0, len(tags)+len(r.server.extraTags)), tags...),
+		localData:    origin.localData,
+		externalData: origin.externalData,
+	}
+	if timestamp > 0 {
+		sample.ts = time.UnixMilli(timestamp)
+	}
+
+	r.samples = enrichMetricSample(r.samples[:0], sample, "", 0, "", r.server.enrichConfig)
+	for idx := range r.samples {
+		r.samples[idx].Tags = append(r.samples[idx].Tags, r.server.extraTags...)
+		r.server.Debug.StoreMetricStats(r.samples[idx])
+		if r.samples[idx].Timestamp > 0.0 {
+			r.batcher.appendLateSample(r.samples[idx])
+		} else {
+			r.batcher.appendSample(r.samples[idx])
+		}
+	}
+}
+
+// expire forgets the counters which weren't received recently.
+func (r *remoteWriteReceiver) expire(now time.Time) {
+	if now.Sub(r.lastExpiry) < remoteWriteSeriesExpiry {
+		return
+	}
+	for key, cumulative := range r.cumulative {
+		if now.Sub(cumulative.lastSeen) > remoteWriteSeriesExpiry {
+			delete(r.cumulative, key)
+		}
+	}
+	r.lastExpiry = now
+}
+
+// seriesKey returns a key identifying the series.
+func seriesKey(name string, labels []prompb.Label) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, label := range labels {
+		b.WriteByte('\xff')
+		b.WriteString(label.Name)
+		b.WriteByte('\xff')
+		b.WriteString(label.Value)
+	}
+	return b.String()
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
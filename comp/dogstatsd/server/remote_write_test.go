@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func encodeRemoteWriteRequest(t *testing.T, req *prompb.WriteRequest) []byte {
+	payload, err := req.Marshal()
+	require.NoError(t, err)
+	return payload
+}
+
+func testSeries(name string, value float64, timestamp int64, labels ...string) prompb.TimeSeries {
+	series := prompb.TimeSeries{
+		Labels:  []prompb.Label{{Name: "__name__", Value: name}},
+		Samples: []prompb.Sample{{Value: value, Timestamp: timestamp}},
+	}
+	for i := 0; i+1 < len(labels); i += 2 {
+		series.Labels = append(series.Labels, prompb.Label{Name: labels[i], Value: labels[i+1]})
+	}
+	return series
+}
+
+func newTestRemoteWriteReceiver(t *testing.T) (*remoteWriteReceiver, *batcherMock) {
+	_, s := fulfillDepsWithInactiveServer(t, map[string]interface{}{})
+	batcher := &batcherMock{}
+	return newRemoteWriteReceiver(s, batcher), batcher
+}
+
+func TestReadRemoteWriteRequest(t *testing.T) {
+	req := &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{
+			testSeries("http_requests_total", 12, 1700000000000, "code", "200"),
+		},
+		Metadata: []prompb.MetricMetadata{{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "http_requests", Help: "help"}},
+	}
+	payload := encodeRemoteWriteRequest(t, req)
+
+	httpReq := httptest.NewRequest(http.MethodPost, remoteWritePath, bytes.NewReader(snappy.Encode(nil, payload)))
+	decoded, err := readRemoteWriteRequest(httptest.NewRecorder(), httpReq)
+	require.NoError(t, err)
+	assert.Equal(t, req.Timeseries, decoded.Timeseries)
+	assert.Equal(t, req.Metadata, decoded.Metadata)
+
+	httpReq = httptest.NewRequest(http.MethodPost, remoteWritePath, bytes.NewReader(snappy.Encode(nil, payload[:len(payload)-3])))
+	_, err = readRemoteWriteRequest(httptest.NewRecorder(), httpReq)
+	assert.Error(t, err)
+}
+
+func TestRemoteWriteNativeHistogramsAreIgnored(t *testing.T) {
+	r, batcher := newTestRemoteWriteReceiver(t)
+
+	r.process(&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{{
+		Labels:     []prompb.Label{{Name: "__name__", Value: "native"}},
+		Histograms: []prompb.Histogram{{Count: &prompb.Histogram_CountInt{CountInt: 3}, Timestamp: 1700000000000}},
+	}}}, remoteWriteOrigin{})
+
+	assert.Empty(t, batcher.samples)
+	assert.Empty(t, batcher.lateSamples)
+}
+
+func TestRemoteWriteGauges(t *testing.T) {
+	r, batcher := newTestRemoteWriteReceiver(t)
+
+	r.process(&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		testSeries("temperature", 21.5, 1700000000000, "room", "kitchen", "empty", ""),
+		testSeries("temperature", math.NaN(), 1700000001000, "room", "garage"),
+	}}, remoteWriteOrigin{})
+
+	require.Len(t, batcher.lateSamples, 1)
+	sample := batcher.lateSamples[0]
+	assert.Equal(t, "temperature", sample.Name)
+	assert.Equal(t, metrics.GaugeType, sample.Mtype)
+	assert.Equal(t, 21.5, sample.Value)
+	assert.Equal(t, float64(1700000000), sample.Timestamp)
+	assert.Equal(t, []string{"room:kitchen"}, sample.Tags)
+	assert.Empty(t, batcher.samples)
+}
+
+func TestRemoteWriteCounters(t *testing.T) {
+	r, batcher := newTestRemoteWriteReceiver(t)
+
+	request := func(requests, errors float64) *prompb.WriteRequest {
+		return &prompb.WriteRequest{
+			Timeseries: []prompb.TimeSeries{
+				testSeries("http_requests_total", requests, 1700000000000),
+				testSeries("errors", errors, 1700000000000),
+			},
+			Metadata: []prompb.MetricMetadata{{Type: prompb.MetricMetadata_COUNTER, MetricFamilyName: "errors"}},
+		}
+	}
+
+	// the first values are only recorded
+	r.process(request(10, 1), remoteWriteOrigin{})
+	assert.Empty(t, batcher.lateSamples)
+
+	r.process(request(15, 4), remoteWriteOrigin{})
+	require.Len(t, batcher.lateSamples, 2)
+	assert.Equal(t, metrics.CounterType, batcher.lateSamples[0].Mtype)
+	assert.Equal(t, "http_requests_total", batcher.lateSamples[0].Name)
+	assert.Equal(t, 5.0, batcher.lateSamples[0].Value)
+	assert.Equal(t, metrics.CounterType, batcher.lateSamples[1].Mtype)
+	assert.Equal(t, 3.0, batcher.lateSamples[1].Value)
+	batcher.clear()
+
+	// the counters were reset
+	r.process(request(2, 1), remoteWriteOrigin{})
+	require.Len(t, batcher.lateSamples, 2)
+	assert.Equal(t, 2.0, batcher.lateSamples[0].Value)
+	assert.Equal(t, 1.0, batcher.lateSamples[1].Value)
+}
+
+func TestRemoteWriteHistograms(t *testing.T) {
+	r, batcher := newTestRemoteWriteReceiver(t)
+
+	request := func(buckets [4]float64, sum float64) *prompb.WriteRequest {
+		return &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+			testSeries("latency_bucket", buckets[0], 1700000000000, "le", "0.1", "path", "/"),
+			testSeries("latency_bucket", buckets[1], 1700000000000, "le", "0.5", "path", "/"),
+			testSeries("latency_bucket", buckets[2], 1700000000000, "le", "1", "path", "/"),
+			testSeries("latency_bucket", buckets[3], 1700000000000, "le", "+Inf", "path", "/"),
+			testSeries("latency_sum", sum, 1700000000000, "path", "/"),
+			testSeries("latency_count", buckets[3], 1700000000000, "path", "/"),
+		}}
+	}
+
+	r.process(request([4]float64{1, 2, 3, 4}, 2), remoteWriteOrigin{})
+	assert.Empty(t, batcher.samples)
+	assert.Empty(t, batcher.lateSamples)
+
+	// 2 observations in ]0, 0.1], 1 in ]0.1, 0.5] and 1 above 1
+	r.process(request([4]float64{3, 5, 6, 8}, 5), remoteWriteOrigin{})
+	require.Len(t, batcher.samples, 3)
+	for _, sample := range batcher.samples {
+		assert.Equal(t, "latency", sample.Name)
+		assert.Equal(t, metrics.DistributionType, sample.Mtype)
+		assert.Equal(t, []string{"path:/"}, sample.Tags)
+		assert.Zero(t, sample.Timestamp)
+	}
+	assert.Equal(t, 0.05, batcher.samples[0].Value)
+	assert.Equal(t, 0.5, batcher.samples[0].SampleRate)
+	assert.Equal(t, 0.3, batcher.samples[1].Value)
+	assert.Equal(t, 1.0, batcher.samples[1].SampleRate)
+	// the observations of the +Inf bucket are at the largest bound
+	assert.Equal(t, 1.0, batcher.samples[2].Value)
+	assert.Equal(t, 1.0, batcher.samples[2].SampleRate)
+
+	require.Len(t, batcher.lateSamples, 2)
+	assert.Equal(t, "latency_sum", batcher.lateSamples[0].Name)
+	assert.Equal(t, metrics.CounterType, batcher.lateSamples[0].Mtype)
+	assert.Equal(t, 3.0, batcher.lateSamples[0].Value)
+	assert.Equal(t, "latency_count", batcher.lateSamples[1].Name)
+	assert.Equal(t, 4.0, batcher.lateSamples[1].Value)
+}
+
+func TestRemoteWriteSeriesExpiry(t *testing.T) {
+	r, _ := newTestRemoteWriteReceiver(t)
+
+	r.process(&prompb.WriteRequest{Timeseries: []prompb.TimeSeries{testSeries("requests_total", 1, 0)}}, remoteWriteOrigin{})
+	require.Len(t, r.cumulative, 1)
+
+	r.expire(time.Now().Add(remoteWriteSeriesExpiry / 2))
+	assert.Len(t, r.cumulative, 1)
+	r.expire(time.Now().Add(2 * remoteWriteSeriesExpiry))
+	assert.Empty(t, r.cumulative)
+}
+
+func TestRemoteWriteServeHTTP(t *testing.T) {
+	r, batcher := newTestRemoteWriteReceiver(t)
+
+	payload := snappy.Encode(nil, encodeRemoteWriteRequest(t, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		testSeries("temperature", 21.5, 1700000000000),
+	}}))
+
+	for _, tc := range []struct {
+		method   string
+		encoding string
+		body     []byte
+		status   int
+	}{
+		{http.MethodPost, "snappy", payload, http.StatusNoContent},
+		{http.MethodGet, "snappy", payload, http.StatusMethodNotAllowed},
+		{http.MethodPost, "gzip", payload, http.StatusUnsupportedMediaType},
+		{http.MethodPost, "snappy", []byte("not snappy"), http.StatusBadRequest},
+		{http.MethodPost, "snappy", snappy.Encode(nil, []byte{0xff, 0xff}), http.StatusBadRequest},
+	} {
+		t.Run(fmt.Sprintf("%s %s %d", tc.method, tc.encoding, tc.status), func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, remoteWritePath, bytes.NewReader(tc.body))
+			req.Header.Set("Content-Encoding", tc.encoding)
+			req.Header.Set(remoteWriteLocalDataHeader, "ci-abcdef")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+
+	require.Len(t, batcher.lateSamples, 1)
+	assert.Equal(t, "abcdef", batcher.lateSamples[0].OriginInfo.LocalData.ContainerID)
+}
+
+func TestRemoteWriteReceiver(t *testing.T) {
+	deps := fulfillDepsWithConfigOverride(t, map[string]interface{}{
+		"dogstatsd_port":                    listeners.RandomPortName,
+		"dogstatsd_remote_write_port":       listeners.RandomPortName,
+		"dogstatsd_no_aggregation_pipeline": true,
+	})
+	s := deps.Server.(*server)
+	require.NotNil(t, s.remoteWrite)
+
+	payload := snappy.Encode(nil, encodeRemoteWriteRequest(t, &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{
+		testSeries("temperature", 21.5, time.Now().UnixMilli(), "room", "kitchen"),
+	}}))
+	resp, err := http.Post("http://"+s.remoteWrite.listener.Addr().String()+remoteWritePath, "application/x-protobuf", bytes.NewReader(payload))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	_, timedSamples := deps.Demultiplexer.WaitForSamples(2 * time.Second)
+	require.Len(t, timedSamples, 1)
+	assert.Equal(t, "temperature", timedSamples[0].Name)
+	assert.Equal(t, 21.5, timedSamples[0].Value)
+	assert.Equal(t, []string{"room:kitchen"}, timedSamples[0].Tags)
+
+	s.Stop()
+	_, err = http.Post("http://"+s.remoteWrite.listener.Addr().String()+remoteWritePath, "application/x-protobuf", bytes.NewReader(payload))
+	assert.Error(t, err)
+}
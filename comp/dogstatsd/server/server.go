@@ -53,6 +53,7 @@ var (
 	dogstatsdMetricPackets            = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
+	dogstatsdMetricMapperDropped      = expvar.Int{}
 
 	// while we try to add the origin tag in the tlmProcessed metric, we want to
 	// avoid having it growing indefinitely, hence this safeguard to limit the
@@ -135,6 +136,7 @@ type server struct {
 	eolTerminationUDP       bool
 	eolTerminationUDS       bool
 	eolTerminationNamedPipe bool
+	eolTerminationTCP       bool
 	// disableVerboseLogs is a feature flag to disable the logs capable
 	// of flooding the logger output (e.g. parsing messages error).
 	// NOTE(remy): this should probably be dropped and use a throttler logger, see
@@ -153,6 +155,10 @@ type server struct {
 	ServerlessMode bool
 	udpLocalAddr   string
 
+	// remoteWrite receives the metrics pushed with the Prometheus
+	// remote-write protocol, if enabled.
+	remoteWrite *remoteWriteReceiver
+
 	// originTelemetry is true if we want to report telemetry per origin.
 	originTelemetry bool
 
@@ -179,6 +185,7 @@ func initTelemetry() {
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
+	dogstatsdExpvars.Set("MetricMapperDropped", &dogstatsdMetricMapperDropped)
 }
 
 // TODO: (components) - merge with newServerCompat once NewServerlessServer is removed
@@ -246,6 +253,7 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 	eolTerminationUDP := false
 	eolTerminationUDS := false
 	eolTerminationNamedPipe := false
+	eolTerminationTCP := false
 
 	for _, v := range cfg.GetStringSlice("dogstatsd_eol_required") {
 		switch v {
@@ -255,6 +263,8 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 			eolTerminationUDS = true
 		case "named_pipe":
 			eolTerminationNamedPipe = true
+		case "tcp":
+			eolTerminationTCP = true
 		default:
 			log.Errorf("Invalid dogstatsd_eol_required value: %s", v)
 		}
@@ -284,6 +294,7 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 		eolTerminationUDP:       eolTerminationUDP,
 		eolTerminationUDS:       eolTerminationUDS,
 		eolTerminationNamedPipe: eolTerminationNamedPipe,
+		eolTerminationTCP:       eolTerminationTCP,
 		disableVerboseLogs:      cfg.GetBool("dogstatsd_disable_verbose_logs"),
 		Debug:                   debug,
 		originTelemetry: cfg.GetBool("telemetry.enabled") &&
@@ -397,6 +408,15 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_tcp_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("%s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -460,6 +480,18 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	// receive the Prometheus remote-write requests
+	// ----------------------
+
+	if !s.ServerlessMode && (s.config.GetString("dogstatsd_remote_write_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_remote_write_port") > 0) {
+		receiver := newRemoteWriteReceiver(s, newBatcher(s.demultiplexer.(aggregator.DemultiplexerWithAggregator), s.tlmChannel))
+		if err := receiver.listen(remoteWriteAddr(s.config)); err != nil {
+			s.log.Errorf("Can't init the Prometheus remote-write receiver: %s", err.Error())
+		} else {
+			s.remoteWrite = receiver
+		}
+	}
+
 	// start the workers processing the packets read on the socket
 	// ----------------------
 
@@ -476,6 +508,9 @@ func (s *server) stop(context.Context) error {
 	for _, l := range s.listeners {
 		l.Stop()
 	}
+	if s.remoteWrite != nil {
+		s.remoteWrite.stop()
+	}
 	if s.Statistics != nil {
 		s.Statistics.Stop()
 	}
@@ -612,6 +647,8 @@ func (s *server) eolEnabled(sourceType packets.SourceType) bool {
 		return s.eolTerminationUDP
 	case packets.NamedPipe:
 		return s.eolTerminationNamedPipe
+	case packets.TCP:
+		return s.eolTerminationTCP
 	}
 	return false
 }
@@ -751,11 +788,24 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 	}
 
 	if s.mapper != nil {
-		mapResult := s.mapper.Map(sample.name)
-		if mapResult != nil {
+		mapResult := s.mapper.MapWithTags(sample.name, sample.tags)
+		if mapResult != nil && mapResult.Drop {
+			s.log.Tracef("Dogstatsd mapper: metric %q dropped", sample.name)
+			dogstatsdMetricMapperDropped.Add(1)
+			if len(sample.values) > 0 {
+				s.sharedFloat64List.put(sample.values)
+			}
+			return metricSamples, nil
+		} else if mapResult != nil {
 			s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
 			sample.name = mapResult.Name
+			sample.tags = mapResult.TransformTags(sample.tags)
 			sample.tags = append(sample.tags, mapResult.Tags...)
+			// the summaries aggregated by the clients are only supported for
+			// distributions, whose type is kept
+			if mapResult.MetricType != "" && sample.metricType != setType && sample.summary == nil {
+				sample.metricType = mapperMetricType(mapResult.MetricType)
+			}
 		}
 	}
 
@@ -9,6 +9,7 @@ package server
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -141,6 +142,33 @@ func runTestMetrics(t *testing.T, deps serverDeps, input []byte, expTests []*tMe
 	}
 }
 
+func TestSummaryWithTimestamp(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_port"] = listeners.RandomPortName
+	cfg["dogstatsd_no_aggregation_pipeline"] = true
+	deps := fulfillDepsWithConfigOverride(t, cfg)
+	s := deps.Server.(*server)
+
+	demux := deps.Demultiplexer
+	demux.Reset()
+	histogram := deps.Telemetry.NewHistogram("test-dogstatsd", "summary_channel_latency", []string{"shard", "message_type"}, "", defaultChannelBuckets)
+	parser := newParser(deps.Config, s.sharedFloat64List, 1, deps.WMeta, s.stringInternerTelemetry)
+	s.parsePackets(newBatcher(demux, histogram), parser, genTestPackets(
+		[]byte("daemon:0|d|T1658328888|S:2:3:1:2"),
+		[]byte("daemon:666|g|T1658328888"),
+	), metrics.MetricSampleBatch{})
+
+	// the summaries are merged into the sketches rather than being sent
+	// without aggregation, which doesn't support distributions
+	samples, timedSamples := demux.WaitForNumberOfSamples(1, 1, time.Second)
+	require.Len(t, samples, 1)
+	assert.Equal(t, metrics.DistributionType, samples[0].Mtype)
+	assert.Equal(t, 1658328888.0, samples[0].Timestamp)
+	assert.NotNil(t, samples[0].Summary)
+	require.Len(t, timedSamples, 1)
+	assert.Equal(t, metrics.GaugeType, timedSamples[0].Mtype)
+}
+
 func TestEvents(t *testing.T) {
 	cfg := make(map[string]interface{})
 	cfg["dogstatsd_port"] = listeners.RandomPortName
@@ -332,6 +360,63 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Transformations",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.debug.*"
+        action: drop
+      - match: "test.request.*"
+        match_tags:
+          env: "prod|staging"
+        name: "test.request"
+        metric_type: distribution
+        tags:
+          endpoint: "$1"
+        remove_tags: ["request_id"]
+        rewrite_tags:
+          - tag: status
+            match: '(\d)\d\d'
+            value: '${1}xx'
+`,
+			packets: [][]byte{
+				[]byte("test.debug.queue:666|g"),
+				[]byte("test.request.login:666|h|#env:prod,request_id:1234,status:404"),
+				[]byte("test.request.login:666|h|#env:dev,request_id:1234"),
+			},
+			expectedSamples: []*tMetricSample{
+				defaultMetric().withName("test.request").withType(metrics.DistributionType).withTags([]string{"env:prod", "status:4xx", "endpoint:login"}),
+				defaultMetric().withName("test.request.login").withType(metrics.HistogramType).withTags([]string{"env:dev", "request_id:1234"}),
+			},
+			expectedCacheSize: 1000,
+		},
+		{
+			name: "Summary type kept",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.latency.*"
+        name: "test.latency"
+        metric_type: histogram
+`,
+			packets: [][]byte{
+				[]byte("test.latency.login:666|d|S:2:3:1:2"),
+				[]byte("test.latency.login:666|d"),
+			},
+			expectedSamples: []*tMetricSample{
+				// the summaries are only supported for distributions
+				defaultMetric().withName("test.latency").withType(metrics.DistributionType).withTags(nil),
+				defaultMetric().withName("test.latency").withType(metrics.HistogramType).withTags(nil),
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
@@ -367,6 +452,7 @@ dogstatsd_mapper_profiles:
 			var b batcherMock
 			s.parsePackets(&b, parser, genTestPackets(scenario.packets...), metrics.MetricSampleBatch{})
 
+			require.Len(t, b.samples, len(scenario.expectedSamples))
 			for idx, sample := range b.samples {
 				scenario.expectedSamples[idx].testMetric(t, sample)
 			}
@@ -35,6 +35,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/metadata/inventoryagent"
 	rctypes "github.com/DataDog/datadog-agent/comp/remote-config/rcclient/types"
 	logscompression "github.com/DataDog/datadog-agent/comp/serializer/logscompression/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -87,6 +88,7 @@ type dependencies struct {
 	SchedulerProviders []schedulers.Scheduler `group:"log-agent-scheduler"`
 	Tagger             tagger.Component
 	Compression        logscompression.Component
+	SenderManager      sender.SenderManager `optional:"true"`
 }
 
 type provides struct {
@@ -126,6 +128,8 @@ type logAgent struct {
 	schedulerProviders        []schedulers.Scheduler
 	integrationsLogs          integrations.Component
 	compression               logscompression.Component
+	senderManager             sender.SenderManager
+	metricsGenerator          *metricsGenerator
 
 	// make sure this is done only once, when we're ready
 	prepareSchedulers sync.Once
@@ -158,6 +162,7 @@ func newLogsAgent(deps dependencies) provides {
 			integrationsLogs:   integrationsLogs,
 			tagger:             deps.Tagger,
 			compression:        deps.Compression,
+			senderManager:      deps.SenderManager,
 		}
 		deps.Lc.Append(fx.Hook{
 			OnStart: logsAgent.start,
@@ -262,6 +267,7 @@ func (a *logAgent) startPipeline() {
 	starter := startstop.NewStarter(
 		a.destinationsCtx,
 		a.auditor,
+		a.metricsGenerator,
 		a.pipelineProvider,
 		a.diagnosticMessageReceiver,
 		a.launchers,
@@ -298,6 +304,7 @@ func (a *logAgent) stop(context.Context) error {
 		a.schedulers,
 		a.launchers,
 		a.pipelineProvider,
+		a.metricsGenerator,
 		a.auditor,
 		a.destinationsCtx,
 		a.diagnosticMessageReceiver,
@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentimpl
+
+import (
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// logsToMetricsSenderID identifies the sender of the metrics generated
+	// from logs, it is distinct from the default sender to not share its
+	// commits with other components.
+	logsToMetricsSenderID checkid.ID = "logs_to_metrics"
+	// logsToMetricsCommitInterval is the interval at which the generated
+	// metrics are committed, the same as the default check interval.
+	logsToMetricsCommitInterval = 15 * time.Second
+)
+
+// metricsGenerator submits the metrics generated from logs by the
+// generate_metric processing rules to the aggregator, committing them
+// periodically. It does nothing when the aggregator isn't available.
+type metricsGenerator struct {
+	sender sender.Sender
+	stop   chan struct{}
+	done   chan struct{}
+}
+
+func newMetricsGenerator(senderManager sender.SenderManager) *metricsGenerator {
+	g := &metricsGenerator{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if senderManager == nil {
+		return g
+	}
+	s, err := senderManager.GetSender(logsToMetricsSenderID)
+	if err != nil {
+		log.Warnf("Metrics can't be generated from logs: %v", err)
+		return g
+	}
+	g.sender = s
+	return g
+}
+
+// MetricSender returns the sender of the generated metrics, nil if they
+// can't be submitted.
+func (g *metricsGenerator) MetricSender() processor.MetricSender {
+	if g.sender == nil {
+		return nil
+	}
+	return g.sender
+}
+
+// Start starts committing the generated metrics.
+func (g *metricsGenerator) Start() {
+	if g.sender == nil {
+		close(g.done)
+		return
+	}
+	go func() {
+		defer close(g.done)
+		ticker := time.NewTicker(logsToMetricsCommitInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				g.sender.Commit()
+			case <-g.stop:
+				g.sender.Commit()
+				return
+			}
+		}
+	}()
+}
+
+// Stop commits the last generated metrics, it must be called once the
+// pipelines are stopped.
+func (g *metricsGenerator) Stop() {
+	close(g.stop)
+	<-g.done
+}
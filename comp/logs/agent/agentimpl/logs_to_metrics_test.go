@@ -0,0 +1,35 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentimpl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+)
+
+func TestMetricsGenerator(t *testing.T) {
+	sender := mocksender.NewMockSender(logsToMetricsSenderID)
+	sender.SetupAcceptAll()
+
+	generator := newMetricsGenerator(sender.GetSenderManager())
+	generator.Start()
+	generator.MetricSender().Count("app.errors", 1, "", []string{"component:db"})
+	generator.Stop()
+
+	sender.AssertMetric(t, "Count", "app.errors", 1, "", []string{"component:db"})
+	// the last metrics are committed when stopping
+	sender.AssertNumberOfCalls(t, "Commit", 1)
+}
+
+func TestMetricsGeneratorWithoutAggregator(t *testing.T) {
+	generator := newMetricsGenerator(nil)
+	assert.Nil(t, generator.MetricSender())
+	generator.Start()
+	generator.Stop()
+}
@@ -74,6 +74,7 @@ func compareEndpoint(t *testing.T, expected Endpoint, actual Endpoint) {
 	assert.Equal(t, expected.TrackType, actual.TrackType, "TrackType is not Equal")
 	assert.Equal(t, expected.Protocol, actual.Protocol, "Protocol is not Equal")
 	assert.Equal(t, expected.Origin, actual.Origin, "Origin is not Equal")
+	assert.Equal(t, expected.Format, actual.Format, "Format is not Equal")
 }
 
 func (suite *ConfigTestSuite) compareEndpoints(expected *Endpoints, actual *Endpoints) {
@@ -255,6 +256,29 @@ func (suite *ConfigTestSuite) TestMultipleHttpEndpointsEnvVar() {
 	suite.compareEndpoints(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestOTLPAdditionalEndpoint() {
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[{"host": "otel-collector", "port": 4318, "use_ssl": false, "is_reliable": false, "format": "otlp"}]`)
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.logs_dd_url", "agent-http-intake.logs.datadoghq.com:443")
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Equal(EndpointFormat(""), endpoints.Main.Format)
+	suite.Require().Len(endpoints.Endpoints, 2)
+
+	additional := endpoints.Endpoints[1]
+	suite.Equal(FormatOTLP, additional.Format)
+	suite.Equal("otel-collector", additional.Host)
+	suite.False(additional.UseSSL())
+	suite.False(additional.IsReliable())
+	suite.Equal("Sending compressed logs in OTLP/HTTP to otel-collector on port 4318", additional.GetStatus("", true))
+
+	// OTLP endpoints can't receive the logs sent over TCP
+	endpoints, err = buildTCPEndpoints(suite.config, defaultLogsConfigKeys(suite.config))
+	suite.Nil(err)
+	suite.Len(endpoints.Endpoints, 1)
+}
+
 func (suite *ConfigTestSuite) TestMultipleTCPEndpointsEnvVar() {
 	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[{"api_key": "456      \n", "host": "additional.endpoint", "port": 1234}]`)
 
@@ -28,6 +28,17 @@ type IntakeProtocol string
 // IntakeOrigin indicates the log source to use for an endpoint intake.
 type IntakeOrigin string
 
+// EndpointFormat indicates the format of the payloads sent to an endpoint.
+type EndpointFormat string
+
+const (
+	// FormatOTLP is the format of the endpoints receiving logs as OTLP/HTTP
+	// protobuf payloads, the default format being the one of the Datadog intake.
+	FormatOTLP EndpointFormat = "otlp"
+	// DefaultOTLPPort is the default port of the OTLP/HTTP endpoints.
+	DefaultOTLPPort = 4318
+)
+
 const (
 	_ EPIntakeVersion = iota
 	// EPIntakeVersion1 is version 1 of the envets platform intake API
@@ -71,6 +82,7 @@ type Endpoint struct {
 	TrackType IntakeTrackType
 	Protocol  IntakeProtocol
 	Origin    IntakeOrigin
+	Format    EndpointFormat `mapstructure:"format" json:"format"`
 }
 
 // unmarshalEndpoint is used to load additional endpoints from the configuration which stored as JSON/mapstructure.
@@ -143,6 +155,10 @@ func loadTCPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys) []Endpoint {
 
 	newEndpoints := make([]Endpoint, 0, len(additionals))
 	for idx, e := range additionals {
+		if e.Format == FormatOTLP {
+			log.Warnf("Ignoring the additional endpoint %s: OTLP endpoints are only supported when logs are sent over HTTP", e.Host)
+			continue
+		}
 		newE := NewEndpoint(e.APIKey, configKeyUsed, e.Host, e.Port, false)
 
 		newE.isAdditionalEndpoint = true
@@ -198,6 +214,7 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 		newE.TrackType = e.TrackType
 		newE.Protocol = e.Protocol
 		newE.Origin = e.Origin
+		newE.Format = e.Format
 
 		if e.UseSSL != nil {
 			newE.useSSL = *e.UseSSL
@@ -241,7 +258,15 @@ func (e *Endpoint) GetStatus(prefix string, useHTTP bool) string {
 	port := e.Port
 
 	var protocol string
-	if useHTTP {
+	if e.Format == FormatOTLP {
+		protocol = "OTLP/HTTP"
+		if e.UseSSL() {
+			protocol = "OTLP/HTTPS"
+		}
+		if port == 0 {
+			port = DefaultOTLPPort
+		}
+	} else if useHTTP {
 		if e.UseSSL() {
 			protocol = "HTTPS"
 			if port == 0 {
@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// grokMaxDepth bounds the recursive expansion of grok patterns referencing
+// other grok patterns, to protect against cycles.
+const grokMaxDepth = 16
+
+// grokReference matches a grok reference: %{NAME} or %{NAME:field}.
+var grokReference = regexp.MustCompile(`%\{([A-Z0-9_]+)(?::([A-Za-z_][A-Za-z0-9_.]*))?\}`)
+
+// grokPatterns is the library of grok patterns usable in a processing rule pattern.
+// It is a subset of the patterns commonly shipped with grok implementations.
+var grokPatterns = map[string]string{
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"INT":               `[+-]?[0-9]+`,
+	"NUMBER":            `[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+)`,
+	"BASE16NUM":         `(?:0[xX])?[0-9A-Fa-f]+`,
+	"POSINT":            `\b[1-9][0-9]*\b`,
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"QUOTEDSTRING":      `"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*'`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1?[0-9]?[0-9])`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}[0-9A-Fa-f]{0,4}`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATHPARAM":      `/[^\s?#]*(?:\?[^\s#]*)?`,
+	"LOGLEVEL":          `(?i:trace|debug|info|notice|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?|alert)`,
+	"YEAR":              `[0-9]{4}`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0?[1-9]|[12][0-9]|3[01])`,
+	"MONTH":             `\b(?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\b`,
+	"HOUR":              `(?:[01]?[0-9]|2[0-3])`,
+	"MINUTE":            `[0-5][0-9]`,
+	"SECOND":            `(?:[0-5]?[0-9]|60)(?:[.,][0-9]+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} [+-]?[0-9]{4}`,
+}
+
+// expandGrokPattern replaces every grok reference found in the pattern by its
+// regular expression. A reference carrying a field name, e.g. %{IP:client},
+// is expanded as a named capture group.
+// Patterns without any grok reference are returned untouched.
+func expandGrokPattern(pattern string) (string, error) {
+	return expandGrokPatternWithDepth(pattern, 0)
+}
+
+func expandGrokPatternWithDepth(pattern string, depth int) (string, error) {
+	if !strings.Contains(pattern, "%{") {
+		return pattern, nil
+	}
+	if depth > grokMaxDepth {
+		return "", fmt.Errorf("grok pattern expansion is too deep")
+	}
+
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(pattern, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		submatches := grokReference.FindStringSubmatch(ref)
+		name, field := submatches[1], submatches[2]
+
+		definition, exists := grokPatterns[name]
+		if !exists {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+
+		var inner string
+		inner, err = expandGrokPatternWithDepth(definition, depth+1)
+		if err != nil {
+			return ""
+		}
+
+		if field != "" {
+			return "(?P<" + sanitizeCaptureName(field) + ">" + inner + ")"
+		}
+		return "(?:" + inner + ")"
+	})
+	if err != nil {
+		return "", err
+	}
+	return expanded, nil
+}
+
+// sanitizeCaptureName makes a grok field name usable as a regexp group name,
+// regexp group names can't contain dots so nested attribute names are
+// written with a double underscore and restored by captureNameToAttribute.
+func sanitizeCaptureName(field string) string {
+	return strings.ReplaceAll(field, ".", "__")
+}
+
+// captureNameToAttribute returns the attribute name of a regexp capture group.
+func captureNameToAttribute(name string) string {
+	return strings.ReplaceAll(name, "__", ".")
+}
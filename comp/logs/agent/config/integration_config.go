@@ -18,6 +18,7 @@ import (
 const (
 	TCPType           = "tcp"
 	UDPType           = "udp"
+	SyslogType        = "syslog"
 	FileType          = "file"
 	DockerType        = "docker"
 	ContainerdType    = "containerd"
@@ -25,6 +26,7 @@ const (
 	IntegrationType   = "integration"
 	WindowsEventType  = "windows_event"
 	StringChannelType = "string_channel"
+	KafkaType         = "kafka"
 
 	// UTF16BE for UTF-16 Big endian encoding
 	UTF16BE string = "utf-16-be"
@@ -41,9 +43,11 @@ type LogsConfig struct {
 
 	IntegrationName string
 
-	Port        int    // Network
-	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout"` // Network
-	Path        string // File, Journald
+	Port        int        // Network
+	IdleTimeout string     `mapstructure:"idle_timeout" json:"idle_timeout" yaml:"idle_timeout"` // Network
+	Protocol    string     `mapstructure:"protocol" json:"protocol" yaml:"protocol"`             // Syslog
+	TLS         *TLSConfig `mapstructure:"tls" json:"tls" yaml:"tls"`                            // TCP, Syslog
+	Path        string     // File, Journald
 
 	Encoding     string           `mapstructure:"encoding" json:"encoding" yaml:"encoding"`                   // File
 	ExcludePaths StringSliceField `mapstructure:"exclude_paths" json:"exclude_paths" yaml:"exclude_paths"`    // File
@@ -70,6 +74,13 @@ type LogsConfig struct {
 	ChannelPath string `mapstructure:"channel_path" json:"channel_path" yaml:"channel_path"` // Windows Event
 	Query       string // Windows Event
 
+	Brokers       StringSliceField `mapstructure:"brokers" json:"brokers" yaml:"brokers"`                      // Kafka
+	Topics        StringSliceField `mapstructure:"topics" json:"topics" yaml:"topics"`                         // Kafka
+	ConsumerGroup string           `mapstructure:"consumer_group" json:"consumer_group" yaml:"consumer_group"` // Kafka
+	// HeaderTags lists the record headers added as tags to the messages, all
+	// headers are added when empty.
+	HeaderTags StringSliceField `mapstructure:"header_tags" json:"header_tags" yaml:"header_tags"` // Kafka
+
 	// used as input only by the Channel tailer.
 	// could have been unidirectional but the tailer could not close it in this case.
 	Channel chan *ChannelMessage
@@ -94,6 +105,15 @@ type LogsConfig struct {
 	AutoMultiLineMatchThreshold float64 `mapstructure:"auto_multi_line_match_threshold" json:"auto_multi_line_match_threshold" yaml:"auto_multi_line_match_threshold"`
 }
 
+// TLSConfig configures the TLS termination of a network listener.
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert_file" json:"cert_file" yaml:"cert_file"`
+	KeyFile  string `mapstructure:"key_file" json:"key_file" yaml:"key_file"`
+	// CAFile enables mutual TLS: clients must present a certificate signed
+	// by one of the authorities it contains.
+	CAFile string `mapstructure:"ca_file" json:"ca_file" yaml:"ca_file"`
+}
+
 // StringSliceField is a custom type for unmarshalling comma-separated string values or typical yaml fields into a slice of strings.
 type StringSliceField []string
 
@@ -141,9 +161,15 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case TCPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("TLS: %t,"), c.TLS != nil)
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+	case SyslogType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
+		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Protocol: %#v,"), c.Protocol)
+		fmt.Fprintf(&b, ws("TLS: %t,"), c.TLS != nil)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -166,6 +192,12 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case WindowsEventType:
 		fmt.Fprintf(&b, ws("ChannelPath: %#v,"), c.ChannelPath)
 		fmt.Fprintf(&b, ws("Query: %#v,"), c.Query)
+	case KafkaType:
+		fmt.Fprintf(&b, ws("Brokers: %#v,"), c.Brokers)
+		fmt.Fprintf(&b, ws("Topics: %#v,"), c.Topics)
+		fmt.Fprintf(&b, ws("ConsumerGroup: %#v,"), c.ConsumerGroup)
+		fmt.Fprintf(&b, ws("HeaderTags: %#v,"), c.HeaderTags)
+		fmt.Fprintf(&b, ws("TailingMode: %#v,"), c.TailingMode)
 	case StringChannelType:
 		fmt.Fprintf(&b, ws("Channel: %p,"), c.Channel)
 		c.ChannelTagsMutex.Lock()
@@ -200,11 +232,13 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		Type            string            `json:"type,omitempty"`
 		Port            int               `json:"port,omitempty"`           // Network
+		Protocol        string            `json:"protocol,omitempty"`       // Syslog
 		Path            string            `json:"path,omitempty"`           // File, Journald
 		Encoding        string            `json:"encoding,omitempty"`       // File
 		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
-		TailingMode     string            `json:"start_position,omitempty"` // File
+		TailingMode     string            `json:"start_position,omitempty"` // File, Kafka
 		ChannelPath     string            `json:"channel_path,omitempty"`   // Windows Event
+		Topics          []string          `json:"topics,omitempty"`         // Kafka
 		Service         string            `json:"service,omitempty"`
 		Source          string            `json:"source,omitempty"`
 		Tags            []string          `json:"tags,omitempty"`
@@ -213,11 +247,13 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 	}{
 		Type:            c.Type,
 		Port:            c.Port,
+		Protocol:        c.Protocol,
 		Path:            c.Path,
 		Encoding:        c.Encoding,
 		ExcludePaths:    c.ExcludePaths,
 		TailingMode:     c.TailingMode,
 		ChannelPath:     c.ChannelPath,
+		Topics:          c.Topics,
 		Service:         c.Service,
 		Source:          c.Source,
 		Tags:            c.Tags,
@@ -287,6 +323,34 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == SyslogType:
+		if c.Port == 0 {
+			return fmt.Errorf("syslog source must have a port")
+		}
+		if c.Protocol != "" && c.Protocol != TCPType && c.Protocol != UDPType {
+			return fmt.Errorf("invalid protocol '%v' for syslog source, must be tcp or udp", c.Protocol)
+		}
+	case c.Type == KafkaType:
+		if len(c.Brokers) == 0 {
+			return fmt.Errorf("kafka source must have brokers")
+		}
+		if len(c.Topics) == 0 {
+			return fmt.Errorf("kafka source must have topics")
+		}
+		if c.ConsumerGroup == "" {
+			return fmt.Errorf("kafka source must have a consumer group")
+		}
+		if c.TailingMode != "" && c.TailingMode != "beginning" && c.TailingMode != "end" {
+			return fmt.Errorf("invalid tailing mode '%v' for kafka source, must be beginning or end", c.TailingMode)
+		}
+	}
+	if c.TLS != nil {
+		if !c.isTCPListener() {
+			return fmt.Errorf("tls is only supported by sources listening on a tcp port")
+		}
+		if err := c.TLS.validate(); err != nil {
+			return err
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
@@ -295,6 +359,27 @@ func (c *LogsConfig) Validate() error {
 	return CompileProcessingRules(c.ProcessingRules)
 }
 
+func (t *TLSConfig) validate() error {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return fmt.Errorf("tls requires both a cert_file and a key_file")
+	}
+	return nil
+}
+
+// isTCPListener returns true for the sources served by the TCP listener,
+// which is the only one terminating TLS.
+func (c *LogsConfig) isTCPListener() bool {
+	return c.Type == TCPType || (c.Type == SyslogType && c.SyslogProtocol() == TCPType)
+}
+
+// SyslogProtocol returns the transport protocol of a syslog source, udp by default.
+func (c *LogsConfig) SyslogProtocol() string {
+	if c.Protocol == "" {
+		return UDPType
+	}
+	return c.Protocol
+}
+
 func (c *LogsConfig) validateTailingMode() error {
 	mode, found := TailingModeFromString(c.TailingMode)
 	if !found && c.TailingMode != "" {
@@ -20,8 +20,13 @@ func TestValidateShouldSucceedWithValidConfigs(t *testing.T) {
 	validConfigs := []*LogsConfig{
 		{Type: FileType, Path: "/var/log/foo.log"},
 		{Type: TCPType, Port: 1234},
+		{Type: TCPType, Port: 1234, TLS: &TLSConfig{CertFile: "/etc/cert.pem", KeyFile: "/etc/key.pem", CAFile: "/etc/ca.pem"}},
 		{Type: UDPType, Port: 5678},
+		{Type: SyslogType, Port: 514},
+		{Type: SyslogType, Port: 514, Protocol: TCPType, TLS: &TLSConfig{CertFile: "/etc/cert.pem", KeyFile: "/etc/key.pem"}},
 		{Type: DockerType},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}, ConsumerGroup: "datadog"},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}, ConsumerGroup: "datadog", TailingMode: "beginning"},
 		{Type: JournaldType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch, Pattern: ".*"}}},
 	}
 
@@ -37,6 +42,16 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 		{Type: FileType},
 		{Type: TCPType},
 		{Type: UDPType},
+		{Type: UDPType, Port: 5678, TLS: &TLSConfig{CertFile: "/etc/cert.pem", KeyFile: "/etc/key.pem"}},
+		{Type: TCPType, Port: 1234, TLS: &TLSConfig{CAFile: "/etc/ca.pem"}},
+		{Type: SyslogType},
+		{Type: SyslogType, Port: 514, Protocol: "sctp"},
+		{Type: SyslogType, Port: 514, TLS: &TLSConfig{CertFile: "/etc/cert.pem", KeyFile: "/etc/key.pem"}},
+		{Type: SyslogType, Port: 514, Protocol: TCPType, TLS: &TLSConfig{CertFile: "/etc/cert.pem"}},
+		{Type: KafkaType, Topics: []string{"logs"}, ConsumerGroup: "datadog"},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, ConsumerGroup: "datadog"},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}},
+		{Type: KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"logs"}, ConsumerGroup: "datadog", TailingMode: "forceBeginning"},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: "bar"}}},
 		{Type: DockerType, ProcessingRules: []*ProcessingRule{{Name: "foo", Type: ExcludeAtMatch}}},
@@ -52,6 +67,17 @@ func TestValidateShouldFailWithInvalidConfigs(t *testing.T) {
 	}
 }
 
+func TestSyslogProtocol(t *testing.T) {
+	assert.Equal(t, UDPType, (&LogsConfig{Type: SyslogType}).SyslogProtocol())
+	assert.Equal(t, TCPType, (&LogsConfig{Type: SyslogType, Protocol: TCPType}).SyslogProtocol())
+
+	lc := LogsConfig{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"type":"syslog","port":514,"protocol":"tcp","tls":{"cert_file":"cert.pem","key_file":"key.pem"}}`), &lc))
+	assert.NoError(t, lc.Validate())
+	assert.Equal(t, TCPType, lc.SyslogProtocol())
+	assert.Equal(t, &TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}, lc.TLS)
+}
+
 func TestAutoMultilineEnabled(t *testing.T) {
 	decode := func(cfg string) *LogsConfig {
 		lc := LogsConfig{}
@@ -8,6 +8,7 @@ package config
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 // Processing rule types
@@ -16,8 +17,43 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	// ParseAttributes extracts the named capture groups of a regex or grok
+	// pattern as attributes of the log.
+	ParseAttributes = "parse_attributes"
+	// Deduplicate collapses consecutive identical logs matching the pattern.
+	Deduplicate = "deduplicate"
+	// Sample keeps only a share of the logs matching the pattern.
+	Sample = "sample"
+	// DropField removes a field from JSON logs.
+	DropField = "drop_field"
+	// RenameField moves a field of JSON logs to the target path.
+	RenameField = "rename_field"
+	// HashField replaces the value of a field of JSON logs with its SHA-256 hash.
+	HashField = "hash_field"
+	// PromoteField copies a field of JSON logs to the status, the timestamp
+	// or a tag of the log.
+	PromoteField = "promote_field"
+	// GenerateMetric generates a metric from the logs matching the pattern.
+	GenerateMetric = "generate_metric"
 )
 
+// Types of the metrics generated by a GenerateMetric rule.
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+)
+
+// Targets of a PromoteField rule, a tag target is written "tag:<tag_name>".
+const (
+	PromoteToStatus    = "status"
+	PromoteToTimestamp = "timestamp"
+	PromoteToTagPrefix = "tag:"
+)
+
+// DefaultDeduplicationWindow is the window, in seconds, during which identical
+// logs are collapsed by a Deduplicate rule not configuring any window.
+const DefaultDeduplicationWindow = 10
+
 // ProcessingRule defines an exclusion or a masking rule to
 // be applied on log lines
 type ProcessingRule struct {
@@ -25,16 +61,57 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder" yaml:"replace_placeholder"`
 	Pattern            string
+	// Window is the deduplication window in seconds of a Deduplicate rule.
+	Window int `mapstructure:"window" json:"window" yaml:"window"`
+	// SampleRate is the share, between 0 and 1, of matching logs kept by a Sample rule.
+	SampleRate float64 `mapstructure:"sample_rate" json:"sample_rate" yaml:"sample_rate"`
+	// MaxPerSecond is the number of matching logs kept per second by a Sample rule,
+	// per value of the first capture group of the pattern if any.
+	MaxPerSecond int `mapstructure:"max_per_second" json:"max_per_second" yaml:"max_per_second"`
+	// Field is the dot-separated path of the JSON field a field rule applies to.
+	Field string `mapstructure:"field" json:"field" yaml:"field"`
+	// Target is the path a RenameField rule moves the field to, or the
+	// destination of a PromoteField rule.
+	Target string `mapstructure:"target" json:"target" yaml:"target"`
+	// MetricName is the name of the metric generated by a GenerateMetric rule.
+	MetricName string `mapstructure:"metric_name" json:"metric_name" yaml:"metric_name"`
+	// MetricType is the type of the metric generated by a GenerateMetric rule,
+	// MetricTypeCount by default.
+	MetricType string `mapstructure:"metric_type" json:"metric_type" yaml:"metric_type"`
+	// ValueGroup is the named capture group of the pattern holding the value
+	// of the metric generated by a GenerateMetric rule. The other named
+	// capture groups are used as tags.
+	ValueGroup string `mapstructure:"value_group" json:"value_group" yaml:"value_group"`
 	// TODO: should be moved out
 	Regex       *regexp.Regexp
 	Placeholder []byte
+	// AttributeNames maps every capture group of Regex to the attribute it
+	// fills, empty for unnamed groups. Only set for ParseAttributes and
+	// GenerateMetric rules, the latter using them as tag names.
+	AttributeNames []string
+	// ValueIndex is the index of the capture group of ValueGroup in Regex,
+	// -1 if the rule has no value group. Only set for GenerateMetric rules.
+	ValueIndex int
+	// FieldPath and TargetPath are the split Field and Target of field rules.
+	FieldPath  []string
+	TargetPath []string
+}
+
+// IsFieldRule returns true if the rule applies to a field of JSON logs
+// rather than to the raw content of the logs.
+func (r *ProcessingRule) IsFieldRule() bool {
+	switch r.Type {
+	case DropField, RenameField, HashField, PromoteField:
+		return true
+	}
+	return false
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, or a valid field for field rules
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -42,8 +119,32 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		}
 
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
+		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine, ParseAttributes:
 			break
+		case Deduplicate:
+			if rule.Window < 0 {
+				return fmt.Errorf("window must be positive for processing rule `%s`", rule.Name)
+			}
+		case Sample:
+			if rule.SampleRate < 0 || rule.SampleRate > 1 {
+				return fmt.Errorf("sample_rate must be between 0 and 1 for processing rule `%s`", rule.Name)
+			}
+			if rule.MaxPerSecond < 0 {
+				return fmt.Errorf("max_per_second must be positive for processing rule `%s`", rule.Name)
+			}
+			if rule.SampleRate == 0 && rule.MaxPerSecond == 0 {
+				return fmt.Errorf("sample_rate or max_per_second must be set for processing rule `%s`", rule.Name)
+			}
+		case DropField, RenameField, HashField, PromoteField:
+			if err := validateFieldRule(rule); err != nil {
+				return err
+			}
+			continue
+		case GenerateMetric:
+			if err := validateGenerateMetricRule(rule); err != nil {
+				return err
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -53,6 +154,12 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		if rule.Pattern == "" {
 			return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
 		}
+		if rule.Type == ParseAttributes {
+			if err := validateParseAttributesRule(rule); err != nil {
+				return err
+			}
+			continue
+		}
 		_, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
@@ -64,13 +171,37 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.Type == ParseAttributes {
+			if err := compileParseAttributesRule(rule); err != nil {
+				return err
+			}
+			continue
+		}
+		if rule.Type == GenerateMetric {
+			if err := compileGenerateMetricRule(rule); err != nil {
+				return err
+			}
+			continue
+		}
+		if rule.IsFieldRule() {
+			rule.FieldPath = strings.Split(rule.Field, ".")
+			if rule.Type == RenameField {
+				rule.TargetPath = strings.Split(rule.Target, ".")
+			}
+			continue
+		}
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, Sample:
+			rule.Regex = re
+		case Deduplicate:
 			rule.Regex = re
+			if rule.Window == 0 {
+				rule.Window = DefaultDeduplicationWindow
+			}
 		case MaskSequences:
 			rule.Regex = re
 			rule.Placeholder = []byte(rule.ReplacePlaceholder)
@@ -83,3 +214,126 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 	}
 	return nil
 }
+
+// validateFieldRule makes sure a field rule has a valid field path and,
+// depending on its type, a valid target.
+func validateFieldRule(rule *ProcessingRule) error {
+	if !isValidFieldPath(rule.Field) {
+		return fmt.Errorf("invalid field %q for processing rule: %s", rule.Field, rule.Name)
+	}
+	switch rule.Type {
+	case RenameField:
+		if !isValidFieldPath(rule.Target) {
+			return fmt.Errorf("invalid target %q for processing rule: %s", rule.Target, rule.Name)
+		}
+	case PromoteField:
+		switch {
+		case rule.Target == PromoteToStatus, rule.Target == PromoteToTimestamp:
+		case strings.HasPrefix(rule.Target, PromoteToTagPrefix) && len(rule.Target) > len(PromoteToTagPrefix):
+		default:
+			return fmt.Errorf("target of processing rule %s must be %q, %q or %q<tag_name>", rule.Name, PromoteToStatus, PromoteToTimestamp, PromoteToTagPrefix)
+		}
+	}
+	return nil
+}
+
+// isValidFieldPath returns true if path is a dot-separated list of non-empty keys.
+func isValidFieldPath(path string) bool {
+	if path == "" {
+		return false
+	}
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// validateParseAttributesRule makes sure the pattern of a ParseAttributes rule
+// expands and compiles, and that it captures at least one attribute.
+func validateParseAttributesRule(rule *ProcessingRule) error {
+	expanded, err := expandGrokPattern(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("pattern %s for processing rule: %s does not contain any named capture group", rule.Pattern, rule.Name)
+}
+
+// compileParseAttributesRule expands the grok references of a ParseAttributes
+// rule pattern and compiles the resulting regular expression.
+func compileParseAttributesRule(rule *ProcessingRule) error {
+	expanded, err := expandGrokPattern(rule.Pattern)
+	if err != nil {
+		return err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return err
+	}
+	names := re.SubexpNames()
+	attributes := make([]string, len(names))
+	for i, name := range names {
+		attributes[i] = captureNameToAttribute(name)
+	}
+	rule.Regex = re
+	rule.AttributeNames = attributes
+	return nil
+}
+
+// validateGenerateMetricRule makes sure a GenerateMetric rule has a metric
+// name, a supported metric type and a pattern capturing its value if needed.
+func validateGenerateMetricRule(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("no metric_name provided for processing rule: %s", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", MetricTypeCount:
+	case MetricTypeDistribution:
+		if rule.ValueGroup == "" {
+			return fmt.Errorf("value_group must be set for the distribution of processing rule: %s", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule `%s`", rule.MetricType, rule.Name)
+	}
+	if rule.Pattern == "" {
+		return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+	}
+	expanded, err := expandGrokPattern(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+	if rule.ValueGroup != "" && re.SubexpIndex(sanitizeCaptureName(rule.ValueGroup)) < 0 {
+		return fmt.Errorf("pattern %s for processing rule: %s does not contain the value_group %s", rule.Pattern, rule.Name, rule.ValueGroup)
+	}
+	return nil
+}
+
+// compileGenerateMetricRule compiles the pattern of a GenerateMetric rule
+// like a ParseAttributes one and locates its value group.
+func compileGenerateMetricRule(rule *ProcessingRule) error {
+	if err := compileParseAttributesRule(rule); err != nil {
+		return err
+	}
+	if rule.MetricType == "" {
+		rule.MetricType = MetricTypeCount
+	}
+	rule.ValueIndex = -1
+	if rule.ValueGroup != "" {
+		rule.ValueIndex = rule.Regex.SubexpIndex(sanitizeCaptureName(rule.ValueGroup))
+	}
+	return nil
+}
@@ -33,3 +33,120 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestCompileParseAttributesRule(t *testing.T) {
+	rules := []*ProcessingRule{{
+		Name:    "access_log",
+		Type:    ParseAttributes,
+		Pattern: `%{IPORHOST:network.client.ip} %{WORD:http.method} (?P<path>\S+) %{INT:http.status_code}`,
+	}}
+	assert.Nil(t, ValidateProcessingRules(rules))
+	assert.Nil(t, CompileProcessingRules(rules))
+	assert.NotNil(t, rules[0].Regex)
+	assert.Equal(t, []string{"", "network.client.ip", "http.method", "path", "http.status_code"}, rules[0].AttributeNames)
+	assert.True(t, rules[0].Regex.MatchString("10.0.0.1 GET /index.html 200"))
+}
+
+func TestValidateParseAttributesRule(t *testing.T) {
+	invalidRules := []*ProcessingRule{
+		{Name: "no_capture", Type: ParseAttributes, Pattern: `%{IP} \w+`},
+		{Name: "unknown_grok", Type: ParseAttributes, Pattern: `%{NOT_A_PATTERN:foo}`},
+		{Name: "invalid_regex", Type: ParseAttributes, Pattern: `(?P<foo>[a-z`},
+	}
+
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestExpandGrokPattern(t *testing.T) {
+	expanded, err := expandGrokPattern(`no grok here \d+`)
+	assert.Nil(t, err)
+	assert.Equal(t, `no grok here \d+`, expanded)
+
+	expanded, err = expandGrokPattern(`%{INT:count} items`)
+	assert.Nil(t, err)
+	assert.Equal(t, `(?P<count>[+-]?[0-9]+) items`, expanded)
+
+	expanded, err = expandGrokPattern(`%{USER}`)
+	assert.Nil(t, err)
+	assert.Equal(t, `(?:(?:[a-zA-Z0-9._-]+))`, expanded)
+}
+
+func TestValidateDeduplicationAndSamplingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "dedup", Type: Deduplicate, Pattern: ".*"},
+		{Name: "dedup_window", Type: Deduplicate, Pattern: ".*", Window: 30},
+		{Name: "sample_rate", Type: Sample, Pattern: "DEBUG", SampleRate: 0.1},
+		{Name: "sample_budget", Type: Sample, Pattern: `user=(\w+)`, MaxPerSecond: 10},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Equal(t, DefaultDeduplicationWindow, validRules[0].Window)
+	assert.Equal(t, 30, validRules[1].Window)
+	for _, rule := range validRules {
+		assert.NotNil(t, rule.Regex, rule.Name)
+	}
+
+	invalidRules := []*ProcessingRule{
+		{Name: "negative_window", Type: Deduplicate, Pattern: ".*", Window: -1},
+		{Name: "no_sampling", Type: Sample, Pattern: ".*"},
+		{Name: "invalid_rate", Type: Sample, Pattern: ".*", SampleRate: 1.5},
+		{Name: "negative_budget", Type: Sample, Pattern: ".*", MaxPerSecond: -1},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateFieldRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "drop", Type: DropField, Field: "password"},
+		{Name: "rename", Type: RenameField, Field: "usr.mail", Target: "user.email"},
+		{Name: "hash", Type: HashField, Field: "user.email"},
+		{Name: "status", Type: PromoteField, Field: "level", Target: PromoteToStatus},
+		{Name: "timestamp", Type: PromoteField, Field: "ts", Target: PromoteToTimestamp},
+		{Name: "tag", Type: PromoteField, Field: "env", Target: "tag:env"},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Equal(t, []string{"usr", "mail"}, validRules[1].FieldPath)
+	assert.Equal(t, []string{"user", "email"}, validRules[1].TargetPath)
+	assert.Nil(t, validRules[1].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_field", Type: DropField},
+		{Name: "empty_key", Type: HashField, Field: "user..email"},
+		{Name: "no_target", Type: RenameField, Field: "usr"},
+		{Name: "invalid_target", Type: RenameField, Field: "usr", Target: "user."},
+		{Name: "unknown_promotion", Type: PromoteField, Field: "level", Target: "service"},
+		{Name: "empty_tag", Type: PromoteField, Field: "env", Target: "tag:"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count", Type: GenerateMetric, Pattern: `ERROR`, MetricName: "app.errors"},
+		{Name: "distribution", Type: GenerateMetric, Pattern: `took %{NUMBER:http.duration}ms`, MetricName: "app.latency", MetricType: MetricTypeDistribution, ValueGroup: "http.duration"},
+	}
+	assert.Nil(t, ValidateProcessingRules(validRules))
+	assert.Nil(t, CompileProcessingRules(validRules))
+	assert.Equal(t, MetricTypeCount, validRules[0].MetricType)
+	assert.Equal(t, -1, validRules[0].ValueIndex)
+	assert.Equal(t, 1, validRules[1].ValueIndex)
+	assert.Equal(t, []string{"", "http.duration"}, validRules[1].AttributeNames)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_name", Type: GenerateMetric, Pattern: `ERROR`},
+		{Name: "no_pattern", Type: GenerateMetric, MetricName: "app.errors"},
+		{Name: "unknown_type", Type: GenerateMetric, Pattern: `ERROR`, MetricName: "app.errors", MetricType: "gauge"},
+		{Name: "no_value", Type: GenerateMetric, Pattern: `took \d+ms`, MetricName: "app.latency", MetricType: MetricTypeDistribution},
+		{Name: "missing_value", Type: GenerateMetric, Pattern: `took (?P<ms>\d+)ms`, MetricName: "app.latency", ValueGroup: "duration"},
+	}
+	for _, rule := range invalidRules {
+		assert.NotNil(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -9,4 +9,6 @@ package auditor
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	IsCompleted(identifier string) bool
+	MarkCompleted(identifier string, offset string)
 }
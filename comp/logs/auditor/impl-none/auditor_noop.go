@@ -37,6 +37,14 @@ func (a *NullAuditor) GetTailingMode(_ string) string {
 	return ""
 }
 
+// IsCompleted returns false
+func (a *NullAuditor) IsCompleted(_ string) bool {
+	return false
+}
+
+// MarkCompleted does nothing
+func (a *NullAuditor) MarkCompleted(_ string, _ string) {}
+
 // Start starts the NullAuditor main loop
 func (a *NullAuditor) Start() {
 	go a.run()
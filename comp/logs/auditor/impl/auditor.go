@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,6 +40,10 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	Completed          bool
+	// CompletionOffset is the offset which completes the source once it has
+	// been committed.
+	CompletionOffset string `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -172,6 +178,41 @@ func (a *registryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// IsCompleted returns true if the source matching identifier has been read
+// entirely and must not be read again.
+func (a *registryAuditor) IsCompleted(identifier string) bool {
+	entry, exists := a.readOnlyRegistryEntryCopy(identifier)
+	if !exists {
+		return false
+	}
+	return entry.Completed
+}
+
+// MarkCompleted records that the source matching identifier has been read
+// entirely. The source is completed once offset, the offset of its last log,
+// has been committed, so that the logs still being sent are read again after a
+// restart. An empty offset completes the source right away.
+func (a *registryAuditor) MarkCompleted(identifier string, offset string) {
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	if identifier == "" {
+		return
+	}
+
+	entry, ok := a.registry[identifier]
+	if !ok {
+		entry = &RegistryEntry{}
+		a.registry[identifier] = entry
+	}
+	entry.LastUpdated = time.Now().UTC()
+	if offset == "" || isOffsetReached(entry.Offset, offset) {
+		entry.Completed = true
+		entry.CompletionOffset = ""
+		return
+	}
+	entry.CompletionOffset = offset
+}
+
 // run keeps up to date the registry on different events
 func (a *registryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -242,13 +283,26 @@ func (a *registryAuditor) cleanupRegistry() {
 	defer a.registryMutex.Unlock()
 	expireBefore := time.Now().UTC().Add(-a.entryTTL)
 	for path, entry := range a.registry {
-		if entry.LastUpdated.Before(expireBefore) {
+		// completed sources are kept while their file exists, so that they
+		// are not read again
+		if entry.LastUpdated.Before(expireBefore) && (!entry.Completed || !completedFileExists(path)) {
 			a.log.Debugf("TTL for %s is expired, removing from registry.", path)
 			delete(a.registry, path)
 		}
 	}
 }
 
+// completedFileExists reports whether the file of a completed source still
+// exists, its identifier being file: followed by the path of the file.
+func completedFileExists(identifier string) bool {
+	path, ok := strings.CutPrefix(identifier, "file:")
+	if !ok {
+		return false
+	}
+	_, err := os.Stat(path)
+	return !os.IsNotExist(err)
+}
+
 // updateRegistry updates the registry entry matching identifier with the new offset and timestamp
 func (a *registryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
@@ -262,10 +316,19 @@ func (a *registryAuditor) updateRegistry(identifier string, offset string, taili
 
 	// Don't update the registry with a value older than the current one
 	// This can happen when dual shipping and 2 destinations are sending the same payload successfully
+	var completed bool
+	var completionOffset string
 	if v, ok := a.registry[identifier]; ok {
 		if v.IngestionTimestamp > ingestionTimestamp {
 			return
 		}
+		completed = v.Completed
+		completionOffset = v.CompletionOffset
+	}
+	// a source read entirely is completed once its last log has been sent
+	if completionOffset != "" && isOffsetReached(offset, completionOffset) {
+		completed = true
+		completionOffset = ""
 	}
 
 	a.registry[identifier] = &RegistryEntry{
@@ -273,7 +336,22 @@ func (a *registryAuditor) updateRegistry(identifier string, offset string, taili
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Completed:          completed,
+		CompletionOffset:   completionOffset,
+	}
+}
+
+// isOffsetReached returns true if the committed offset is at or after target.
+func isOffsetReached(committed string, target string) bool {
+	committedOffset, err := strconv.ParseInt(committed, 10, 64)
+	if err != nil {
+		return false
+	}
+	targetOffset, err := strconv.ParseInt(target, 10, 64)
+	if err != nil {
+		return false
 	}
+	return committedOffset >= targetOffset
 }
 
 // readOnlyRegistryCopy returns a read only copy of the registry
@@ -71,6 +71,87 @@ func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
 }
 
+func (suite *AuditorTestSuite) TestAuditorRecordsCompletion() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "beginning", 0)
+	suite.False(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.a.MarkCompleted(suite.source.Config.Path, "84")
+	// the last log has not been sent yet
+	suite.False(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.Equal("42", suite.a.GetOffset(suite.source.Config.Path))
+
+	suite.a.updateRegistry(suite.source.Config.Path, "63", "beginning", 1)
+	suite.False(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.a.updateRegistry(suite.source.Config.Path, "84", "beginning", 2)
+	suite.True(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.Equal("84", suite.a.GetOffset(suite.source.Config.Path))
+	suite.Equal("beginning", suite.a.GetTailingMode(suite.source.Config.Path))
+
+	suite.NoError(suite.a.flushRegistry())
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.True(suite.a.IsCompleted(suite.source.Config.Path))
+	suite.False(suite.a.IsCompleted("anotherpath"))
+}
+
+func (suite *AuditorTestSuite) TestAuditorRecordsCompletionOfSentSource() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry(suite.source.Config.Path, "84", "beginning", 0)
+	// the last log has already been sent
+	suite.a.MarkCompleted(suite.source.Config.Path, "84")
+	suite.True(suite.a.IsCompleted(suite.source.Config.Path))
+
+	// a source without logs is completed right away
+	suite.a.MarkCompleted("anotherpath", "")
+	suite.True(suite.a.IsCompleted("anotherpath"))
+}
+
+func (suite *AuditorTestSuite) TestAuditorKeepsCompletedEntries() {
+	path := filepath.Join(suite.T().TempDir(), "app.log-20060112.gz")
+	suite.NoError(os.WriteFile(path, nil, 0644))
+	identifier := "file:" + path
+
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[identifier] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		Completed:   true,
+	}
+
+	// the entry is kept past its TTL while the file exists
+	suite.a.cleanupRegistry()
+	suite.True(suite.a.IsCompleted(identifier))
+
+	// and expires once the file is removed
+	suite.NoError(os.Remove(path))
+	suite.a.cleanupRegistry()
+	suite.False(suite.a.IsCompleted(identifier))
+	suite.Empty(suite.a.registry)
+}
+
+func (suite *AuditorTestSuite) TestAuditorExpiresCompletedEntriesWithoutFile() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		Completed:   true,
+	}
+	suite.a.registry["file:"+filepath.Join(suite.T().TempDir(), "missing.gz")] = &RegistryEntry{
+		LastUpdated: time.Date(2006, time.January, 12, 1, 1, 1, 1, time.UTC),
+		Offset:      "42",
+		Completed:   true,
+	}
+	// the entries within their TTL are kept
+	suite.a.registry["file:recent.gz"] = &RegistryEntry{
+		LastUpdated: time.Now().UTC(),
+		Offset:      "42",
+		Completed:   true,
+	}
+
+	suite.a.cleanupRegistry()
+	suite.Len(suite.a.registry, 1)
+	suite.True(suite.a.IsCompleted("file:recent.gz"))
+}
+
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -81,7 +162,7 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.NoError(suite.a.flushRegistry())
 	r, err := os.ReadFile(suite.testRegistryPath)
 	suite.NoError(err)
-	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0}}}", string(r))
+	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0,\"Completed\":false}}}", string(r))
 
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry = suite.a.recoverRegistry()
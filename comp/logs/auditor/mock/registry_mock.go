@@ -16,6 +16,7 @@ import (
 type RegistryMock struct {
 	offset      string
 	tailingMode string
+	completed   bool
 }
 
 // GetOffset returns the offset.
@@ -38,6 +39,21 @@ func (r *RegistryMock) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
 
+// IsCompleted returns the completion state.
+func (r *RegistryMock) IsCompleted(_ string) bool {
+	return r.completed
+}
+
+// MarkCompleted sets the completion state.
+func (r *RegistryMock) MarkCompleted(_ string, _ string) {
+	r.completed = true
+}
+
+// SetCompleted sets the completion state.
+func (r *RegistryMock) SetCompleted(completed bool) {
+	r.completed = completed
+}
+
 // Channel returns a channel
 func (r *RegistryMock) Channel() chan *message.Payload {
 	return nil
@@ -44,6 +44,8 @@ func Module() fxutil.Module {
 
 const (
 	agentTaskTimeout = 5 * time.Minute
+	// rollupRulesSetting is the runtime setting of the rollup rules of the metrics
+	rollupRulesSetting = "aggregator_rollup_rules"
 )
 
 type rcClient struct {
@@ -324,6 +326,10 @@ func (rc rcClient) agentConfigUpdateCallback(updates map[string]state.RawConfig,
 
 	var errs error
 
+	if err := rc.applyRollupRules(mergedConfig.RollupRules); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
 	targetCmp := rc.config
 	localSysProbeConf, isSet := rc.sysprobeConfig.Get()
 	if isSet && rc.isSystemProbe {
@@ -386,6 +392,27 @@ func (rc rcClient) agentConfigUpdateCallback(updates map[string]state.RawConfig,
 	}
 }
 
+// applyRollupRules sets the rollup rules of the metrics received through remote
+// config, or removes the ones previously received when there are none.
+func (rc rcClient) applyRollupRules(rules []byte) error {
+	if len(rules) == 0 {
+		if rc.config.GetSource(rollupRulesSetting) == model.SourceRC {
+			rc.config.UnsetForSource(rollupRulesSetting, model.SourceRC)
+			pkglog.Infof("Removing remote-config rollup rules")
+		}
+		return nil
+	}
+
+	pkglog.Infof("Changing the rollup rules through remote config")
+	err := rc.settingsComponent.SetRuntimeSetting(rollupRulesSetting, string(rules), model.SourceRC)
+	var notFound *settings.SettingNotFoundError
+	if errors.As(err, &notFound) {
+		// only the agents running an aggregator have rollup rules
+		return nil
+	}
+	return err
+}
+
 // agentTaskUpdateCallback is the callback function called when there is an AGENT_TASK config update
 // The RCClient can directly call back listeners, because there would be no way to send back
 // RCTE2 configuration applied state to RC backend.
@@ -283,3 +283,99 @@ func TestAgentMRFConfigCallback(t *testing.T) {
 	assert.False(t, *cmpntSettings.logs)
 	assert.True(t, *cmpntSettings.apm)
 }
+
+type mockRollupRulesRuntimeSetting struct{}
+
+func (m *mockRollupRulesRuntimeSetting) Get(cfg config.Component) (interface{}, error) {
+	return cfg.Get(m.Name()), nil
+}
+
+func (m *mockRollupRulesRuntimeSetting) Set(cfg config.Component, v interface{}, source model.Source) error {
+	cfg.Set(m.Name(), v, source)
+	return nil
+}
+
+func (m *mockRollupRulesRuntimeSetting) Name() string {
+	return "aggregator_rollup_rules"
+}
+
+func (m *mockRollupRulesRuntimeSetting) Description() string {
+	return ""
+}
+
+func (m *mockRollupRulesRuntimeSetting) Hidden() bool {
+	return true
+}
+
+type mockSettingsWithoutRollupRules struct {
+	settings.Component
+}
+
+func (m *mockSettingsWithoutRollupRules) SetRuntimeSetting(setting string, _ interface{}, _ model.Source) error {
+	return &settings.SettingNotFoundError{Name: setting}
+}
+
+func TestAgentConfigCallbackRollupRules(t *testing.T) {
+	pkglog.SetupLogger(pkglog.Default(), "info")
+	cfg := configmock.New(t)
+
+	rc := fxutil.Test[rcclient.Component](t,
+		fx.Options(
+			Module(),
+			fx.Provide(func() log.Component { return logmock.New(t) }),
+			fx.Provide(func() config.Component { return cfg }),
+			sysprobeconfig.NoneModule(),
+			fx.Supply(
+				rcclient.Params{
+					AgentName:    "test-agent",
+					AgentVersion: "7.0.0",
+				},
+			),
+			fx.Supply(
+				settings.Params{
+					Settings: map[string]settings.RuntimeSetting{
+						"log_level":               &mockLogLevelRuntimeSettings{logLevel: "info"},
+						"aggregator_rollup_rules": &mockRollupRulesRuntimeSetting{},
+					},
+					Config: cfg,
+				},
+			),
+			settingsimpl.Module(),
+		),
+	)
+
+	layerRollup := state.RawConfig{Config: []byte(`{"name": "layer1", "config": {"rollup_rules": [{"metric_name": "http.requests", "keep_tags": ["service"]}]}}`)}
+	layerEmpty := state.RawConfig{Config: []byte(`{"name": "layer1", "config": {}}`)}
+	configOrder := state.RawConfig{Config: []byte(`{"internal_order": ["layer1"]}`)}
+
+	structRC := rc.(rcClient)
+
+	ipcAddress, err := pkgconfigsetup.GetIPCAddress(cfg)
+	assert.NoError(t, err)
+
+	structRC.client, _ = client.NewUnverifiedGRPCClient(
+		ipcAddress, pkgconfigsetup.GetIPCPort(), func() (string, error) { return security.FetchAuthToken(cfg) },
+		client.WithAgent("test-agent", "9.99.9"),
+		client.WithProducts(state.ProductAgentConfig),
+		client.WithPollInterval(time.Hour),
+	)
+
+	structRC.agentConfigUpdateCallback(map[string]state.RawConfig{
+		"datadog/2/AGENT_CONFIG/layer1/configname":              layerRollup,
+		"datadog/2/AGENT_CONFIG/configuration_order/configname": configOrder,
+	}, applyEmpty)
+	assert.Equal(t, `[{"metric_name": "http.requests", "keep_tags": ["service"]}]`, cfg.Get("aggregator_rollup_rules"))
+	assert.Equal(t, model.SourceRC, cfg.GetSource("aggregator_rollup_rules"))
+
+	// the rules are removed once they are no longer sent
+	structRC.agentConfigUpdateCallback(map[string]state.RawConfig{
+		"datadog/2/AGENT_CONFIG/layer1/configname":              layerEmpty,
+		"datadog/2/AGENT_CONFIG/configuration_order/configname": configOrder,
+	}, applyEmpty)
+	assert.Nil(t, cfg.Get("aggregator_rollup_rules"))
+	assert.NotEqual(t, model.SourceRC, cfg.GetSource("aggregator_rollup_rules"))
+
+	// the agents without an aggregator ignore them
+	structRC.settingsComponent = &mockSettingsWithoutRollupRules{}
+	assert.NoError(t, structRC.applyRollupRules([]byte(`[{"metric_name": "http.requests"}]`)))
+}
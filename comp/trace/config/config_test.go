@@ -977,6 +977,27 @@ func TestLoadEnv(t *testing.T) {
 		assert.Equal(t, true, cfg.ErrorTrackingStandalone)
 	})
 
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_TAIL_SAMPLING_ENABLED", "true")
+		t.Setenv("DD_APM_TAIL_SAMPLING_DECISION_WAIT", "30s")
+		t.Setenv(env, `[{"name":"slow","min_duration_ms":500},{"error":true,"tags":["http.route:/checkout"]}]`)
+
+		config := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/undocumented.yaml"},
+		}))
+		cfg := config.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSampling.Enabled)
+		assert.Equal(t, 30*time.Second, cfg.TailSampling.DecisionWait)
+		assert.Equal(t, 50000, cfg.TailSampling.MaxTraces)
+		assert.Equal(t, []*traceconfig.TailSamplingPolicy{
+			{Name: "slow", MinDurationMs: 500},
+			{Error: true, Tags: []string{"http.route:/checkout"}},
+		}, cfg.TailSampling.Policies)
+	})
+
 	for _, envKey := range []string{
 		"DD_IGNORE_RESOURCE", // deprecated
 		"DD_APM_IGNORE_RESOURCES",
@@ -1038,6 +1059,22 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SPAN_FILTERS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name":"ping","service":"^redis$","resource":"^PING$","action":"drop"},{"meta":{"http.route":"/health"},"action":"remove_tags","keys":["http.url"]}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.SpanFilterRule{
+			{Name: "ping", Service: "^redis$", Resource: "^PING$", Action: traceconfig.SpanFilterDrop},
+			{Meta: map[string]string{"http.route": "/health"}, Action: traceconfig.SpanFilterRemoveTags, Keys: []string{"http.url"}},
+		}, cfg.SpanFilters)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
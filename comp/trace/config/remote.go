@@ -18,12 +18,16 @@ import (
 )
 
 func remote(c corecompcfg.Component, ipcAddress string) (config.RemoteClient, error) {
+	products := []string{state.ProductAPMSampling, state.ProductAgentConfig}
+	if c.GetBool("remote_configuration.apm_span_filters.enabled") {
+		products = append(products, state.ProductAPMSpanFilters)
+	}
 	return rc.NewGRPCClient(
 		ipcAddress,
 		pkgconfigsetup.GetIPCPort(),
 		func() (string, error) { return security.FetchAuthToken(c) },
 		rc.WithAgent(rcClientName, version.AgentVersion),
-		rc.WithProducts(state.ProductAPMSampling, state.ProductAgentConfig),
+		rc.WithProducts(products...),
 		rc.WithPollInterval(rcClientPollInterval),
 		rc.WithDirectorRootOverride(c.GetString("site"), c.GetString("remote_configuration.director_root")),
 	)
@@ -33,6 +33,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config/structure"
 	"github.com/DataDog/datadog-agent/pkg/config/utils"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters"
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/util/fargate"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
@@ -119,6 +120,7 @@ func prepareConfig(c corecompcfg.Component, tagger tagger.Component) (*config.Ag
 			log.Errorf("Error when subscribing to remote config management %v", err)
 		} else {
 			cfg.RemoteConfigClient = client
+			cfg.SpanFiltersRemoteConfig = coreConfigObject.GetBool("remote_configuration.apm_span_filters.enabled")
 		}
 	}
 	cfg.ContainerTags = func(cid string) ([]string, error) {
@@ -316,6 +318,27 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.ErrorTrackingStandalone = core.GetBool("apm_config.error_tracking_standalone.enabled")
 	}
 
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSampling.Enabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSampling.DecisionWait = core.GetDuration("apm_config.tail_sampling.decision_wait")
+	}
+	if core.IsSet("apm_config.tail_sampling.max_traces") {
+		c.TailSampling.MaxTraces = core.GetInt("apm_config.tail_sampling.max_traces")
+	}
+	if core.IsSet("apm_config.tail_sampling.max_memory") {
+		c.TailSampling.MaxMemory = core.GetInt64("apm_config.tail_sampling.max_memory")
+	}
+	if k := "apm_config.tail_sampling.policies"; core.IsSet(k) {
+		policies := make([]*config.TailSamplingPolicy, 0)
+		if err := structure.UnmarshalKey(core, k, &policies); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"slow\",\"min_duration_ms\":500}]', error: %v", k, err)
+		} else {
+			c.TailSampling.Policies = policies
+		}
+	}
+
 	if core.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = core.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
@@ -366,6 +389,18 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	if k := "apm_config.span_filters"; core.IsSet(k) {
+		rules := make([]*config.SpanFilterRule, 0)
+		if err := structure.UnmarshalKey(core, k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"rule_name\",\"resource\":\"pattern\",\"action\":\"drop\"}]', error: %v", k, err)
+		} else {
+			if _, err := filters.NewSpanFilter(rules); err != nil {
+				return fmt.Errorf("span_filters: %s", err)
+			}
+			c.SpanFilters = rules
+		}
+	}
+
 	if core.IsSet("bind_host") || core.IsSet("apm_config.apm_non_local_traffic") {
 		if core.IsSet("bind_host") {
 			host := core.GetString("bind_host")
@@ -462,6 +497,9 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	}
 	c.Obfuscation.Memcached.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.memcached.enabled")
 	c.Obfuscation.Memcached.KeepCommand = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.memcached.keep_command")
+	c.Obfuscation.GraphQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled")
+	c.Obfuscation.GraphQL.RemoveFragments = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.remove_fragments")
+	c.Obfuscation.CQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.enabled")
 	c.Obfuscation.Redis.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.redis.enabled")
 	c.Obfuscation.Redis.RemoveAllArgs = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.redis.remove_all_args")
 	c.Obfuscation.Valkey.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.valkey.enabled")
@@ -48,6 +48,9 @@ const (
 // tagsetTlm handles telemetry for large tagsets.
 var tagsetTlm *tagsetTelemetry
 
+// contextLimitTlm reports the offenders of the context limits.
+var contextLimitTlm *contextLimitTelemetry
+
 // Stats stores a statistic from several past flushes allowing computations like median or percentiles
 type Stats struct {
 	Flushes    [32]int64 // circular buffer of recent flushes stat
@@ -142,6 +145,8 @@ var (
 	aggregatorOrchestratorManifests            = expvar.Int{}
 	aggregatorOrchestratorManifestsErrors      = expvar.Int{}
 	aggregatorDogstatsdContexts                = expvar.Int{}
+	aggregatorDogstatsdContextsCollapsed       = expvar.Int{}
+	aggregatorContextsLimited                  = expvar.Int{}
 	aggregatorDogstatsdContextsByMtype         = []expvar.Int{}
 	aggregatorEventPlatformEvents              = expvar.Map{}
 	aggregatorEventPlatformEventsErrors        = expvar.Map{}
@@ -157,6 +162,8 @@ var (
 		[]string{"shard"}, "Number of time buckets in the dogstatsd sampler")
 	tlmDogstatsdContexts = telemetry.NewGauge("aggregator", "dogstatsd_contexts",
 		[]string{"shard"}, "Count the number of dogstatsd contexts in the aggregator")
+	tlmDogstatsdContextsCollapsed = telemetry.NewCounter("aggregator", "dogstatsd_contexts_collapsed",
+		[]string{"shard"}, "Count of dogstatsd contexts merged into another one by the tag filter rules")
 	tlmDogstatsdContextsByMtype = telemetry.NewGauge("aggregator", "dogstatsd_contexts_by_mtype",
 		[]string{"shard", "metric_type"}, "Count the number of dogstatsd contexts in the aggregator, by metric type")
 	tlmDogstatsdContextsBytesByMtype = telemetry.NewGauge("aggregator", "dogstatsd_contexts_bytes_by_mtype",
@@ -209,6 +216,8 @@ func init() {
 	aggregatorExpvars.Set("OrchestratorManifests", &aggregatorOrchestratorManifests)
 	aggregatorExpvars.Set("OrchestratorManifestsErrors", &aggregatorOrchestratorManifestsErrors)
 	aggregatorExpvars.Set("DogstatsdContexts", &aggregatorDogstatsdContexts)
+	aggregatorExpvars.Set("DogstatsdContextsCollapsed", &aggregatorDogstatsdContextsCollapsed)
+	aggregatorExpvars.Set("ContextsLimited", &aggregatorContextsLimited)
 	aggregatorExpvars.Set("EventPlatformEvents", &aggregatorEventPlatformEvents)
 	aggregatorExpvars.Set("EventPlatformEventsErrors", &aggregatorEventPlatformEventsErrors)
 
@@ -224,6 +233,9 @@ func init() {
 	tagsetTlm = newTagsetTelemetry([]uint64{90, 100})
 
 	aggregatorExpvars.Set("MetricTags", expvar.Func(expMetricTags))
+
+	contextLimitTlm = newContextLimitTelemetry()
+	aggregatorExpvars.Set("ContextLimits", expvar.Func(contextLimitTlm.exp))
 }
 
 // BufferedAggregator aggregates metrics in buckets for dogstatsd Metrics
@@ -269,6 +281,8 @@ type BufferedAggregator struct {
 	globalTags                  func(types.TagCardinality) ([]string, error) // This function gets global tags from the tagger when host tags are not available
 	tagger                      tagger.Component
 	flushAndSerializeInParallel FlushAndSerializeInParallel
+	// contextLimiter bounds the number of contexts of the samplers if it isn't nil
+	contextLimiter *contextLimiter
 }
 
 // FlushAndSerializeInParallel contains options for flushing metrics and serializing in parallel.
@@ -346,6 +360,7 @@ func NewBufferedAggregator(s serializer.MetricSerializer, eventPlatformForwarder
 		globalTags:                  tagger.GlobalTags,
 		tagger:                      tagger,
 		flushAndSerializeInParallel: NewFlushAndSerializeInParallel(pkgconfigsetup.Datadog()),
+		contextLimiter:              newContextLimiter(pkgconfigsetup.Datadog()),
 	}
 
 	return aggregator
@@ -993,5 +1008,6 @@ func (agg *BufferedAggregator) handleRegisterSampler(id checkid.ID) {
 		agg.tagsStore,
 		id,
 		agg.tagger,
+		agg.contextLimiter,
 	)
 }
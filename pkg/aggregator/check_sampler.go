@@ -33,13 +33,14 @@ type CheckSampler struct {
 	contextResolverMetrics bool
 }
 
-// newCheckSampler returns a newly initialized CheckSampler
-func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID, tagger tagger.Component) *CheckSampler {
+// newCheckSampler returns a newly initialized CheckSampler, whose contexts are
+// limited by limiter if it isn't nil.
+func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID, tagger tagger.Component, limiter *contextLimiter) *CheckSampler {
 	return &CheckSampler{
 		id:                     id,
 		series:                 make([]*metrics.Serie, 0),
 		sketches:               make(metrics.SketchSeriesList, 0),
-		contextResolver:        newCountBasedContextResolver(expirationCount, cache, tagger, string(id)),
+		contextResolver:        newCountBasedContextResolver(expirationCount, cache, tagger, string(id), limiter),
 		metrics:                metrics.NewCheckMetrics(expireMetrics, statefulTimeout),
 		sketchMap:              make(sketchMap),
 		lastBucketValue:        make(map[ckey.ContextKey]int64),
@@ -48,7 +49,10 @@ func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMet
 }
 
 func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
-	contextKey := cs.contextResolver.trackContext(metricSample)
+	contextKey, ok := cs.contextResolver.trackContext(metricSample)
+	if !ok {
+		return
+	}
 
 	if metricSample.Mtype == metrics.DistributionType {
 		cs.sketchMap.insert(int64(metricSample.Timestamp), contextKey, metricSample.Value, metricSample.SampleRate)
@@ -93,7 +97,10 @@ func (cs *CheckSampler) addBucket(bucket *metrics.HistogramBucket) {
 		return
 	}
 
-	contextKey := cs.contextResolver.trackContext(bucket)
+	contextKey, ok := cs.contextResolver.trackContext(bucket)
+	if !ok {
+		return
+	}
 
 	// if the bucket is monotonic and we have already seen the bucket we only send the delta
 	if bucket.Monotonic {
@@ -0,0 +1,218 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// ContextLimitDrop drops the samples of the contexts over the limits.
+	ContextLimitDrop = "drop"
+	// ContextLimitOverflow folds the samples of the contexts over the limits
+	// into an overflow context per metric and origin, tagged with
+	// cardinalityLimitedTag in place of the metric tags.
+	ContextLimitOverflow = "overflow"
+
+	cardinalityLimitedTag = "cardinality_limited:true"
+
+	// contextLimitTopCount is the number of offenders reported.
+	contextLimitTopCount = 10
+	// contextLimitMaxOffenders bounds the number of origins and metrics whose
+	// samples over the limits are counted.
+	contextLimitMaxOffenders = 1000
+)
+
+// contextLimitOrigin identifies the origin of a context: the check sending
+// it, or the tags of the origin detection of DogStatsD. none is set for the
+// DogStatsD samples without any origin, which aren't limited per origin as
+// they can come from any client.
+type contextLimitOrigin struct {
+	check string
+	tags  ckey.TagsKey
+	none  bool
+}
+
+// contextLimiter bounds the number of live contexts by origin and by metric
+// name. It is shared by all the samplers, as the contexts of an origin are
+// spread across the time samplers.
+type contextLimiter struct {
+	originLimit int
+	metricLimit int
+	overflow    bool
+
+	mu       sync.Mutex
+	byOrigin map[contextLimitOrigin]int
+	byMetric map[string]int
+}
+
+// newContextLimiter returns the limiter configured in cfg, or nil when no
+// limit is set.
+func newContextLimiter(cfg model.Reader) *contextLimiter {
+	originLimit := cfg.GetInt("aggregator_context_limit_per_origin")
+	metricLimit := cfg.GetInt("aggregator_context_limit_per_metric")
+	if originLimit <= 0 && metricLimit <= 0 {
+		return nil
+	}
+
+	overflow := true
+	switch action := cfg.GetString("aggregator_context_limit_action"); action {
+	case ContextLimitOverflow:
+	case ContextLimitDrop:
+		overflow = false
+	default:
+		log.Errorf("Invalid aggregator_context_limit_action %q, must be %q or %q, using %q", action, ContextLimitOverflow, ContextLimitDrop, ContextLimitOverflow)
+	}
+
+	return &contextLimiter{
+		originLimit: originLimit,
+		metricLimit: metricLimit,
+		overflow:    overflow,
+		byOrigin:    make(map[contextLimitOrigin]int),
+		byMetric:    make(map[string]int),
+	}
+}
+
+// admit returns whether a new context of the origin and metric can be
+// tracked, and counts it if so. The contexts admitted must be released once
+// expired.
+func (l *contextLimiter) admit(origin contextLimitOrigin, name string) (admitted bool, limit string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.originLimit > 0 && !origin.none && l.byOrigin[origin] >= l.originLimit {
+		return false, "origin"
+	}
+	if l.metricLimit > 0 && l.byMetric[name] >= l.metricLimit {
+		return false, "metric"
+	}
+	if !origin.none {
+		l.byOrigin[origin]++
+	}
+	l.byMetric[name]++
+	return true, ""
+}
+
+// release forgets a context previously admitted.
+func (l *contextLimiter) release(origin contextLimitOrigin, name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !origin.none {
+		if l.byOrigin[origin] <= 1 {
+			delete(l.byOrigin, origin)
+		} else {
+			l.byOrigin[origin]--
+		}
+	}
+	if l.byMetric[name] <= 1 {
+		delete(l.byMetric, name)
+	} else {
+		l.byMetric[name]--
+	}
+}
+
+// contextLimitTelemetry counts the samples of the contexts over the limits,
+// by origin and metric name, to report the top offenders.
+type contextLimitTelemetry struct {
+	mu      sync.Mutex
+	origins map[contextLimitOrigin]*ContextLimitOffender
+	metrics map[string]*ContextLimitOffender
+
+	tlmLimited telemetry.Counter
+}
+
+// ContextLimitOffender is an origin or a metric whose contexts were over the
+// limits.
+type ContextLimitOffender struct {
+	Name    string
+	Samples uint64
+}
+
+func newContextLimitTelemetry() *contextLimitTelemetry {
+	return &contextLimitTelemetry{
+		origins: make(map[contextLimitOrigin]*ContextLimitOffender),
+		metrics: make(map[string]*ContextLimitOffender),
+		tlmLimited: telemetry.NewCounter("aggregator", "contexts_limited",
+			[]string{"limit", "action"}, "Count of samples whose context was over the context limits"),
+	}
+}
+
+// limited counts a sample over the limit of the origin, whose tagger tags
+// are used to name it, and of the metric.
+func (t *contextLimitTelemetry) limited(limit string, overflow bool, origin contextLimitOrigin, taggerTags []string, name string) {
+	action := ContextLimitDrop
+	if overflow {
+		action = ContextLimitOverflow
+	}
+	t.tlmLimited.Inc(limit, action)
+	aggregatorContextsLimited.Add(1)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if offender, ok := t.origins[origin]; ok {
+		offender.Samples++
+	} else if len(t.origins) < contextLimitMaxOffenders {
+		t.origins[origin] = &ContextLimitOffender{Name: contextLimitOriginName(origin.check, taggerTags), Samples: 1}
+	}
+	if offender, ok := t.metrics[name]; ok {
+		offender.Samples++
+	} else if len(t.metrics) < contextLimitMaxOffenders {
+		t.metrics[name] = &ContextLimitOffender{Name: name, Samples: 1}
+	}
+}
+
+func (t *contextLimitTelemetry) exp() interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	origins := make([]ContextLimitOffender, 0, len(t.origins))
+	for _, offender := range t.origins {
+		origins = append(origins, *offender)
+	}
+	metrics := make([]ContextLimitOffender, 0, len(t.metrics))
+	for _, offender := range t.metrics {
+		metrics = append(metrics, *offender)
+	}
+	return map[string][]ContextLimitOffender{
+		"TopOrigins": topContextLimitOffenders(origins),
+		"TopMetrics": topContextLimitOffenders(metrics),
+	}
+}
+
+func topContextLimitOffenders(offenders []ContextLimitOffender) []ContextLimitOffender {
+	sort.Slice(offenders, func(i, j int) bool {
+		if offenders[i].Samples != offenders[j].Samples {
+			return offenders[i].Samples > offenders[j].Samples
+		}
+		return offenders[i].Name < offenders[j].Name
+	})
+	if len(offenders) > contextLimitTopCount {
+		offenders = offenders[:contextLimitTopCount]
+	}
+	return offenders
+}
+
+// contextLimitOriginName returns the name of an origin reported in the
+// telemetry.
+func contextLimitOriginName(check string, taggerTags []string) string {
+	if check != "" {
+		return check
+	}
+	if len(taggerTags) == 0 {
+		return "none"
+	}
+	tags := append([]string(nil), taggerTags...)
+	sort.Strings(tags)
+	return strings.Join(tags, ",")
+}
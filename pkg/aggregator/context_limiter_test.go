@@ -0,0 +1,165 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func newTestContextLimiter(t *testing.T, perOrigin, perMetric int, action string) *contextLimiter {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("aggregator_context_limit_per_origin", perOrigin)
+	cfg.SetWithoutSource("aggregator_context_limit_per_metric", perMetric)
+	cfg.SetWithoutSource("aggregator_context_limit_action", action)
+
+	contextLimitTlm.origins = make(map[contextLimitOrigin]*ContextLimitOffender)
+	contextLimitTlm.metrics = make(map[string]*ContextLimitOffender)
+	return newContextLimiter(cfg)
+}
+
+func TestNewContextLimiterDisabled(t *testing.T) {
+	assert.Nil(t, newTestContextLimiter(t, 0, 0, ContextLimitDrop))
+	assert.NotNil(t, newTestContextLimiter(t, 0, 1, ContextLimitDrop))
+
+	// invalid actions fall back to overflow
+	limiter := newTestContextLimiter(t, 1, 0, "invalid")
+	require.NotNil(t, limiter)
+	assert.True(t, limiter.overflow)
+}
+
+func TestContextLimitPerOriginDrop(t *testing.T) {
+	limiter := newTestContextLimiter(t, 2, 0, ContextLimitDrop)
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", 2, 4, nil, limiter)
+
+	_, ok := resolver.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = resolver.trackContext(&mockSample{"bar", []string{"pod:a"}, []string{"id:2"}}, 0)
+	assert.True(t, ok)
+	_, ok = resolver.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:3"}}, 0)
+	assert.False(t, ok)
+	// the contexts already tracked and the other origins aren't limited
+	_, ok = resolver.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:1"}}, 1)
+	assert.True(t, ok)
+	_, ok = resolver.trackContext(&mockSample{"foo", []string{"pod:b"}, []string{"id:3"}}, 1)
+	assert.True(t, ok)
+	assert.Equal(t, 3, resolver.length())
+
+	// the expired contexts make room for new ones
+	resolver.expireContexts(3)
+	assert.Equal(t, 2, resolver.length())
+	_, ok = resolver.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"id:3"}}, 3)
+	assert.True(t, ok)
+
+	assert.Equal(t, map[string][]ContextLimitOffender{
+		"TopOrigins": {{Name: "pod:a", Samples: 1}},
+		"TopMetrics": {{Name: "foo", Samples: 1}},
+	}, contextLimitTlm.exp())
+}
+
+func TestContextLimitPerOriginWithoutOrigin(t *testing.T) {
+	limiter := newTestContextLimiter(t, 2, 3, ContextLimitDrop)
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", 2, 4, nil, limiter)
+
+	// the samples of several clients without origin detection don't share
+	// the limit of an origin
+	for client := 0; client < 3; client++ {
+		for i := 0; i < 2; i++ {
+			_, ok := resolver.trackContext(&mockSample{fmt.Sprintf("client%d.foo", client), nil, []string{fmt.Sprintf("id:%d", i)}}, 0)
+			assert.True(t, ok)
+		}
+	}
+	assert.Equal(t, 6, resolver.length())
+	assert.Empty(t, limiter.byOrigin)
+
+	// the limit per metric still applies
+	for i := 2; i < 4; i++ {
+		_, ok := resolver.trackContext(&mockSample{"client0.foo", nil, []string{fmt.Sprintf("id:%d", i)}}, 0)
+		assert.Equal(t, i < 3, ok)
+	}
+	assert.Equal(t, map[string][]ContextLimitOffender{
+		"TopOrigins": {{Name: "none", Samples: 1}},
+		"TopMetrics": {{Name: "client0.foo", Samples: 1}},
+	}, contextLimitTlm.exp())
+
+	resolver.expireContexts(3)
+	assert.Equal(t, 0, resolver.length())
+	assert.Empty(t, limiter.byMetric)
+}
+
+func TestContextLimitPerMetricOverflow(t *testing.T) {
+	limiter := newTestContextLimiter(t, 0, 2, ContextLimitOverflow)
+	resolver := newTimestampContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", 2, 4, nil, limiter)
+
+	var keys []ckey.ContextKey
+	for i := 0; i < 5; i++ {
+		key, ok := resolver.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{fmt.Sprintf("id:%d", i)}}, 0)
+		require.True(t, ok)
+		keys = append(keys, key)
+	}
+	_, ok := resolver.trackContext(&mockSample{"bar", []string{"pod:a"}, []string{"id:5"}}, 0)
+	assert.True(t, ok)
+
+	// the samples over the limit are folded into the overflow context
+	assert.Equal(t, 4, resolver.length())
+	assert.Equal(t, keys[2], keys[3])
+	assert.Equal(t, keys[2], keys[4])
+	context, _ := resolver.get(keys[2])
+	assertContext(t, context, "foo", []string{"pod:a", cardinalityLimitedTag}, "noop")
+
+	assert.Equal(t, map[string][]ContextLimitOffender{
+		"TopOrigins": {{Name: "pod:a", Samples: 3}},
+		"TopMetrics": {{Name: "foo", Samples: 3}},
+	}, contextLimitTlm.exp())
+
+	// the overflow context doesn't count against the limit
+	resolver.expireContexts(3)
+	assert.Equal(t, 0, resolver.length())
+	assert.Empty(t, limiter.byMetric)
+	assert.Empty(t, limiter.byOrigin)
+}
+
+func TestContextLimitPerCheck(t *testing.T) {
+	limiter := newTestContextLimiter(t, 1, 0, ContextLimitDrop)
+	resolver := newCountBasedContextResolver(2, tags.NewStore(true, "test"), nooptagger.NewComponent(), "check:1", limiter)
+
+	_, ok := resolver.trackContext(&mockSample{"foo", nil, []string{"id:1"}})
+	assert.True(t, ok)
+	_, ok = resolver.trackContext(&mockSample{"foo", nil, []string{"id:2"}})
+	assert.False(t, ok)
+
+	// the contexts of the other checks aren't limited
+	other := newCountBasedContextResolver(2, tags.NewStore(true, "test"), nooptagger.NewComponent(), "check:2", limiter)
+	_, ok = other.trackContext(&mockSample{"foo", nil, []string{"id:2"}})
+	assert.True(t, ok)
+
+	assert.Equal(t, []ContextLimitOffender{{Name: "check:1", Samples: 1}}, contextLimitTlm.exp().(map[string][]ContextLimitOffender)["TopOrigins"])
+
+	resolver.release()
+	other.release()
+	assert.Empty(t, limiter.byOrigin)
+}
+
+func TestTopContextLimitOffenders(t *testing.T) {
+	var offenders []ContextLimitOffender
+	for i := 0; i < contextLimitTopCount+5; i++ {
+		offenders = append(offenders, ContextLimitOffender{Name: fmt.Sprintf("origin%02d", i), Samples: uint64(i % 3)})
+	}
+	top := topContextLimitOffenders(offenders)
+	require.Len(t, top, contextLimitTopCount)
+	assert.Equal(t, ContextLimitOffender{Name: "origin02", Samples: 2}, top[0])
+	assert.Equal(t, ContextLimitOffender{Name: "origin05", Samples: 2}, top[1])
+}
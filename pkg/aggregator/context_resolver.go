@@ -32,6 +32,12 @@ type Context struct {
 type resolverEntry struct {
 	lastSeen int64
 	context  *Context
+	// limitOrigin is the origin the context counts against in the context
+	// limiter, if counted is set. noOrigin is set when the context has no
+	// origin to count against.
+	limitOrigin ckey.TagsKey
+	counted     bool
+	noOrigin    bool
 }
 
 const (
@@ -76,6 +82,20 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+
+	// tagFilter removes tags before the contexts are resolved, collapsing
+	// the contexts only differing by these tags.
+	tagFilter *tagFilterStore
+	// filteredContexts holds the keys of the contexts whose tags were
+	// filtered, and filteredOrigins estimates the number of distinct contexts
+	// they result from, since the last call to resetCollapsed.
+	filteredContexts map[ckey.ContextKey]struct{}
+	filteredOrigins  *distinctEstimator
+
+	// limiter bounds the number of contexts if it isn't nil. limitCheck is
+	// the origin of all the contexts of the resolver of a check.
+	limiter    *contextLimiter
+	limitCheck string
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -84,6 +104,10 @@ func (cr *contextResolver) generateContextKey(metricSampleContext metrics.Metric
 }
 
 func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *contextResolver {
+	return newContextResolverWithTagFilter(tagger, cache, id, nil)
+}
+
+func newContextResolverWithTagFilter(tagger tagger.Component, cache *tags.Store, id string, tagFilter *tagFilterStore) *contextResolver {
 	return &contextResolver{
 		id:               id,
 		contextsByKey:    make(map[ckey.ContextKey]resolverEntry),
@@ -96,46 +120,116 @@ func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *
 		keyGenerator:     ckey.NewKeyGenerator(),
 		taggerBuffer:     tagset.NewHashingTagsAccumulator(),
 		metricBuffer:     tagset.NewHashingTagsAccumulator(),
+		tagFilter:        tagFilter,
+		filteredContexts: make(map[ckey.ContextKey]struct{}),
+		filteredOrigins:  newDistinctEstimator(),
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) ckey.ContextKey {
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+// It returns false if the sample must be dropped as its context is over the context limits.
+func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, cr.tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
 
+	filtered := false
+	var removedHash uint64
+	if rule := cr.tagFilter.load().match(metricSampleContext.GetName()); rule != nil {
+		filtered, removedHash = rule.apply(cr.taggerBuffer, cr.metricBuffer)
+	}
+
 	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
+	if filtered {
+		cr.trackCollapsed(contextKey, removedHash)
+	}
 
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		context := &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
-			noIndex:    metricSampleContext.IsNoIndex(),
-			source:     metricSampleContext.GetSource(),
-		}
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  context,
+	if cr.touch(contextKey, timestamp) {
+		return contextKey, true
+	}
+
+	counted := false
+	noOrigin := cr.limitCheck == "" && len(cr.taggerBuffer.Get()) == 0
+	if cr.limiter != nil {
+		origin := contextLimitOrigin{check: cr.limitCheck, tags: taggerKey, none: noOrigin}
+		admitted, limit := cr.limiter.admit(origin, metricSampleContext.GetName())
+		if admitted {
+			counted = true
+		} else {
+			contextLimitTlm.limited(limit, cr.limiter.overflow, origin, cr.taggerBuffer.Get(), metricSampleContext.GetName())
+			if !cr.limiter.overflow {
+				return contextKey, false
+			}
+			// fold the sample into the overflow context of its metric and origin
+			cr.metricBuffer.Reset()
+			cr.metricBuffer.Append(cardinalityLimitedTag)
+			contextKey, taggerKey, metricKey = cr.generateContextKey(metricSampleContext)
+			if cr.touch(contextKey, timestamp) {
+				return contextKey, true
+			}
 		}
+	}
+
+	mtype := metricSampleContext.GetMetricType()
+	context := &Context{
+		Name:       metricSampleContext.GetName(),
+		taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		noIndex:    metricSampleContext.IsNoIndex(),
+		source:     metricSampleContext.GetSource(),
+	}
+	cr.contextsByKey[contextKey] = resolverEntry{
+		lastSeen:    timestamp,
+		context:     context,
+		limitOrigin: taggerKey,
+		counted:     counted,
+		noOrigin:    noOrigin,
+	}
 
-		cr.seendByMtype[mtype] = true
-		cr.countsByMtype[mtype]++
-		cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
-		cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
-	} else {
+	cr.seendByMtype[mtype] = true
+	cr.countsByMtype[mtype]++
+	cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
+	cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
+
+	return contextKey, true
+}
+
+// touch updates the last time the context was seen, and returns false if it
+// isn't tracked.
+func (cr *contextResolver) touch(contextKey ckey.ContextKey, timestamp int64) bool {
+	entry, ok := cr.contextsByKey[contextKey]
+	if ok {
 		// We can't assign to a field of a struct contained in map
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  entry.context,
-		}
+		entry.lastSeen = timestamp
+		cr.contextsByKey[contextKey] = entry
 	}
+	return ok
+}
+
+// trackCollapsed tracks a context whose tags were filtered, removedHash
+// being the hash of the tags removed. The contexts it results from are only
+// counted by an estimator, as there are as many of them as the tags filtered
+// allow.
+func (cr *contextResolver) trackCollapsed(contextKey ckey.ContextKey, removedHash uint64) {
+	cr.filteredContexts[contextKey] = struct{}{}
+	cr.filteredOrigins.add(uint64(contextKey) ^ mixHash(removedHash))
+}
 
-	return contextKey
+// resetCollapsed returns the estimated number of contexts collapsed since
+// its last call, and forgets the contexts seen so far.
+func (cr *contextResolver) resetCollapsed() uint64 {
+	if len(cr.filteredContexts) == 0 {
+		return 0
+	}
+	var collapsed uint64
+	if origins := cr.filteredOrigins.estimate(); origins > uint64(len(cr.filteredContexts)) {
+		collapsed = origins - uint64(len(cr.filteredContexts))
+	}
+	cr.filteredContexts = make(map[ckey.ContextKey]struct{})
+	cr.filteredOrigins.reset()
+	return collapsed
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -148,8 +242,10 @@ func (cr *contextResolver) length() int {
 }
 
 func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
-	context := cr.contextsByKey[expiredContextKey].context
+	entry := cr.contextsByKey[expiredContextKey]
+	context := entry.context
 	delete(cr.contextsByKey, expiredContextKey)
+	cr.releaseLimit(entry)
 
 	if context != nil {
 		cr.countsByMtype[context.mtype]--
@@ -179,6 +275,14 @@ func (cr *contextResolver) updateMetrics(countsByMTypeGauge telemetry.Gauge, byt
 func (cr *contextResolver) release() {
 	for _, c := range cr.contextsByKey {
 		c.context.release()
+		cr.releaseLimit(c)
+	}
+}
+
+// releaseLimit forgets the entry in the context limiter.
+func (cr *contextResolver) releaseLimit(entry resolverEntry) {
+	if entry.counted {
+		cr.limiter.release(contextLimitOrigin{check: cr.limitCheck, tags: entry.limitOrigin, none: entry.noOrigin}, entry.context.Name)
 	}
 }
 
@@ -221,9 +325,11 @@ type timestampContextResolver struct {
 	counterExpireTime int64
 }
 
-func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64, tagFilter *tagFilterStore, limiter *contextLimiter) *timestampContextResolver {
+	resolver := newContextResolverWithTagFilter(tagger, cache, id, tagFilter)
+	resolver.limiter = limiter
 	return &timestampContextResolver{
-		resolver: newContextResolver(tagger, cache, id),
+		resolver: resolver,
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
@@ -231,9 +337,8 @@ func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id
 }
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, currentTimestamp)
-	return contextKey
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, currentTimestamp)
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -244,6 +349,10 @@ func (cr *timestampContextResolver) countsByMtype() []uint64 {
 	return cr.resolver.countsByMtype
 }
 
+func (cr *timestampContextResolver) resetCollapsed() uint64 {
+	return cr.resolver.resetCollapsed()
+}
+
 func (cr *timestampContextResolver) get(key ckey.ContextKey) (*Context, bool) {
 	return cr.resolver.get(key)
 }
@@ -281,9 +390,14 @@ type countBasedContextResolver struct {
 	expireCountInterval int64
 }
 
-func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string) *countBasedContextResolver {
+// newCountBasedContextResolver returns a resolver for the check id. The
+// contexts of the check are limited by limiter if it isn't nil.
+func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string, limiter *contextLimiter) *countBasedContextResolver {
+	resolver := newContextResolver(tagger, cache, id)
+	resolver.limiter = limiter
+	resolver.limitCheck = id
 	return &countBasedContextResolver{
-		resolver:            newContextResolver(tagger, cache, id),
+		resolver:            resolver,
 		expireCount:         0,
 		expireCountInterval: int64(expireCountInterval),
 	}
@@ -299,9 +413,8 @@ func (cr *countBasedContextResolver) updateMetrics(countsByMTypeGauge telemetry.
 }
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, cr.expireCount)
-	return contextKey
+func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, cr.expireCount)
 }
 
 func (cr *countBasedContextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -78,9 +78,9 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 	contextResolver := newContextResolver(nooptagger.NewComponent(), store, "test")
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 0)
-	contextKey2 := contextResolver.trackContext(&mSample2, 0)
-	contextKey3 := contextResolver.trackContext(&mSample3, 0)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 0)
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 0)
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 0)
 
 	// When we look up the 2 keys, they return the correct contexts
 	context1 := contextResolver.contextsByKey[contextKey1].context
@@ -139,12 +139,12 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4, nil, nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4) // expires after 6
-	contextKey2 := contextResolver.trackContext(&mSample2, 6) // expires after 8
-	contextKey3 := contextResolver.trackContext(&mSample3, 6) // expires after 10
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4) // expires after 6
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6) // expires after 8
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 6) // expires after 10
 
 	// With an expireTimestap of 3, both contexts are still valid
 	contextResolver.expireContexts(4)
@@ -191,13 +191,13 @@ func testCountBasedExpireContexts(t *testing.T, store *tags.Store) {
 	mSample1 := metrics.MetricSample{Name: "my.metric.name1"}
 	mSample2 := metrics.MetricSample{Name: "my.metric.name2"}
 	mSample3 := metrics.MetricSample{Name: "my.metric.name3"}
-	contextResolver := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "test")
+	contextResolver := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "test", nil)
 
-	contextKey1 := contextResolver.trackContext(&mSample1)
-	contextKey2 := contextResolver.trackContext(&mSample2)
+	contextKey1, _ := contextResolver.trackContext(&mSample1)
+	contextKey2, _ := contextResolver.trackContext(&mSample2)
 	require.Len(t, contextResolver.expireContexts(), 0)
 
-	contextKey3 := contextResolver.trackContext(&mSample3)
+	contextKey3, _ := contextResolver.trackContext(&mSample3)
 	contextResolver.trackContext(&mSample2)
 	require.Len(t, contextResolver.expireContexts(), 0)
 
@@ -218,7 +218,7 @@ func TestCountBasedExpireContexts(t *testing.T) {
 func testTagDeduplication(t *testing.T, store *tags.Store) {
 	resolver := newContextResolver(nooptagger.NewComponent(), store, "test")
 
-	ckey := resolver.trackContext(&metrics.MetricSample{
+	ckey, _ := resolver.trackContext(&metrics.MetricSample{
 		Name: "foo",
 		Tags: []string{"bar", "bar"},
 	}, 0)
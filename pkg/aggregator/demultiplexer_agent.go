@@ -9,6 +9,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"strconv"
 	"sync"
 	"time"
 
@@ -29,8 +31,13 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
+	"github.com/DataDog/datadog-agent/pkg/serializer/openmetrics"
 )
 
+// openMetricsSeriesExpiry is the time after which the series not flushed
+// again are no longer exposed by the OpenMetrics exporter.
+const openMetricsSeriesExpiry = 5 * time.Minute
+
 // DemultiplexerWithAggregator is a Demultiplexer running an Aggregator.
 // This flavor uses a AgentDemultiplexerOptions struct for startup configuration.
 type DemultiplexerWithAggregator interface {
@@ -42,6 +49,7 @@ type DemultiplexerWithAggregator interface {
 	GetEventPlatformForwarder() (eventplatform.Forwarder, error)
 	GetEventsAndServiceChecksChannels() (chan []*event.Event, chan []*servicecheck.ServiceCheck)
 	DumpDogstatsdContexts(io.Writer) error
+	FlushedMetrics(query FlushedMetricsQuery) []FlushedMetric
 }
 
 // AgentDemultiplexer is the demultiplexer implementation for the main Agent.
@@ -67,6 +75,14 @@ type AgentDemultiplexer struct {
 
 	hostTagProvider *HostTagProvider
 
+	// flushedMetrics keeps the last metrics flushed, nil when disabled.
+	flushedMetrics *flushedMetrics
+	// openMetrics exposes the metrics flushed in the OpenMetrics format, nil
+	// when disabled.
+	openMetrics *openmetrics.Exporter
+	// rollupRules rolls up the metrics flushed by all the samplers.
+	rollupRules *rollupStore
+
 	// sharded statsd time samplers
 	statsd
 }
@@ -81,6 +97,11 @@ type AgentDemultiplexerOptions struct {
 
 	UseDogstatsdContextLimiter bool
 	DogstatsdMaxMetricsTags    int
+
+	// OpenMetricsExporterPort is the port the OpenMetrics exporter listens
+	// on, 0 to disable it. Only the long-running agent enables it, so that
+	// the one-shot commands don't bind its port.
+	OpenMetricsExporterPort int
 }
 
 // DefaultAgentDemultiplexerOptions returns the default options to initialize an AgentDemultiplexer.
@@ -170,12 +191,13 @@ func initAgentDemultiplexer(log log.Component,
 	log.Debug("the Demultiplexer will use", statsdPipelinesCount, "pipelines")
 
 	statsdWorkers := make([]*timeSamplerWorker, statsdPipelinesCount)
+	tagFilter := newTagFilterStore(pkgconfigsetup.Datadog())
 
 	for i := 0; i < statsdPipelinesCount; i++ {
 		// the sampler
 		tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname)
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname, tagFilter, agg.contextLimiter)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -215,6 +237,9 @@ func initAgentDemultiplexer(log log.Component,
 		},
 
 		hostTagProvider: NewHostTagProvider(),
+		flushedMetrics:  newFlushedMetrics(pkgconfigsetup.Datadog()),
+		openMetrics:     newOpenMetricsExporter(log, options.OpenMetricsExporterPort, pkgconfigsetup.Datadog()),
+		rollupRules:     newRollupStore(pkgconfigsetup.Datadog()),
 		senders:         newSenders(agg),
 
 		// statsd time samplers
@@ -229,6 +254,22 @@ func initAgentDemultiplexer(log log.Component,
 	return demux
 }
 
+// newOpenMetricsExporter returns the OpenMetrics exporter listening on port,
+// or nil when it is disabled or can't listen.
+func newOpenMetricsExporter(log log.Component, port int, cfg model.Reader) *openmetrics.Exporter {
+	if port <= 0 {
+		return nil
+	}
+
+	exporter := openmetrics.NewExporter(openMetricsSeriesExpiry, log)
+	addr := net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), strconv.Itoa(port))
+	if _, err := exporter.Listen(addr); err != nil {
+		log.Errorf("Unable to start the OpenMetrics exporter on %s: %v", addr, err)
+		return nil
+	}
+	return exporter
+}
+
 // Options returns options used during the demux initialization.
 func (d *AgentDemultiplexer) Options() AgentDemultiplexerOptions {
 	return d.options
@@ -368,6 +409,9 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 
 	// misc
 
+	if d.openMetrics != nil {
+		d.openMetrics.Stop()
+	}
 	d.dataOutputs.sharedSerializer = nil
 	d.senders = nil
 }
@@ -412,6 +456,15 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			seriesSink, sketchesSink = d.flushedMetrics.sinks(start, seriesSink, sketchesSink)
+			if d.openMetrics != nil {
+				seriesSink, sketchesSink = d.openMetrics.Sinks(start, seriesSink, sketchesSink)
+			}
+			// the metrics of all the samplers are rolled up together
+			rollup := newMetricsRollup(d.rollupRules.load())
+			rolledUpSeriesSink, rolledUpSketchesSink := seriesSink, sketchesSink
+			seriesSink, sketchesSink = rollup.sinks(seriesSink, sketchesSink)
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -447,6 +500,11 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 				d.aggregator.flushChan <- t
 				<-t.trigger.blockChan
 			}
+
+			// flush the metrics rolled up
+			// ---------------------------
+
+			rollup.flush(rolledUpSeriesSink, rolledUpSketchesSink)
 		}, func(serieSource metrics.SerieSource) {
 			sendIterableSeries(d.sharedSerializer, start, serieSource)
 		},
@@ -563,6 +621,12 @@ func (d *AgentDemultiplexer) DumpDogstatsdContexts(dest io.Writer) error {
 	return nil
 }
 
+// FlushedMetrics returns the series and sketches recently flushed matching
+// the query, the oldest first.
+func (d *AgentDemultiplexer) FlushedMetrics(query FlushedMetricsQuery) []FlushedMetric {
+	return d.flushedMetrics.query(query)
+}
+
 // GetSender returns a sender.Sender with passed ID, properly registered with the aggregator
 // If no error is returned here, DestroySender must be called with the same ID
 // once the sender is not used anymore
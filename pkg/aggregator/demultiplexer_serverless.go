@@ -40,6 +40,9 @@ type ServerlessDemultiplexer struct {
 
 	hostTagProvider *HostTagProvider
 
+	// rollupRules rolls up the metrics flushed by the time sampler.
+	rollupRules *rollupStore
+
 	*senders
 }
 
@@ -55,7 +58,7 @@ func InitAndStartServerlessDemultiplexer(keysPerDomain map[string][]string, forw
 	metricSamplePool := metrics.NewMetricSamplePool(MetricSamplePoolBatchSize, utils.IsTelemetryEnabled(pkgconfigsetup.Datadog()))
 	tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), "timesampler")
 
-	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, tagger, "")
+	statsdSampler := NewTimeSampler(TimeSamplerID(0), bucketSize, tagsStore, tagger, "", newTagFilterStore(config), nil)
 	flushAndSerializeInParallel := NewFlushAndSerializeInParallel(pkgconfigsetup.Datadog())
 	statsdWorker := newTimeSamplerWorker(statsdSampler, DefaultFlushInterval, bufferSize, metricSamplePool, flushAndSerializeInParallel, tagsStore)
 
@@ -69,6 +72,7 @@ func InitAndStartServerlessDemultiplexer(keysPerDomain map[string][]string, forw
 		flushLock:                   &sync.Mutex{},
 		hostTagProvider:             NewHostTagProvider(),
 		flushAndSerializeInParallel: flushAndSerializeInParallel,
+		rollupRules:                 newRollupStore(config),
 	}
 
 	// start routines
@@ -116,6 +120,10 @@ func (d *ServerlessDemultiplexer) ForceFlushToSerializer(start time.Time, waitFo
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			rollup := newMetricsRollup(d.rollupRules.load())
+			rolledUpSeriesSink, rolledUpSketchesSink := seriesSink, sketchesSink
+			seriesSink, sketchesSink = rollup.sinks(seriesSink, sketchesSink)
+
 			trigger := flushTrigger{
 				trigger: trigger{
 					time:              start,
@@ -128,6 +136,7 @@ func (d *ServerlessDemultiplexer) ForceFlushToSerializer(start time.Time, waitFo
 
 			d.statsdWorker.flushChan <- trigger
 			<-trigger.blockChan
+			rollup.flush(rolledUpSeriesSink, rolledUpSketchesSink)
 		}, func(serieSource metrics.SerieSource) {
 			sendIterableSeries(d.serializer, start, serieSource)
 		}, func(sketches metrics.SketchesSource) {
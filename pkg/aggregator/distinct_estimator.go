@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"math"
+	"math/bits"
+)
+
+const (
+	// distinctEstimatorBits is the number of bits of the hashes selecting
+	// the register, the estimator has 2^distinctEstimatorBits registers and a
+	// standard error of about 1.04/sqrt(2^distinctEstimatorBits), 6.5%.
+	distinctEstimatorBits      = 8
+	distinctEstimatorRegisters = 1 << distinctEstimatorBits
+
+	// distinctEstimatorExactLimit is the number of distinct hashes counted
+	// exactly, before relying on the estimate only.
+	distinctEstimatorExactLimit = 1024
+)
+
+// distinctEstimator estimates the number of distinct hashes added to it, in a
+// fixed amount of memory. They are counted exactly up to
+// distinctEstimatorExactLimit, and estimated with the HyperLogLog algorithm
+// past it.
+type distinctEstimator struct {
+	registers [distinctEstimatorRegisters]uint8
+	empty     bool
+	// exact holds the hashes added, nil once there are more than
+	// distinctEstimatorExactLimit of them.
+	exact map[uint64]struct{}
+}
+
+func newDistinctEstimator() *distinctEstimator {
+	return &distinctEstimator{empty: true, exact: make(map[uint64]struct{})}
+}
+
+// add adds a hash to the estimator.
+func (e *distinctEstimator) add(hash uint64) {
+	if e.exact != nil {
+		e.exact[hash] = struct{}{}
+		if len(e.exact) > distinctEstimatorExactLimit {
+			e.exact = nil
+		}
+	}
+
+	hash = mixHash(hash)
+	register := hash >> (64 - distinctEstimatorBits)
+	rank := uint8(bits.LeadingZeros64(hash<<distinctEstimatorBits|1<<(distinctEstimatorBits-1)) + 1)
+	if rank > e.registers[register] {
+		e.registers[register] = rank
+	}
+	e.empty = false
+}
+
+// estimate returns the estimated number of distinct hashes added.
+func (e *distinctEstimator) estimate() uint64 {
+	if e.empty {
+		return 0
+	}
+	if e.exact != nil {
+		return uint64(len(e.exact))
+	}
+	const m = float64(distinctEstimatorRegisters)
+	sum := 0.0
+	zeros := 0
+	for _, r := range e.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	// the linear counting is more accurate for the small cardinalities
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(math.Round(estimate))
+}
+
+// reset forgets the hashes added.
+func (e *distinctEstimator) reset() {
+	if !e.empty {
+		*e = distinctEstimator{empty: true, exact: make(map[uint64]struct{})}
+	}
+}
+
+// mixHash spreads the bits of a hash, as the hashes of the tags combined with
+// xor aren't uniformly distributed enough for the estimator.
+func mixHash(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistinctEstimator(t *testing.T) {
+	e := newDistinctEstimator()
+	assert.Zero(t, e.estimate())
+
+	// the small cardinalities are exact
+	for i := uint64(0); i < 3*distinctEstimatorExactLimit; i++ {
+		e.add(i % distinctEstimatorExactLimit)
+	}
+	assert.Equal(t, uint64(distinctEstimatorExactLimit), e.estimate())
+
+	e.reset()
+	assert.Zero(t, e.estimate())
+
+	// the larger ones are estimated
+	for _, n := range []uint64{2000, 10000, 100000} {
+		e.reset()
+		for i := uint64(0); i < n; i++ {
+			e.add(i)
+			e.add(i)
+		}
+		assert.InEpsilon(t, n, e.estimate(), 0.15, "n=%d", n)
+		assert.Nil(t, e.exact)
+	}
+}
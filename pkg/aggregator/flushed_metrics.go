@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"path"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// FlushedMetric is a serie or a sketch recently flushed to the serializer.
+type FlushedMetric struct {
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	Host     string   `json:"host"`
+	Tags     []string `json:"tags"`
+	Interval int64    `json:"interval"`
+	// FlushTime is the time of the flush, in seconds since the epoch.
+	FlushTime int64          `json:"flush_time"`
+	Points    []FlushedPoint `json:"points"`
+}
+
+// FlushedPoint is a point of a flushed metric. Sketch is only set for the
+// points of the sketches, whose Value is the average.
+type FlushedPoint struct {
+	Timestamp int64          `json:"timestamp"`
+	Value     float64        `json:"value"`
+	Sketch    *FlushedSketch `json:"sketch,omitempty"`
+}
+
+// FlushedSketch summarizes a flushed sketch.
+type FlushedSketch struct {
+	Count int64   `json:"count"`
+	Sum   float64 `json:"sum"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+}
+
+// FlushedMetricsQuery selects flushed metrics.
+type FlushedMetricsQuery struct {
+	// Name is the pattern, in the syntax of path.Match, the names of the
+	// metrics must match. Empty matches all the names.
+	Name string
+	// Tags are the patterns, in the syntax of path.Match, each matching at
+	// least one of the tags of the metrics.
+	Tags []string
+}
+
+// Validate returns an error if a pattern of the query is malformed.
+func (q FlushedMetricsQuery) Validate() error {
+	for _, pattern := range append([]string{q.Name}, q.Tags...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (q FlushedMetricsQuery) match(m *FlushedMetric) bool {
+	if q.Name != "" {
+		if ok, _ := path.Match(q.Name, m.Name); !ok {
+			return false
+		}
+	}
+	for _, pattern := range q.Tags {
+		found := false
+		for _, tag := range m.Tags {
+			if ok, _ := path.Match(pattern, tag); ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// flushedMetrics keeps the last series and sketches flushed to the serializer
+// in a ring, to query what the agent sent without going to the backend.
+type flushedMetrics struct {
+	mu      sync.Mutex
+	entries []FlushedMetric
+	next    int
+	full    bool
+}
+
+// newFlushedMetrics returns the ring sized in cfg, or nil when it is
+// disabled.
+func newFlushedMetrics(cfg model.Reader) *flushedMetrics {
+	size := cfg.GetInt("aggregator_flushed_metrics_buffer_size")
+	if size <= 0 {
+		return nil
+	}
+	return &flushedMetrics{entries: make([]FlushedMetric, size)}
+}
+
+func (f *flushedMetrics) add(m FlushedMetric) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.entries[f.next] = m
+	f.next++
+	if f.next == len(f.entries) {
+		f.next = 0
+		f.full = true
+	}
+}
+
+// query returns the metrics matching q, the oldest first.
+func (f *flushedMetrics) query(q FlushedMetricsQuery) []FlushedMetric {
+	result := []FlushedMetric{}
+	if f == nil {
+		return result
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	start, count := 0, f.next
+	if f.full {
+		start, count = f.next, len(f.entries)
+	}
+	for i := 0; i < count; i++ {
+		m := &f.entries[(start+i)%len(f.entries)]
+		if q.match(m) {
+			result = append(result, *m)
+		}
+	}
+	return result
+}
+
+// sinks returns sinks recording the series and sketches appended to them
+// before appending them to seriesSink and sketchesSink.
+func (f *flushedMetrics) sinks(start time.Time, seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) (metrics.SerieSink, metrics.SketchesSink) {
+	if f == nil {
+		return seriesSink, sketchesSink
+	}
+	flushTime := start.Unix()
+	return &flushedSerieSink{SerieSink: seriesSink, flushed: f, flushTime: flushTime},
+		&flushedSketchesSink{SketchesSink: sketchesSink, flushed: f, flushTime: flushTime}
+}
+
+type flushedSerieSink struct {
+	metrics.SerieSink
+	flushed   *flushedMetrics
+	flushTime int64
+}
+
+func (s *flushedSerieSink) Append(serie *metrics.Serie) {
+	points := make([]FlushedPoint, 0, len(serie.Points))
+	for _, p := range serie.Points {
+		points = append(points, FlushedPoint{Timestamp: int64(p.Ts), Value: p.Value})
+	}
+	s.flushed.add(FlushedMetric{
+		Name:      serie.Name,
+		Type:      serie.MType.String(),
+		Host:      serie.Host,
+		Tags:      append([]string(nil), serie.Tags.UnsafeToReadOnlySliceString()...),
+		Interval:  serie.Interval,
+		FlushTime: s.flushTime,
+		Points:    points,
+	})
+	s.SerieSink.Append(serie)
+}
+
+type flushedSketchesSink struct {
+	metrics.SketchesSink
+	flushed   *flushedMetrics
+	flushTime int64
+}
+
+func (s *flushedSketchesSink) Append(sketch *metrics.SketchSeries) {
+	points := make([]FlushedPoint, 0, len(sketch.Points))
+	for _, p := range sketch.Points {
+		point := FlushedPoint{Timestamp: p.Ts}
+		if p.Sketch != nil {
+			b := p.Sketch.Basic
+			point.Value = b.Avg
+			point.Sketch = &FlushedSketch{Count: b.Cnt, Sum: b.Sum, Min: b.Min, Max: b.Max, Avg: b.Avg}
+		}
+		points = append(points, point)
+	}
+	s.flushed.add(FlushedMetric{
+		Name:      sketch.Name,
+		Type:      "distribution",
+		Host:      sketch.Host,
+		Tags:      append([]string(nil), sketch.Tags.UnsafeToReadOnlySliceString()...),
+		Interval:  sketch.Interval,
+		FlushTime: s.flushTime,
+		Points:    points,
+	})
+	s.SketchesSink.Append(sketch)
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func newTestFlushedMetrics(t *testing.T, size int) *flushedMetrics {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("aggregator_flushed_metrics_buffer_size", size)
+	return newFlushedMetrics(cfg)
+}
+
+func TestFlushedMetricsDisabled(t *testing.T) {
+	flushed := newTestFlushedMetrics(t, 0)
+	require.Nil(t, flushed)
+
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	seriesSink, sketchesSink := flushed.sinks(time.Now(), &series, &sketches)
+	assert.Equal(t, &series, seriesSink)
+	assert.Equal(t, &sketches, sketchesSink)
+	assert.Empty(t, flushed.query(FlushedMetricsQuery{}))
+}
+
+func TestFlushedMetricsSinks(t *testing.T) {
+	flushed := newTestFlushedMetrics(t, 10)
+
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	seriesSink, sketchesSink := flushed.sinks(time.Unix(1000, 0), &series, &sketches)
+
+	serie := &metrics.Serie{
+		Name:     "my.gauge",
+		Points:   []metrics.Point{{Ts: 990, Value: 12}},
+		Tags:     tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Host:     "host",
+		MType:    metrics.APIGaugeType,
+		Interval: 10,
+	}
+	seriesSink.Append(serie)
+
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1, 2, 3)
+	sketchSeries := &metrics.SketchSeries{
+		Name:     "my.distribution",
+		Tags:     tagset.CompositeTagsFromSlice([]string{"env:staging"}),
+		Host:     "host",
+		Interval: 10,
+		Points:   []metrics.SketchPoint{{Sketch: sketch, Ts: 990}},
+	}
+	sketchesSink.Append(sketchSeries)
+
+	// the metrics are still appended to the underlying sinks
+	assert.Equal(t, metrics.Series{serie}, series)
+	assert.Equal(t, metrics.SketchSeriesList{sketchSeries}, sketches)
+
+	assert.Equal(t, []FlushedMetric{
+		{
+			Name:      "my.gauge",
+			Type:      "gauge",
+			Host:      "host",
+			Tags:      []string{"env:prod"},
+			Interval:  10,
+			FlushTime: 1000,
+			Points:    []FlushedPoint{{Timestamp: 990, Value: 12}},
+		},
+		{
+			Name:      "my.distribution",
+			Type:      "distribution",
+			Host:      "host",
+			Tags:      []string{"env:staging"},
+			Interval:  10,
+			FlushTime: 1000,
+			Points: []FlushedPoint{{
+				Timestamp: 990,
+				Value:     2,
+				Sketch:    &FlushedSketch{Count: 3, Sum: 6, Min: 1, Max: 3, Avg: 2},
+			}},
+		},
+	}, flushed.query(FlushedMetricsQuery{}))
+}
+
+func TestFlushedMetricsQuery(t *testing.T) {
+	flushed := newTestFlushedMetrics(t, 4)
+	for i := 0; i < 6; i++ {
+		flushed.add(FlushedMetric{
+			Name: fmt.Sprintf("metric.%d", i%3),
+			Tags: []string{fmt.Sprintf("id:%d", i), fmt.Sprintf("parity:%d", i%2)},
+		})
+	}
+
+	names := func(q FlushedMetricsQuery) []string {
+		result := []string{}
+		for _, m := range flushed.query(q) {
+			result = append(result, m.Tags[0])
+		}
+		return result
+	}
+
+	// the oldest metrics are overwritten
+	assert.Equal(t, []string{"id:2", "id:3", "id:4", "id:5"}, names(FlushedMetricsQuery{}))
+	assert.Equal(t, []string{"id:2", "id:5"}, names(FlushedMetricsQuery{Name: "metric.2"}))
+	assert.Equal(t, []string{"id:2", "id:4"}, names(FlushedMetricsQuery{Name: "metric.*", Tags: []string{"parity:0"}}))
+	assert.Equal(t, []string{"id:5"}, names(FlushedMetricsQuery{Tags: []string{"parity:1", "id:?"}, Name: "*.2"}))
+	assert.Empty(t, names(FlushedMetricsQuery{Tags: []string{"missing"}}))
+
+	assert.NoError(t, FlushedMetricsQuery{Name: "metric.*", Tags: []string{"env:*"}}.Validate())
+	assert.Error(t, FlushedMetricsQuery{Name: "metric.["}.Validate())
+	assert.Error(t, FlushedMetricsQuery{Tags: []string{"env:["}}.Validate())
+}
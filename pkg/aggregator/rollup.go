@@ -0,0 +1,365 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// RollupRulesKey is the setting holding the rollup rules of the metrics.
+const RollupRulesKey = "aggregator_rollup_rules"
+
+// RollupRule rolls up, when they are flushed, the metrics whose name matches
+// MetricName, which is either a metric name or a glob pattern: their series
+// are re-keyed on the tags whose key is listed in KeepTags and their host,
+// the values of the series sharing these are summed and their sketches
+// merged. The series of the hosts listed in FullCardinalityHosts are also
+// flushed with all their tags.
+//
+// The max and min of the histograms are rolled up with their own function,
+// their averages, medians and percentiles are flushed as they are. The
+// metrics sent with a timestamp, which bypass the aggregation, are not
+// rolled up.
+type RollupRule struct {
+	MetricName           string   `mapstructure:"metric_name" json:"metric_name" yaml:"metric_name"`
+	KeepTags             []string `mapstructure:"keep_tags" json:"keep_tags" yaml:"keep_tags"`
+	FullCardinalityHosts []string `mapstructure:"full_cardinality_hosts" json:"full_cardinality_hosts" yaml:"full_cardinality_hosts"`
+}
+
+type rollupRule struct {
+	keys  map[string]struct{}
+	hosts map[string]struct{}
+}
+
+// keep returns whether the tag is kept by the rule.
+func (r *rollupRule) keep(tag string) bool {
+	key := tag
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		key = tag[:i]
+	}
+	_, ok := r.keys[key]
+	return ok
+}
+
+// fullCardinality returns whether the series of the host are also flushed
+// with all their tags.
+func (r *rollupRule) fullCardinality(host string) bool {
+	_, ok := r.hosts[host]
+	return ok
+}
+
+// rollupTags returns the tags kept by the rule, sorted and deduplicated.
+func (r *rollupRule) rollupTags(tags tagset.CompositeTags) []string {
+	var kept []string
+	tags.ForEach(func(tag string) {
+		if r.keep(tag) {
+			kept = append(kept, tag)
+		}
+	})
+	slices.Sort(kept)
+	return slices.Compact(kept)
+}
+
+type rollupGlob struct {
+	pattern string
+	rule    *rollupRule
+}
+
+// rollupList is a compiled set of rollup rules. Rules on a metric name take
+// precedence over the glob patterns, which are tried in order.
+type rollupList struct {
+	byName map[string]*rollupRule
+	globs  []rollupGlob
+}
+
+// ValidateRollupRules returns an error if one of the rules is invalid.
+func ValidateRollupRules(rules []RollupRule) error {
+	_, err := newRollupList(rules)
+	return err
+}
+
+// newRollupList compiles the rules, returning an error if one is invalid.
+func newRollupList(rules []RollupRule) (*rollupList, error) {
+	l := &rollupList{byName: make(map[string]*rollupRule)}
+	for i, rule := range rules {
+		if rule.MetricName == "" {
+			return nil, fmt.Errorf("rule %d: metric_name is required", i)
+		}
+		compiled := &rollupRule{
+			keys:  make(map[string]struct{}, len(rule.KeepTags)),
+			hosts: make(map[string]struct{}, len(rule.FullCardinalityHosts)),
+		}
+		for _, key := range rule.KeepTags {
+			compiled.keys[key] = struct{}{}
+		}
+		for _, host := range rule.FullCardinalityHosts {
+			compiled.hosts[host] = struct{}{}
+		}
+
+		if !strings.ContainsAny(rule.MetricName, `*?[\`) {
+			if _, exists := l.byName[rule.MetricName]; !exists {
+				l.byName[rule.MetricName] = compiled
+			}
+			continue
+		}
+		if _, err := path.Match(rule.MetricName, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid metric_name pattern %q: %v", i, rule.MetricName, err)
+		}
+		l.globs = append(l.globs, rollupGlob{pattern: rule.MetricName, rule: compiled})
+	}
+	return l, nil
+}
+
+// match returns the rule applying to the metric, or nil if there is none.
+func (l *rollupList) match(name string) *rollupRule {
+	if l == nil {
+		return nil
+	}
+	if rule, ok := l.byName[name]; ok {
+		return rule
+	}
+	for _, glob := range l.globs {
+		if matched, _ := path.Match(glob.pattern, name); matched {
+			return glob.rule
+		}
+	}
+	return nil
+}
+
+func (l *rollupList) empty() bool {
+	return l == nil || (len(l.byName) == 0 && len(l.globs) == 0)
+}
+
+// rollupKey identifies a rolled up serie or sketch.
+type rollupKey struct {
+	name  string
+	host  string
+	tags  string
+	mType metrics.APIMetricType
+}
+
+// rollupAggregate returns how the points of the series with the suffix are
+// rolled up, and false if they can't be: the averages, medians and
+// percentiles of the histograms can't be computed from the ones of their
+// contexts, and are flushed as they are.
+func rollupAggregate(nameSuffix string) (func(a, b float64) float64, bool) {
+	switch nameSuffix {
+	case "", ".sum", ".count":
+		return func(a, b float64) float64 { return a + b }, true
+	case ".max":
+		return math.Max, true
+	case ".min":
+		return math.Min, true
+	default:
+		return nil, false
+	}
+}
+
+// metricsRollup rolls up the series and sketches of a flush.
+type metricsRollup struct {
+	rules    *rollupList
+	keyGen   *ckey.KeyGenerator
+	series   map[rollupKey]*metrics.Serie
+	sketches map[rollupKey]*metrics.SketchSeries
+}
+
+// newMetricsRollup returns the rollup of a flush by the rules, or nil if
+// there is no rule.
+func newMetricsRollup(rules *rollupList) *metricsRollup {
+	if rules.empty() {
+		return nil
+	}
+	return &metricsRollup{
+		rules:    rules,
+		keyGen:   ckey.NewKeyGenerator(),
+		series:   make(map[rollupKey]*metrics.Serie),
+		sketches: make(map[rollupKey]*metrics.SketchSeries),
+	}
+}
+
+// addSerie rolls up the serie if a rule matches its metric, and returns
+// whether the serie must be flushed as is.
+func (r *metricsRollup) addSerie(serie *metrics.Serie) bool {
+	if r == nil {
+		return true
+	}
+	rule := r.rules.match(strings.TrimSuffix(serie.Name, serie.NameSuffix))
+	if rule == nil {
+		return true
+	}
+	aggregate, ok := rollupAggregate(serie.NameSuffix)
+	if !ok {
+		return true
+	}
+
+	tags := rule.rollupTags(serie.Tags)
+	key := rollupKey{name: serie.Name, host: serie.Host, tags: strings.Join(tags, ","), mType: serie.MType}
+	rolled, ok := r.series[key]
+	if !ok {
+		rolled = &metrics.Serie{
+			Name:           serie.Name,
+			Tags:           tagset.CompositeTagsFromSlice(tags),
+			Host:           serie.Host,
+			MType:          serie.MType,
+			Interval:       serie.Interval,
+			SourceTypeName: serie.SourceTypeName,
+			ContextKey:     r.keyGen.Generate(serie.Name, serie.Host, tagset.NewHashingTagsAccumulatorWithTags(tags)),
+			NameSuffix:     serie.NameSuffix,
+			NoIndex:        serie.NoIndex,
+			Source:         serie.Source,
+		}
+		r.series[key] = rolled
+	}
+	for _, p := range serie.Points {
+		i := slices.IndexFunc(rolled.Points, func(rp metrics.Point) bool { return rp.Ts == p.Ts })
+		if i < 0 {
+			rolled.Points = append(rolled.Points, p)
+		} else {
+			rolled.Points[i].Value = aggregate(rolled.Points[i].Value, p.Value)
+		}
+	}
+	return rule.fullCardinality(serie.Host)
+}
+
+// addSketch rolls up the sketch if a rule matches its metric, and returns
+// whether the sketch must be flushed as is.
+func (r *metricsRollup) addSketch(sketch *metrics.SketchSeries) bool {
+	if r == nil {
+		return true
+	}
+	rule := r.rules.match(sketch.Name)
+	if rule == nil {
+		return true
+	}
+
+	tags := rule.rollupTags(sketch.Tags)
+	key := rollupKey{name: sketch.Name, host: sketch.Host, tags: strings.Join(tags, ",")}
+	rolled, ok := r.sketches[key]
+	if !ok {
+		rolled = &metrics.SketchSeries{
+			Name:       sketch.Name,
+			Tags:       tagset.CompositeTagsFromSlice(tags),
+			Host:       sketch.Host,
+			Interval:   sketch.Interval,
+			ContextKey: r.keyGen.Generate(sketch.Name, sketch.Host, tagset.NewHashingTagsAccumulatorWithTags(tags)),
+			Source:     sketch.Source,
+			NoIndex:    sketch.NoIndex,
+		}
+		r.sketches[key] = rolled
+	}
+	for _, p := range sketch.Points {
+		i := slices.IndexFunc(rolled.Points, func(rp metrics.SketchPoint) bool { return rp.Ts == p.Ts })
+		if i < 0 {
+			// the sketch may also be flushed as is, merge into a copy
+			rolled.Points = append(rolled.Points, metrics.SketchPoint{Ts: p.Ts, Sketch: p.Sketch.Copy()})
+		} else {
+			rolled.Points[i].Sketch.Merge(sketchConfig, p.Sketch)
+		}
+	}
+	return rule.fullCardinality(sketch.Host)
+}
+
+// sinks returns sinks rolling up the series and sketches appended to them,
+// and appending the others to seriesSink and sketchesSink. The rolled up
+// series and sketches are appended by flush, once all the samplers were
+// flushed, so that the contexts of all of them are rolled up together.
+func (r *metricsRollup) sinks(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) (metrics.SerieSink, metrics.SketchesSink) {
+	if r == nil {
+		return seriesSink, sketchesSink
+	}
+	return &rollupSerieSink{SerieSink: seriesSink, rollup: r},
+		&rollupSketchesSink{SketchesSink: sketchesSink, rollup: r}
+}
+
+// flush appends the rolled up series and sketches to seriesSink and
+// sketchesSink.
+func (r *metricsRollup) flush(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+	if r == nil {
+		return
+	}
+	for _, serie := range r.series {
+		seriesSink.Append(serie)
+	}
+	for _, sketch := range r.sketches {
+		sketchesSink.Append(sketch)
+	}
+}
+
+type rollupSerieSink struct {
+	metrics.SerieSink
+	rollup *metricsRollup
+}
+
+func (s *rollupSerieSink) Append(serie *metrics.Serie) {
+	if s.rollup.addSerie(serie) {
+		s.SerieSink.Append(serie)
+	}
+}
+
+type rollupSketchesSink struct {
+	metrics.SketchesSink
+	rollup *metricsRollup
+}
+
+func (s *rollupSketchesSink) Append(sketch *metrics.SketchSeries) {
+	if s.rollup.addSketch(sketch) {
+		s.SketchesSink.Append(sketch)
+	}
+}
+
+// rollupStore holds the rollup rules in use, reloading them when
+// RollupRulesKey is changed at runtime.
+type rollupStore struct {
+	rules atomic.Pointer[rollupList]
+}
+
+func newRollupStore(cfg model.Reader) *rollupStore {
+	s := &rollupStore{}
+	s.reload(cfg)
+	cfg.OnUpdate(func(setting string, _, _ any) {
+		if setting == RollupRulesKey {
+			s.reload(cfg)
+		}
+	})
+	return s
+}
+
+// reload replaces the rules with the ones of the configuration, keeping the
+// current ones if they are invalid.
+func (s *rollupStore) reload(cfg model.Reader) {
+	var rules []RollupRule
+	if cfg.IsSet(RollupRulesKey) {
+		if err := structure.UnmarshalKey(cfg, RollupRulesKey, &rules); err != nil {
+			log.Errorf("Could not parse %s: %v", RollupRulesKey, err)
+			return
+		}
+	}
+	list, err := newRollupList(rules)
+	if err != nil {
+		log.Errorf("Invalid %s: %v", RollupRulesKey, err)
+		return
+	}
+	s.rules.Store(list)
+}
+
+func (s *rollupStore) load() *rollupList {
+	if s == nil {
+		return nil
+	}
+	return s.rules.Load()
+}
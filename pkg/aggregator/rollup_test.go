@@ -0,0 +1,295 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func newTestRollupStore(t *testing.T, rules ...interface{}) *rollupStore {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource(RollupRulesKey, rules)
+	return newRollupStore(cfg)
+}
+
+func TestValidateRollupRules(t *testing.T) {
+	assert.NoError(t, ValidateRollupRules(nil))
+	assert.NoError(t, ValidateRollupRules([]RollupRule{
+		{MetricName: "http.requests", KeepTags: []string{"service"}, FullCardinalityHosts: []string{"canary-1"}},
+		{MetricName: "http.*"},
+	}))
+
+	assert.Error(t, ValidateRollupRules([]RollupRule{{KeepTags: []string{"service"}}}))
+	assert.Error(t, ValidateRollupRules([]RollupRule{{MetricName: "http.[requests"}}))
+}
+
+func TestRollupRuleTags(t *testing.T) {
+	list, err := newRollupList([]RollupRule{
+		{MetricName: "http.*", KeepTags: []string{"status_code", "service"}},
+	})
+	require.NoError(t, err)
+
+	rule := list.match("http.requests")
+	require.NotNil(t, rule)
+	assert.Equal(t, []string{"service:api", "status_code:200"}, rule.rollupTags(tagset.CompositeTagsFromSlice(
+		[]string{"status_code:200", "pod:api-1", "service:api", "service_name:api"},
+	)))
+	assert.Equal(t, []string{"service:api"}, rule.rollupTags(tagset.NewCompositeTags(
+		[]string{"service:api"}, []string{"service:api", "pod:api-1"},
+	)))
+	assert.Nil(t, list.match("db.queries"))
+
+	var empty *rollupList
+	assert.Nil(t, empty.match("http.requests"))
+	assert.Nil(t, newMetricsRollup(empty))
+}
+
+func TestRollupStoreReload(t *testing.T) {
+	cfg := configmock.New(t)
+	store := newRollupStore(cfg)
+	assert.Nil(t, newMetricsRollup(store.load()))
+
+	cfg.Set(RollupRulesKey, []interface{}{
+		map[string]interface{}{"metric_name": "http.requests", "keep_tags": []interface{}{"service"}},
+	}, model.SourceRC)
+	require.NotNil(t, store.load().match("http.requests"))
+
+	// invalid rules are ignored
+	cfg.Set(RollupRulesKey, []interface{}{
+		map[string]interface{}{"keep_tags": []interface{}{"service"}},
+	}, model.SourceRC)
+	require.NotNil(t, store.load().match("http.requests"))
+}
+
+// flushRolledUp flushes the metrics of flush through the rollup of the rules,
+// as the demultiplexers do.
+func flushRolledUp(rules *rollupStore, flush func(metrics.SerieSink, metrics.SketchesSink)) (metrics.Series, metrics.SketchSeriesList) {
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	rollup := newMetricsRollup(rules.load())
+	seriesSink, sketchesSink := rollup.sinks(&series, &sketches)
+	flush(seriesSink, sketchesSink)
+	rollup.flush(&series, &sketches)
+	return series, sketches
+}
+
+func testRollupTimeSamplers(t *testing.T, store *tags.Store) {
+	rules := newTestRollupStore(t, map[string]interface{}{
+		"metric_name":            "http.requests",
+		"keep_tags":              []interface{}{"service", "status_code"},
+		"full_cardinality_hosts": []interface{}{"canary-1"},
+	})
+	// the contexts are spread across the samplers of the pipelines
+	sampler1 := NewTimeSampler(TimeSamplerID(0), 10, store, nooptagger.NewComponent(), "host", nil, nil)
+	sampler2 := NewTimeSampler(TimeSamplerID(1), 10, store, nooptagger.NewComponent(), "host", nil, nil)
+
+	sample := func(name, host string, value float64, tags ...string) *metrics.MetricSample {
+		return &metrics.MetricSample{Name: name, Host: host, Value: value, Mtype: metrics.CountType, Tags: tags, SampleRate: 1}
+	}
+	sampler1.sample(sample("http.requests", "web-1", 1, "service:api", "status_code:200", "pod:api-1"), 12341.0)
+	sampler2.sample(sample("http.requests", "web-1", 2, "service:api", "status_code:200", "pod:api-2"), 12342.0)
+	sampler2.sample(sample("http.requests", "web-1", 4, "service:api", "status_code:500", "pod:api-2"), 12343.0)
+	sampler1.sample(sample("http.requests", "web-1", 8, "service:api", "status_code:200", "pod:api-1"), 12351.0)
+	sampler2.sample(sample("http.requests", "canary-1", 16, "service:api", "status_code:200", "pod:api-3"), 12352.0)
+	sampler1.sample(sample("db.queries", "web-1", 32, "service:api", "pod:api-1"), 12343.0)
+
+	series, _ := flushRolledUp(rules, func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+		sampler1.flush(12360.0, seriesSink, sketchesSink)
+		sampler2.flush(12360.0, seriesSink, sketchesSink)
+	})
+
+	expected := []*metrics.Serie{
+		{
+			Name:   "http.requests",
+			Host:   "web-1",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"service:api", "status_code:200"}),
+			Points: []metrics.Point{{Ts: 12340.0, Value: 3}, {Ts: 12350.0, Value: 8}},
+			MType:  metrics.APICountType,
+		},
+		{
+			Name:   "http.requests",
+			Host:   "web-1",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"service:api", "status_code:500"}),
+			Points: []metrics.Point{{Ts: 12340.0, Value: 4}},
+			MType:  metrics.APICountType,
+		},
+		{
+			Name:   "http.requests",
+			Host:   "canary-1",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"service:api", "status_code:200"}),
+			Points: []metrics.Point{{Ts: 12350.0, Value: 16}},
+			MType:  metrics.APICountType,
+		},
+		// the full cardinality series of the allowed hosts
+		{
+			Name:   "http.requests",
+			Host:   "canary-1",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"service:api", "status_code:200", "pod:api-3"}),
+			Points: []metrics.Point{{Ts: 12350.0, Value: 16}},
+			MType:  metrics.APICountType,
+		},
+		// the metrics without a rule are left untouched
+		{
+			Name:   "db.queries",
+			Host:   "web-1",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"service:api", "pod:api-1"}),
+			Points: []metrics.Point{{Ts: 12340.0, Value: 32}},
+			MType:  metrics.APICountType,
+		},
+	}
+	for _, serie := range expected {
+		serie.Interval = 10
+		serie.ContextKey = generateSerieContextKey(serie)
+	}
+	metrics.AssertSeriesEqual(t, expected, series)
+}
+
+func TestRollupTimeSamplers(t *testing.T) {
+	testWithTagsStore(t, testRollupTimeSamplers)
+}
+
+func testRollupTimeSamplersSketches(t *testing.T, store *tags.Store) {
+	rules := newTestRollupStore(t, map[string]interface{}{
+		"metric_name": "http.*",
+		"keep_tags":   []interface{}{"service"},
+	})
+	samplers := []*TimeSampler{
+		NewTimeSampler(TimeSamplerID(0), 10, store, nooptagger.NewComponent(), "host", nil, nil),
+		NewTimeSampler(TimeSamplerID(1), 10, store, nooptagger.NewComponent(), "host", nil, nil),
+	}
+
+	for i, pod := range []string{"pod:api-1", "pod:api-2", "pod:api-3"} {
+		samplers[i%2].sample(&metrics.MetricSample{
+			Name:       "http.latency",
+			Value:      float64(i + 1),
+			Mtype:      metrics.DistributionType,
+			Tags:       []string{"service:api", pod},
+			SampleRate: 1,
+		}, 12341.0)
+	}
+
+	_, sketches := flushRolledUp(rules, func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+		for _, sampler := range samplers {
+			sampler.flush(12360.0, seriesSink, sketchesSink)
+		}
+	})
+
+	require.Len(t, sketches, 1)
+	assert.Equal(t, "http.latency", sketches[0].Name)
+	assert.Equal(t, []string{"service:api"}, sketches[0].Tags.UnsafeToReadOnlySliceString())
+	require.Len(t, sketches[0].Points, 1)
+	assert.Equal(t, int64(12340), sketches[0].Points[0].Ts)
+	summary := sketches[0].Points[0].Sketch.Basic
+	assert.Equal(t, int64(3), summary.Cnt)
+	assert.Equal(t, 6.0, summary.Sum)
+	assert.Equal(t, 1.0, summary.Min)
+	assert.Equal(t, 3.0, summary.Max)
+}
+
+func TestRollupTimeSamplersSketches(t *testing.T) {
+	testWithTagsStore(t, testRollupTimeSamplersSketches)
+}
+
+func testRollupCheckSampler(t *testing.T, store *tags.Store) {
+	rules := newTestRollupStore(t, map[string]interface{}{
+		"metric_name": "kafka.consumer_lag",
+		"keep_tags":   []interface{}{"topic"},
+	})
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("kafka:1234"), nooptagger.NewComponent(), nil)
+
+	for i, partition := range []string{"partition:0", "partition:1", "partition:2"} {
+		checkSampler.addSample(&metrics.MetricSample{
+			Name:       "kafka.consumer_lag",
+			Value:      float64(10 * (i + 1)),
+			Mtype:      metrics.GaugeType,
+			Tags:       []string{"topic:orders", partition},
+			SampleRate: 1,
+			Timestamp:  12345.0,
+		})
+	}
+
+	checkSampler.commit(12349.0)
+	series, _ := flushRolledUp(rules, func(seriesSink metrics.SerieSink, _ metrics.SketchesSink) {
+		flushed, _ := checkSampler.flush()
+		for _, serie := range flushed {
+			seriesSink.Append(serie)
+		}
+	})
+
+	expected := &metrics.Serie{
+		Name:           "kafka.consumer_lag",
+		Tags:           tagset.CompositeTagsFromSlice([]string{"topic:orders"}),
+		Points:         []metrics.Point{{Ts: 12349.0, Value: 60}},
+		MType:          metrics.APIGaugeType,
+		SourceTypeName: checksSourceTypeName,
+	}
+	expected.ContextKey = generateSerieContextKey(expected)
+	metrics.AssertSeriesEqual(t, []*metrics.Serie{expected}, series)
+}
+
+func TestRollupCheckSampler(t *testing.T) {
+	testWithTagsStore(t, testRollupCheckSampler)
+}
+
+func TestRollupHistogramAggregates(t *testing.T) {
+	rules := newTestRollupStore(t, map[string]interface{}{
+		"metric_name": "http.latency",
+		"keep_tags":   []interface{}{"service"},
+	})
+
+	histogramSerie := func(suffix string, mType metrics.APIMetricType, value float64, pod string) *metrics.Serie {
+		return &metrics.Serie{
+			Name:       "http.latency" + suffix,
+			NameSuffix: suffix,
+			Tags:       tagset.CompositeTagsFromSlice([]string{"service:api", pod}),
+			Points:     []metrics.Point{{Ts: 12340.0, Value: value}},
+			MType:      mType,
+			Interval:   10,
+		}
+	}
+	series, _ := flushRolledUp(rules, func(seriesSink metrics.SerieSink, _ metrics.SketchesSink) {
+		for i, pod := range []string{"pod:api-1", "pod:api-2"} {
+			value := float64(i + 1)
+			seriesSink.Append(histogramSerie(".max", metrics.APIGaugeType, 10*value, pod))
+			seriesSink.Append(histogramSerie(".min", metrics.APIGaugeType, value, pod))
+			seriesSink.Append(histogramSerie(".count", metrics.APIRateType, 3*value, pod))
+			seriesSink.Append(histogramSerie(".avg", metrics.APIGaugeType, 5*value, pod))
+			seriesSink.Append(histogramSerie(".95percentile", metrics.APIGaugeType, 9*value, pod))
+		}
+	})
+
+	values := make(map[string][]float64)
+	for _, serie := range series {
+		for _, p := range serie.Points {
+			values[serie.Name] = append(values[serie.Name], p.Value)
+		}
+		if serie.Name == "http.latency.avg" || serie.Name == "http.latency.95percentile" {
+			assert.Len(t, serie.Tags.UnsafeToReadOnlySliceString(), 2, serie.Name)
+		} else {
+			assert.Equal(t, []string{"service:api"}, serie.Tags.UnsafeToReadOnlySliceString(), serie.Name)
+		}
+	}
+	assert.Equal(t, []float64{20}, values["http.latency.max"])
+	assert.Equal(t, []float64{1}, values["http.latency.min"])
+	assert.Equal(t, []float64{9}, values["http.latency.count"])
+	// the aggregates which can't be rolled up are flushed as they are
+	assert.ElementsMatch(t, []float64{5, 10}, values["http.latency.avg"])
+	assert.ElementsMatch(t, []float64{9, 18}, values["http.latency.95percentile"])
+}
@@ -11,8 +11,11 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile/summary"
 )
 
+var sketchConfig = quantile.Default()
+
 type sketchMap map[int64]map[ckey.ContextKey]*quantile.Agent
 
 // Len returns the number of sketches stored
@@ -48,6 +51,54 @@ func (m sketchMap) insertInterp(ts int64, ck ckey.ContextKey, lower float64, upp
 	return true
 }
 
+// insertSummary merges a distribution aggregated by the client into the sketch
+// for the given (ts, contextKey), without expanding it into single values.
+func (m sketchMap) insertSummary(ts int64, ck ckey.ContextKey, s *metrics.DistributionSummary) bool {
+	if s.Count == 0 || !isFinite(s.Sum) || !isFinite(s.Min) || !isFinite(s.Max) || s.Min > s.Max {
+		return false
+	}
+	// the counts are inserted as they are in the bins of the sketch, which
+	// are allocated for every 65535 values
+	if s.Count > metrics.MaxDistributionSummaryCount {
+		return false
+	}
+
+	var agent quantile.Agent
+	if len(s.Buckets) == 0 {
+		agent.InsertInterpolate(s.Min, s.Max, uint(s.Count))
+	} else {
+		for _, b := range s.Buckets {
+			if !isFinite(b.Value) || b.Count > s.Count {
+				return false
+			}
+			if b.Count > 0 {
+				agent.InsertInterpolate(b.Value, b.Value, uint(b.Count))
+			}
+		}
+	}
+
+	sketch := agent.Finish()
+	if sketch == nil {
+		return false
+	}
+	// the bins are approximations of the values, the exact statistics are
+	// the ones computed by the client.
+	sketch.Basic = summary.Summary{
+		Cnt: int64(s.Count),
+		Sum: s.Sum,
+		Min: s.Min,
+		Max: s.Max,
+		Avg: s.Sum / float64(s.Count),
+	}
+
+	m.getOrCreate(ts, ck).Sketch.Merge(sketchConfig, sketch)
+	return true
+}
+
+func isFinite(v float64) bool {
+	return !math.IsInf(v, 0) && !math.IsNaN(v)
+}
+
 func (m sketchMap) getOrCreate(ts int64, ck ckey.ContextKey) *quantile.Agent {
 	// level 1: ts -> ctx
 	byCtx, ok := m[ts]
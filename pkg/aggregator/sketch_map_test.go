@@ -8,10 +8,15 @@
 package aggregator
 
 import (
+	"math"
 	"testing"
 
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile/summary"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
 
@@ -33,3 +38,83 @@ func TestInsert(t *testing.T) {
 	sketchMap.insert(2, generateContextKey(&mSample1), 2, 1)
 	assert.Equal(t, 2, sketchMap.Len())
 }
+
+func TestInsertSummary(t *testing.T) {
+	sketchMap := make(sketchMap)
+	mSample := metrics.MetricSample{
+		Name:  "test.metric.name",
+		Mtype: metrics.DistributionType,
+	}
+	ck := generateContextKey(&mSample)
+
+	assert.True(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{
+		Count:   4,
+		Sum:     10.5,
+		Min:     1,
+		Max:     4,
+		Buckets: []metrics.DistributionBucket{{Value: 1, Count: 1}, {Value: 2, Count: 1}, {Value: 4, Count: 2}},
+	}))
+	// the summary is merged with the single values
+	assert.True(t, sketchMap.insert(10, ck, 10, 1))
+	// without buckets, the values are spread between min and max
+	assert.True(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{Count: 5, Sum: 15, Min: 2, Max: 4}))
+
+	assert.False(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{}))
+	assert.False(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{Count: 1, Sum: math.NaN()}))
+
+	var points []metrics.SketchPoint
+	sketchMap.flushBefore(20, func(_ ckey.ContextKey, p metrics.SketchPoint) {
+		points = append(points, p)
+	})
+	require.Len(t, points, 1)
+	sketch := points[0].Sketch
+	assert.Equal(t, summary.Summary{Cnt: 10, Sum: 35.5, Min: 1, Max: 10, Avg: 3.55}, roundSummary(sketch.Basic))
+	assert.InDelta(t, 10, sketch.Quantile(quantile.Default(), 1), 0)
+	assert.InDelta(t, 3, sketch.Quantile(quantile.Default(), 0.5), 1)
+}
+
+func roundSummary(s summary.Summary) summary.Summary {
+	s.Avg = math.Round(s.Avg*100) / 100
+	return s
+}
+
+func TestInsertSummaryHugeCount(t *testing.T) {
+	sketchMap := make(sketchMap)
+	mSample := metrics.MetricSample{
+		Name:  "test.metric.name",
+		Mtype: metrics.DistributionType,
+	}
+	ck := generateContextKey(&mSample)
+
+	assert.False(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{Count: math.MaxUint64, Sum: 1, Min: 1, Max: 4}))
+	assert.False(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{
+		Count:   math.MaxUint64,
+		Sum:     1,
+		Min:     1,
+		Max:     4,
+		Buckets: []metrics.DistributionBucket{{Value: 1, Count: math.MaxUint64}},
+	}))
+	assert.False(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{
+		Count:   4,
+		Sum:     1,
+		Min:     1,
+		Max:     4,
+		Buckets: []metrics.DistributionBucket{{Value: 1, Count: math.MaxUint64}},
+	}))
+	assert.Equal(t, 0, sketchMap.Len())
+
+	// the largest count is supported
+	assert.True(t, sketchMap.insertSummary(10, ck, &metrics.DistributionSummary{
+		Count:   metrics.MaxDistributionSummaryCount,
+		Sum:     metrics.MaxDistributionSummaryCount,
+		Min:     1,
+		Max:     1,
+		Buckets: []metrics.DistributionBucket{{Value: 1, Count: metrics.MaxDistributionSummaryCount}},
+	}))
+	var points []metrics.SketchPoint
+	sketchMap.flushBefore(20, func(_ ckey.ContextKey, p metrics.SketchPoint) {
+		points = append(points, p)
+	})
+	require.Len(t, points, 1)
+	assert.Equal(t, int64(metrics.MaxDistributionSummaryCount), points[0].Sketch.Basic.Cnt)
+}
@@ -0,0 +1,199 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package aggregator
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// TagFilterListKey is the setting holding the tag filter rules of the
+// DogStatsD metrics.
+const TagFilterListKey = "dogstatsd_tag_filterlist"
+
+const (
+	// TagFilterInclude keeps only the listed tag keys.
+	TagFilterInclude = "include"
+	// TagFilterExclude removes the listed tag keys.
+	TagFilterExclude = "exclude"
+
+	// tagFilterCacheSize is the number of metric names whose glob pattern
+	// matching is cached.
+	tagFilterCacheSize = 1000
+)
+
+// TagFilterRule removes tags from the DogStatsD metrics whose name matches
+// MetricName, which is either a metric name or a glob pattern.
+type TagFilterRule struct {
+	MetricName string   `mapstructure:"metric_name" json:"metric_name" yaml:"metric_name"`
+	Action     string   `mapstructure:"action" json:"action" yaml:"action"`
+	Tags       []string `mapstructure:"tags" json:"tags" yaml:"tags"`
+}
+
+type tagFilterRule struct {
+	include bool
+	keys    map[string]struct{}
+}
+
+// keep returns whether the tag is kept by the rule.
+func (r *tagFilterRule) keep(tag string) bool {
+	key := tag
+	if i := strings.IndexByte(tag, ':'); i >= 0 {
+		key = tag[:i]
+	}
+	_, listed := r.keys[key]
+	return listed == r.include
+}
+
+type tagFilterGlob struct {
+	pattern string
+	rule    *tagFilterRule
+}
+
+// tagFilterList is a compiled set of tag filter rules. Rules on a metric name
+// take precedence over the glob patterns, which are tried in order.
+type tagFilterList struct {
+	byName map[string]*tagFilterRule
+	globs  []tagFilterGlob
+	// globCache holds the rule of the glob patterns matching the metric
+	// names, nil if none does. It is nil when there are no glob patterns.
+	globCache *lru.Cache[string, *tagFilterRule]
+}
+
+// ValidateTagFilterRules returns an error if one of the rules is invalid.
+func ValidateTagFilterRules(rules []TagFilterRule) error {
+	_, err := newTagFilterList(rules)
+	return err
+}
+
+// newTagFilterList compiles the rules, returning an error if one is invalid.
+func newTagFilterList(rules []TagFilterRule) (*tagFilterList, error) {
+	l := &tagFilterList{byName: make(map[string]*tagFilterRule)}
+	for i, rule := range rules {
+		if rule.MetricName == "" {
+			return nil, fmt.Errorf("rule %d: metric_name is required", i)
+		}
+		if rule.Action != TagFilterInclude && rule.Action != TagFilterExclude {
+			return nil, fmt.Errorf("rule %d: invalid action %q, must be %q or %q", i, rule.Action, TagFilterInclude, TagFilterExclude)
+		}
+		compiled := &tagFilterRule{
+			include: rule.Action == TagFilterInclude,
+			keys:    make(map[string]struct{}, len(rule.Tags)),
+		}
+		for _, key := range rule.Tags {
+			compiled.keys[key] = struct{}{}
+		}
+
+		if !strings.ContainsAny(rule.MetricName, `*?[\`) {
+			if _, exists := l.byName[rule.MetricName]; !exists {
+				l.byName[rule.MetricName] = compiled
+			}
+			continue
+		}
+		if _, err := path.Match(rule.MetricName, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid metric_name pattern %q: %v", i, rule.MetricName, err)
+		}
+		l.globs = append(l.globs, tagFilterGlob{pattern: rule.MetricName, rule: compiled})
+	}
+	if len(l.globs) > 0 {
+		cache, err := lru.New[string, *tagFilterRule](tagFilterCacheSize)
+		if err != nil {
+			return nil, err
+		}
+		l.globCache = cache
+	}
+	return l, nil
+}
+
+// match returns the rule applying to the metric, or nil if there is none.
+func (l *tagFilterList) match(name string) *tagFilterRule {
+	if l == nil {
+		return nil
+	}
+	if rule, ok := l.byName[name]; ok {
+		return rule
+	}
+	if l.globCache == nil {
+		return nil
+	}
+	if rule, ok := l.globCache.Get(name); ok {
+		return rule
+	}
+	var rule *tagFilterRule
+	for _, glob := range l.globs {
+		if matched, _ := path.Match(glob.pattern, name); matched {
+			rule = glob.rule
+			break
+		}
+	}
+	l.globCache.Add(name, rule)
+	return rule
+}
+
+// apply removes the tags filtered by the rule from the accumulators and
+// returns whether any tag was removed, and the combined hash of the tags
+// removed.
+func (r *tagFilterRule) apply(accumulators ...*tagset.HashingTagsAccumulator) (bool, uint64) {
+	removed := 0
+	var removedHash uint64
+	for _, acc := range accumulators {
+		n, hash := acc.Retain(r.keep)
+		removed += n
+		removedHash ^= hash
+	}
+	return removed > 0, removedHash
+}
+
+// tagFilterStore holds the tag filter rules in use, reloading them when
+// TagFilterListKey is changed at runtime.
+type tagFilterStore struct {
+	filter atomic.Pointer[tagFilterList]
+}
+
+func newTagFilterStore(cfg model.Reader) *tagFilterStore {
+	s := &tagFilterStore{}
+	s.reload(cfg)
+	cfg.OnUpdate(func(setting string, _, _ any) {
+		if setting == TagFilterListKey {
+			s.reload(cfg)
+		}
+	})
+	return s
+}
+
+// reload replaces the rules with the ones of the configuration, keeping the
+// current ones if they are invalid.
+func (s *tagFilterStore) reload(cfg model.Reader) {
+	var rules []TagFilterRule
+	if cfg.IsSet(TagFilterListKey) {
+		if err := structure.UnmarshalKey(cfg, TagFilterListKey, &rules); err != nil {
+			log.Errorf("Could not parse %s: %v", TagFilterListKey, err)
+			return
+		}
+	}
+	filter, err := newTagFilterList(rules)
+	if err != nil {
+		log.Errorf("Invalid %s: %v", TagFilterListKey, err)
+		return
+	}
+	s.filter.Store(filter)
+}
+
+func (s *tagFilterStore) load() *tagFilterList {
+	if s == nil {
+		return nil
+	}
+	return s.filter.Load()
+}
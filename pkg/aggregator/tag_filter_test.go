@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package aggregator
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestValidateTagFilterRules(t *testing.T) {
+	assert.NoError(t, ValidateTagFilterRules(nil))
+	assert.NoError(t, ValidateTagFilterRules([]TagFilterRule{
+		{MetricName: "my.metric", Action: TagFilterExclude, Tags: []string{"request_id"}},
+		{MetricName: "my.*", Action: TagFilterInclude, Tags: []string{"env"}},
+	}))
+
+	assert.Error(t, ValidateTagFilterRules([]TagFilterRule{{Action: TagFilterExclude}}))
+	assert.Error(t, ValidateTagFilterRules([]TagFilterRule{{MetricName: "my.metric", Action: "drop"}}))
+	assert.Error(t, ValidateTagFilterRules([]TagFilterRule{{MetricName: "my.[metric", Action: TagFilterExclude}}))
+}
+
+func TestTagFilterListMatch(t *testing.T) {
+	filter, err := newTagFilterList([]TagFilterRule{
+		{MetricName: "http.*", Action: TagFilterExclude, Tags: []string{"request_id"}},
+		{MetricName: "http.requests", Action: TagFilterInclude, Tags: []string{"env", "service"}},
+		{MetricName: "*", Action: TagFilterExclude, Tags: []string{"pod_uid"}},
+	})
+	require.NoError(t, err)
+
+	// rules on a metric name take precedence over the patterns
+	rule := filter.match("http.requests")
+	require.NotNil(t, rule)
+	assert.True(t, rule.keep("env:prod"))
+	assert.False(t, rule.keep("request_id:1234"))
+	assert.False(t, rule.keep("version"))
+
+	// patterns are tried in order
+	rule = filter.match("http.latency")
+	require.NotNil(t, rule)
+	assert.False(t, rule.keep("request_id:1234"))
+	assert.True(t, rule.keep("pod_uid:abcd"))
+	assert.True(t, rule.keep("request_id_prefix:12"))
+
+	rule = filter.match("db.queries")
+	require.NotNil(t, rule)
+	assert.False(t, rule.keep("pod_uid"))
+
+	// the results of the patterns are cached
+	cached, ok := filter.globCache.Get("http.latency")
+	require.True(t, ok)
+	assert.Same(t, filter.match("http.latency"), cached)
+	assert.Same(t, rule, filter.match("db.queries"))
+
+	noGlob, err := newTagFilterList([]TagFilterRule{{MetricName: "http.requests", Action: TagFilterExclude, Tags: []string{"request_id"}}})
+	require.NoError(t, err)
+	assert.Nil(t, noGlob.match("db.queries"))
+	assert.Nil(t, noGlob.globCache)
+
+	var empty *tagFilterList
+	assert.Nil(t, empty.match("http.requests"))
+}
+
+func TestTagFilterStoreReload(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource(TagFilterListKey, []interface{}{
+		map[string]interface{}{"metric_name": "my.metric", "action": "exclude", "tags": []interface{}{"request_id"}},
+	})
+
+	store := newTagFilterStore(cfg)
+	require.NotNil(t, store.load().match("my.metric"))
+	assert.Nil(t, store.load().match("other.metric"))
+
+	cfg.Set(TagFilterListKey, []interface{}{
+		map[string]interface{}{"metric_name": "other.*", "action": "include", "tags": []interface{}{"env"}},
+	}, model.SourceCLI)
+	assert.Nil(t, store.load().match("my.metric"))
+	require.NotNil(t, store.load().match("other.metric"))
+
+	// invalid rules are ignored
+	cfg.Set(TagFilterListKey, []interface{}{
+		map[string]interface{}{"metric_name": "my.metric", "action": "drop"},
+	}, model.SourceCLI)
+	require.NotNil(t, store.load().match("other.metric"))
+}
+
+func testTagFilterCollapsesContexts(t *testing.T, store *tags.Store) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource(TagFilterListKey, []interface{}{
+		map[string]interface{}{"metric_name": "my.metric", "action": "exclude", "tags": []interface{}{"request_id"}},
+	})
+	resolver := newContextResolverWithTagFilter(nooptagger.NewComponent(), store, "test", newTagFilterStore(cfg))
+
+	sample := func(name string, tags ...string) *metrics.MetricSample {
+		return &metrics.MetricSample{Name: name, Value: 1, Mtype: metrics.GaugeType, Tags: tags, SampleRate: 1}
+	}
+
+	key1, _ := resolver.trackContext(sample("my.metric", "env:prod", "request_id:1"), 0)
+	key2, _ := resolver.trackContext(sample("my.metric", "env:prod", "request_id:2"), 0)
+	key3, _ := resolver.trackContext(sample("my.metric", "env:prod", "request_id:2"), 0)
+	key4, _ := resolver.trackContext(sample("my.metric", "env:staging", "request_id:3"), 0)
+	other1, _ := resolver.trackContext(sample("other.metric", "request_id:1"), 0)
+	other2, _ := resolver.trackContext(sample("other.metric", "request_id:2"), 0)
+
+	assert.Equal(t, key1, key2)
+	assert.Equal(t, key1, key3)
+	assert.NotEqual(t, key1, key4)
+	assert.NotEqual(t, other1, other2)
+	assertContext(t, resolver.contextsByKey[key1].context, "my.metric", []string{"env:prod"}, "")
+	assertContext(t, resolver.contextsByKey[key4].context, "my.metric", []string{"env:staging"}, "")
+	assert.Equal(t, 4, resolver.length())
+
+	// request_id:2 was merged into request_id:1
+	assert.Equal(t, uint64(1), resolver.resetCollapsed())
+	assert.Equal(t, uint64(0), resolver.resetCollapsed())
+
+	resolver.trackContext(sample("my.metric", "env:prod", "request_id:4"), 0)
+	resolver.trackContext(sample("my.metric", "env:prod", "request_id:5"), 0)
+	assert.Equal(t, uint64(1), resolver.resetCollapsed())
+}
+
+func TestTagFilterCollapsesContexts(t *testing.T) {
+	testWithTagsStore(t, testTagFilterCollapsesContexts)
+}
+
+func TestTagFilterCollapsesManyContexts(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource(TagFilterListKey, []interface{}{
+		map[string]interface{}{"metric_name": "my.*", "action": "exclude", "tags": []interface{}{"request_id"}},
+	})
+	resolver := newContextResolverWithTagFilter(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", newTagFilterStore(cfg))
+
+	for i := 0; i < 10000; i++ {
+		sample := &metrics.MetricSample{
+			Name:       "my.metric",
+			Value:      1,
+			Mtype:      metrics.GaugeType,
+			Tags:       []string{"env:prod", fmt.Sprintf("request_id:%d", i)},
+			SampleRate: 1,
+		}
+		resolver.trackContext(sample, 0)
+	}
+	assert.Equal(t, 1, resolver.length())
+	// only the filtered contexts are held, the collapsed ones are estimated
+	assert.Len(t, resolver.filteredContexts, 1)
+	assert.InEpsilon(t, 9999, resolver.resetCollapsed(), 0.2)
+}
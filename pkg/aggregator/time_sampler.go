@@ -44,8 +44,10 @@ type TimeSampler struct {
 	hostname string
 }
 
-// NewTimeSampler returns a newly initialized TimeSampler
-func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger tagger.Component, hostname string) *TimeSampler {
+// NewTimeSampler returns a newly initialized TimeSampler. The tags of the
+// samples are filtered by the rules of tagFilter, and the contexts limited by
+// limiter, if they aren't nil.
+func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger tagger.Component, hostname string, tagFilter *tagFilterStore, limiter *contextLimiter) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -58,7 +60,7 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger
 
 	s := &TimeSampler{
 		interval:           interval,
-		contextResolver:    newTimestampContextResolver(tagger, cache, idString, contextExpireTime, counterExpireTime),
+		contextResolver:    newTimestampContextResolver(tagger, cache, idString, contextExpireTime, counterExpireTime, tagFilter, limiter),
 		metricsByTimestamp: map[int64]metrics.ContextMetrics{},
 		sketchMap:          make(sketchMap),
 		id:                 id,
@@ -84,11 +86,18 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 	}
 
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	contextKey, ok := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	if !ok {
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
 	case metrics.DistributionType:
+		if metricSample.Summary != nil {
+			s.sketchMap.insertSummary(bucketStart, contextKey, metricSample.Summary)
+			break
+		}
 		s.sketchMap.insert(bucketStart, contextKey, metricSample.Value, metricSample.SampleRate)
 	default:
 		// If it's a new bucket, initialize it
@@ -241,6 +250,11 @@ func (s *TimeSampler) updateMetrics() {
 	tlmDogstatsdContexts.Set(float64(totalContexts), s.idString)
 	tlmDogstatsdTimeBuckets.Set(float64(len(s.metricsByTimestamp)), s.idString)
 
+	if collapsed := s.contextResolver.resetCollapsed(); collapsed > 0 {
+		aggregatorDogstatsdContextsCollapsed.Add(int64(collapsed))
+		tlmDogstatsdContextsCollapsed.Add(float64(collapsed), s.idString)
+	}
+
 	countByMtype := s.contextResolver.countsByMtype()
 	for i := 0; i < int(metrics.NumMetricTypes); i++ {
 		count := countByMtype[i]
@@ -11,6 +11,8 @@ type Product string
 const (
 	// ProductAPMSampling is the apm sampling product
 	ProductAPMSampling Product = "APM_SAMPLING"
+	// ProductAPMSpanFilters is the apm span filter rules product, experimental
+	ProductAPMSpanFilters Product = "APM_SPAN_FILTERS"
 	// ProductCWSDD is the cloud workload security product
 	ProductCWSDD Product = "CWS_DD"
 	// ProductCWSCustom is the cloud workload security product
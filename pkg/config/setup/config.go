@@ -1209,6 +1209,8 @@ func remoteconfig(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("remote_configuration.clients.cache_bypass_limit", 5)
 	// Remote config products
 	config.BindEnvAndSetDefault("remote_configuration.apm_sampling.enabled", true)
+	// Experimental: the APM_SPAN_FILTERS product isn't served by the backend yet.
+	config.BindEnvAndSetDefault("remote_configuration.apm_span_filters.enabled", false)
 	config.BindEnvAndSetDefault("remote_configuration.agent_integrations.enabled", false)
 	config.BindEnvAndSetDefault("remote_configuration.agent_integrations.allow_list", defaultAllowedRCIntegrations)
 	config.BindEnvAndSetDefault("remote_configuration.agent_integrations.block_list", []string{})
@@ -1361,6 +1363,19 @@ func aggregator(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("aggregator_stop_timeout", 2)
 	config.BindEnvAndSetDefault("aggregator_buffer_size", 100)
 	config.BindEnvAndSetDefault("aggregator_use_tags_store", true)
+	config.BindEnvAndSetDefault("aggregator_context_limit_per_origin", 0)
+	config.BindEnvAndSetDefault("aggregator_context_limit_per_metric", 0)
+	config.BindEnvAndSetDefault("aggregator_context_limit_action", "overflow")
+	config.BindEnvAndSetDefault("aggregator_flushed_metrics_buffer_size", 0)
+	config.BindEnv("aggregator_rollup_rules")
+	config.ParseEnvAsSlice("aggregator_rollup_rules", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"aggregator_rollup_rules" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+	config.BindEnvAndSetDefault("openmetrics_exporter_port", 0)              // Notice: 0 means the OpenMetrics exporter is disabled
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
@@ -1414,8 +1429,15 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("use_dogstatsd", true)
 	config.BindEnvAndSetDefault("dogstatsd_port", 8125)    // Notice: 0 means UDP port closed
 	config.BindEnvAndSetDefault("dogstatsd_pipe_name", "") // experimental and not officially supported for now.
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)   // Notice: 0 means TCP port closed
+	// Options are: length_prefixed, newline
+	config.BindEnvAndSetDefault("dogstatsd_tcp_framing", "length_prefixed")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls_key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_idle_timeout", 5*time.Minute) // Notice: 0 means the connections are never closed
+	config.BindEnvAndSetDefault("dogstatsd_remote_write_port", 0) // Notice: 0 means the Prometheus remote-write receiver is disabled
 	// Experimental and not officially supported for now.
-	// Options are: udp, uds, named_pipe
+	// Options are: udp, uds, named_pipe, tcp
 	config.BindEnvAndSetDefault("dogstatsd_eol_required", []string{})
 
 	// The following options allow to configure how the dogstatsd intake buffers and queues incoming datagrams.
@@ -1499,6 +1521,15 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("statsd_metric_blocklist", []string{})
 	config.BindEnvAndSetDefault("statsd_metric_blocklist_match_prefix", false)
 
+	config.BindEnv("dogstatsd_tag_filterlist")
+	config.ParseEnvAsSlice("dogstatsd_tag_filterlist", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"dogstatsd_tag_filterlist" can not be parsed: %v`, err)
+		}
+		return rules
+	})
+
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution", false)
 	config.BindEnvAndSetDefault("histogram_copy_to_distribution_prefix", "")
 	config.BindEnvAndSetDefault("histogram_aggregates", []string{"max", "median", "avg", "count"})
@@ -1589,6 +1620,14 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("logs_config.message_channel_size", 100)
 	config.BindEnvAndSetDefault("logs_config.payload_channel_size", 10)
 
+	// on-disk buffer storing the payloads while all the logs intakes are unreachable
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.enabled", false)
+	// defaults to <logs_config.run_path>/disk_buffer
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.path", "")
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.max_size_bytes", 500*1024*1024)
+	// payloads older than this (seconds) are dropped as the intake would reject them
+	config.BindEnvAndSetDefault("logs_config.disk_buffer.max_age", 18*60*60)
+
 	// maximum time that the unix tailer will hold a log file open after it has been rotated
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
 	// maximum time that the windows tailer will hold a log file open, while waiting for
@@ -10,6 +10,7 @@ package mock
 type Registry struct {
 	offset      string
 	tailingMode string
+	completed   bool
 }
 
 // NewRegistry returns a new registry.
@@ -36,3 +37,18 @@ func (r *Registry) GetTailingMode(_ string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// IsCompleted returns the completion state.
+func (r *Registry) IsCompleted(_ string) bool {
+	return r.completed
+}
+
+// MarkCompleted sets the completion state.
+func (r *Registry) MarkCompleted(_ string, _ string) {
+	r.completed = true
+}
+
+// SetCompleted sets the completion state.
+func (r *Registry) SetCompleted(completed bool) {
+	r.completed = completed
+}
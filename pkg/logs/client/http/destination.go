@@ -67,6 +67,7 @@ type Destination struct {
 	destinationsContext *client.DestinationsContext
 	protocol            config.IntakeProtocol
 	origin              config.IntakeOrigin
+	format              config.EndpointFormat
 	isMRF               bool
 
 	// Concurrency
@@ -124,6 +125,10 @@ func newDestination(endpoint config.Endpoint,
 	if maxConcurrentBackgroundSends <= 0 {
 		maxConcurrentBackgroundSends = 1
 	}
+	if endpoint.Format == config.FormatOTLP {
+		// the payloads are re-encoded before being sent
+		contentType = ProtobufContentType
+	}
 	policy := backoff.NewExpBackoffPolicy(
 		endpoint.BackoffFactor,
 		endpoint.BackoffBase,
@@ -152,6 +157,7 @@ func newDestination(endpoint config.Endpoint,
 		backoff:             policy,
 		protocol:            endpoint.Protocol,
 		origin:              endpoint.Origin,
+		format:              endpoint.Format,
 		lastRetryError:      nil,
 		retryLock:           sync.Mutex{},
 		shouldRetry:         shouldRetry,
@@ -234,6 +240,13 @@ func (d *Destination) sendConcurrent(payload *message.Payload, output chan *mess
 
 // Send sends a payload over HTTP,
 func (d *Destination) sendAndRetry(payload *message.Payload, output chan *message.Payload, isRetrying chan bool) {
+	encoded, err := d.encodePayload(payload)
+	if err != nil {
+		log.Warnf("Could not encode payload, dropping it: %v", err)
+		output <- payload
+		return
+	}
+
 	for {
 
 		d.retryLock.Lock()
@@ -249,7 +262,7 @@ func (d *Destination) sendAndRetry(payload *message.Payload, output chan *messag
 			metrics.TlmRetryCount.Add(1)
 		}
 
-		err := d.unconditionalSend(payload)
+		err = d.unconditionalSend(encoded)
 
 		if err != nil {
 			metrics.DestinationErrors.Add(1)
@@ -281,6 +294,24 @@ func (d *Destination) sendAndRetry(payload *message.Payload, output chan *messag
 	}
 }
 
+// encodePayload returns the payload to send to the endpoint. Payloads are
+// encoded for the Datadog intake and are re-encoded, once, for the endpoints
+// using another format.
+func (d *Destination) encodePayload(payload *message.Payload) (*message.Payload, error) {
+	if d.format != config.FormatOTLP {
+		return payload, nil
+	}
+	body, encoding, err := encodeOTLPPayload(payload, d.endpoint.UseCompression)
+	if err != nil {
+		tlmDropped.Inc()
+		return nil, err
+	}
+	encoded := *payload
+	encoded.Encoded = body
+	encoded.Encoding = encoding
+	return &encoded, nil
+}
+
 func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 	defer func() {
 		tlmSend.Inc(d.host, errorToTag(err))
@@ -302,23 +333,18 @@ func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 		// this can happen when the method or the url are valid.
 		return err
 	}
-	req.Header.Set("DD-API-KEY", d.endpoint.GetAPIKey())
 	req.Header.Set("Content-Type", d.contentType)
 	req.Header.Set("User-Agent", fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
 
 	if payload.Encoding != "" {
 		req.Header.Set("Content-Encoding", payload.Encoding)
 	}
-	if d.protocol != "" {
-		req.Header.Set("DD-PROTOCOL", string(d.protocol))
-	}
-	if d.origin != "" {
-		req.Header.Set("DD-EVP-ORIGIN", string(d.origin))
-		req.Header.Set("DD-EVP-ORIGIN-VERSION", version.AgentVersion)
-	}
-	req.Header.Set("dd-message-timestamp", strconv.FormatInt(getMessageTimestamp(payload.Messages), 10))
 	then := time.Now()
-	req.Header.Set("dd-current-timestamp", strconv.FormatInt(then.UnixMilli(), 10))
+	if d.format != config.FormatOTLP {
+		// the Datadog headers, including the API key, are only sent to the
+		// Datadog intake
+		d.setDatadogHeaders(req, payload, then)
+	}
 
 	req = req.WithContext(ctx)
 	resp, err := d.client.Do(req)
@@ -369,6 +395,19 @@ func (d *Destination) unconditionalSend(payload *message.Payload) (err error) {
 	}
 }
 
+func (d *Destination) setDatadogHeaders(req *http.Request, payload *message.Payload, now time.Time) {
+	req.Header.Set("DD-API-KEY", d.endpoint.GetAPIKey())
+	if d.protocol != "" {
+		req.Header.Set("DD-PROTOCOL", string(d.protocol))
+	}
+	if d.origin != "" {
+		req.Header.Set("DD-EVP-ORIGIN", string(d.origin))
+		req.Header.Set("DD-EVP-ORIGIN-VERSION", version.AgentVersion)
+	}
+	req.Header.Set("dd-message-timestamp", strconv.FormatInt(getMessageTimestamp(payload.Messages), 10))
+	req.Header.Set("dd-current-timestamp", strconv.FormatInt(now.UnixMilli(), 10))
+}
+
 func (d *Destination) updateRetryState(err error, isRetrying chan bool) bool {
 	d.retryLock.Lock()
 	defer d.retryLock.Unlock()
@@ -434,6 +473,8 @@ func buildURL(endpoint config.Endpoint) string {
 	var address string
 	if endpoint.Port != 0 {
 		address = fmt.Sprintf("%v:%v", endpoint.Host, endpoint.Port)
+	} else if endpoint.Format == config.FormatOTLP {
+		address = fmt.Sprintf("%v:%v", endpoint.Host, config.DefaultOTLPPort)
 	} else {
 		address = endpoint.Host
 	}
@@ -441,7 +482,9 @@ func buildURL(endpoint config.Endpoint) string {
 		Scheme: scheme,
 		Host:   address,
 	}
-	if endpoint.Version == config.EPIntakeVersion2 && endpoint.TrackType != "" {
+	if endpoint.Format == config.FormatOTLP {
+		url.Path = otlpPath
+	} else if endpoint.Version == config.EPIntakeVersion2 && endpoint.TrackType != "" {
 		url.Path = fmt.Sprintf("/api/v2/%s", endpoint.TrackType)
 	} else {
 		url.Path = "/v1/input"
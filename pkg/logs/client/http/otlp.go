@@ -0,0 +1,261 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// otlpPath is the path of the OTLP/HTTP logs receivers.
+const otlpPath = "/v1/logs"
+
+// otlpTagAttributes maps the keys of the unified service tags to the
+// attribute names of the OpenTelemetry semantic conventions.
+var otlpTagAttributes = map[string]string{
+	"env":     "deployment.environment.name",
+	"service": "service.name",
+	"version": "service.version",
+}
+
+// otlpSourceAttribute is the attribute of the source of the logs, as read by
+// the Datadog exporter of the OpenTelemetry collector.
+const otlpSourceAttribute = "datadog.log.source"
+
+// otlpResource identifies the resource of the logs.
+type otlpResource struct {
+	hostname string
+	service  string
+}
+
+// encodeOTLPPayload encodes a payload, encoded as a JSON array for the Datadog
+// intake, as an OTLP/HTTP ExportLogsServiceRequest. Only the encoded content of
+// the payload is used, so that payloads replayed from disk, which don't have
+// their messages, can be encoded as well. The request is gzip compressed if
+// compress is true, and the returned encoding is the one of the
+// Content-Encoding header.
+func encodeOTLPPayload(payload *message.Payload, compress bool) ([]byte, string, error) {
+	content, err := decompressPayload(payload)
+	if err != nil {
+		return nil, "", fmt.Errorf("can't decompress the payload: %v", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	var messages []map[string]interface{}
+	if err := decoder.Decode(&messages); err != nil {
+		return nil, "", fmt.Errorf("can't decode the payload: %v", err)
+	}
+
+	logs := plog.NewLogs()
+	observed := pcommon.NewTimestampFromTime(time.Now())
+	byResource := make(map[otlpResource]plog.LogRecordSlice)
+	for _, fields := range messages {
+		resource := otlpResource{
+			hostname: stringField(fields, "hostname"),
+			service:  stringField(fields, "service"),
+		}
+		records, ok := byResource[resource]
+		if !ok {
+			resourceLogs := logs.ResourceLogs().AppendEmpty()
+			if resource.hostname != "" {
+				resourceLogs.Resource().Attributes().PutStr("host.name", resource.hostname)
+			}
+			if resource.service != "" {
+				resourceLogs.Resource().Attributes().PutStr("service.name", resource.service)
+			}
+			scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+			scopeLogs.Scope().SetName("datadog-agent")
+			scopeLogs.Scope().SetVersion(version.AgentVersion)
+			records = scopeLogs.LogRecords()
+			byResource[resource] = records
+		}
+		if err := fillOTLPLogRecord(records.AppendEmpty(), fields, observed); err != nil {
+			return nil, "", err
+		}
+	}
+
+	request, err := plogotlp.NewExportRequestFromLogs(logs).MarshalProto()
+	if err != nil {
+		return nil, "", err
+	}
+	if !compress {
+		return request, "", nil
+	}
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(request); err != nil {
+		return nil, "", err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), compression.GzipEncoding, nil
+}
+
+// decompressPayload returns the content of a payload, decompressed according
+// to its encoding.
+func decompressPayload(payload *message.Payload) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+	switch payload.Encoding {
+	case "", "identity":
+		return payload.Encoded, nil
+	case compression.GzipEncoding:
+		reader, err = gzip.NewReader(bytes.NewReader(payload.Encoded))
+	case compression.ZlibEncoding:
+		reader, err = zlib.NewReader(bytes.NewReader(payload.Encoded))
+	case compression.ZstdEncoding:
+		var decoder *zstd.Decoder
+		decoder, err = zstd.NewReader(bytes.NewReader(payload.Encoded))
+		if err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q", payload.Encoding)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// fillOTLPLogRecord fills a LogRecord with the fields of a message. The
+// message is the body of the record, the other fields, e.g. its source and
+// attributes, are the attributes of the record, and its tags are split into
+// one attribute per tag key.
+func fillOTLPLogRecord(record plog.LogRecord, fields map[string]interface{}, observed pcommon.Timestamp) error {
+	if ts, ok := fields["timestamp"].(json.Number); ok {
+		if ms, err := ts.Int64(); err == nil && ms > 0 {
+			record.SetTimestamp(pcommon.Timestamp(ms * int64(time.Millisecond)))
+		}
+	}
+	record.SetObservedTimestamp(observed)
+
+	if status := stringField(fields, "status"); status != "" {
+		record.SetSeverityNumber(otlpSeverityNumber(status))
+		record.SetSeverityText(status)
+	}
+	record.Body().SetStr(stringField(fields, "message"))
+
+	if source := stringField(fields, "ddsource"); source != "" {
+		record.Attributes().PutStr(otlpSourceAttribute, source)
+	}
+
+	// sort the attributes to encode the records deterministically
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		switch name {
+		case "message", "status", "timestamp", "hostname", "service", "ddsource", "ddtags":
+		default:
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := record.Attributes().PutEmpty(name).FromRaw(rawValue(fields[name])); err != nil {
+			return fmt.Errorf("can't encode the attribute %s: %v", name, err)
+		}
+	}
+
+	putOTLPTags(record.Attributes(), stringField(fields, "ddtags"))
+	return nil
+}
+
+// putOTLPTags adds the comma separated key:value tags to attrs, without
+// overriding the attributes of the message. The values of the keys set
+// several times are grouped in a slice, and the tags without a value are
+// added with an empty value.
+func putOTLPTags(attrs pcommon.Map, tags string) {
+	fromTags := make(map[string]bool)
+	for _, tag := range strings.Split(tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(tag, ":")
+		if name, ok := otlpTagAttributes[key]; ok {
+			key = name
+		}
+
+		existing, ok := attrs.Get(key)
+		switch {
+		case !ok:
+			attrs.PutStr(key, value)
+			fromTags[key] = true
+		case !fromTags[key]:
+			// the attributes of the message take precedence over the tags
+		case existing.Type() == pcommon.ValueTypeSlice:
+			existing.Slice().AppendEmpty().SetStr(value)
+		default:
+			values := pcommon.NewValueSlice()
+			values.Slice().AppendEmpty().SetStr(existing.Str())
+			values.Slice().AppendEmpty().SetStr(value)
+			values.CopyTo(existing)
+		}
+	}
+}
+
+// rawValue converts the numbers of a decoded JSON value to the int64 and
+// float64 values supported by pcommon.
+func rawValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = rawValue(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = rawValue(elem)
+		}
+	}
+	return value
+}
+
+// otlpSeverityNumber returns the OTLP severity number of a status.
+func otlpSeverityNumber(status string) plog.SeverityNumber {
+	switch strings.ToLower(status) {
+	case message.StatusEmergency, message.StatusAlert, message.StatusCritical:
+		return plog.SeverityNumberFatal
+	case message.StatusError:
+		return plog.SeverityNumberError
+	case message.StatusWarning, "warning":
+		return plog.SeverityNumberWarn
+	case message.StatusDebug:
+		return plog.SeverityNumberDebug
+	case "trace":
+		return plog.SeverityNumberTrace
+	default:
+		return plog.SeverityNumberInfo
+	}
+}
+
+func stringField(fields map[string]interface{}, name string) string {
+	value, _ := fields[name].(string)
+	return value
+}
@@ -0,0 +1,250 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// newJSONPayload returns a payload encoded as a JSON array for the Datadog
+// intake, without its messages, like the payloads replayed from disk.
+func newJSONPayload(contents ...string) *message.Payload {
+	encoded := []byte("[" + strings.Join(contents, ",") + "]")
+	return &message.Payload{Encoded: encoded, UnencodedSize: len(encoded)}
+}
+
+// decodeOTLPRequest decodes an ExportLogsServiceRequest.
+func decodeOTLPRequest(t *testing.T, body []byte) plog.Logs {
+	request := plogotlp.NewExportRequest()
+	require.NoError(t, request.UnmarshalProto(body))
+	return request.Logs()
+}
+
+func TestBuildURLForOTLPEndpoints(t *testing.T) {
+	e := config.NewEndpoint("bar", "", "foo", 0, false)
+	e.Format = config.FormatOTLP
+	assert.Equal(t, "http://foo:4318/v1/logs", buildURL(e))
+
+	e = config.NewEndpoint("bar", "", "foo", 1234, true)
+	e.Format = config.FormatOTLP
+	e.Version = config.EPIntakeVersion2
+	e.TrackType = "test-track"
+	assert.Equal(t, "https://foo:1234/v1/logs", buildURL(e))
+}
+
+func TestEncodeOTLPPayload(t *testing.T) {
+	payload := newJSONPayload(
+		`{"message":"hello","status":"error","timestamp":1700000000123,"hostname":"host-a","service":"web","ddsource":"nginx","ddtags":"env:prod,team:logs,team:apm,version:1.2,canary","user":"bob"}`,
+		`{"message":"world","status":"info","timestamp":1700000000456,"hostname":"host-b","service":"web","ddsource":"nginx","ddtags":""}`,
+		`{"message":"again","status":"warn","timestamp":1700000000789,"hostname":"host-a","service":"web","ddsource":"nginx"}`,
+	)
+
+	encoded, encoding, err := encodeOTLPPayload(payload, false)
+	require.NoError(t, err)
+	assert.Empty(t, encoding)
+
+	logs := decodeOTLPRequest(t, encoded)
+	require.Equal(t, 2, logs.ResourceLogs().Len())
+
+	// the records of the same host are grouped in the same resource
+	first := logs.ResourceLogs().At(0)
+	assert.Equal(t, map[string]interface{}{"host.name": "host-a", "service.name": "web"}, first.Resource().Attributes().AsRaw())
+	require.Equal(t, 1, first.ScopeLogs().Len())
+	scope := first.ScopeLogs().At(0)
+	assert.Equal(t, "datadog-agent", scope.Scope().Name())
+	records := scope.LogRecords()
+	require.Equal(t, 2, records.Len())
+
+	record := records.At(0)
+	assert.Equal(t, pcommon.Timestamp(1700000000123*time.Millisecond), record.Timestamp())
+	assert.NotZero(t, record.ObservedTimestamp())
+	assert.Equal(t, plog.SeverityNumberError, record.SeverityNumber())
+	assert.Equal(t, "error", record.SeverityText())
+	assert.Equal(t, "hello", record.Body().Str())
+	assert.Equal(t, map[string]interface{}{
+		"datadog.log.source":          "nginx",
+		"deployment.environment.name": "prod",
+		"team":                        []interface{}{"logs", "apm"},
+		"service.version":             "1.2",
+		"canary":                      "",
+		"user":                        "bob",
+	}, record.Attributes().AsRaw())
+
+	record = records.At(1)
+	assert.Equal(t, plog.SeverityNumberWarn, record.SeverityNumber())
+	assert.Equal(t, map[string]interface{}{"datadog.log.source": "nginx"}, record.Attributes().AsRaw())
+
+	second := logs.ResourceLogs().At(1)
+	assert.Equal(t, map[string]interface{}{"host.name": "host-b", "service.name": "web"}, second.Resource().Attributes().AsRaw())
+	assert.Equal(t, map[string]interface{}{"datadog.log.source": "nginx"}, second.ScopeLogs().At(0).LogRecords().At(0).Attributes().AsRaw())
+}
+
+func TestEncodeOTLPPayloadKeepsAttributesThatAreNotStrings(t *testing.T) {
+	payload := newJSONPayload(`{"message":"hello","count":3,"ratio":0.5,"ok":true,"none":null,"user":{"id":42,"roles":["admin",7]}}`)
+
+	encoded, _, err := encodeOTLPPayload(payload, false)
+	require.NoError(t, err)
+
+	record := decodeOTLPRequest(t, encoded).ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, map[string]interface{}{
+		"count": int64(3),
+		"ratio": 0.5,
+		"ok":    true,
+		"none":  nil,
+		"user":  map[string]interface{}{"id": int64(42), "roles": []interface{}{"admin", int64(7)}},
+	}, record.Attributes().AsRaw())
+}
+
+func TestEncodeOTLPPayloadTagsDontOverrideAttributes(t *testing.T) {
+	payload := newJSONPayload(`{"message":"hello","user":"bob","ddtags":"user:alice,user:carol, team:logs ,"}`)
+
+	encoded, _, err := encodeOTLPPayload(payload, false)
+	require.NoError(t, err)
+
+	record := decodeOTLPRequest(t, encoded).ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+	assert.Equal(t, map[string]interface{}{"user": "bob", "team": "logs"}, record.Attributes().AsRaw())
+}
+
+func TestEncodeOTLPPayloadFromCompressedPayloads(t *testing.T) {
+	content := []byte(`[{"message":"hello","status":"info"}]`)
+
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, err := gzipWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	var deflated bytes.Buffer
+	zlibWriter := zlib.NewWriter(&deflated)
+	_, err = zlibWriter.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, zlibWriter.Close())
+
+	zstdEncoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	zstded := zstdEncoder.EncodeAll(content, nil)
+
+	for _, payload := range []*message.Payload{
+		{Encoded: content, Encoding: "identity"},
+		{Encoded: gzipped.Bytes(), Encoding: "gzip"},
+		{Encoded: deflated.Bytes(), Encoding: "deflate"},
+		{Encoded: zstded, Encoding: "zstd"},
+	} {
+		t.Run(payload.Encoding, func(t *testing.T) {
+			encoded, _, err := encodeOTLPPayload(payload, false)
+			require.NoError(t, err)
+			record := decodeOTLPRequest(t, encoded).ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0)
+			assert.Equal(t, "hello", record.Body().Str())
+		})
+	}
+
+	_, _, err = encodeOTLPPayload(&message.Payload{Encoded: content, Encoding: "br"}, false)
+	assert.Error(t, err)
+}
+
+func TestEncodeOTLPPayloadCompressed(t *testing.T) {
+	payload := newJSONPayload(`{"message":"hello","status":"info"}`)
+
+	compressed, encoding, err := encodeOTLPPayload(payload, true)
+	require.NoError(t, err)
+	assert.Equal(t, "gzip", encoding)
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	require.NoError(t, err)
+	decompressed, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", decodeOTLPRequest(t, decompressed).ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Body().Str())
+}
+
+func TestEncodeOTLPPayloadInvalidPayload(t *testing.T) {
+	_, _, err := encodeOTLPPayload(&message.Payload{Encoded: []byte(`not json`)}, false)
+	assert.Error(t, err)
+}
+
+func TestOTLPSeverityNumber(t *testing.T) {
+	assert.Equal(t, plog.SeverityNumberFatal, otlpSeverityNumber(message.StatusCritical))
+	assert.Equal(t, plog.SeverityNumberError, otlpSeverityNumber("ERROR"))
+	assert.Equal(t, plog.SeverityNumberWarn, otlpSeverityNumber("warning"))
+	assert.Equal(t, plog.SeverityNumberInfo, otlpSeverityNumber(message.StatusNotice))
+	assert.Equal(t, plog.SeverityNumberDebug, otlpSeverityNumber(message.StatusDebug))
+	assert.Equal(t, plog.SeverityNumberInfo, otlpSeverityNumber("unknown"))
+}
+
+func TestOTLPDestinationRetriesAndSendsProtobuf(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	statusCode := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		w.WriteHeader(statusCode)
+		// the collector recovers after the first failure
+		statusCode = http.StatusOK
+	}))
+	defer server.Close()
+
+	url := strings.Split(server.URL, ":")
+	port, _ := strconv.Atoi(url[2])
+	endpoint := config.NewEndpoint("secret", "", strings.TrimPrefix(url[1], "//"), port, false)
+	endpoint.Format = config.FormatOTLP
+	endpoint.BackoffFactor = 1
+	endpoint.BackoffBase = 1
+	endpoint.BackoffMax = 10
+	endpoint.RecoveryInterval = 1
+
+	destCtx := client.NewDestinationsContext()
+	destCtx.Start()
+	defer destCtx.Stop()
+	dest := NewDestination(endpoint, JSONContentType, destCtx, 0, true, client.NewNoopDestinationMetadata(), configmock.New(t), metrics.NewNoopPipelineMonitor(""))
+
+	input := make(chan *message.Payload)
+	output := make(chan *message.Payload)
+	dest.Start(input, output, nil)
+	payload := newJSONPayload(`{"message":"hello","status":"info"}`)
+	input <- payload
+	assert.Equal(t, payload, <-output)
+	close(input)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 2)
+	// the payload is encoded once and the same request is retried
+	assert.Equal(t, bodies[0], bodies[1])
+	for i, r := range requests {
+		assert.Equal(t, otlpPath, r.URL.Path)
+		assert.Equal(t, ProtobufContentType, r.Header.Get("Content-Type"))
+		assert.Empty(t, r.Header.Get("DD-API-KEY"))
+		assert.Empty(t, r.Header.Get("dd-message-timestamp"))
+		assert.Equal(t, 1, decodeOTLPRequest(t, bodies[i]).ResourceLogs().Len())
+	}
+}
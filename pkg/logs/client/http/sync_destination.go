@@ -70,7 +70,10 @@ func (d *SyncDestination) run(input chan *message.Payload, output chan *message.
 		tlmIdle.Add(idle, d.destination.destMeta.TelemetryName())
 		var startInUse = time.Now()
 
-		err := d.destination.unconditionalSend(p)
+		encoded, err := d.destination.encodePayload(p)
+		if err == nil {
+			err = d.destination.unconditionalSend(encoded)
+		}
 		if err != nil {
 			metrics.DestinationErrors.Add(1)
 			metrics.TlmDestinationErrors.Inc()
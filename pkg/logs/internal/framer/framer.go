@@ -42,6 +42,11 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Octet-counted frames, as used by syslog over TCP, e.g. `11 hello world`.
+	// Frames which are not prefixed by their length are considered as
+	// newline-terminated text in UTF-8.
+	OctetCounting
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -101,6 +106,8 @@ func NewFramer(
 		matcher = &dockerStreamMatcher{contentLenLimit}
 	case NoFraming:
 		matcher = &noFramingMatcher{}
+	case OctetCounting:
+		matcher = newOctetCountingMatcher(contentLenLimit)
 	default:
 		panic(fmt.Sprintf("unknown framing %d", framing))
 	}
@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+// maxOctetCountDigits is the maximum number of digits of an octet count,
+// longer counts are not considered as such.
+const maxOctetCountDigits = 9
+
+// octetCountingMatcher implements EndLineMatcher for frames prefixed by their
+// length in bytes, written in ASCII digits and followed by a space, as described
+// for syslog over TCP in RFC 6587 section 3.4.1 (e.g. `11 hello world`).
+//
+// Frames not starting with a non-zero digit are considered newline-terminated,
+// so that senders using non-transparent framing are supported as well.
+type octetCountingMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Frames longer than this value will be split into multiple frames.
+	contentLenLimit int
+
+	// remaining is the number of bytes of an oversized frame left to be returned.
+	remaining int
+
+	newline oneByteNewLineMatcher
+}
+
+func newOctetCountingMatcher(contentLenLimit int) *octetCountingMatcher {
+	return &octetCountingMatcher{
+		contentLenLimit: contentLenLimit,
+		newline:         oneByteNewLineMatcher{contentLenLimit},
+	}
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (oc *octetCountingMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if oc.remaining > 0 {
+		n := min(oc.remaining, oc.contentLenLimit)
+		if len(buf) < n {
+			return nil, 0
+		}
+		oc.remaining -= n
+		return buf[:n], n
+	}
+
+	if len(buf) == 0 || buf[0] < '1' || buf[0] > '9' {
+		return oc.newline.FindFrame(buf, seen)
+	}
+
+	length := 0
+	for i, c := range buf {
+		switch {
+		case c >= '0' && c <= '9' && i < maxOctetCountDigits:
+			length = length*10 + int(c-'0')
+		case c == ' ':
+			start := i + 1
+			end := start + length
+			if end > oc.contentLenLimit && len(buf) >= oc.contentLenLimit {
+				// the frame is too long, return what fits in the limit and
+				// the rest of it with the next calls.
+				oc.remaining = end - oc.contentLenLimit
+				return buf[start:oc.contentLenLimit], oc.contentLenLimit
+			}
+			if len(buf) < end {
+				return nil, 0
+			}
+			return buf[start:end], end
+		default:
+			// not an octet count
+			return oc.newline.FindFrame(buf, seen)
+		}
+	}
+
+	// the octet count is not complete yet
+	return nil, 0
+}
@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func TestOctetCountingMatcher_FindFrame(t *testing.T) {
+	content, rawDataLen := newOctetCountingMatcher(100).FindFrame([]byte("11 hello\nworld5 abcde"), 0)
+	assert.Equal(t, []byte("hello\nworld"), content)
+	assert.Equal(t, 14, rawDataLen)
+}
+
+func TestOctetCountingMatcher_FindFrame_incomplete(t *testing.T) {
+	for _, input := range []string{"1", "12", "12 hello", "12 hello worl"} {
+		content, rawDataLen := newOctetCountingMatcher(100).FindFrame([]byte(input), 0)
+		assert.Nil(t, content, input)
+		assert.Equal(t, 0, rawDataLen, input)
+	}
+}
+
+func TestOctetCountingMatcher_FindFrame_newline(t *testing.T) {
+	// frames not starting with an octet count are newline-terminated
+	testFindFrame(t, newOctetCountingMatcher(100), []byte("<13>hello\n5 abcde"), 9, 10)
+	testFindFrame(t, newOctetCountingMatcher(100), []byte("12:03 hello\n"), 11, 12)
+	testFindFrame(t, newOctetCountingMatcher(100), []byte("0 hello\n"), 7, 8)
+	testFindFrame(t, newOctetCountingMatcher(100), []byte("1234567890 hello\n"), 16, 17)
+}
+
+func TestOctetCountingMatcher_FindFrame_cll(t *testing.T) {
+	m := newOctetCountingMatcher(10)
+	buf := []byte("16 abcdefghijklmnop3 xyz")
+
+	content, rawDataLen := m.FindFrame(buf, 0)
+	assert.Equal(t, []byte("abcdefg"), content)
+	assert.Equal(t, 10, rawDataLen)
+
+	content, rawDataLen = m.FindFrame(buf[10:], 0)
+	assert.Equal(t, []byte("hijklmnop"), content)
+	assert.Equal(t, 9, rawDataLen)
+
+	content, rawDataLen = m.FindFrame(buf[19:], 0)
+	assert.Equal(t, []byte("xyz"), content)
+	assert.Equal(t, 5, rawDataLen)
+}
+
+func TestOctetCountingFraming(t *testing.T) {
+	input := []byte("5 hello11 hello world\n<13>plain line\n3 end")
+	for _, size := range []int{1, 2, 7, len(input)} {
+		var got []string
+		outputFn := func(msg *message.Message, _ int) { got = append(got, string(msg.GetContent())) }
+		framer := NewFramer(outputFn, OctetCounting, contentLenLimit)
+		for start := 0; start < len(input); start += size {
+			end := min(start+size, len(input))
+			framer.Process(message.NewMessage(input[start:end], nil, "", 0))
+		}
+		require.Equal(t, []string{"hello", "hello world", "", "<13>plain line", "end"}, got, "chunk size %d", size)
+	}
+}
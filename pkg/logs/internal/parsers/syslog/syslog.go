@@ -0,0 +1,352 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for syslog messages, following either
+// RFC 5424 or the BSD syslog format described in RFC 3164.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Tags names set by the parser.
+const (
+	facilityTagName = "syslog_facility"
+	hostnameTagName = "syslog_hostname"
+	appnameTagName  = "syslog_appname"
+	procidTagName   = "syslog_procid"
+	msgidTagName    = "syslog_msgid"
+)
+
+// nilValue is the RFC 5424 NILVALUE, used for absent header fields.
+const nilValue = "-"
+
+// rfc3164TimestampLen is the length of an RFC 3164 timestamp, e.g. "Jan  2 15:04:05".
+const rfc3164TimestampLen = len(time.Stamp)
+
+var (
+	errNoPriority     = errors.New("syslog message doesn't start with a priority")
+	errInvalidHeader  = errors.New("invalid syslog message header")
+	errInvalidSDBlock = errors.New("invalid syslog structured data")
+
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+)
+
+var severityStatuses = []string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// New creates a new parser that parses syslog messages.
+//
+// RFC 5424 messages follow the pattern
+// '<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG',
+// for example: `<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [exampleSDID@32473 iut="3"] An application event`.
+//
+// RFC 3164 messages follow the pattern '<PRI>TIMESTAMP HOSTNAME TAG: MSG',
+// for example: `<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8`.
+//
+// The priority is turned into the message status, the timestamp into the message
+// timestamp, the other header fields into tags and the structured data into
+// attributes, as its values are usually unbounded.
+// Messages which can't be parsed are left untouched.
+func New() parsers.Parser {
+	return &syslogFormat{now: time.Now}
+}
+
+type syslogFormat struct {
+	// now is used to infer the year of RFC 3164 timestamps.
+	now func() time.Time
+}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	content := msg.GetContent()
+
+	pri, rest, err := parsePriority(content)
+	if err != nil {
+		return msg, err
+	}
+	msg.Status = severityStatuses[pri%8]
+	msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, facilityTagName+":"+facilityName(pri/8))
+
+	var h header
+	if len(rest) > 1 && rest[0] == '1' && rest[1] == ' ' {
+		h, err = parseRFC5424(rest[2:])
+	} else {
+		h, err = p.parseRFC3164(rest)
+	}
+	if err != nil {
+		// keep the content following the priority, the status is still relevant
+		msg.SetContent(rest)
+		return msg, err
+	}
+
+	if !h.timestamp.IsZero() {
+		msg.LogTimestamp = h.timestamp.UTC()
+	}
+	msg.ParsingExtra.Tags = append(msg.ParsingExtra.Tags, h.tags()...)
+	if len(h.structuredData) > 0 {
+		if msg.ProcessingAttributes == nil {
+			msg.ProcessingAttributes = make(map[string]string, len(h.structuredData))
+		}
+		for name, value := range h.structuredData {
+			msg.ProcessingAttributes[name] = value
+		}
+	}
+	msg.SetContent(h.msg)
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+// header contains the fields parsed from a syslog message.
+type header struct {
+	timestamp      time.Time
+	hostname       string
+	appname        string
+	procid         string
+	msgid          string
+	structuredData map[string]string
+	msg            []byte
+}
+
+func (h *header) tags() []string {
+	var tags []string
+	for _, field := range []struct{ name, value string }{
+		{hostnameTagName, h.hostname},
+		{appnameTagName, h.appname},
+		{procidTagName, h.procid},
+		{msgidTagName, h.msgid},
+	} {
+		if field.value != "" && field.value != nilValue {
+			tags = append(tags, field.name+":"+field.value)
+		}
+	}
+	return tags
+}
+
+// parsePriority parses the '<PRI>' prefix of a syslog message.
+func parsePriority(content []byte) (int, []byte, error) {
+	if len(content) < 3 || content[0] != '<' {
+		return 0, content, errNoPriority
+	}
+	end := bytes.IndexByte(content[:min(len(content), 5)], '>')
+	if end < 2 {
+		return 0, content, errNoPriority
+	}
+	pri, err := strconv.Atoi(string(content[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, content, errNoPriority
+	}
+	return pri, content[end+1:], nil
+}
+
+func facilityName(facility int) string {
+	if facility < len(facilityNames) {
+		return facilityNames[facility]
+	}
+	return strconv.Itoa(facility)
+}
+
+// parseRFC5424 parses the header of an RFC 5424 message, following its version.
+func parseRFC5424(content []byte) (header, error) {
+	var h header
+	fields := make([]string, 5)
+	for i := range fields {
+		field, rest, found := bytes.Cut(content, []byte{' '})
+		if !found {
+			return h, errInvalidHeader
+		}
+		fields[i] = string(field)
+		content = rest
+	}
+
+	if fields[0] != nilValue {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return h, errInvalidHeader
+		}
+		h.timestamp = ts
+	}
+	h.hostname, h.appname, h.procid, h.msgid = fields[1], fields[2], fields[3], fields[4]
+
+	sd, rest, err := parseStructuredData(content)
+	if err != nil {
+		return h, err
+	}
+	h.structuredData = sd
+	if len(rest) > 0 && rest[0] == ' ' {
+		rest = rest[1:]
+	}
+	h.msg = bytes.TrimPrefix(rest, utf8BOM)
+	return h, nil
+}
+
+// parseStructuredData parses the STRUCTURED-DATA part of an RFC 5424
+// message into params named '<SD-ID>.<PARAM-NAME>'.
+func parseStructuredData(content []byte) (map[string]string, []byte, error) {
+	if len(content) > 0 && content[0] == '-' {
+		return nil, content[1:], nil
+	}
+
+	params := make(map[string]string)
+	for len(content) > 0 && content[0] == '[' {
+		content = content[1:]
+		idEnd := bytes.IndexAny(content, " ]")
+		if idEnd <= 0 {
+			return nil, content, errInvalidSDBlock
+		}
+		id := string(content[:idEnd])
+		content = content[idEnd:]
+
+		for len(content) > 0 && content[0] == ' ' {
+			content = content[1:]
+			nameEnd := bytes.Index(content, []byte(`="`))
+			if nameEnd <= 0 {
+				return nil, content, errInvalidSDBlock
+			}
+			name := string(content[:nameEnd])
+			content = content[nameEnd+2:]
+
+			value, rest, err := parseParamValue(content)
+			if err != nil {
+				return nil, content, err
+			}
+			params[id+"."+name] = value
+			content = rest
+		}
+
+		if len(content) == 0 || content[0] != ']' {
+			return nil, content, errInvalidSDBlock
+		}
+		content = content[1:]
+	}
+
+	if len(params) == 0 {
+		return nil, content, errInvalidSDBlock
+	}
+	return params, content, nil
+}
+
+// parseParamValue parses a quoted SD-PARAM value, unescaping '"', '\' and ']'.
+func parseParamValue(content []byte) (string, []byte, error) {
+	var value []byte
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '\\':
+			if i+1 < len(content) && (content[i+1] == '"' || content[i+1] == '\\' || content[i+1] == ']') {
+				i++
+				value = append(value, content[i])
+			} else {
+				value = append(value, c)
+			}
+		case '"':
+			return string(value), content[i+1:], nil
+		default:
+			value = append(value, c)
+		}
+	}
+	return "", content, errInvalidSDBlock
+}
+
+// parseRFC3164 parses the header of a BSD syslog message. As there's no strict
+// format for those messages, parsing is lenient: the timestamp can also be an
+// RFC 3339 one, and the TAG is optional.
+func (p *syslogFormat) parseRFC3164(content []byte) (header, error) {
+	var h header
+
+	switch {
+	case len(content) >= rfc3164TimestampLen && content[3] == ' ':
+		ts, err := time.ParseInLocation(time.Stamp, string(content[:rfc3164TimestampLen]), time.Local)
+		if err != nil {
+			return h, errInvalidHeader
+		}
+		h.timestamp = p.withYear(ts)
+		content = content[rfc3164TimestampLen:]
+	case len(content) > 0 && content[0] >= '0' && content[0] <= '9':
+		field, rest, _ := bytes.Cut(content, []byte{' '})
+		ts, err := time.Parse(time.RFC3339Nano, string(field))
+		if err != nil {
+			return h, errInvalidHeader
+		}
+		h.timestamp = ts
+		content = rest
+	default:
+		// no timestamp, the rest of the message is the content
+		h.msg = content
+		return h, nil
+	}
+
+	content = bytes.TrimLeft(content, " ")
+	hostname, rest, found := bytes.Cut(content, []byte{' '})
+	if !found {
+		h.msg = content
+		return h, nil
+	}
+	h.hostname = string(hostname)
+	content = rest
+
+	h.appname, h.procid, content = parseTag(content)
+	h.msg = content
+	return h, nil
+}
+
+// parseTag parses the 'app[pid]: ' TAG prefixing the content of an RFC 3164 message.
+// The content is returned untouched when it doesn't start with a TAG.
+func parseTag(content []byte) (string, string, []byte) {
+	end := bytes.IndexAny(content, ":[ ")
+	if end <= 0 || end > 48 || content[end] == ' ' {
+		return "", "", content
+	}
+	appname := string(content[:end])
+	rest := content[end:]
+
+	var procid string
+	if rest[0] == '[' {
+		pidEnd := bytes.IndexByte(rest, ']')
+		if pidEnd < 0 {
+			return "", "", content
+		}
+		procid = string(rest[1:pidEnd])
+		rest = rest[pidEnd+1:]
+	}
+	if len(rest) == 0 || rest[0] != ':' {
+		return "", "", content
+	}
+	return appname, procid, bytes.TrimPrefix(rest[1:], []byte{' '})
+}
+
+// withYear sets the year of an RFC 3164 timestamp, which doesn't contain it,
+// considering that messages are not coming from the future.
+func (p *syslogFormat) withYear(ts time.Time) time.Time {
+	now := p.now()
+	ts = ts.AddDate(now.Year(), 0, 0)
+	if ts.After(now.Add(24 * time.Hour)) {
+		ts = ts.AddDate(-1, 0, 0)
+	}
+	return ts
+}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTestParser(now time.Time) *syslogFormat {
+	return &syslogFormat{now: func() time.Time { return now }}
+}
+
+func TestParseRFC5424(t *testing.T) {
+	parser := New()
+	msg, err := parser.Parse(message.NewMessage([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="Application"][origin ip="192.0.2.1"] An application event`), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "An application event", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC), msg.LogTimestamp)
+	assert.Equal(t, []string{
+		"syslog_facility:local4",
+		"syslog_hostname:mymachine.example.com",
+		"syslog_appname:evntslog",
+		"syslog_procid:1234",
+		"syslog_msgid:ID47",
+	}, msg.ParsingExtra.Tags)
+	assert.Equal(t, map[string]string{
+		"exampleSDID@32473.iut":         "3",
+		"exampleSDID@32473.eventSource": "Application",
+		"origin.ip":                     "192.0.2.1",
+	}, msg.ProcessingAttributes)
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	parser := New()
+	msg, err := parser.Parse(message.NewMessage([]byte("<34>1 - - su - - - \xef\xbb\xbf'su root' failed"), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "'su root' failed", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.True(t, msg.LogTimestamp.IsZero())
+	assert.Equal(t, []string{"syslog_facility:auth", "syslog_appname:su"}, msg.ParsingExtra.Tags)
+	assert.Nil(t, msg.ProcessingAttributes)
+}
+
+func TestParseRFC5424EscapedStructuredData(t *testing.T) {
+	parser := New()
+	msg, err := parser.Parse(message.NewMessage([]byte(`<14>1 2003-10-11T22:14:15Z host app - - [meta msg="a \"quoted\" \] value"]`), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Empty(t, msg.GetContent())
+	assert.Equal(t, `a "quoted" ] value`, msg.ProcessingAttributes["meta.msg"])
+}
+
+func TestParseRFC5424Invalid(t *testing.T) {
+	parser := New()
+	for _, content := range []string{
+		`<14>1 not-a-timestamp host app - - - msg`,
+		`<14>1 2003-10-11T22:14:15Z host`,
+		`<14>1 2003-10-11T22:14:15Z host app - - [unterminated a="b" msg`,
+	} {
+		msg, err := parser.Parse(message.NewMessage([]byte(content), nil, "", 0))
+		assert.NotNil(t, err, content)
+		assert.Equal(t, message.StatusInfo, msg.Status)
+		assert.Equal(t, content[4:], string(msg.GetContent()))
+	}
+}
+
+func TestParseRFC3164(t *testing.T) {
+	parser := newTestParser(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local))
+	msg, err := parser.Parse(message.NewMessage([]byte(`<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8`), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	// the timestamp can't be in the future, it's from the previous year
+	assert.Equal(t, time.Date(2023, 10, 11, 22, 14, 15, 0, time.Local).UTC(), msg.LogTimestamp)
+	assert.Equal(t, []string{
+		"syslog_facility:auth",
+		"syslog_hostname:mymachine",
+		"syslog_appname:su",
+		"syslog_procid:230",
+	}, msg.ParsingExtra.Tags)
+}
+
+func TestParseRFC3164Variants(t *testing.T) {
+	parser := newTestParser(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local))
+
+	// single-digit day, no PID
+	msg, err := parser.Parse(message.NewMessage([]byte(`<13>Feb  5 17:32:18 10.0.0.99 myapp: Use the BFG!`), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "Use the BFG!", string(msg.GetContent()))
+	assert.Equal(t, time.Date(2024, 2, 5, 17, 32, 18, 0, time.Local).UTC(), msg.LogTimestamp)
+	assert.Equal(t, []string{"syslog_facility:user", "syslog_hostname:10.0.0.99", "syslog_appname:myapp"}, msg.ParsingExtra.Tags)
+
+	// RFC 3339 timestamp, no TAG
+	msg, err = parser.Parse(message.NewMessage([]byte(`<191>2024-02-05T17:32:18+01:00 router link down on eth0`), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "link down on eth0", string(msg.GetContent()))
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.Equal(t, time.Date(2024, 2, 5, 16, 32, 18, 0, time.UTC), msg.LogTimestamp)
+	assert.Equal(t, []string{"syslog_facility:local7", "syslog_hostname:router"}, msg.ParsingExtra.Tags)
+
+	// no timestamp at all
+	msg, err = parser.Parse(message.NewMessage([]byte(`<11>something went wrong`), nil, "", 0))
+	assert.Nil(t, err)
+	assert.Equal(t, "something went wrong", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.True(t, msg.LogTimestamp.IsZero())
+}
+
+func TestParseWithoutPriority(t *testing.T) {
+	parser := New()
+	for _, content := range []string{"", "hello world", "<>1 - - - - - -", "<1000>x", "<abc>x"} {
+		msg, err := parser.Parse(message.NewMessage([]byte(content), nil, "", 0))
+		assert.Equal(t, errNoPriority, err, content)
+		assert.Equal(t, content, string(msg.GetContent()))
+		assert.Empty(t, msg.ParsingExtra.Tags)
+	}
+}
+
+func TestSupportsPartialLine(t *testing.T) {
+	assert.False(t, New().SupportsPartialLine())
+}
@@ -42,6 +42,20 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// IsCompleted implements auditor.Registry#IsCompleted.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) IsCompleted(identifier string) bool {
+	panic("unused")
+}
+
+// MarkCompleted implements auditor.Registry#MarkCompleted.
+//
+//nolint:revive // TODO(AML) Fix revive linter
+func (r *fakeRegistry) MarkCompleted(identifier string, offset string) {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
@@ -7,6 +7,8 @@
 package file
 
 import (
+	"io"
+	"path/filepath"
 	"regexp"
 	"time"
 
@@ -32,6 +34,10 @@ import (
 // DefaultSleepDuration represents the amount of time the tailer waits before reading new data when no data is received
 const DefaultSleepDuration = 1 * time.Second
 
+// maxDrainAttempts is the number of scans during which the archive of a
+// rotated file is looked for.
+const maxDrainAttempts = 6
+
 // Launcher checks all files provided by fileProvider and create new tailers
 // or update the old ones if needed
 type Launcher struct {
@@ -43,6 +49,8 @@ type Launcher struct {
 	fileProvider        *fileprovider.FileProvider
 	tailers             *tailers.TailerContainer[*tailer.Tailer]
 	rotatedTailers      []*tailer.Tailer
+	pendingDrains       map[*tailer.Tailer]int
+	completedArchives   map[string]struct{}
 	registry            auditor.Registry
 	tailerSleepDuration time.Duration
 	stop                chan struct{}
@@ -75,6 +83,8 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		fileProvider:           fileprovider.NewFileProvider(tailingLimit, wildcardStrategy),
 		tailers:                tailers.NewTailerContainer[*tailer.Tailer](),
 		rotatedTailers:         []*tailer.Tailer{},
+		pendingDrains:          make(map[*tailer.Tailer]int),
+		completedArchives:      make(map[string]struct{}),
 		tailerSleepDuration:    tailerSleepDuration,
 		stop:                   make(chan struct{}),
 		done:                   make(chan struct{}),
@@ -116,6 +126,7 @@ func (s *Launcher) run() {
 		case source := <-s.removedSources:
 			s.removeSource(source)
 		case <-scanTicker.C:
+			s.drainRotatedTailers()
 			s.cleanUpRotatedTailers()
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
@@ -151,6 +162,7 @@ func (s *Launcher) scan() {
 	files := s.fileProvider.FilesToTail(s.validatePodContainerID, s.activeSources)
 	filesTailed := make(map[string]bool)
 	var allFiles []string
+	s.forgetRemovedArchives(files)
 
 	log.Debugf("Scan - got %d files from FilesToTail and currently tailing %d files\n", len(files), s.tailers.Count())
 
@@ -171,6 +183,9 @@ func (s *Launcher) scan() {
 		scanKey := file.GetScanKey()
 		tailer, isTailed := s.tailers.Get(scanKey)
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsCompleted() {
+				s.completedArchives[scanKey] = struct{}{}
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
@@ -215,7 +230,7 @@ func (s *Launcher) scan() {
 	for _, file := range files {
 		scanKey := file.GetScanKey()
 		isTailed := s.tailers.Contains(scanKey)
-		if !isTailed && tailersLen < s.tailingLimit {
+		if !isTailed && tailersLen < s.tailingLimit && !s.isArchiveCompleted(file) {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
 			succeeded := s.startNewTailer(file, config.Beginning)
 			if !succeeded {
@@ -236,17 +251,90 @@ func (s *Launcher) scan() {
 	}
 }
 
-// cleanUpRotatedTailers removes any rotated tailers that have stopped from the list
+// cleanUpRotatedTailers removes any rotated tailers that have stopped from the list,
+// unless the archive they must be drained from is still awaited.
 func (s *Launcher) cleanUpRotatedTailers() {
 	pendingTailers := []*tailer.Tailer{}
 	for _, tailer := range s.rotatedTailers {
-		if !tailer.IsFinished() {
+		if _, pendingDrain := s.pendingDrains[tailer]; !tailer.IsFinished() || pendingDrain {
 			pendingTailers = append(pendingTailers, tailer)
 		}
 	}
 	s.rotatedTailers = pendingTailers
 }
 
+// drainRotatedTailers starts a tailer reading the archive of each rotated
+// file which got compressed, from where its tailer stopped reading, so that
+// the logs it did not read before the rotation are not lost. As the archive
+// is usually written after the rotation, it is looked for again on the next
+// scans when it can't be found or read.
+func (s *Launcher) drainRotatedTailers() {
+	for _, rotatedTailer := range s.rotatedTailers {
+		if !rotatedTailer.IsFinished() || rotatedTailer.IsArchive() {
+			continue
+		}
+		attempts, pending := s.pendingDrains[rotatedTailer]
+		if pending && attempts >= maxDrainAttempts {
+			log.Debugf("Could not find the archive of the rotated file %s", rotatedTailer.GetId())
+			delete(s.pendingDrains, rotatedTailer)
+			continue
+		}
+		s.pendingDrains[rotatedTailer] = attempts + 1
+
+		archive, found := rotatedTailer.FindRotatedArchive()
+		if !found {
+			continue
+		}
+		source := rotatedTailer.Source()
+		if matched, _ := filepath.Match(source.Config.Path, archive); matched {
+			// the archive is collected on its own
+			delete(s.pendingDrains, rotatedTailer)
+			continue
+		}
+
+		tailerInfo := status.NewInfoRegistry()
+		file := tailer.NewFile(archive, source, false)
+		channel, monitor := s.pipelineProvider.NextPipelineChanWithMonitor()
+		drainTailer := rotatedTailer.NewDrainTailer(file, channel, monitor, decoder.NewDecoderFromSourceWithPattern(file.Source, rotatedTailer.GetDetectedPattern(), tailerInfo), tailerInfo, s.tagger)
+		offset := rotatedTailer.LastReadOffset()
+		log.Infof("Draining %s from offset %d, containing the rotated file %s", archive, offset, rotatedTailer.GetId())
+		if err := drainTailer.Start(offset, io.SeekStart); err != nil {
+			log.Warn(err)
+			continue
+		}
+		delete(s.pendingDrains, rotatedTailer)
+		// the drain tailer stops by itself at the end of the archive
+		s.rotatedTailers = append(s.rotatedTailers, drainTailer)
+	}
+}
+
+// isArchiveCompleted returns true if the file is an archive which has already
+// been read entirely.
+func (s *Launcher) isArchiveCompleted(file *tailer.File) bool {
+	if !file.IsArchive() {
+		return false
+	}
+	_, completed := s.completedArchives[file.GetScanKey()]
+	return completed
+}
+
+// forgetRemovedArchives stops tracking the completed archives which are not
+// to be tailed anymore.
+func (s *Launcher) forgetRemovedArchives(files []*tailer.File) {
+	if len(s.completedArchives) == 0 {
+		return
+	}
+	scanKeys := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		scanKeys[file.GetScanKey()] = struct{}{}
+	}
+	for scanKey := range s.completedArchives {
+		if _, exists := scanKeys[scanKey]; !exists {
+			delete(s.completedArchives, scanKey)
+		}
+	}
+}
+
 // addSource keeps track of the new source and launch new tailers for this source.
 func (s *Launcher) addSource(source *sources.LogSource) {
 	s.activeSources = append(s.activeSources, source)
@@ -281,7 +369,7 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 			return
 		}
 
-		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) {
+		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) || s.isArchiveCompleted(file) {
 			continue
 		}
 		if tailer, isTailed := s.tailers.Get(file.GetScanKey()); isTailed {
@@ -317,6 +405,11 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	var offset int64
 	var whence int
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
+	if file.IsArchive() && mode != config.ForceBeginning && s.registry.IsCompleted(tailer.Identifier()) {
+		log.Debugf("Archive %s has already been read entirely, skipping it", file.Path)
+		s.completedArchives[file.GetScanKey()] = struct{}{}
+		return false
+	}
 	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
@@ -394,6 +487,7 @@ func (s *Launcher) createTailer(file *tailer.File, outputChan chan *message.Mess
 		Info:            tailerInfo,
 		TagAdder:        s.tagger,
 		PipelineMonitor: pipelineMonitor,
+		Registry:        s.registry,
 	}
 
 	return tailer.NewTailer(tailerOptions)
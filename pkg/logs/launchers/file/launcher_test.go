@@ -8,6 +8,7 @@
 package file
 
 import (
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -742,6 +743,170 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 	assert.True(t, launcher.tailers.Contains(path("b.log")))
 }
 
+func writeGzipFile(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	assert.Nil(t, err)
+	defer f.Close()
+	w := gzip.NewWriter(f)
+	_, err = w.Write([]byte(content))
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+}
+
+func createArchiveLauncher(t *testing.T, path string, registry *auditor.Registry) *Launcher {
+	fc := flareController.NewFlareController()
+	launcher := NewLauncher(2, 20*time.Millisecond, false, 10*time.Second, "by_name", fc, taggerMock.SetupFakeTagger(t))
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = registry
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.Clear()
+	status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+	t.Cleanup(status.Clear)
+	return launcher
+}
+
+func TestLauncherReadsArchiveOnce(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	writeGzipFile(t, path, "hello\nworld\n")
+
+	registry := auditor.NewRegistry()
+	launcher := createArchiveLauncher(t, fmt.Sprintf("%s/*.gz", testDir), registry)
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	assert.Equal(t, 1, launcher.tailers.Count())
+	msg := <-outputChan
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	msg = <-outputChan
+	assert.Equal(t, "world", string(msg.GetContent()))
+	assert.Eventually(t, func() bool { return registry.IsCompleted("file:" + path) }, 5*time.Second, 10*time.Millisecond)
+
+	archiveTailer, _ := launcher.tailers.Get(path)
+	assert.Eventually(t, archiveTailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+
+	// the archive is not read again
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Contains(t, launcher.completedArchives, path)
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Empty(t, outputChan)
+
+	// the archive is forgotten once removed
+	assert.Nil(t, os.Remove(path))
+	launcher.scan()
+	assert.Empty(t, launcher.completedArchives)
+}
+
+func TestLauncherSkipsCompletedArchive(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	writeGzipFile(t, path, "hello\n")
+
+	registry := auditor.NewRegistry()
+	registry.SetCompleted(true)
+	launcher := createArchiveLauncher(t, path, registry)
+	defer launcher.cleanup()
+
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Contains(t, launcher.completedArchives, path)
+}
+
+func TestLauncherDrainsCompressedRotatedFile(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("first\n"), 0644))
+
+	launcher := createArchiveLauncher(t, path, auditor.NewRegistry())
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	msg := <-outputChan
+	assert.Equal(t, "first", string(msg.GetContent()))
+
+	// the file is rotated and compressed before the tailer could read its last line
+	rotatedTailer, _ := launcher.tailers.Get(path)
+	rotatedTailer.Stop()
+	launcher.tailers.Remove(rotatedTailer)
+	launcher.rotatedTailers = append(launcher.rotatedTailers, rotatedTailer)
+	writeGzipFile(t, path+".1.gz", "first\nsecond\n")
+	assert.Nil(t, os.Truncate(path, 0))
+
+	launcher.drainRotatedTailers()
+	launcher.cleanUpRotatedTailers()
+	assert.Len(t, launcher.rotatedTailers, 1)
+
+	msg = <-outputChan
+	assert.Equal(t, "second", string(msg.GetContent()))
+	// the offsets of the drained archive are tracked
+	assert.Equal(t, "file:"+path+".1.gz", msg.Origin.Identifier)
+	assert.Equal(t, "13", msg.Origin.Offset)
+	assert.Eventually(t, launcher.rotatedTailers[0].IsFinished, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestLauncherRetriesDrainUntilArchiveIsWritten(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("first\n"), 0644))
+
+	launcher := createArchiveLauncher(t, path, auditor.NewRegistry())
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	msg := <-outputChan
+	assert.Equal(t, "first", string(msg.GetContent()))
+
+	rotatedTailer, _ := launcher.tailers.Get(path)
+	rotatedTailer.Stop()
+	launcher.tailers.Remove(rotatedTailer)
+	launcher.rotatedTailers = append(launcher.rotatedTailers, rotatedTailer)
+	assert.Nil(t, os.Truncate(path, 0))
+
+	// the archive is not written yet, the rotated tailer is kept
+	launcher.drainRotatedTailers()
+	launcher.cleanUpRotatedTailers()
+	assert.Equal(t, []*filetailer.Tailer{rotatedTailer}, launcher.rotatedTailers)
+
+	writeGzipFile(t, path+".1.gz", "first\nsecond\n")
+	launcher.drainRotatedTailers()
+	launcher.cleanUpRotatedTailers()
+	assert.Len(t, launcher.rotatedTailers, 1)
+	assert.NotEqual(t, rotatedTailer, launcher.rotatedTailers[0])
+	msg = <-outputChan
+	assert.Equal(t, "second", string(msg.GetContent()))
+}
+
+func TestLauncherGivesUpDrainWithoutArchive(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("first\n"), 0644))
+
+	launcher := createArchiveLauncher(t, path, auditor.NewRegistry())
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+	defer launcher.cleanup()
+
+	launcher.scan()
+	<-outputChan
+	rotatedTailer, _ := launcher.tailers.Get(path)
+	rotatedTailer.Stop()
+	launcher.tailers.Remove(rotatedTailer)
+	launcher.rotatedTailers = append(launcher.rotatedTailers, rotatedTailer)
+
+	for i := 0; i <= maxDrainAttempts; i++ {
+		assert.Len(t, launcher.rotatedTailers, 1)
+		launcher.drainRotatedTailers()
+		launcher.cleanUpRotatedTailers()
+	}
+	assert.Empty(t, launcher.rotatedTailers)
+	assert.Empty(t, launcher.pendingDrains)
+}
+
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
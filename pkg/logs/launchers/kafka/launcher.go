@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package kafka implements the launcher of the kafka sources.
+package kafka
+
+import (
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/logs/tailers"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/kafka"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+)
+
+// Launcher starts a tailer for each kafka source, and stops it when the
+// source is removed.
+type Launcher struct {
+	pipelineProvider pipeline.Provider
+	registry         auditor.Registry
+	consumerFactory  tailer.ConsumerFactory
+	addedSources     chan *sources.LogSource
+	removedSources   chan *sources.LogSource
+	tailers          map[*sources.LogSource]*tailer.Tailer
+	stop             chan struct{}
+	done             chan struct{}
+}
+
+// NewLauncher returns a new Launcher.
+func NewLauncher() *Launcher {
+	return NewLauncherWithFactory(tailer.NewConsumer)
+}
+
+// NewLauncherWithFactory returns a new Launcher creating the consumers of
+// the tailers with consumerFactory.
+func NewLauncherWithFactory(consumerFactory tailer.ConsumerFactory) *Launcher {
+	return &Launcher{
+		consumerFactory: consumerFactory,
+		tailers:         make(map[*sources.LogSource]*tailer.Tailer),
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Start starts the launcher.
+func (l *Launcher) Start(sourceProvider launchers.SourceProvider, pipelineProvider pipeline.Provider, registry auditor.Registry, _ *tailers.TailerTracker) {
+	l.pipelineProvider = pipelineProvider
+	l.registry = registry
+	l.addedSources, l.removedSources = sourceProvider.SubscribeForType(config.KafkaType)
+	go l.run()
+}
+
+// Stop stops the launcher and all its tailers.
+func (l *Launcher) Stop() {
+	close(l.stop)
+	<-l.done
+	stopper := startstop.NewParallelStopper()
+	for source, tailer := range l.tailers {
+		stopper.Add(tailer)
+		delete(l.tailers, source)
+	}
+	stopper.Stop()
+}
+
+func (l *Launcher) run() {
+	defer close(l.done)
+	for {
+		select {
+		case source := <-l.addedSources:
+			l.startTailer(source)
+		case source := <-l.removedSources:
+			l.stopTailer(source)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+func (l *Launcher) startTailer(source *sources.LogSource) {
+	if _, exists := l.tailers[source]; exists {
+		return
+	}
+	tailer := tailer.NewTailer(source, l.pipelineProvider.NextPipelineChan(), l.registry, l.consumerFactory)
+	if err := tailer.Start(); err != nil {
+		log.Warnf("Could not consume kafka topics %v: %v", source.Config.Topics, err)
+		return
+	}
+	l.tailers[source] = tailer
+}
+
+func (l *Launcher) stopTailer(source *sources.LogSource) {
+	if tailer, exists := l.tailers[source]; exists {
+		tailer.Stop()
+		delete(l.tailers, source)
+	}
+}
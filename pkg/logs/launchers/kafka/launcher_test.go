@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/logs/tailers"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/kafka"
+)
+
+// idleConsumer never returns any record.
+type idleConsumer struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *idleConsumer) Poll(ctx context.Context) ([]*kgo.Record, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *idleConsumer) Commit(context.Context, map[string]map[int32]int64) error { return nil }
+
+func (c *idleConsumer) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+}
+
+func (c *idleConsumer) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func TestLauncherStartsAndStopsTailers(t *testing.T) {
+	consumers := make(chan *idleConsumer, 2)
+	launcher := NewLauncherWithFactory(func(*config.LogsConfig, func(context.Context, map[string][]int32)) (tailer.Consumer, error) {
+		consumer := &idleConsumer{}
+		consumers <- consumer
+		return consumer, nil
+	})
+	logSources := sources.NewLogSources()
+	launcher.Start(logSources, pipeline.NewMockProvider(), auditor.NewRegistry(), tailers.NewTailerTracker())
+
+	first := sources.NewLogSource("first", &config.LogsConfig{Type: config.KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"a"}, ConsumerGroup: "datadog"})
+	second := sources.NewLogSource("second", &config.LogsConfig{Type: config.KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"b"}, ConsumerGroup: "datadog"})
+	logSources.AddSource(first)
+	logSources.AddSource(second)
+	firstConsumer, secondConsumer := <-consumers, <-consumers
+
+	logSources.RemoveSource(first)
+	assert.Eventually(t, firstConsumer.isClosed, time.Second, 10*time.Millisecond)
+	assert.False(t, secondConsumer.isClosed())
+
+	launcher.Stop()
+	assert.True(t, secondConsumer.isClosed())
+	assert.Empty(t, launcher.tailers)
+}
+
+func TestLauncherReportsConsumerErrors(t *testing.T) {
+	launcher := NewLauncherWithFactory(func(*config.LogsConfig, func(context.Context, map[string][]int32)) (tailer.Consumer, error) {
+		return nil, errors.New("no brokers")
+	})
+	logSources := sources.NewLogSources()
+	launcher.Start(logSources, pipeline.NewMockProvider(), auditor.NewRegistry(), tailers.NewTailerTracker())
+	defer launcher.Stop()
+
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.KafkaType, Brokers: []string{"localhost:9092"}, Topics: []string{"a"}, ConsumerGroup: "datadog"})
+	logSources.AddSource(source)
+	assert.Eventually(t, source.Status.IsError, time.Second, 10*time.Millisecond)
+}
@@ -6,6 +6,8 @@
 package listener
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"net"
 	"sync"
@@ -15,6 +17,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
 	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/socket"
 	"github.com/DataDog/datadog-agent/pkg/util/startstop"
 )
@@ -29,8 +32,17 @@ type TCPListener struct {
 	tailers          []*tailer.Tailer
 	mu               sync.Mutex
 	stop             chan struct{}
+
+	// TLS handshake failures, reported on the status page
+	tlsHandshakeFailures  *status.CountInfo
+	tlsLastHandshakeError *status.MappedInfo
 }
 
+// tlsHandshakeTimeout is the maximum duration of a TLS handshake.
+const tlsHandshakeTimeout = 10 * time.Second
+
+const lastHandshakeErrorKey = "last"
+
 // NewTCPListener returns an initialized TCPListener
 func NewTCPListener(pipelineProvider pipeline.Provider, source *sources.LogSource, frameSize int) *TCPListener {
 	var idleTimeout time.Duration
@@ -43,14 +55,21 @@ func NewTCPListener(pipelineProvider pipeline.Provider, source *sources.LogSourc
 		}
 	}
 
-	return &TCPListener{
-		pipelineProvider: pipelineProvider,
-		source:           source,
-		idleTimeout:      idleTimeout,
-		frameSize:        frameSize,
-		tailers:          []*tailer.Tailer{},
-		stop:             make(chan struct{}, 1),
+	listener := &TCPListener{
+		pipelineProvider:      pipelineProvider,
+		source:                source,
+		idleTimeout:           idleTimeout,
+		frameSize:             frameSize,
+		tailers:               []*tailer.Tailer{},
+		stop:                  make(chan struct{}, 1),
+		tlsHandshakeFailures:  status.NewCountInfo("TLS Handshake Failures"),
+		tlsLastHandshakeError: status.NewMappedInfo("TLS Last Handshake Failure"),
+	}
+	if source.Config.TLS != nil {
+		source.RegisterInfo(listener.tlsHandshakeFailures)
+		source.RegisterInfo(listener.tlsLastHandshakeError)
 	}
+	return listener
 }
 
 // Start starts the listener to accepts new incoming connections.
@@ -120,10 +139,22 @@ func (l *TCPListener) run() {
 
 // startListener starts a new listener, returns an error if it failed.
 func (l *TCPListener) startListener() error {
+	var tlsConfig *tls.Config
+	if l.source.Config.TLS != nil {
+		var err error
+		tlsConfig, err = buildTLSConfig(l.source.Config.TLS)
+		if err != nil {
+			return err
+		}
+	}
+
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", l.source.Config.Port))
 	if err != nil {
 		return err
 	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
 	l.listener = listener
 	return nil
 }
@@ -133,6 +164,12 @@ func (l *TCPListener) read(tailer *tailer.Tailer) ([]byte, string, error) {
 	if l.idleTimeout > 0 {
 		tailer.Conn.SetReadDeadline(time.Now().Add(l.idleTimeout)) //nolint:errcheck
 	}
+	if tlsConn, ok := tailer.Conn.(*tls.Conn); ok && !tlsConn.ConnectionState().HandshakeComplete {
+		if err := l.handshake(tlsConn); err != nil {
+			go l.stopTailer(tailer)
+			return nil, "", err
+		}
+	}
 	frame := make([]byte, l.frameSize)
 	n, err := tailer.Conn.Read(frame)
 	if err != nil {
@@ -143,6 +180,21 @@ func (l *TCPListener) read(tailer *tailer.Tailer) ([]byte, string, error) {
 	return frame[:n], tailer.Conn.RemoteAddr().String(), nil
 }
 
+// handshake runs the TLS handshake of a new connection, failures are
+// reported on the status page of the source rather than as a source error
+// since they are caused by a single client.
+func (l *TCPListener) handshake(conn *tls.Conn) error {
+	ctx, cancel := context.WithTimeout(context.Background(), tlsHandshakeTimeout)
+	defer cancel()
+	err := conn.HandshakeContext(ctx)
+	if err != nil {
+		log.Warnf("TLS handshake failed on port %d with %s: %v", l.source.Config.Port, conn.RemoteAddr(), err)
+		l.tlsHandshakeFailures.Add(1)
+		l.tlsLastHandshakeError.SetMessage(lastHandshakeErrorKey, fmt.Sprintf("%s: %v", conn.RemoteAddr(), err))
+	}
+	return err
+}
+
 // startTailer creates and starts a new tailer that reads from the connection.
 func (l *TCPListener) startTailer(conn net.Conn) {
 	l.mu.Lock()
@@ -10,6 +10,7 @@ import (
 	"net"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -64,3 +65,30 @@ func TestTCPDoesNotTruncateMessagesThatAreBiggerThanTheReadBufferSize(t *testing
 
 	listener.Stop()
 }
+
+func TestTCPSyslogShouldParseOctetCountedMessages(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Protocol: config.TCPType, Port: tcpTestPort}), 9000)
+	listener.Start()
+	conn, err := net.Dial("tcp", listener.listener.Addr().String())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	first := "<165>1 2003-10-11T22:14:15.003Z host app 42 - - multi\nline event"
+	second := "<11>Oct 11 22:14:15 router sshd[7]: bad login"
+	fmt.Fprintf(conn, "%d %s%d %s", len(first), first, len(second), second)
+
+	msg := <-msgChan
+	assert.Equal(t, "multi\nline event", string(msg.GetContent()))
+	assert.Equal(t, "notice", msg.Status)
+	assert.Equal(t, "2003-10-11T22:14:15.003Z", msg.LogTimestamp.Format(time.RFC3339Nano))
+	assert.Subset(t, msg.Tags(), []string{"syslog_facility:local4", "syslog_hostname:host", "syslog_appname:app", "syslog_procid:42"})
+
+	msg = <-msgChan
+	assert.Equal(t, "bad login", string(msg.GetContent()))
+	assert.Equal(t, "error", msg.Status)
+	assert.Subset(t, msg.Tags(), []string{"syslog_facility:user", "syslog_hostname:router", "syslog_appname:sshd", "syslog_procid:7"})
+
+	listener.Stop()
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// testCertificates contains a certificate authority and the server and
+// client certificates it signed, written in a temporary directory.
+type testCertificates struct {
+	caFile     string
+	serverCert string
+	serverKey  string
+	clientCert tls.Certificate
+	caPool     *x509.CertPool
+}
+
+func generateTestCertificates(t *testing.T) testCertificates {
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "localhost"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1"), net.IPv6loopback},
+			DNSNames:     []string{"localhost"},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return der, key
+	}
+
+	writePEM := func(name, blockType string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0600))
+		return path
+	}
+
+	serverDER, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	serverKeyDER, err := x509.MarshalECPrivateKey(serverKey)
+	require.NoError(t, err)
+
+	clientDER, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	return testCertificates{
+		caFile:     writePEM("ca.pem", "CERTIFICATE", caDER),
+		serverCert: writePEM("server.pem", "CERTIFICATE", serverDER),
+		serverKey:  writePEM("server.key", "EC PRIVATE KEY", serverKeyDER),
+		clientCert: tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey},
+		caPool:     pool,
+	}
+}
+
+func TestTCPTLSShouldReceiveMessages(t *testing.T) {
+	certs := generateTestCertificates(t)
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewTCPListener(pp, sources.NewLogSource("", &config.LogsConfig{
+		Port: tcpTestPort,
+		TLS:  &config.TLSConfig{CertFile: certs.serverCert, KeyFile: certs.serverKey},
+	}), 9000)
+	listener.Start()
+	defer listener.Stop()
+
+	conn, err := tls.Dial("tcp", listener.listener.Addr().String(), &tls.Config{RootCAs: certs.caPool, ServerName: "localhost"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	fmt.Fprint(conn, "hello over tls\n")
+	msg := <-msgChan
+	assert.Equal(t, "hello over tls", string(msg.GetContent()))
+}
+
+func TestTCPMutualTLS(t *testing.T) {
+	certs := generateTestCertificates(t)
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	source := sources.NewLogSource("", &config.LogsConfig{
+		Port: tcpTestPort,
+		TLS:  &config.TLSConfig{CertFile: certs.serverCert, KeyFile: certs.serverKey, CAFile: certs.caFile},
+	})
+	listener := NewTCPListener(pp, source, 9000)
+	listener.Start()
+	defer listener.Stop()
+	addr := listener.listener.Addr().String()
+
+	// a client presenting a certificate signed by the authority is accepted
+	conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: certs.caPool, ServerName: "localhost", Certificates: []tls.Certificate{certs.clientCert}})
+	require.NoError(t, err)
+	defer conn.Close()
+	fmt.Fprint(conn, "authenticated\n")
+	msg := <-msgChan
+	assert.Equal(t, "authenticated", string(msg.GetContent()))
+
+	// a client without certificate is rejected and the failure is reported
+	anonymous, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: certs.caPool, ServerName: "localhost"})
+	if err == nil {
+		// with TLS 1.3 the client learns about the rejection on its first read
+		defer anonymous.Close()
+		fmt.Fprint(anonymous, "anonymous\n")
+		_, err = anonymous.Read(make([]byte, 1))
+	}
+	assert.Error(t, err)
+
+	assert.Eventually(t, func() bool { return listener.tlsHandshakeFailures.Get() == 1 }, 5*time.Second, 10*time.Millisecond)
+	info := source.GetInfo("TLS Last Handshake Failure").Info()
+	require.Len(t, info, 1)
+	assert.Contains(t, info[0], "certificate")
+	assert.True(t, source.Status.IsSuccess())
+	assert.Empty(t, msgChan)
+}
+
+func TestTCPTLSInvalidCertificate(t *testing.T) {
+	pp := mock.NewMockProvider()
+	source := sources.NewLogSource("", &config.LogsConfig{
+		Port: tcpTestPort,
+		TLS:  &config.TLSConfig{CertFile: "/does/not/exist.pem", KeyFile: "/does/not/exist.key"},
+	})
+	listener := NewTCPListener(pp, source, 9000)
+	listener.Start()
+	assert.True(t, source.Status.IsError())
+	assert.Contains(t, source.Status.GetError(), "TLS certificate")
+}
@@ -46,3 +46,27 @@ func TestUDPShouldStopWhenNotStarted(_ *testing.T) {
 	// Don't call start, this is similar to if `startNewTailer` fails when start is called (such as if the port is already in use)
 	listener.Stop()
 }
+
+func TestUDPSyslogShouldParseMessage(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	listener := NewUDPListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.SyslogType, Port: udpTestPort}), 9000)
+	listener.Start()
+
+	conn, err := net.Dial("udp", listener.tailer.Conn.LocalAddr().String())
+	assert.Nil(t, err)
+
+	fmt.Fprint(conn, `<14>1 2003-10-11T22:14:15Z host app - ID1 [meta sequenceId="1"] hello world`)
+	msg := <-msgChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Subset(t, msg.Tags(), []string{"syslog_hostname:host", "syslog_appname:app", "syslog_msgid:ID1"})
+	assert.Equal(t, map[string]string{"meta.sequenceId": "1"}, msg.ProcessingAttributes)
+
+	// datagrams are not octet-counted
+	fmt.Fprint(conn, "11 hello world")
+	msg = <-msgChan
+	assert.Equal(t, "11 hello world", string(msg.GetContent()))
+
+	listener.Stop()
+}
@@ -71,6 +71,12 @@ type Message struct {
 	RawDataLen int
 	// Tags added on processing
 	ProcessingTags []string
+	// Attributes extracted by parsers and on processing, sent along the
+	// message by encoders supporting structured attributes.
+	ProcessingAttributes map[string]string
+	// Timestamp of the log, e.g. parsed from its content. Optional, must be UTC.
+	// The encoders use the current time when it isn't set.
+	LogTimestamp time.Time
 	// Extra information from the parsers
 	ParsingExtra
 	// Extra information for Serverless Logs messages
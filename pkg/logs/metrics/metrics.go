@@ -25,6 +25,16 @@ var (
 	// TlmLogsProcessed is the total number of processed logs.
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
+	// LogsCollapsed is the total number of logs collapsed by deduplication rules.
+	LogsCollapsed = expvar.Int{}
+	// TlmLogsCollapsed is the total number of logs collapsed by deduplication rules.
+	TlmLogsCollapsed = telemetry.NewCounter("logs", "collapsed",
+		nil, "Total number of logs collapsed by deduplication rules")
+	// LogsSampledOut is the total number of logs dropped by sampling rules.
+	LogsSampledOut = expvar.Int{}
+	// TlmLogsSampledOut is the total number of logs dropped by sampling rules.
+	TlmLogsSampledOut = telemetry.NewCounter("logs", "sampled_out",
+		nil, "Total number of logs dropped by sampling rules")
 
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
@@ -68,6 +78,18 @@ var (
 	// TlmSenderLatency a histogram of http sender latency (ms)
 	TlmSenderLatency = telemetry.NewHistogram("logs", "sender_latency",
 		nil, "Histogram of http sender latency in ms", []float64{10, 25, 50, 75, 100, 250, 500, 1000, 10000})
+	// DiskBufferExpVars contains the number of payloads and bytes held by the
+	// logs senders on-disk buffers, and the number of payloads they dropped.
+	DiskBufferExpVars = expvar.Map{}
+	// TlmDiskBufferPayloads is the number of payloads held by the on-disk buffers
+	TlmDiskBufferPayloads = telemetry.NewGauge("logs_sender_disk_buffer", "payloads",
+		nil, "Number of payloads held by the on-disk buffers")
+	// TlmDiskBufferBytes is the number of bytes held by the on-disk buffers
+	TlmDiskBufferBytes = telemetry.NewGauge("logs_sender_disk_buffer", "bytes",
+		nil, "Number of bytes held by the on-disk buffers")
+	// TlmDiskBufferDropped is the number of payloads dropped by the on-disk buffers
+	TlmDiskBufferDropped = telemetry.NewCounter("logs_sender_disk_buffer", "dropped",
+		[]string{"reason"}, "Number of payloads dropped by the on-disk buffers")
 	// DestinationExpVars a map of sender utilization metrics for each http destination
 	DestinationExpVars = expvar.Map{}
 	// TODO: Add LogsCollected for the total number of collected logs.
@@ -82,6 +104,11 @@ var (
 	// TlmLogsDiscardedFromSDSBuffer how many messages were dropped when waiting for an SDS configuration because the buffer is full
 	TlmLogsDiscardedFromSDSBuffer = telemetry.NewCounter("logs", "sds__dropped_from_buffer", nil, "Count of messages dropped from the buffer while waiting for an SDS configuration")
 
+	// TlmLogsToMetricsSamples is the number of metric samples generated from logs, by metric name
+	TlmLogsToMetricsSamples = telemetry.NewCounter("logs", "logs_to_metrics_samples", []string{"metric_name"}, "Count of metric samples generated from logs")
+	// TlmLogsToMetricsErrors is the number of logs from which a metric couldn't be generated, by metric name
+	TlmLogsToMetricsErrors = telemetry.NewCounter("logs", "logs_to_metrics_errors", []string{"metric_name"}, "Count of logs with an invalid metric value")
+
 	// TlmUtilizationRatio is the utilization ratio of a component.
 	// Utilization ratio is calculated as the ratio of time spent in use to the total time.
 	// This metric is internally sampled and exposed as an ewma in order to produce a useable value.
@@ -97,6 +124,8 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsCollapsed", &LogsCollapsed)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
@@ -107,4 +136,5 @@ func init() {
 	LogsExpvars.Set("BytesMissed", &BytesMissed)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
+	LogsExpvars.Set("DiskBuffer", &DiskBufferExpVars)
 }
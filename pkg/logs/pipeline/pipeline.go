@@ -8,8 +8,10 @@ package pipeline
 
 import (
 	"context"
+	"path/filepath"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -26,6 +28,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/sender"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	compressioncommon "github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Pipeline processes and sends messages to the backend
@@ -52,6 +55,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) *Pipeline {
 
 	var senderDoneChan chan *sync.WaitGroup
@@ -83,11 +87,18 @@ func NewPipeline(outputChan chan *message.Payload,
 
 	strategy := getStrategy(strategyInput, senderInput, flushChan, endpoints, serverless, flushWg, pipelineMonitor, compression)
 	logsSender = sender.NewSender(cfg, senderInput, outputChan, mainDestinations, pkgconfigsetup.Datadog().GetInt("logs_config.payload_channel_size"), senderDoneChan, flushWg, pipelineMonitor)
+	if !serverless && pkgconfigsetup.Datadog().GetBool("logs_config.disk_buffer.enabled") {
+		if spool, err := newDiskSpool(pkgconfigsetup.Datadog(), pipelineID); err != nil {
+			log.Warnf("Could not create the logs disk buffer of pipeline %d, payloads won't be buffered on disk: %v", pipelineID, err)
+		} else {
+			logsSender.SetSpool(spool)
+		}
+	}
 
 	inputChan := make(chan *message.Message, pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size"))
 
 	processor := processor.New(cfg, inputChan, strategyInput, processingRules,
-		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor)
+		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor, metricSender)
 
 	return &Pipeline{
 		InputChan:       inputChan,
@@ -101,6 +112,19 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 }
 
+// newDiskSpool returns the spool of the pipeline, each pipeline having its own
+// directory in the disk buffer.
+func newDiskSpool(cfg pkgconfigmodel.Reader, pipelineID int) (sender.Spool, error) {
+	path := cfg.GetString("logs_config.disk_buffer.path")
+	if path == "" {
+		path = filepath.Join(cfg.GetString("logs_config.run_path"), "disk_buffer")
+	}
+	// the buffer is shared between the pipelines
+	maxSize := cfg.GetInt64("logs_config.disk_buffer.max_size_bytes") / int64(max(cfg.GetInt("logs_config.pipelines"), 1))
+	maxAge := time.Duration(cfg.GetInt("logs_config.disk_buffer.max_age")) * time.Second
+	return sender.NewDiskSpool(filepath.Join(path, strconv.Itoa(pipelineID)), maxSize, maxAge)
+}
+
 // Start launches the pipeline
 func (p *Pipeline) Start() {
 	p.sender.Start()
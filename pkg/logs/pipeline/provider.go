@@ -20,6 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -55,10 +56,11 @@ type provider struct {
 
 	serverless bool
 
-	status      statusinterface.Status
-	hostname    hostnameinterface.Component
-	cfg         pkgconfigmodel.Reader
-	compression logscompression.Component
+	status       statusinterface.Status
+	hostname     hostnameinterface.Component
+	cfg          pkgconfigmodel.Reader
+	compression  logscompression.Component
+	metricSender processor.MetricSender
 }
 
 // NewProvider returns a new Provider
@@ -72,8 +74,9 @@ func NewProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, compression)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, compression, metricSender)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
@@ -87,9 +90,10 @@ func NewServerlessProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
 
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, compression)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, compression, metricSender)
 }
 
 // NewMockProvider creates a new provider that will not provide any pipelines.
@@ -108,6 +112,7 @@ func newProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
@@ -123,6 +128,7 @@ func newProvider(numberOfPipelines int,
 		hostname:                  hostname,
 		cfg:                       cfg,
 		compression:               compression,
+		metricSender:              metricSender,
 	}
 }
 
@@ -132,7 +138,7 @@ func (p *provider) Start() {
 	p.outputChan = p.auditor.Channel()
 
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.compression)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.compression, p.metricSender)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// RepeatCountTagName is the name of the tag carrying the number of identical
+// logs collapsed into a single one by a deduplication rule.
+const RepeatCountTagName = "repeat_count"
+
+// deduplicationFlushInterval is how often the processor releases the
+// messages for which the deduplication window expired.
+const deduplicationFlushInterval = time.Second
+
+// deduplicator collapses consecutive identical messages of an origin, e.g. a
+// file, matching a deduplication rule.
+// The first message of a series is held until either the window of the rule
+// expires, or a different message is received from the same origin.
+// It is then released carrying the number of messages it represents.
+type deduplicator struct {
+	// pending is keyed by the identifier of the origin of the messages, so
+	// that the files of a wildcard source are deduplicated separately and the
+	// offset of each of them is committed.
+	pending map[string]*pendingDuplicate
+}
+
+type pendingDuplicate struct {
+	msg       *message.Message
+	content   []byte
+	count     int
+	expiresAt time.Time
+}
+
+func newDeduplicator() *deduplicator {
+	return &deduplicator{
+		pending: make(map[string]*pendingDuplicate),
+	}
+}
+
+// process handles a message about to be sent, rule is the deduplication rule
+// matching this message, nil if none.
+// It returns the message previously held for the same origin if it must be
+// released, and whether the given message is now held by the deduplicator.
+func (d *deduplicator) process(msg *message.Message, rule *config.ProcessingRule, now time.Time) (released *message.Message, held bool) {
+	origin := messageOrigin(msg)
+	content := msg.GetContent()
+
+	if pending, exists := d.pending[origin]; exists {
+		if rule != nil && now.Before(pending.expiresAt) && bytes.Equal(pending.content, content) {
+			pending.collapse(msg)
+			return nil, true
+		}
+		delete(d.pending, origin)
+		released = pending.release()
+	}
+
+	if rule == nil {
+		return released, false
+	}
+
+	d.pending[origin] = &pendingDuplicate{
+		msg:       msg,
+		content:   append([]byte(nil), content...),
+		count:     1,
+		expiresAt: now.Add(time.Duration(rule.Window) * time.Second),
+	}
+	return released, true
+}
+
+// releaseExpired returns the held messages for which the window expired.
+func (d *deduplicator) releaseExpired(now time.Time) []*message.Message {
+	var released []*message.Message
+	for origin, pending := range d.pending {
+		if !now.Before(pending.expiresAt) {
+			delete(d.pending, origin)
+			released = append(released, pending.release())
+		}
+	}
+	return released
+}
+
+// releaseAll returns all the held messages.
+func (d *deduplicator) releaseAll() []*message.Message {
+	var released []*message.Message
+	for origin, pending := range d.pending {
+		delete(d.pending, origin)
+		released = append(released, pending.release())
+	}
+	return released
+}
+
+// collapse accounts for a duplicate of the held message. The held message
+// takes over the offset of the duplicate so that the auditor doesn't make the
+// tailer read the duplicate again after a restart.
+func (p *pendingDuplicate) collapse(duplicate *message.Message) {
+	p.count++
+	if p.msg.Origin != nil && duplicate.Origin != nil {
+		p.msg.Origin.Offset = duplicate.Origin.Offset
+	}
+	metrics.LogsCollapsed.Add(1)
+	metrics.TlmLogsCollapsed.Inc()
+}
+
+func (p *pendingDuplicate) release() *message.Message {
+	if p.count > 1 {
+		p.msg.ProcessingTags = append(p.msg.ProcessingTags, RepeatCountTagName+":"+strconv.Itoa(p.count))
+	}
+	return p.msg
+}
+
+// messageOrigin returns the identifier of the origin of the message, empty if
+// it has none.
+func messageOrigin(msg *message.Message) string {
+	if msg.Origin == nil {
+		return ""
+	}
+	return msg.Origin.Identifier
+}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func newDeduplicationRule(window int) *config.ProcessingRule {
+	rule := newProcessingRule(config.Deduplicate, "", "error")
+	rule.Window = window
+	return rule
+}
+
+// newFileMessage returns a message read from the given file of the source.
+func newFileMessage(content string, source *sources.LogSource, path string) *message.Message {
+	msg := newMessage([]byte(content), source, "")
+	msg.Origin.Identifier = "file:" + path
+	return msg
+}
+
+func TestDeduplicatorCollapsesIdenticalMessages(t *testing.T) {
+	rule := newDeduplicationRule(10)
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	d := newDeduplicator()
+	now := time.Now()
+
+	first := newMessage([]byte("error: boom"), source, "")
+	first.Origin.Offset = "1"
+	released, held := d.process(first, rule, now)
+	assert.Nil(t, released)
+	assert.True(t, held)
+
+	for i, offset := range []string{"2", "3"} {
+		msg := newMessage([]byte("error: boom"), source, "")
+		msg.Origin.Offset = offset
+		released, held = d.process(msg, rule, now.Add(time.Duration(i+1)*time.Second))
+		assert.Nil(t, released)
+		assert.True(t, held)
+	}
+
+	// a different message releases the held one
+	other := newMessage([]byte("error: something else"), source, "")
+	released, held = d.process(other, rule, now.Add(4*time.Second))
+	assert.True(t, held)
+	assert.Equal(t, first, released)
+	assert.Equal(t, []string{"repeat_count:3"}, released.ProcessingTags)
+	assert.Equal(t, "3", released.Origin.Offset)
+
+	// the other message is released untagged once its window expired
+	assert.Empty(t, d.releaseExpired(now.Add(5*time.Second)))
+	expired := d.releaseExpired(now.Add(14 * time.Second))
+	assert.Equal(t, []*message.Message{other}, expired)
+	assert.Empty(t, other.ProcessingTags)
+	assert.Empty(t, d.pending)
+}
+
+func TestDeduplicatorWindowExpiration(t *testing.T) {
+	rule := newDeduplicationRule(2)
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	d := newDeduplicator()
+	now := time.Now()
+
+	first := newMessage([]byte("error: boom"), source, "")
+	d.process(first, rule, now)
+
+	// identical content after the window expiration starts a new series
+	second := newMessage([]byte("error: boom"), source, "")
+	released, held := d.process(second, rule, now.Add(3*time.Second))
+	assert.Equal(t, first, released)
+	assert.True(t, held)
+	assert.Empty(t, first.ProcessingTags)
+
+	assert.Equal(t, []*message.Message{second}, d.releaseAll())
+}
+
+func TestDeduplicatorReleasesOnNonMatchingMessage(t *testing.T) {
+	rule := newDeduplicationRule(10)
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	d := newDeduplicator()
+	now := time.Now()
+
+	first := newFileMessage("error: boom", source, "/var/log/a.log")
+	d.process(first, rule, now)
+
+	// messages from other files don't release the held message
+	released, held := d.process(newFileMessage("info", source, "/var/log/b.log"), nil, now)
+	assert.Nil(t, released)
+	assert.False(t, held)
+
+	// a message from the same file not matching any rule keeps the order
+	released, held = d.process(newFileMessage("info", source, "/var/log/a.log"), nil, now)
+	assert.Equal(t, first, released)
+	assert.False(t, held)
+	assert.Empty(t, d.pending)
+}
+
+func TestDeduplicatorKeysByOrigin(t *testing.T) {
+	rule := newDeduplicationRule(10)
+	// a wildcard source tailing two files
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	d := newDeduplicator()
+	now := time.Now()
+
+	a := newFileMessage("error: boom", source, "/var/log/a.log")
+	a.Origin.Offset = "10"
+	d.process(a, rule, now)
+	b := newFileMessage("error: boom", source, "/var/log/b.log")
+	b.Origin.Offset = "20"
+	released, held := d.process(b, rule, now)
+	// identical lines of different files aren't collapsed together
+	assert.Nil(t, released)
+	assert.True(t, held)
+
+	releasedAll := d.releaseAll()
+	assert.ElementsMatch(t, []*message.Message{a, b}, releasedAll)
+	assert.Equal(t, "10", a.Origin.Offset)
+	assert.Equal(t, "20", b.Origin.Offset)
+	assert.Empty(t, a.ProcessingTags)
+	assert.Empty(t, b.ProcessingTags)
+}
+
+func TestProcessorDeduplication(t *testing.T) {
+	collapsed := metrics.LogsCollapsed.Value()
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{newDeduplicationRule(10)}})
+	pm := metrics.NewNoopPipelineMonitor("")
+	p := &Processor{
+		encoder:                   JSONEncoder,
+		inputChan:                 make(chan *message.Message, 10),
+		outputChan:                make(chan *message.Message, 10),
+		diagnosticMessageReceiver: diagnostic.NewBufferedMessageReceiver(nil, nil),
+		pipelineMonitor:           pm,
+		utilization:               pm.MakeUtilizationMonitor("processor"),
+	}
+
+	for _, content := range []string{"error: boom", "error: boom", "error: boom", "info: ok"} {
+		p.inputChan <- newMessage([]byte(content), source, "")
+	}
+	p.Flush(context.Background())
+
+	assert.Len(t, p.outputChan, 2)
+	collapsedMsg := <-p.outputChan
+	assert.Equal(t, []string{"repeat_count:3"}, collapsedMsg.ProcessingTags)
+	assert.Equal(t, message.StateEncoded, collapsedMsg.State)
+	assert.Empty(t, (<-p.outputChan).ProcessingTags)
+	assert.Equal(t, collapsed+2, metrics.LogsCollapsed.Value())
+}
@@ -8,6 +8,7 @@ package processor
 
 import (
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -18,6 +19,18 @@ type Encoder interface {
 	Encode(msg *message.Message, hostname string) error
 }
 
+// messageTimestamp returns the timestamp of the log, or the current time
+// when it's unknown.
+func messageTimestamp(msg *message.Message) time.Time {
+	if !msg.LogTimestamp.IsZero() {
+		return msg.LogTimestamp
+	}
+	if !msg.ServerlessExtra.Timestamp.IsZero() {
+		return msg.ServerlessExtra.Timestamp
+	}
+	return time.Now().UTC()
+}
+
 // toValidUtf8 ensures all characters are UTF-8.
 func toValidUtf8(msg []byte) string {
 	if utf8.Valid(msg) {
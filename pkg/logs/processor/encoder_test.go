@@ -222,6 +222,53 @@ func TestJsonEncoder(t *testing.T) {
 	assert.NotEmpty(t, log.Timestamp)
 }
 
+func TestJsonEncoderWithAttributes(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{Service: "Service", Source: "Source"})
+
+	msg := newMessage([]byte("message"), source, message.StatusInfo)
+	msg.State = message.StateRendered
+	msg.ProcessingAttributes = map[string]string{
+		"http.method": "GET",
+		"service":     "overridden",
+	}
+
+	err := JSONEncoder.Encode(msg, "unknown")
+	assert.Nil(t, err)
+
+	fields := make(map[string]interface{})
+	err = json.Unmarshal(msg.GetContent(), &fields)
+	assert.Nil(t, err)
+
+	assert.Equal(t, "message", fields["message"])
+	assert.Equal(t, "GET", fields["http.method"])
+	// reserved fields can't be overridden by attributes
+	assert.Equal(t, "Service", fields["service"])
+	assert.Equal(t, "Source", fields["ddsource"])
+	assert.Equal(t, message.StatusInfo, fields["status"])
+	assert.NotEmpty(t, fields["timestamp"])
+}
+
+func TestEncodersUseLogTimestamp(t *testing.T) {
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	ts := time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC)
+
+	msg := newMessage([]byte("message"), source, message.StatusInfo)
+	msg.State = message.StateRendered
+	msg.LogTimestamp = ts
+	assert.Nil(t, JSONEncoder.Encode(msg, "unknown"))
+	jsonLog := &jsonPayload{}
+	assert.Nil(t, json.Unmarshal(msg.GetContent(), jsonLog))
+	assert.Equal(t, ts.UnixMilli(), jsonLog.Timestamp)
+
+	msg = newMessage([]byte("message"), source, message.StatusInfo)
+	msg.State = message.StateRendered
+	msg.LogTimestamp = ts
+	assert.Nil(t, ProtoEncoder.Encode(msg, "unknown"))
+	protoLog := &pb.Log{}
+	assert.Nil(t, protoLog.Unmarshal(msg.GetContent()))
+	assert.Equal(t, ts.UnixNano(), protoLog.Timestamp)
+}
+
 func TestEncoderToValidUTF8(t *testing.T) {
 	// valid utf-8
 	assert.Equal(t, "", toValidUtf8(nil))
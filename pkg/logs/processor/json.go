@@ -8,7 +8,6 @@ package processor
 import (
 	"encoding/json"
 	"fmt"
-	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
@@ -41,12 +40,9 @@ func (j *jsonEncoder) Encode(msg *message.Message, hostname string) error {
 		return fmt.Errorf("message passed to encoder isn't rendered")
 	}
 
-	ts := time.Now().UTC()
-	if !msg.ServerlessExtra.Timestamp.IsZero() {
-		ts = msg.ServerlessExtra.Timestamp
-	}
+	ts := messageTimestamp(msg)
 
-	encoded, err := json.Marshal(jsonPayload{
+	payload := jsonPayload{
 		Message:   toValidUtf8(msg.GetContent()),
 		Status:    msg.GetStatus(),
 		Timestamp: ts.UnixNano() / nanoToMillis,
@@ -54,7 +50,15 @@ func (j *jsonEncoder) Encode(msg *message.Message, hostname string) error {
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
 		Tags:      msg.TagsToString(),
-	})
+	}
+
+	var encoded []byte
+	var err error
+	if len(msg.ProcessingAttributes) > 0 {
+		encoded, err = json.Marshal(payload.withAttributes(msg.ProcessingAttributes))
+	} else {
+		encoded, err = json.Marshal(payload)
+	}
 
 	if err != nil {
 		return fmt.Errorf("can't encode the message: %v", err)
@@ -63,3 +67,20 @@ func (j *jsonEncoder) Encode(msg *message.Message, hostname string) error {
 	msg.SetEncoded(encoded)
 	return nil
 }
+
+// withAttributes returns the payload as a map also containing the given
+// attributes. Attributes can't override the reserved payload fields.
+func (p jsonPayload) withAttributes(attributes map[string]string) map[string]interface{} {
+	fields := make(map[string]interface{}, len(attributes)+7)
+	for name, value := range attributes {
+		fields[name] = value
+	}
+	fields["message"] = p.Message
+	fields["status"] = p.Status
+	fields["timestamp"] = p.Timestamp
+	fields["hostname"] = p.Hostname
+	fields["service"] = p.Service
+	fields["ddsource"] = p.Source
+	fields["ddtags"] = p.Tags
+	return fields
+}
@@ -0,0 +1,202 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// jsonFields is the content of a log parsed as a JSON object, to which field
+// rules are applied. Content which isn't a JSON object is left untouched by
+// the field rules.
+type jsonFields struct {
+	fields   map[string]interface{}
+	parsed   bool
+	modified bool
+}
+
+// apply applies the field rule to the content, parsing it first if needed.
+func (j *jsonFields) apply(rule *config.ProcessingRule, content []byte, msg *message.Message) {
+	if !j.parsed {
+		j.parse(content)
+	}
+	if j.fields == nil {
+		return
+	}
+
+	switch rule.Type {
+	case config.DropField:
+		if _, ok := removeField(j.fields, rule.FieldPath); ok {
+			j.modified = true
+		}
+	case config.RenameField:
+		if value, ok := removeField(j.fields, rule.FieldPath); ok {
+			setField(j.fields, rule.TargetPath, value)
+			j.modified = true
+		}
+	case config.HashField:
+		if value, ok := getField(j.fields, rule.FieldPath); ok && value != nil {
+			setField(j.fields, rule.FieldPath, hashFieldValue(value))
+			j.modified = true
+		}
+	case config.PromoteField:
+		if value, ok := getField(j.fields, rule.FieldPath); ok {
+			promoteField(rule.Target, value, msg)
+		}
+	}
+}
+
+// content returns the original content with the changes made by the field
+// rules, and forgets the parsed content.
+func (j *jsonFields) content(content []byte) []byte {
+	defer j.reset()
+	if !j.modified {
+		return content
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(j.fields); err != nil {
+		return content
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func (j *jsonFields) parse(content []byte) {
+	j.parsed = true
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	// keep numbers as they were written
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil || decoder.More() {
+		return
+	}
+	j.fields = fields
+}
+
+func (j *jsonFields) reset() {
+	*j = jsonFields{}
+}
+
+// getField returns the value at path in fields.
+func getField(fields map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := fields[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		fields = child
+	}
+	value, ok := fields[path[len(path)-1]]
+	return value, ok
+}
+
+// setField sets the value at path in fields, creating the intermediate
+// objects and replacing the intermediate values which aren't objects.
+func setField(fields map[string]interface{}, path []string, value interface{}) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := fields[key].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			fields[key] = child
+		}
+		fields = child
+	}
+	fields[path[len(path)-1]] = value
+}
+
+// removeField removes the value at path in fields and returns it.
+func removeField(fields map[string]interface{}, path []string) (interface{}, bool) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := fields[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		fields = child
+	}
+	key := path[len(path)-1]
+	value, ok := fields[key]
+	delete(fields, key)
+	return value, ok
+}
+
+// hashFieldValue returns the hex encoded SHA-256 hash of a string value, or
+// of the JSON representation of any other value.
+func hashFieldValue(value interface{}) string {
+	var data []byte
+	if s, ok := value.(string); ok {
+		data = []byte(s)
+	} else {
+		data, _ = json.Marshal(value)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// promoteField copies the value to the status, the timestamp or a tag of the
+// message. Values which can't be converted are ignored.
+func promoteField(target string, value interface{}, msg *message.Message) {
+	switch target {
+	case config.PromoteToStatus:
+		if status, ok := value.(string); ok && status != "" {
+			msg.Status = strings.ToLower(status)
+		}
+	case config.PromoteToTimestamp:
+		if ts, ok := fieldTimestamp(value); ok {
+			msg.LogTimestamp = ts
+		}
+	default:
+		var tagValue string
+		switch v := value.(type) {
+		case string:
+			tagValue = v
+		case json.Number:
+			tagValue = v.String()
+		case bool:
+			tagValue = strconv.FormatBool(v)
+		default:
+			return
+		}
+		msg.ProcessingTags = append(msg.ProcessingTags, strings.TrimPrefix(target, config.PromoteToTagPrefix)+":"+tagValue)
+	}
+}
+
+// fieldTimestamp converts an RFC 3339 date or a UNIX timestamp, in seconds or
+// in milliseconds, to a time.
+func fieldTimestamp(value interface{}) (time.Time, bool) {
+	var number json.Number
+	switch v := value.(type) {
+	case string:
+		if ts, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return ts.UTC(), true
+		}
+		number = json.Number(v)
+	case json.Number:
+		number = v
+	default:
+		return time.Time{}, false
+	}
+
+	f, err := number.Float64()
+	if err != nil || f <= 0 {
+		return time.Time{}, false
+	}
+	// timestamps in seconds can't be after year 5138
+	if f < 1e11 {
+		f *= 1000
+	}
+	return time.UnixMicro(int64(math.Round(f * 1000))).UTC(), true
+}
@@ -0,0 +1,59 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// MetricSender submits the metrics generated from logs. It is implemented by
+// the senders of the aggregator.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+}
+
+// applyGenerateMetricRule submits the metric of the rule if it matches the
+// content, tagged with its named capture groups. The metric is submitted for
+// the default hostname of the aggregator.
+func (p *Processor) applyGenerateMetricRule(rule *config.ProcessingRule, content []byte) {
+	if p.metricSender == nil || !isMatchingLiteralPrefix(rule.Regex, content) {
+		return
+	}
+	submatches := rule.Regex.FindSubmatchIndex(content)
+	if submatches == nil {
+		return
+	}
+
+	value := 1.0
+	var tags []string
+	for i, name := range rule.AttributeNames {
+		start, end := submatches[2*i], submatches[2*i+1]
+		if name == "" || start < 0 {
+			continue
+		}
+		if i == rule.ValueIndex {
+			var err error
+			if value, err = strconv.ParseFloat(string(content[start:end]), 64); err != nil {
+				metrics.TlmLogsToMetricsErrors.Inc(rule.MetricName)
+				return
+			}
+			continue
+		}
+		tags = append(tags, name+":"+string(content[start:end]))
+	}
+
+	switch rule.MetricType {
+	case config.MetricTypeDistribution:
+		p.metricSender.Distribution(rule.MetricName, value, "", tags)
+	default:
+		p.metricSender.Count(rule.MetricName, value, "", tags)
+	}
+	metrics.TlmLogsToMetricsSamples.Inc(rule.MetricName)
+}
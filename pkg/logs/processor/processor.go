@@ -10,6 +10,9 @@ import (
 	"context"
 	"regexp"
 	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -42,6 +45,15 @@ type Processor struct {
 
 	sds sdsProcessor
 
+	// deduplication and sampling rules state, lazily created
+	dedup   *deduplicator
+	sampler *sampler
+	// clock provides the time to the deduplication and sampling rules
+	clock clock.Clock
+
+	// metricSender submits the metrics of the generate_metric rules
+	metricSender MetricSender
+
 	// Telemetry
 	pipelineMonitor metrics.PipelineMonitor
 	utilization     metrics.UtilizationMonitor
@@ -63,7 +75,7 @@ type sdsProcessor struct {
 // New returns an initialized Processor.
 func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule,
 	encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
-	pipelineMonitor metrics.PipelineMonitor) *Processor {
+	pipelineMonitor metrics.PipelineMonitor, metricSender MetricSender) *Processor {
 
 	waitForSDSConfig := sds.ShouldBufferUntilSDSConfiguration(cfg)
 	maxBufferSize := sds.WaitForConfigurationBufferMaxSize(cfg)
@@ -79,6 +91,8 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 		hostname:                  hostname,
 		pipelineMonitor:           pipelineMonitor,
 		utilization:               pipelineMonitor.MakeUtilizationMonitor("processor"),
+		metricSender:              metricSender,
+		clock:                     clock.New(),
 
 		sds: sdsProcessor{
 			// will immediately starts buffering if it has been configured as so
@@ -118,6 +132,7 @@ func (p *Processor) Flush(ctx context.Context) {
 			return
 		default:
 			if len(p.inputChan) == 0 {
+				p.releaseDuplicates(true)
 				return
 			}
 			msg := <-p.inputChan
@@ -132,6 +147,9 @@ func (p *Processor) run() {
 		p.done <- struct{}{}
 	}()
 
+	deduplicationTicker := time.NewTicker(deduplicationFlushInterval)
+	defer deduplicationTicker.Stop()
+
 	for {
 		select {
 		// Processing, usual main loop
@@ -139,6 +157,7 @@ func (p *Processor) run() {
 
 		case msg, ok := <-p.inputChan:
 			if !ok { // channel has been closed
+				p.releaseDuplicates(true)
 				return
 			}
 
@@ -163,6 +182,14 @@ func (p *Processor) run() {
 			p.mu.Lock()
 			p.applySDSReconfiguration(order)
 			p.mu.Unlock()
+
+		// Deduplication windows expiration
+		// --------------------------------
+
+		case <-deduplicationTicker.C:
+			p.mu.Lock()
+			p.releaseDuplicates(false)
+			p.mu.Unlock()
 		}
 	}
 }
@@ -233,40 +260,114 @@ func (p *Processor) processMessage(msg *message.Message) {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
 
-		// render the message
-		rendered, err := msg.Render()
-		if err != nil {
-			log.Error("can't render the msg", err)
-			return
+		released, held := p.applyDeduplicationRules(msg)
+		if released != nil {
+			p.sendMessage(released)
+			// sendMessage stopped the monitor before writing on the output channel
+			p.utilization.Start()
+		}
+		if !held {
+			p.sendMessage(msg)
 		}
-		msg.SetRendered(rendered)
+	}
 
-		// report this message to diagnostic receivers (e.g. `stream-logs` command)
-		p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+}
 
-		// encode the message to its final format, it is done in-place
-		if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
-			log.Error("unable to encode msg ", err)
-			return
+// sendMessage renders and encodes the message before sending it to the strategy.
+func (p *Processor) sendMessage(msg *message.Message) {
+	// render the message
+	rendered, err := msg.Render()
+	if err != nil {
+		log.Error("can't render the msg", err)
+		return
+	}
+	msg.SetRendered(rendered)
+
+	// report this message to diagnostic receivers (e.g. `stream-logs` command)
+	p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+
+	// encode the message to its final format, it is done in-place
+	if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
+		log.Error("unable to encode msg ", err)
+		return
+	}
+
+	p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
+	p.outputChan <- msg
+	p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+}
+
+// applyDeduplicationRules passes the message to the deduplicator if a
+// deduplication rule matches its content, or if a message from the same
+// source is currently held.
+// It returns the message to release before this one, if any, and whether
+// this message is held by the deduplicator.
+func (p *Processor) applyDeduplicationRules(msg *message.Message) (released *message.Message, held bool) {
+	var matching *config.ProcessingRule
+	content := msg.GetContent()
+	for _, rule := range p.rules(msg) {
+		if rule.Type == config.Deduplicate && isMatchingLiteralPrefix(rule.Regex, content) && rule.Regex.Match(content) {
+			matching = rule
+			break
 		}
+	}
 
-		p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
-		p.outputChan <- msg
-		p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+	if matching == nil && p.dedup == nil {
+		return nil, false
 	}
+	if p.dedup == nil {
+		p.dedup = newDeduplicator()
+	}
+	return p.dedup.process(msg, matching, p.now())
+}
+
+// releaseDuplicates sends the messages held by the deduplication rules, all
+// of them or only the ones for which the deduplication window expired.
+func (p *Processor) releaseDuplicates(all bool) {
+	if p.dedup == nil {
+		return
+	}
+	var released []*message.Message
+	if all {
+		released = p.dedup.releaseAll()
+	} else {
+		released = p.dedup.releaseExpired(p.now())
+	}
+	for _, msg := range released {
+		// sendMessage stops the monitor before writing on the output channel
+		p.utilization.Start()
+		p.sendMessage(msg)
+	}
+	// in case the last message couldn't be sent
+	p.utilization.Stop()
+}
 
+// now returns the current time of the clock of the processor.
+func (p *Processor) now() time.Time {
+	if p.clock == nil {
+		return time.Now()
+	}
+	return p.clock.Now()
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
 // it applies the change directly on the Message content.
 func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	var content []byte = msg.GetContent()
+	var fields jsonFields
 
 	// Use the internal scrubbing implementation of the Agent
 	// ---------------------------
 
-	rules := append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
-	for _, rule := range rules {
+	for _, rule := range p.rules(msg) {
+		if rule.IsFieldRule() {
+			fields.apply(rule, content, msg)
+			continue
+		}
+		// the other rules apply to the content with the changes made by
+		// the previous field rules
+		content = fields.content(content)
+
 		switch rule.Type {
 		case config.ExcludeAtMatch:
 			// if this message matches, we ignore it
@@ -282,9 +383,22 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			if isMatchingLiteralPrefix(rule.Regex, content) {
 				content = rule.Regex.ReplaceAll(content, rule.Placeholder)
 			}
+		case config.ParseAttributes:
+			applyParseAttributesRule(rule, content, msg)
+		case config.GenerateMetric:
+			p.applyGenerateMetricRule(rule, content)
+		case config.Sample:
+			if p.sampler == nil {
+				p.sampler = newSampler()
+			}
+			if !p.sampler.keep(rule, content, p.now()) {
+				return false
+			}
 		}
 	}
 
+	content = fields.content(content)
+
 	// Use the SDS implementation
 	// --------------------------
 
@@ -302,6 +416,35 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 	return true // we want to send this message
 }
 
+// rules returns the global processing rules followed by the processing rules
+// of the message source.
+func (p *Processor) rules(msg *message.Message) []*config.ProcessingRule {
+	return append(p.processingRules, msg.Origin.LogSource.Config.ProcessingRules...)
+}
+
+// applyParseAttributesRule stores the named capture groups of the rule
+// matching the content as attributes of the message. Empty captures are
+// ignored and a later rule overrides the attributes set by a previous one.
+func applyParseAttributesRule(rule *config.ProcessingRule, content []byte, msg *message.Message) {
+	if !isMatchingLiteralPrefix(rule.Regex, content) {
+		return
+	}
+	submatches := rule.Regex.FindSubmatchIndex(content)
+	if submatches == nil {
+		return
+	}
+	for i, name := range rule.AttributeNames {
+		start, end := submatches[2*i], submatches[2*i+1]
+		if name == "" || start < 0 || start == end {
+			continue
+		}
+		if msg.ProcessingAttributes == nil {
+			msg.ProcessingAttributes = make(map[string]string)
+		}
+		msg.ProcessingAttributes[name] = string(content[start:end])
+	}
+}
+
 // isMatchingLiteralPrefix uses a potential literal prefix from the given regex
 // to indicate if the contant even has a chance of matching the regex
 func isMatchingLiteralPrefix(r *regexp.Regexp, content []byte) bool {
@@ -6,6 +6,7 @@
 package processor
 
 import (
+	"encoding/json"
 	"regexp"
 	"sync/atomic"
 	"testing"
@@ -279,6 +280,161 @@ func TestMask(t *testing.T) {
 	}
 }
 
+// parse attributes tests
+// ----------------------
+
+func TestParseAttributes(t *testing.T) {
+	rule := &config.ProcessingRule{
+		Name:    "access_log",
+		Type:    config.ParseAttributes,
+		Pattern: `^%{IP:network.client.ip} %{WORD:http.method} (?P<path>\S+) %{INT:http.status_code}(?: (?P<duration>\d+)ms)?`,
+	}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+	p := &Processor{}
+
+	tests := []struct {
+		input      []byte
+		attributes map[string]string
+	}{
+		{
+			input: []byte("10.0.0.1 GET /index.html 200 12ms"),
+			attributes: map[string]string{
+				"network.client.ip": "10.0.0.1",
+				"http.method":       "GET",
+				"path":              "/index.html",
+				"http.status_code":  "200",
+				"duration":          "12",
+			},
+		},
+		{
+			// optional group not matching, no empty attribute
+			input: []byte("10.0.0.1 POST /login 401"),
+			attributes: map[string]string{
+				"network.client.ip": "10.0.0.1",
+				"http.method":       "POST",
+				"path":              "/login",
+				"http.status_code":  "401",
+			},
+		},
+		{
+			input:      []byte("not an access log"),
+			attributes: nil,
+		},
+	}
+
+	for _, test := range tests {
+		// unstructured messages
+		msg := newMessage(test.input, &source, "")
+		assert.True(t, p.applyRedactingRules(msg))
+		assert.Equal(t, test.attributes, msg.ProcessingAttributes)
+		assert.Equal(t, test.input, msg.GetContent())
+
+		// structured messages
+		msg = newStructuredMessage(test.input, &source, "")
+		assert.True(t, p.applyRedactingRules(msg))
+		assert.Equal(t, test.attributes, msg.ProcessingAttributes)
+		assert.Equal(t, test.input, msg.GetContent())
+	}
+}
+
+func TestParseAttributesAfterMask(t *testing.T) {
+	parse := &config.ProcessingRule{
+		Name:    "user",
+		Type:    config.ParseAttributes,
+		Pattern: `user=(?P<user>\S+) token=(?P<token>\S+)`,
+	}
+	assert.Nil(t, config.CompileProcessingRules([]*config.ProcessingRule{parse}))
+	p := &Processor{processingRules: []*config.ProcessingRule{newProcessingRule(config.MaskSequences, "token=[masked]", "token=\\S+")}}
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{parse}}}
+
+	msg := newMessage([]byte("login user=bob token=abcdef"), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, map[string]string{"user": "bob", "token": "[masked]"}, msg.ProcessingAttributes)
+}
+
+func newFieldRule(ruleType, field, target string) *config.ProcessingRule {
+	return &config.ProcessingRule{Name: ruleType, Type: ruleType, Field: field, Target: target}
+}
+
+func TestFieldRules(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		newFieldRule(config.DropField, "password", ""),
+		newFieldRule(config.RenameField, "usr.mail", "user.email"),
+		newFieldRule(config.HashField, "user.email", ""),
+		newFieldRule(config.PromoteField, "level", config.PromoteToStatus),
+		newFieldRule(config.PromoteField, "ts", config.PromoteToTimestamp),
+		newFieldRule(config.PromoteField, "env", "tag:env"),
+		newFieldRule(config.PromoteField, "http.status", "tag:http_status"),
+	}
+	assert.Nil(t, config.ValidateProcessingRules(rules))
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: rules}}
+	p := &Processor{}
+
+	msg := newMessage([]byte(`{"message":"<login>","password":"hunter2","usr":{"mail":"bob@example.com"},"level":"ERROR","ts":1700000000123,"env":"prod","http":{"status":401}}`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, `{"env":"prod","http":{"status":401},"level":"ERROR","message":"<login>","ts":1700000000123,"user":{"email":"5ff860bf1190596c7188ab851db691f0f3169c453936e9e1eba2f9a47f7a0018"},"usr":{}}`, string(msg.GetContent()))
+	assert.Equal(t, "error", msg.Status)
+	assert.Equal(t, time.UnixMilli(1700000000123).UTC(), msg.LogTimestamp)
+	assert.Equal(t, []string{"env:prod", "http_status:401"}, msg.ProcessingTags)
+
+	// missing fields are ignored and the content isn't serialized again
+	content := `{ "message": "no field to process" }`
+	msg = newMessage([]byte(content), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, content, string(msg.GetContent()))
+	assert.Equal(t, "", msg.Status)
+	assert.Empty(t, msg.ProcessingTags)
+}
+
+func TestFieldRulesWithInvalidJSON(t *testing.T) {
+	source := sources.LogSource{Config: &config.LogsConfig{}}
+	p := &Processor{processingRules: []*config.ProcessingRule{
+		newFieldRule(config.DropField, "password", ""),
+		newFieldRule(config.PromoteField, "level", config.PromoteToStatus),
+	}}
+	assert.Nil(t, config.CompileProcessingRules(p.processingRules))
+
+	for _, content := range []string{`password=hunter2`, `["password"]`, `{"password":"hunter2"} trailing`, `{"password":`} {
+		msg := newMessage([]byte(content), &source, "")
+		assert.True(t, p.applyRedactingRules(msg))
+		assert.Equal(t, content, string(msg.GetContent()))
+	}
+}
+
+func TestFieldRulesWithOtherRules(t *testing.T) {
+	source := sources.LogSource{Config: &config.LogsConfig{}}
+	p := &Processor{processingRules: []*config.ProcessingRule{
+		newFieldRule(config.DropField, "token", ""),
+		newProcessingRule(config.ExcludeAtMatch, "", "secret"),
+		newProcessingRule(config.MaskSequences, "[masked]", `\d{4}`),
+		newFieldRule(config.RenameField, "card", "payment.card"),
+	}}
+	assert.Nil(t, config.CompileProcessingRules(p.processingRules))
+
+	// the token is dropped before the exclusion rule is applied
+	msg := newMessage([]byte(`{"token":"secret","card":"1234"}`), &source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, `{"payment":{"card":"[masked]"}}`, string(msg.GetContent()))
+}
+
+func TestFieldTimestamp(t *testing.T) {
+	for _, value := range []interface{}{json.Number("1700000000"), json.Number("1700000000000"), "1700000000", "2023-11-14T22:13:20Z", "2023-11-15T00:13:20+02:00"} {
+		ts, ok := fieldTimestamp(value)
+		assert.True(t, ok, value)
+		assert.Equal(t, time.Unix(1700000000, 0).UTC(), ts, value)
+	}
+	ts, ok := fieldTimestamp(json.Number("1700000000.5"))
+	assert.True(t, ok)
+	assert.Equal(t, time.UnixMilli(1700000000500).UTC(), ts)
+
+	for _, value := range []interface{}{"yesterday", json.Number("-1"), true, nil} {
+		_, ok := fieldTimestamp(value)
+		assert.False(t, ok, value)
+	}
+}
+
 func TestTruncate(t *testing.T) {
 	p := &Processor{}
 	source := sources.NewLogSource("", &config.LogsConfig{})
@@ -534,3 +690,58 @@ func BenchmarkMaskSequences(b *testing.B) {
 	})
 
 }
+
+type sentMetric struct {
+	metricType string
+	name       string
+	value      float64
+	tags       []string
+}
+
+type fakeMetricSender struct {
+	metrics []sentMetric
+}
+
+func (s *fakeMetricSender) Count(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, sentMetric{config.MetricTypeCount, metric, value, tags})
+}
+
+func (s *fakeMetricSender) Distribution(metric string, value float64, _ string, tags []string) {
+	s.metrics = append(s.metrics, sentMetric{config.MetricTypeDistribution, metric, value, tags})
+}
+
+func TestGenerateMetric(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{Name: "errors", Type: config.GenerateMetric, Pattern: `ERROR (?P<component>\w+)`, MetricName: "app.errors"},
+		{Name: "bytes", Type: config.GenerateMetric, Pattern: `sent=(?P<bytes>\S+)`, MetricName: "app.bytes_sent", ValueGroup: "bytes"},
+		{Name: "latency", Type: config.GenerateMetric, Pattern: `%{WORD:http.method} \S+ %{INT:http.status_code} %{NUMBER:duration}ms`, MetricName: "app.latency", MetricType: config.MetricTypeDistribution, ValueGroup: "duration"},
+		// the raw access logs are dropped once their metric is generated
+		newProcessingRule(config.ExcludeAtMatch, "", `ms$`),
+	}
+	assert.Nil(t, config.ValidateProcessingRules(rules))
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	sender := &fakeMetricSender{}
+	p := &Processor{processingRules: rules, metricSender: sender}
+	source := sources.LogSource{Config: &config.LogsConfig{}}
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR db connection refused"), &source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("GET /index.html 200 12.5ms"), &source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("INFO nothing to see"), &source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("sent=42"), &source, "")))
+	// invalid values are ignored
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("sent=lots"), &source, "")))
+
+	assert.Equal(t, []sentMetric{
+		{config.MetricTypeCount, "app.errors", 1, []string{"component:db"}},
+		{config.MetricTypeDistribution, "app.latency", 12.5, []string{"http.method:GET", "http.status_code:200"}},
+		{config.MetricTypeCount, "app.bytes_sent", 42, nil},
+	}, sender.metrics)
+}
+
+func TestGenerateMetricWithoutSender(t *testing.T) {
+	rules := []*config.ProcessingRule{{Name: "errors", Type: config.GenerateMetric, Pattern: `ERROR`, MetricName: "app.errors"}}
+	assert.Nil(t, config.CompileProcessingRules(rules))
+	p := &Processor{processingRules: rules}
+	source := sources.LogSource{Config: &config.LogsConfig{}}
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("ERROR"), &source, "")))
+}
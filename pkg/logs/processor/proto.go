@@ -7,7 +7,6 @@ package processor
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/DataDog/agent-payload/v5/pb"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
@@ -25,10 +24,12 @@ func (p *protoEncoder) Encode(msg *message.Message, hostname string) error {
 		return fmt.Errorf("message passed to encoder isn't rendered")
 	}
 
+	ts := messageTimestamp(msg)
+
 	log := &pb.Log{
 		Message:   toValidUtf8(msg.GetContent()),
 		Status:    msg.GetStatus(),
-		Timestamp: time.Now().UTC().UnixNano(),
+		Timestamp: ts.UnixNano(),
 		Hostname:  hostname,
 		Service:   msg.Origin.Service(),
 		Source:    msg.Origin.Source(),
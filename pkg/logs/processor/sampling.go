@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+)
+
+// sampler keeps track of the logs kept by the sampling rules during the
+// current second to enforce their per-second budget.
+type sampler struct {
+	second int64
+	counts map[samplingKey]int
+	random func() float64
+}
+
+type samplingKey struct {
+	rule *config.ProcessingRule
+	key  string
+}
+
+func newSampler() *sampler {
+	return &sampler{
+		counts: make(map[samplingKey]int),
+		random: rand.Float64,
+	}
+}
+
+// keep returns whether the content must be kept according to the given
+// sampling rule. Content not matching the rule is always kept.
+// The per-second budget is tracked per value of the first capture group of
+// the rule pattern if it has any.
+func (s *sampler) keep(rule *config.ProcessingRule, content []byte, now time.Time) bool {
+	if !isMatchingLiteralPrefix(rule.Regex, content) {
+		return true
+	}
+	submatches := rule.Regex.FindSubmatch(content)
+	if submatches == nil {
+		return true
+	}
+
+	if rule.SampleRate > 0 && rule.SampleRate < 1 && s.random() >= rule.SampleRate {
+		sampledOut()
+		return false
+	}
+
+	if rule.MaxPerSecond > 0 {
+		if second := now.Unix(); second != s.second {
+			s.second = second
+			clear(s.counts)
+		}
+		key := samplingKey{rule: rule}
+		if len(submatches) > 1 {
+			key.key = string(submatches[1])
+		}
+		if s.counts[key] >= rule.MaxPerSecond {
+			sampledOut()
+			return false
+		}
+		s.counts[key]++
+	}
+
+	return true
+}
+
+func sampledOut() {
+	metrics.LogsSampledOut.Add(1)
+	metrics.TlmLogsSampledOut.Inc()
+}
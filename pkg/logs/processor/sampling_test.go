@@ -0,0 +1,72 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+func TestSamplerRate(t *testing.T) {
+	rule := newProcessingRule(config.Sample, "", "debug")
+	rule.SampleRate = 0.5
+	s := newSampler()
+	draws := []float64{0.1, 0.7, 0.49, 0.5}
+	s.random = func() float64 {
+		draw := draws[0]
+		draws = draws[1:]
+		return draw
+	}
+	now := time.Now()
+
+	assert.True(t, s.keep(rule, []byte("debug: a"), now))
+	assert.False(t, s.keep(rule, []byte("debug: b"), now))
+	assert.True(t, s.keep(rule, []byte("debug: c"), now))
+	assert.False(t, s.keep(rule, []byte("debug: d"), now))
+	// non matching content is always kept without any draw
+	assert.True(t, s.keep(rule, []byte("info: e"), now))
+}
+
+func TestSamplerBudgetPerKey(t *testing.T) {
+	rule := newProcessingRule(config.Sample, "", `user=(\w+)`)
+	rule.MaxPerSecond = 2
+	s := newSampler()
+	now := time.Unix(1000, 0)
+
+	assert.True(t, s.keep(rule, []byte("user=alice"), now))
+	assert.True(t, s.keep(rule, []byte("user=alice"), now))
+	assert.False(t, s.keep(rule, []byte("user=alice"), now))
+	// each capture has its own budget
+	assert.True(t, s.keep(rule, []byte("user=bob"), now))
+
+	// the budget is reset every second
+	assert.True(t, s.keep(rule, []byte("user=alice"), now.Add(time.Second)))
+}
+
+func TestSampleProcessingRule(t *testing.T) {
+	sampledOut := metrics.LogsSampledOut.Value()
+	rule := newProcessingRule(config.Sample, "", "health")
+	rule.MaxPerSecond = 1
+	source := sources.LogSource{Config: &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}}}
+	mockClock := clock.NewMock()
+	p := &Processor{clock: mockClock}
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET /health"), &source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET /users"), &source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("GET /health"), &source, "")))
+	assert.Equal(t, sampledOut+1, metrics.LogsSampledOut.Value())
+
+	// the budget is reset the next second
+	mockClock.Add(time.Second)
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("GET /health"), &source, "")))
+}
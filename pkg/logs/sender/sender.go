@@ -15,6 +15,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 var (
@@ -23,6 +24,10 @@ var (
 	tlmSendWaitTime    = telemetry.NewCounter("logs_sender", "send_wait", []string{}, "Time spent waiting for all sends to finish")
 )
 
+// spoolReplayInterval is the interval at which the spool is replayed while no
+// new payload is received.
+const spoolReplayInterval = time.Second
+
 // Sender sends logs to different destinations. Destinations can be either
 // reliable or unreliable. The sender ensures that logs are sent to at least
 // one reliable destination and will block the pipeline if they are in an
@@ -42,6 +47,7 @@ type Sender struct {
 
 	pipelineMonitor metrics.PipelineMonitor
 	utilization     metrics.UtilizationMonitor
+	spool           Spool
 }
 
 // NewSender returns a new sender.
@@ -80,79 +86,158 @@ func (s *Sender) run() {
 	sink := additionalDestinationsSink(s.bufferSize)
 	unreliableDestinations := buildDestinationSenders(s.config, s.destinations.Unreliable, sink, s.bufferSize)
 
-	for payload := range s.inputChan {
-		s.utilization.Start()
-		var startInUse = time.Now()
-		senderDoneWg := &sync.WaitGroup{}
-
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					if destSender.destination.Metadata().ReportingEnabled {
-						s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
-					}
-					sent = true
-					if s.senderDoneChan != nil {
-						senderDoneWg.Add(1)
-						s.senderDoneChan <- senderDoneWg
-					}
-				}
-			}
+	var replayTick <-chan time.Time
+	if s.spool != nil {
+		replayTicker := time.NewTicker(spoolReplayInterval)
+		defer replayTicker.Stop()
+		replayTick = replayTicker.C
+	}
 
-			if !sent {
-				// Throttle the poll loop while waiting for a send to succeed
-				// This will only happen when all reliable destinations
-				// are blocked so logs have no where to go.
-				time.Sleep(100 * time.Millisecond)
+	for done := false; !done; {
+		select {
+		case payload, ok := <-s.inputChan:
+			if !ok {
+				done = true
+				break
 			}
+			s.send(payload, reliableDestinations, unreliableDestinations)
+		case <-replayTick:
+			s.replaySpool(reliableDestinations)
 		}
+	}
 
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
-			}
+	// Cleanup the destinations
+	for _, destSender := range reliableDestinations {
+		destSender.Stop()
+	}
+	for _, destSender := range unreliableDestinations {
+		destSender.Stop()
+	}
+	close(sink)
+	s.done <- struct{}{}
+}
+
+// send sends a payload to the reliable destinations, blocking until one of
+// them accepts it or it is stored in the spool, then to the unreliable ones.
+func (s *Sender) send(payload *message.Payload, reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender) {
+	s.utilization.Start()
+	var startInUse = time.Now()
+	senderDoneWg := &sync.WaitGroup{}
+
+	sent, spooled := false, false
+	if s.spool != nil {
+		// Payloads already in the spool must be sent first to keep the order.
+		s.replaySpool(reliableDestinations)
+		if s.spool.IsEmpty() {
+			sent = s.sendReliable(payload, reliableDestinations, senderDoneWg)
+		}
+		if !sent {
+			spooled = s.storeInSpool(payload)
+		}
+	}
+
+	for !sent && !spooled {
+		sent = s.sendReliable(payload, reliableDestinations, senderDoneWg)
+		if !sent {
+			// Throttle the poll loop while waiting for a send to succeed
+			// This will only happen when all reliable destinations
+			// are blocked so logs have no where to go.
+			time.Sleep(100 * time.Millisecond)
 		}
+	}
 
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
+	for i, destSender := range reliableDestinations {
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded && !spooled {
 			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
-				if s.senderDoneChan != nil {
-					senderDoneWg.Add(1)
-					s.senderDoneChan <- senderDoneWg
-				}
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
 			}
 		}
+	}
 
-		inUse := float64(time.Since(startInUse) / time.Millisecond)
-		tlmSendWaitTime.Add(inUse)
-		s.utilization.Stop()
-
-		if s.senderDoneChan != nil && s.flushWg != nil {
-			// Wait for all destinations to finish sending the payload
-			senderDoneWg.Wait()
-			// Decrement the wait group when this payload has been sent
-			s.flushWg.Done()
+	// Attempt to send to unreliable destinations
+	for i, destSender := range unreliableDestinations {
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+			if s.senderDoneChan != nil {
+				senderDoneWg.Add(1)
+				s.senderDoneChan <- senderDoneWg
+			}
 		}
-		s.pipelineMonitor.ReportComponentEgress(payload, "sender")
 	}
 
-	// Cleanup the destinations
+	inUse := float64(time.Since(startInUse) / time.Millisecond)
+	tlmSendWaitTime.Add(inUse)
+	s.utilization.Stop()
+
+	if s.senderDoneChan != nil && s.flushWg != nil {
+		// Wait for all destinations to finish sending the payload
+		senderDoneWg.Wait()
+		// Decrement the wait group when this payload has been sent
+		s.flushWg.Done()
+	}
+	s.pipelineMonitor.ReportComponentEgress(payload, "sender")
+}
+
+// sendReliable makes a single attempt at sending the payload to every reliable
+// destination, it returns true if at least one of them accepted it.
+func (s *Sender) sendReliable(payload *message.Payload, reliableDestinations []*DestinationSender, senderDoneWg *sync.WaitGroup) bool {
+	sent := false
 	for _, destSender := range reliableDestinations {
-		destSender.Stop()
+		if destSender.Send(payload) {
+			if destSender.destination.Metadata().ReportingEnabled {
+				s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
+			}
+			sent = true
+			if s.senderDoneChan != nil {
+				senderDoneWg.Add(1)
+				s.senderDoneChan <- senderDoneWg
+			}
+		}
 	}
-	for _, destSender := range unreliableDestinations {
-		destSender.Stop()
+	return sent
+}
+
+// SetSpool sets the spool storing the payloads while all the reliable
+// destinations are failing, it must be called before Start.
+func (s *Sender) SetSpool(spool Spool) {
+	s.spool = spool
+}
+
+// storeInSpool stores the payload in the spool and hands it over to the
+// auditor since it won't be lost anymore. It returns false if the payload
+// couldn't be stored.
+func (s *Sender) storeInSpool(payload *message.Payload) bool {
+	if err := s.spool.Store(payload); err != nil {
+		log.Warnf("Could not store the payload in the logs disk buffer: %v", err)
+		return false
+	}
+	s.outputChan <- payload
+	return true
+}
+
+// replaySpool sends the payloads of the spool, oldest first, until the spool
+// is empty or no reliable destination accepts them.
+func (s *Sender) replaySpool(reliableDestinations []*DestinationSender) {
+	for !s.spool.IsEmpty() {
+		payload, err := s.spool.Next()
+		if err != nil {
+			log.Warnf("Could not read the logs disk buffer: %v", err)
+			continue
+		}
+		if payload == nil {
+			return
+		}
+		if !s.sendReliable(payload, reliableDestinations, &sync.WaitGroup{}) {
+			return
+		}
+		if err := s.spool.Commit(); err != nil {
+			log.Warnf("Could not remove the payload sent from the logs disk buffer: %v", err)
+		}
 	}
-	close(sink)
-	s.done <- struct{}{}
 }
 
 // Drains the output channel from destinations that don't update the auditor.
@@ -7,8 +7,10 @@ package sender
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
@@ -317,3 +319,47 @@ func TestSenderReliableWhenOneFailsAndRecovers(t *testing.T) {
 	reliableServer2.Stop()
 	sender.Stop()
 }
+
+func TestSenderSpoolsWhenReliableDestinationsFail(t *testing.T) {
+	cfg := configmock.New(t)
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 1)
+
+	respond := make(chan int)
+	server := http.NewTestServerWithOptions(500, 0, true, respond, cfg)
+
+	destinations := client.NewDestinations([]client.Destination{server.Destination}, nil)
+
+	spool, err := NewDiskSpool(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+
+	sender := NewSender(cfg, input, output, destinations, 10, nil, nil, metrics.NewNoopPipelineMonitor(""))
+	sender.SetSpool(spool)
+	sender.Start()
+
+	input <- newTestPayload("first")
+	<-respond // let it respond 500 once
+	<-respond // its in a loop now, the sender has marked the endpoint as retrying
+
+	// the payload is stored on disk and handed over to the auditor
+	input <- newTestPayload("second")
+	payload := <-output
+	assert.Equal(t, "second", string(payload.Encoded))
+	assert.False(t, spool.IsEmpty())
+
+	// Recover the server
+	server.ChangeStatus(200)
+	for (<-respond) != 200 {
+	}
+	payload = <-output
+	assert.Equal(t, "first", string(payload.Encoded))
+
+	// the stored payload is replayed
+	<-respond
+	payload = <-output
+	assert.Equal(t, "second", string(payload.Encoded))
+	assert.Nil(t, payload.Messages)
+
+	server.Stop()
+	sender.Stop()
+}
@@ -0,0 +1,267 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const spoolFileExtension = ".spool"
+
+// Spool durably buffers the payloads which can't be sent while all the
+// reliable destinations are unavailable, to replay them in order once a
+// destination recovers.
+type Spool interface {
+	// Store appends a payload to the spool.
+	Store(payload *message.Payload) error
+	// Next returns the oldest payload of the spool, nil if the spool is empty.
+	// The payload remains in the spool until Commit is called.
+	Next() (*message.Payload, error)
+	// Commit removes the payload returned by the last call to Next.
+	Commit() error
+	// IsEmpty returns true if the spool doesn't hold any payload.
+	IsEmpty() bool
+}
+
+// diskSpool is a Spool storing each payload in its own file. It is bounded
+// by the total size of its files, dropping the oldest payloads when full,
+// and by the age of the payloads, dropping them once they are too old to be
+// accepted by the intake.
+type diskSpool struct {
+	path               string
+	maxSizeInBytes     int64
+	maxAge             time.Duration
+	filenames          []string
+	currentSizeInBytes int64
+	sequence           uint64
+	now                func() time.Time
+}
+
+// NewDiskSpool returns a new Spool storing payloads in the directory at path,
+// reloading the payloads it contains from a previous run.
+func NewDiskSpool(path string, maxSizeInBytes int64, maxAge time.Duration) (Spool, error) {
+	return newDiskSpool(path, maxSizeInBytes, maxAge, time.Now)
+}
+
+func newDiskSpool(path string, maxSizeInBytes int64, maxAge time.Duration, now func() time.Time) (*diskSpool, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	s := &diskSpool{
+		path:           path,
+		maxSizeInBytes: maxSizeInBytes,
+		maxAge:         maxAge,
+		now:            now,
+	}
+	if err := s.reloadExistingFiles(); err != nil {
+		return nil, err
+	}
+	if len(s.filenames) > 0 {
+		log.Infof("Reloaded %d payloads (%d bytes) from the logs disk buffer %s", len(s.filenames), s.currentSizeInBytes, path)
+	}
+	return s, nil
+}
+
+// Store implements Spool#Store
+func (s *diskSpool) Store(payload *message.Payload) error {
+	data := encodeSpooledPayload(payload)
+	size := int64(len(data))
+	if size > s.maxSizeInBytes {
+		s.dropped("too_large")
+		return fmt.Errorf("the payload is too big. Current:%v Maximum:%v", size, s.maxSizeInBytes)
+	}
+	for len(s.filenames) > 0 && s.currentSizeInBytes+size > s.maxSizeInBytes {
+		log.Warnf("Maximum disk space for the logs disk buffer is reached. Removing %s", s.filenames[0])
+		s.dropped("full")
+		if err := s.removeFirst(); err != nil {
+			return err
+		}
+	}
+
+	now := s.now()
+	s.sequence++
+	filename := filepath.Join(s.path, fmt.Sprintf("%020d_%010d%s", now.UnixNano(), s.sequence, spoolFileExtension))
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		_ = os.Remove(filename)
+		return err
+	}
+	s.filenames = append(s.filenames, filename)
+	s.updateSize(size)
+	return nil
+}
+
+// Next implements Spool#Next
+func (s *diskSpool) Next() (*message.Payload, error) {
+	s.removeExpired()
+	if len(s.filenames) == 0 {
+		return nil, nil
+	}
+	filename := s.filenames[0]
+	data, err := os.ReadFile(filename)
+	if err == nil {
+		var payload *message.Payload
+		if payload, err = decodeSpooledPayload(data); err == nil {
+			return payload, nil
+		}
+	}
+	// remove the file to not fail on the next call
+	s.dropped("invalid")
+	if errRemove := s.removeFirst(); errRemove != nil {
+		log.Warnf("Could not remove %s from the logs disk buffer: %v", filename, errRemove)
+	}
+	return nil, err
+}
+
+// Commit implements Spool#Commit
+func (s *diskSpool) Commit() error {
+	if len(s.filenames) == 0 {
+		return nil
+	}
+	return s.removeFirst()
+}
+
+// IsEmpty implements Spool#IsEmpty
+func (s *diskSpool) IsEmpty() bool {
+	return len(s.filenames) == 0
+}
+
+// removeExpired removes the payloads older than the maximum age.
+func (s *diskSpool) removeExpired() {
+	if s.maxAge <= 0 {
+		return
+	}
+	expireBefore := s.now().Add(-s.maxAge)
+	for len(s.filenames) > 0 {
+		storedAt, ok := spoolFileTime(s.filenames[0])
+		if ok && !storedAt.Before(expireBefore) {
+			return
+		}
+		log.Debugf("Removing expired payload %s from the logs disk buffer", s.filenames[0])
+		s.dropped("expired")
+		if err := s.removeFirst(); err != nil {
+			log.Warnf("Could not remove expired payload from the logs disk buffer: %v", err)
+		}
+	}
+}
+
+// removeFirst removes the oldest file of the spool.
+func (s *diskSpool) removeFirst() error {
+	filename := s.filenames[0]
+	// Remove the file from s.filenames also in case of error to not
+	// fail on the next call.
+	s.filenames = s.filenames[1:]
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filename); err != nil {
+		return err
+	}
+	s.updateSize(-info.Size())
+	return nil
+}
+
+func (s *diskSpool) updateSize(delta int64) {
+	count := int64(1)
+	if delta < 0 {
+		count = -1
+	}
+	s.currentSizeInBytes += delta
+	metrics.DiskBufferExpVars.Add("Payloads", count)
+	metrics.DiskBufferExpVars.Add("Bytes", delta)
+	metrics.TlmDiskBufferPayloads.Add(float64(count))
+	metrics.TlmDiskBufferBytes.Add(float64(delta))
+}
+
+func (s *diskSpool) dropped(reason string) {
+	metrics.DiskBufferExpVars.Add("Dropped", 1)
+	metrics.TlmDiskBufferDropped.Inc(reason)
+}
+
+func (s *diskSpool) reloadExistingFiles() error {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != spoolFileExtension {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			log.Warn("Can't get file info", err)
+			continue
+		}
+		s.filenames = append(s.filenames, filepath.Join(s.path, entry.Name()))
+		s.updateSize(info.Size())
+	}
+	// file names start with the time payloads were stored at
+	sort.Strings(s.filenames)
+	return nil
+}
+
+// spoolFileTime returns the time at which the payload in filename was stored.
+func spoolFileTime(filename string) (time.Time, bool) {
+	timestamp, _, _ := strings.Cut(filepath.Base(filename), "_")
+	nanos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// encodeSpooledPayload encodes a payload as its content encoding and
+// unencoded size followed by its encoded content. The messages of the
+// payload are not stored.
+func encodeSpooledPayload(payload *message.Payload) []byte {
+	data := make([]byte, 0, 2*binary.MaxVarintLen64+len(payload.Encoding)+len(payload.Encoded))
+	data = binary.AppendUvarint(data, uint64(len(payload.Encoding)))
+	data = append(data, payload.Encoding...)
+	data = binary.AppendUvarint(data, uint64(payload.UnencodedSize))
+	return append(data, payload.Encoded...)
+}
+
+var errInvalidSpooledPayload = errors.New("invalid payload in the logs disk buffer")
+
+func decodeSpooledPayload(data []byte) (*message.Payload, error) {
+	r := bytes.NewReader(data)
+	encodingLen, err := binary.ReadUvarint(r)
+	if err != nil || encodingLen > uint64(len(data)) {
+		return nil, errInvalidSpooledPayload
+	}
+	encoding := make([]byte, encodingLen)
+	if _, err := io.ReadFull(r, encoding); err != nil {
+		return nil, errInvalidSpooledPayload
+	}
+	unencodedSize, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, errInvalidSpooledPayload
+	}
+	encoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errInvalidSpooledPayload
+	}
+	return &message.Payload{
+		Encoded:       encoded,
+		Encoding:      string(encoding),
+		UnencodedSize: int(unencodedSize),
+	}, nil
+}
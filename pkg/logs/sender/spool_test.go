@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newTestPayload(content string) *message.Payload {
+	return &message.Payload{
+		Messages:      []*message.Message{message.NewMessage([]byte(content), nil, "", 0)},
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: 2 * len(content),
+	}
+}
+
+func readSpool(t *testing.T, spool Spool) []string {
+	var contents []string
+	for !spool.IsEmpty() {
+		payload, err := spool.Next()
+		require.NoError(t, err)
+		contents = append(contents, string(payload.Encoded))
+		require.NoError(t, spool.Commit())
+	}
+	return contents
+}
+
+func TestDiskSpoolStoreAndNext(t *testing.T) {
+	spool, err := NewDiskSpool(t.TempDir(), 1024, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, spool.IsEmpty())
+
+	payload, err := spool.Next()
+	assert.NoError(t, err)
+	assert.Nil(t, payload)
+
+	require.NoError(t, spool.Store(newTestPayload("first")))
+	require.NoError(t, spool.Store(newTestPayload("second")))
+	assert.False(t, spool.IsEmpty())
+
+	// a payload is returned until it is committed
+	for i := 0; i < 2; i++ {
+		payload, err = spool.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(payload.Encoded))
+		assert.Equal(t, "gzip", payload.Encoding)
+		assert.Equal(t, 10, payload.UnencodedSize)
+		assert.Nil(t, payload.Messages)
+	}
+	require.NoError(t, spool.Commit())
+
+	assert.Equal(t, []string{"second"}, readSpool(t, spool))
+}
+
+func TestDiskSpoolReloadsExistingPayloads(t *testing.T) {
+	path := t.TempDir()
+	spool, err := NewDiskSpool(path, 1024, time.Hour)
+	require.NoError(t, err)
+	for _, content := range []string{"a", "b", "c"} {
+		require.NoError(t, spool.Store(newTestPayload(content)))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(path, "unrelated.txt"), []byte("x"), 0600))
+
+	reloaded, err := NewDiskSpool(path, 1024, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, readSpool(t, reloaded))
+}
+
+func TestDiskSpoolDropsOldestWhenFull(t *testing.T) {
+	spool, err := NewDiskSpool(t.TempDir(), 30, time.Hour)
+	require.NoError(t, err)
+
+	// each payload takes 12 bytes on disk
+	for _, content := range []string{"aaaaaa", "bbbbbb", "cccccc"} {
+		require.NoError(t, spool.Store(newTestPayload(content)))
+	}
+	assert.Error(t, spool.Store(newTestPayload("this payload is larger than the spool")))
+
+	assert.Equal(t, []string{"bbbbbb", "cccccc"}, readSpool(t, spool))
+}
+
+func TestDiskSpoolDropsExpiredPayloads(t *testing.T) {
+	now := time.Now()
+	spool, err := newDiskSpool(t.TempDir(), 1024, time.Minute, func() time.Time { return now })
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Store(newTestPayload("old")))
+	now = now.Add(45 * time.Second)
+	require.NoError(t, spool.Store(newTestPayload("recent")))
+	now = now.Add(30 * time.Second)
+
+	assert.Equal(t, []string{"recent"}, readSpool(t, spool))
+}
+
+func TestDiskSpoolSkipsInvalidPayloads(t *testing.T) {
+	path := t.TempDir()
+	spool, err := NewDiskSpool(path, 1024, time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, spool.Store(newTestPayload("valid")))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "00000000000000000001_0000000001.spool"), []byte{0xff}, 0600))
+
+	spool, err = NewDiskSpool(path, 1024, 0)
+	require.NoError(t, err)
+	_, err = spool.Next()
+	assert.Error(t, err)
+	assert.Equal(t, []string{"valid"}, readSpool(t, spool))
+}
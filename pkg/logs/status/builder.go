@@ -173,6 +173,9 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	switch c.Type {
 	case config.TCPType, config.UDPType:
 		dictionary["Port"] = c.Port
+	case config.SyslogType:
+		dictionary["Port"] = c.Port
+		dictionary["Protocol"] = c.SyslogProtocol()
 	case config.FileType:
 		dictionary["Path"] = c.Path
 		dictionary["TailingMode"] = c.TailingMode
@@ -209,6 +212,12 @@ func (b *Builder) getMetricsStatus() map[string]string {
 	metrics["RetryCount"] = fmt.Sprintf("%v", b.logsExpVars.Get("RetryCount").(*expvar.Int).Value())
 	metrics["RetryTimeSpent"] = time.Duration(b.logsExpVars.Get("RetryTimeSpent").(*expvar.Int).Value()).String()
 	metrics["EncodedBytesSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value())
+	// the on-disk buffer metrics are only reported when it is enabled
+	if diskBuffer, ok := b.logsExpVars.Get("DiskBuffer").(*expvar.Map); ok {
+		diskBuffer.Do(func(kv expvar.KeyValue) {
+			metrics["DiskBuffer"+kv.Key] = kv.Value.String()
+		})
+	}
 	return metrics
 }
 
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// headSize is the number of bytes kept from the beginning of a file to
+// recognize it once it has been rotated and compressed.
+const headSize = 1024
+
+// maxArchiveCandidates is the maximum number of archives inspected when
+// looking for the compressed version of a rotated file.
+const maxArchiveCandidates = 3
+
+// IsArchive returns true if the file at path is a compressed archive,
+// which is read once from the beginning to the end instead of being tailed.
+func IsArchive(path string) bool {
+	switch filepath.Ext(path) {
+	case ".gz", ".zst":
+		return true
+	}
+	return false
+}
+
+// newArchiveReader returns a reader decompressing the content of the archive f.
+func newArchiveReader(f io.Reader, path string) (io.ReadCloser, error) {
+	switch filepath.Ext(path) {
+	case ".gz":
+		return gzip.NewReader(f)
+	case ".zst":
+		return zstd.NewReader(f), nil
+	}
+	return nil, fmt.Errorf("unsupported archive format: %q", path)
+}
+
+// setupArchive opens the archive and skips its content until offset, which is
+// an offset in the decompressed content.
+func (t *Tailer) setupArchive(offset int64, whence int) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	log.Info("Opening archive", t.file.Path, "for tailer key", t.file.GetScanKey())
+	f, err := filesystem.OpenShared(fullpath)
+	if err != nil {
+		return err
+	}
+	reader, err := newArchiveReader(f, fullpath)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("could not decompress %q: %w", t.file.Path, err)
+	}
+	t.osFile = f
+	t.archive = reader
+
+	switch whence {
+	case io.SeekEnd:
+		// an archive doesn't grow, there is nothing to read after its end
+		t.archive = io.NopCloser(io.MultiReader())
+		reader.Close()
+	case io.SeekStart:
+		skipped, err := io.CopyN(io.Discard, reader, offset)
+		if err != nil && err != io.EOF {
+			t.closeFile()
+			return fmt.Errorf("could not seek %q to offset %d: %w", t.file.Path, offset, err)
+		}
+		t.lastReadOffset.Store(skipped)
+		t.decodedOffset.Store(skipped)
+	}
+	return nil
+}
+
+// readArchive reads the next chunk of the archive, it returns io.EOF once the
+// archive has been entirely read.
+func (t *Tailer) readArchive() (int, error) {
+	inBuf := make([]byte, 4096)
+	n, err := t.archive.Read(inBuf)
+	if n > 0 {
+		t.lastReadOffset.Add(int64(n))
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+	}
+	switch {
+	case err == io.EOF:
+		log.Infof("Archive %s has been read entirely", t.file.Path)
+		// the completion is recorded in the registry once the decoder has
+		// been flushed, see forwardMessages
+		t.completed.Store(true)
+		t.file.Source.RemoveInput(t.file.Path)
+		return n, io.EOF
+	case err != nil:
+		t.file.Source.Status().Error(err)
+		return n, log.Error("Unexpected error occurred while decompressing archive: ", err)
+	}
+	return n, nil
+}
+
+// markArchiveCompleted records in the registry that the archive has been read
+// entirely. The archive is only completed once the last forwarded log has been
+// committed by the auditor, so that the logs which are not sent yet are read
+// again after a restart. If this log is filtered out before being sent, the
+// archive is read again after a restart rather than losing logs.
+func (t *Tailer) markArchiveCompleted(lastForwardedOffset string) {
+	if t.registry == nil || !t.completed.Load() {
+		return
+	}
+	t.registry.MarkCompleted(t.Identifier(), lastForwardedOffset)
+}
+
+// closeFile closes the file read by the tailer.
+func (t *Tailer) closeFile() {
+	if t.archive != nil {
+		t.archive.Close()
+	}
+	if t.osFile != nil {
+		t.osFile.Close()
+	}
+}
+
+// recordHead keeps the first bytes of the tailed file, data being the bytes
+// read at offset.
+func (t *Tailer) recordHead(offset int64, data []byte) {
+	known := int64(len(t.head))
+	if known >= headSize || offset > known || offset+int64(len(data)) <= known {
+		return
+	}
+	end := min(int64(len(data)), headSize-offset)
+	t.head = append(t.head, data[known-offset:end]...)
+}
+
+// readHead reads the first bytes of the tailed file.
+func (t *Tailer) readHead(f *os.File) {
+	head := make([]byte, headSize)
+	n, _ := f.ReadAt(head, 0)
+	t.head = head[:n]
+}
+
+// FindRotatedArchive returns the path of the archive containing the file read
+// by this tailer when it has been compressed after a rotation. Archives are
+// looked for next to the file and recognized by their first bytes.
+func (t *Tailer) FindRotatedArchive() (string, bool) {
+	if len(t.head) == 0 {
+		return "", false
+	}
+	dir, base := filepath.Split(t.fullpath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Debugf("Could not list rotated archives of %s: %v", t.file.Path, err)
+		return "", false
+	}
+
+	var candidates []os.FileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == base || !strings.HasPrefix(name, base) || !IsArchive(name) || entry.IsDir() {
+			continue
+		}
+		if info, err := entry.Info(); err == nil {
+			candidates = append(candidates, info)
+		}
+	}
+	// the most recent archives are the most likely to contain the rotated file
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].ModTime().After(candidates[j].ModTime())
+	})
+
+	for i := 0; i < len(candidates) && i < maxArchiveCandidates; i++ {
+		path := filepath.Join(dir, candidates[i].Name())
+		if t.archiveStartsWithHead(path) {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// archiveStartsWithHead returns true if the decompressed content of the
+// archive starts with the head of the tailed file.
+func (t *Tailer) archiveStartsWithHead(path string) bool {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	reader, err := newArchiveReader(f, path)
+	if err != nil {
+		return false
+	}
+	defer reader.Close()
+	head := make([]byte, len(t.head))
+	if _, err := io.ReadFull(reader, head); err != nil {
+		return false
+	}
+	return bytes.Equal(head, t.head)
+}
@@ -0,0 +1,189 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	auditor "github.com/DataDog/datadog-agent/pkg/logs/auditor/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+func writeArchive(t *testing.T, path string, content string) {
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.WriteCloser
+	switch filepath.Ext(path) {
+	case ".gz":
+		w = gzip.NewWriter(f)
+	case ".zst":
+		w = zstd.NewWriter(f)
+	}
+	_, err = io.WriteString(w, content)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+}
+
+func newTestTailer(path string, outputChan chan *message.Message) *Tailer {
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: path})
+	info := status.NewInfoRegistry()
+	file := NewFile(path, source, false)
+	return NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            file,
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(file.Source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+		Registry:        auditor.NewRegistry(),
+	})
+}
+
+func TestIsArchive(t *testing.T) {
+	assert.True(t, IsArchive("/var/log/app.log.1.gz"))
+	assert.True(t, IsArchive("/var/log/app.log-20240101.zst"))
+	assert.False(t, IsArchive("/var/log/app.log"))
+	assert.False(t, IsArchive("/var/log/app.log.1"))
+	assert.False(t, IsArchive("/var/log/app.gzip"))
+}
+
+func TestTailArchive(t *testing.T) {
+	for _, ext := range []string{".gz", ".zst"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log.1"+ext)
+			writeArchive(t, path, "hello\nworld\n\n")
+
+			outputChan := make(chan *message.Message, 10)
+			tailer := newTestTailer(path, outputChan)
+			require.NoError(t, tailer.StartFromBeginning())
+
+			msg := <-outputChan
+			assert.Equal(t, "hello", string(msg.GetContent()))
+			assert.Equal(t, "6", msg.Origin.Offset)
+
+			msg = <-outputChan
+			assert.Equal(t, "world", string(msg.GetContent()))
+			assert.Equal(t, "12", msg.Origin.Offset)
+			assert.Equal(t, "file:"+path, msg.Origin.Identifier)
+
+			assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+			assert.True(t, tailer.IsCompleted())
+			assert.True(t, tailer.registry.IsCompleted(tailer.Identifier()))
+			assert.Empty(t, tailer.Source().GetInputs())
+			tailer.Stop()
+		})
+	}
+}
+
+func TestTailArchiveFromOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeArchive(t, path, "hello\nworld\n")
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newTestTailer(path, outputChan)
+	require.NoError(t, tailer.Start(6, io.SeekStart))
+
+	msg := <-outputChan
+	assert.Equal(t, "world", string(msg.GetContent()))
+	assert.Equal(t, "12", msg.Origin.Offset)
+	tailer.Stop()
+}
+
+func TestTailArchiveWithoutLogs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeArchive(t, path, "\n\n")
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newTestTailer(path, outputChan)
+	require.NoError(t, tailer.StartFromBeginning())
+
+	// the completion doesn't depend on a log reaching the auditor
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, outputChan)
+	assert.True(t, tailer.registry.IsCompleted(tailer.Identifier()))
+	tailer.Stop()
+}
+
+func TestTailArchiveFromEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeArchive(t, path, "hello\nworld\n")
+
+	outputChan := make(chan *message.Message, 10)
+	tailer := newTestTailer(path, outputChan)
+	require.NoError(t, tailer.Start(0, io.SeekEnd))
+
+	assert.Eventually(t, tailer.IsFinished, 5*time.Second, 10*time.Millisecond)
+	assert.Empty(t, outputChan)
+	tailer.Stop()
+}
+
+func TestTailInvalidArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	require.NoError(t, os.WriteFile(path, []byte("not compressed\n"), 0644))
+
+	tailer := newTestTailer(path, make(chan *message.Message, 10))
+	assert.Error(t, tailer.StartFromBeginning())
+	assert.True(t, tailer.Source().Status.IsError())
+}
+
+func TestRecordHead(t *testing.T) {
+	tailer := &Tailer{}
+	tailer.recordHead(0, []byte("hello "))
+	assert.Equal(t, "hello ", string(tailer.head))
+	// data already known is ignored
+	tailer.recordHead(0, []byte("hello "))
+	assert.Equal(t, "hello ", string(tailer.head))
+	tailer.recordHead(3, []byte("lo world"))
+	assert.Equal(t, "hello world", string(tailer.head))
+	// data past the known head is ignored
+	tailer.recordHead(20, []byte("!"))
+	assert.Equal(t, "hello world", string(tailer.head))
+
+	large := make([]byte, 2*headSize)
+	tailer.recordHead(11, large)
+	assert.Len(t, tailer.head, headSize)
+}
+
+func TestFindRotatedArchive(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("first line\nsecond line\n"), 0644))
+
+	tailer := newTestTailer(path, make(chan *message.Message, 10))
+	require.NoError(t, tailer.StartFromBeginning())
+	defer tailer.Stop()
+
+	_, found := tailer.FindRotatedArchive()
+	assert.False(t, found)
+
+	// archives of other files or with a different content are ignored
+	writeArchive(t, filepath.Join(dir, "other.log.1.gz"), "first line\nsecond line\n")
+	writeArchive(t, filepath.Join(dir, "app.log.2.gz"), "older line\n")
+	_, found = tailer.FindRotatedArchive()
+	assert.False(t, found)
+
+	archive := filepath.Join(dir, "app.log.1.zst")
+	writeArchive(t, archive, "first line\nsecond line\nthird line\n")
+	foundArchive, found := tailer.FindRotatedArchive()
+	assert.True(t, found)
+	assert.Equal(t, archive, foundArchive)
+}
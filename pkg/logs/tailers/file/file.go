@@ -34,6 +34,11 @@ func NewFile(path string, source *sources.LogSource, isWildcardPath bool) *File
 	}
 }
 
+// IsArchive returns true if the file is a compressed archive.
+func (t *File) IsArchive() bool {
+	return IsArchive(t.Path)
+}
+
 // GetScanKey returns a key used by the scanner to index the scanned file.  The
 // string uniquely identifies this File, even if sources for multiple
 // containers use the same Path.
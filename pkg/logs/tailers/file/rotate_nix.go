@@ -22,6 +22,10 @@ import (
 // - removed and recreated
 // - truncated
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.archive != nil {
+		// archives are read once, they are not rotated
+		return false, nil
+	}
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return false, fmt.Errorf("open %q: %w", t.fullpath, err)
@@ -23,6 +23,7 @@ import (
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/tag"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/util"
@@ -62,6 +63,13 @@ type Tailer struct {
 	// is platform-specific.
 	osFile *os.File
 
+	// archive decompresses the content of osFile when the file is an archive.
+	archive io.ReadCloser
+
+	// head contains the first bytes of the file, used to find it back once it
+	// has been rotated and compressed.
+	head []byte
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -99,6 +107,12 @@ type Tailer struct {
 	// didFileRotate is true when we are tailing a file after it has been rotated
 	didFileRotate *atomic.Bool
 
+	// completed is true when the tailer has read its whole archive.
+	completed *atomic.Bool
+
+	// registry records the archives which have been read entirely.
+	registry auditor.Registry
+
 	// stop is monitored by the readForever component, and causes it to stop reading
 	// and close the channel to the decoder.
 	stop chan struct{}
@@ -132,6 +146,7 @@ type TailerOptions struct {
 	Rotated         bool                    // Optional
 	TagAdder        tag.EntityTagAdder      // Required
 	PipelineMonitor metrics.PipelineMonitor // Required
+	Registry        auditor.Registry        // Optional, records when archives have been read entirely
 }
 
 // NewTailer returns an initialized Tailer, read to be started.
@@ -181,6 +196,8 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		stopForward:            stopForward,
 		isFinished:             atomic.NewBool(false),
 		didFileRotate:          atomic.NewBool(false),
+		completed:              atomic.NewBool(false),
+		registry:               opts.Registry,
 		info:                   opts.Info,
 		bytesRead:              bytesRead,
 		movingSum:              movingSum,
@@ -213,11 +230,32 @@ func (t *Tailer) NewRotatedTailer(file *File, outputChan chan *message.Message,
 		Rotated:         true,
 		TagAdder:        tagAdder,
 		PipelineMonitor: pipelineMonitor,
+		Registry:        t.registry,
 	}
 
 	return NewTailer(options)
 }
 
+// NewDrainTailer creates a new tailer reading the archive containing the file
+// of this tailer, after it has been rotated and compressed, to send the logs
+// this tailer couldn't read. It must be started at this tailer's last read offset.
+func (t *Tailer) NewDrainTailer(archive *File, outputChan chan *message.Message, pipelineMonitor metrics.PipelineMonitor, decoder *decoder.Decoder, info *status.InfoRegistry, tagAdder tag.EntityTagAdder) *Tailer {
+	options := &TailerOptions{
+		OutputChan:      outputChan,
+		File:            archive,
+		SleepDuration:   t.sleepDuration,
+		Decoder:         decoder,
+		Info:            info,
+		TagAdder:        tagAdder,
+		PipelineMonitor: pipelineMonitor,
+		Registry:        t.registry,
+	}
+
+	drainTailer := NewTailer(options)
+	addToTailerInfo("Drained From", t.file.Path, info)
+	return drainTailer
+}
+
 // Identifier returns a string that identifies this tailer in the registry.
 func (t *Tailer) Identifier() string {
 	// FIXME(remy): during container rotation, this Identifier() method could return
@@ -232,7 +270,12 @@ func (t *Tailer) Identifier() string {
 
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.file.IsArchive() {
+		err = t.setupArchive(offset, whence)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status().Error(err)
 		return err
@@ -290,13 +333,19 @@ func (t *Tailer) StopAfterFileRotation() {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer func() {
-		t.osFile.Close()
+		t.closeFile()
 		t.decoder.Stop()
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
 
 	for {
-		n, err := t.read()
+		var n int
+		var err error
+		if t.archive != nil {
+			n, err = t.readArchive()
+		} else {
+			n, err = t.read()
+		}
 		if err != nil {
 			return
 		}
@@ -335,10 +384,29 @@ func (t *Tailer) IsFinished() bool {
 	return t.isFinished.Load()
 }
 
+// IsArchive returns true if the tailer reads an archive.
+func (t *Tailer) IsArchive() bool {
+	return t.file.IsArchive()
+}
+
+// IsCompleted returns true if the tailer has read its whole file, which only
+// happens for archives.
+func (t *Tailer) IsCompleted() bool {
+	return t.completed.Load()
+}
+
+// LastReadOffset returns the offset in the file up to which the tailer read.
+func (t *Tailer) LastReadOffset() int64 {
+	return t.lastReadOffset.Load()
+}
+
 // forwardMessages lets the Tailer forward log messages to the output channel
 func (t *Tailer) forwardMessages() {
+	// lastForwardedOffset is the offset of the last message sent to the output
+	var lastForwardedOffset string
 	defer func() {
 		// the decoder has successfully been flushed
+		t.markArchiveCompleted(lastForwardedOffset)
 		t.isFinished.Store(true)
 		close(t.done)
 	}()
@@ -372,6 +440,7 @@ func (t *Tailer) forwardMessages() {
 		select {
 		case t.outputChan <- msg:
 			t.PipelineMonitor.ReportComponentIngress(msg, "processor")
+			lastForwardedOffset = origin.Offset
 		case <-t.forwardContext.Done():
 		}
 	}
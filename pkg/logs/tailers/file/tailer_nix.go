@@ -34,6 +34,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	}
 
 	t.osFile = f
+	t.readHead(f)
 	ret, _ := f.Seek(offset, whence)
 	t.lastReadOffset.Store(ret)
 	t.decodedOffset.Store(ret)
@@ -55,6 +56,7 @@ func (t *Tailer) read() (int, error) {
 	if n == 0 {
 		return 0, nil
 	}
+	t.recordHead(t.lastReadOffset.Load(), inBuf[:n])
 	t.lastReadOffset.Add(int64(n))
 	msg := decoder.NewInput(inBuf[:n])
 	t.decoder.InputChan <- msg
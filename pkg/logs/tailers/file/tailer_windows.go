@@ -34,6 +34,7 @@ func (t *Tailer) setup(offset int64, whence int) error {
 	if err != nil {
 		return err
 	}
+	t.readHead(f)
 	filePos, _ := f.Seek(offset, whence)
 	f.Close()
 
@@ -93,6 +94,7 @@ func (t *Tailer) readAvailable() (int, error) {
 		}
 
 		// record these bytes as having been read
+		t.recordHead(t.lastReadOffset.Load(), inBuf[:n])
 		t.lastReadOffset.Add(int64(n))
 
 		// First, try to send the data to the decoder, but only wait for
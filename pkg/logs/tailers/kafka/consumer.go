@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+)
+
+// errConsumerClosed is returned by Poll once the consumer is closed.
+var errConsumerClosed = errors.New("kafka consumer closed")
+
+// Consumer consumes the records of Kafka topics as a member of a consumer group.
+type Consumer interface {
+	// Poll blocks until records are available or ctx is done, and returns them.
+	Poll(ctx context.Context) ([]*kgo.Record, error)
+	// Commit commits the offsets of the consumer group, by topic and partition.
+	Commit(ctx context.Context, offsets map[string]map[int32]int64) error
+	// Close leaves the consumer group.
+	Close()
+}
+
+// ConsumerFactory creates the consumer of a source, onRevoked is called with
+// the partitions revoked from the consumer before they are assigned to
+// another member of the group.
+type ConsumerFactory func(cfg *config.LogsConfig, onRevoked func(ctx context.Context, partitions map[string][]int32)) (Consumer, error)
+
+// kgoConsumer is a Consumer using a franz-go client.
+type kgoConsumer struct {
+	client *kgo.Client
+}
+
+// NewConsumer returns a new consumer of the brokers of the source, auto commit
+// is disabled: the offsets are committed by the tailer.
+func NewConsumer(cfg *config.LogsConfig, onRevoked func(ctx context.Context, partitions map[string][]int32)) (Consumer, error) {
+	resetOffset := kgo.NewOffset().AtEnd()
+	if cfg.TailingMode == "beginning" {
+		resetOffset = kgo.NewOffset().AtStart()
+	}
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(cfg.Brokers...),
+		kgo.ConsumerGroup(cfg.ConsumerGroup),
+		kgo.ConsumeTopics(cfg.Topics...),
+		kgo.ConsumeResetOffset(resetOffset),
+		kgo.DisableAutoCommit(),
+		kgo.OnPartitionsRevoked(func(ctx context.Context, _ *kgo.Client, partitions map[string][]int32) {
+			onRevoked(ctx, partitions)
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &kgoConsumer{client: client}, nil
+}
+
+// Poll returns the next fetched records, along with the first fetch error.
+func (c *kgoConsumer) Poll(ctx context.Context) ([]*kgo.Record, error) {
+	fetches := c.client.PollFetches(ctx)
+	if fetches.IsClientClosed() {
+		return nil, errConsumerClosed
+	}
+	var err error
+	fetches.EachError(func(topic string, partition int32, fetchErr error) {
+		if err == nil {
+			err = fmt.Errorf("could not fetch records of %s/%d: %w", topic, partition, fetchErr)
+		}
+	})
+	return fetches.Records(), err
+}
+
+// Commit commits the offsets synchronously.
+func (c *kgoConsumer) Commit(ctx context.Context, offsets map[string]map[int32]int64) error {
+	uncommitted := make(map[string]map[int32]kgo.EpochOffset, len(offsets))
+	for topic, partitions := range offsets {
+		uncommitted[topic] = make(map[int32]kgo.EpochOffset, len(partitions))
+		for partition, offset := range partitions {
+			uncommitted[topic][partition] = kgo.EpochOffset{Epoch: -1, Offset: offset}
+		}
+	}
+
+	var commitErr error
+	c.client.CommitOffsetsSync(ctx, uncommitted, func(_ *kgo.Client, _ *kmsg.OffsetCommitRequest, resp *kmsg.OffsetCommitResponse, err error) {
+		if err != nil {
+			commitErr = err
+			return
+		}
+		for _, topic := range resp.Topics {
+			for _, partition := range topic.Partitions {
+				if err := kerr.ErrorForCode(partition.ErrorCode); err != nil && commitErr == nil {
+					commitErr = fmt.Errorf("could not commit offset of %s/%d: %w", topic.Topic, partition.Partition, err)
+				}
+			}
+		}
+	})
+	return commitErr
+}
+
+// Close leaves the consumer group and closes the client.
+func (c *kgoConsumer) Close() {
+	c.client.Close()
+}
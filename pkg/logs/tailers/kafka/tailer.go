@@ -0,0 +1,293 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package kafka implements a tailer consuming Kafka topics.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// commitInterval is the interval at which the offsets of the delivered
+	// records are committed.
+	commitInterval = time.Second
+	// pollErrorBackoff is the delay before polling again after an error.
+	pollErrorBackoff = time.Second
+	// stopCommitTimeout bounds the last commit made when the tailer stops.
+	stopCommitTimeout = 5 * time.Second
+)
+
+type topicPartition struct {
+	topic     string
+	partition int32
+}
+
+// partitionState holds the offsets of a partition consumed by the tailer.
+type partitionState struct {
+	// committed is the last offset committed to the consumer group. It starts
+	// at the offset of the first record consumed since the partition was
+	// assigned, from where the consumer group resumed.
+	committed int64
+	// forwarded is the offset following the last record forwarded as a message.
+	forwarded int64
+	// consumed is the offset following the last consumed record, including
+	// the records without value which are not forwarded.
+	consumed int64
+}
+
+// Tailer consumes the records of Kafka topics with a consumer group, and
+// forwards them as messages.
+//
+// The offsets of the records are committed to the consumer group only once
+// the auditor has recorded the delivery of their messages: each partition has
+// its own identifier in the auditor registry, holding the offset of the
+// record following the last delivered one. Records without value are not
+// forwarded, their offsets are committed once the messages preceding them
+// are delivered.
+type Tailer struct {
+	source          *sources.LogSource
+	outputChan      chan *message.Message
+	registry        auditor.Registry
+	consumerFactory ConsumerFactory
+	consumer        Consumer
+
+	// partitions holds the offsets of each partition consumed by the tailer.
+	partitions   map[topicPartition]*partitionState
+	partitionsMu sync.Mutex
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTailer returns a new tailer.
+func NewTailer(source *sources.LogSource, outputChan chan *message.Message, registry auditor.Registry, consumerFactory ConsumerFactory) *Tailer {
+	return &Tailer{
+		source:          source,
+		outputChan:      outputChan,
+		registry:        registry,
+		consumerFactory: consumerFactory,
+		partitions:      make(map[topicPartition]*partitionState),
+	}
+}
+
+// Identifier returns the identifier of a partition consumed by a consumer
+// group in the auditor registry.
+func Identifier(consumerGroup string, topic string, partition int32) string {
+	return fmt.Sprintf("kafka:%s:%s:%d", consumerGroup, topic, partition)
+}
+
+// Start joins the consumer group and starts consuming the topics.
+func (t *Tailer) Start() error {
+	consumer, err := t.consumerFactory(t.source.Config, t.revoke)
+	if err != nil {
+		t.source.Status.Error(err)
+		return err
+	}
+	t.consumer = consumer
+	t.source.Status.Success()
+	log.Infof("Start consuming kafka topics %v with consumer group %s", t.source.Config.Topics, t.source.Config.ConsumerGroup)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.wg.Add(2)
+	go t.consume(ctx)
+	go t.commitLoop(ctx)
+	return nil
+}
+
+// Stop stops consuming the topics, commits the offsets of the records
+// delivered so far and leaves the consumer group.
+func (t *Tailer) Stop() {
+	log.Infof("Stop consuming kafka topics %v with consumer group %s", t.source.Config.Topics, t.source.Config.ConsumerGroup)
+	t.cancel()
+	t.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), stopCommitTimeout)
+	defer cancel()
+	t.commit(ctx, t.deliveredOffsets(nil))
+	t.consumer.Close()
+}
+
+// consume forwards the polled records until ctx is done.
+func (t *Tailer) consume(ctx context.Context) {
+	defer t.wg.Done()
+	for {
+		records, err := t.consumer.Poll(ctx)
+		if ctx.Err() != nil || errors.Is(err, errConsumerClosed) {
+			return
+		}
+		if err != nil {
+			log.Warnf("Error while consuming kafka topics %v: %v", t.source.Config.Topics, err)
+			t.source.Status.Error(err)
+			select {
+			case <-time.After(pollErrorBackoff):
+			case <-ctx.Done():
+				return
+			}
+		}
+		for _, record := range records {
+			if len(record.Value) == 0 {
+				t.track(record, false)
+				continue
+			}
+			t.track(record, true)
+			select {
+			case t.outputChan <- t.toMessage(record):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// commitLoop periodically commits the offsets of the delivered records.
+func (t *Tailer) commitLoop(ctx context.Context) {
+	defer t.wg.Done()
+	ticker := time.NewTicker(commitInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.commit(ctx, t.deliveredOffsets(nil))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// revoke commits the offsets of the records delivered from the revoked
+// partitions, and stops tracking them.
+func (t *Tailer) revoke(ctx context.Context, partitions map[string][]int32) {
+	revoked := make(map[topicPartition]bool)
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			revoked[topicPartition{topic: topic, partition: partition}] = true
+		}
+	}
+	t.commit(ctx, t.deliveredOffsets(revoked))
+
+	t.partitionsMu.Lock()
+	defer t.partitionsMu.Unlock()
+	for tp := range revoked {
+		delete(t.partitions, tp)
+	}
+}
+
+// track records the offset of a consumed record, forwarded or not. The
+// first record of a partition starts tracking it: the consumer group resumed
+// from its offset, which is the committed one. This prevents committing an
+// offset left in the registry by a previous assignment of the partition.
+func (t *Tailer) track(record *kgo.Record, forwarded bool) {
+	t.partitionsMu.Lock()
+	defer t.partitionsMu.Unlock()
+	tp := topicPartition{topic: record.Topic, partition: record.Partition}
+	state, ok := t.partitions[tp]
+	if !ok {
+		state = &partitionState{committed: record.Offset, forwarded: record.Offset}
+		t.partitions[tp] = state
+	}
+	state.consumed = record.Offset + 1
+	if forwarded {
+		state.forwarded = record.Offset + 1
+	}
+}
+
+// deliveredOffsets returns the offsets recorded by the auditor which are
+// ahead of the committed ones, by topic and partition, for the partitions
+// selected by filter or all the tracked ones when filter is nil. Once all
+// the forwarded records are delivered, the offset of the last consumed one
+// is returned.
+func (t *Tailer) deliveredOffsets(filter map[topicPartition]bool) map[string]map[int32]int64 {
+	t.partitionsMu.Lock()
+	defer t.partitionsMu.Unlock()
+	offsets := make(map[string]map[int32]int64)
+	for tp, state := range t.partitions {
+		if filter != nil && !filter[tp] {
+			continue
+		}
+		offset, err := strconv.ParseInt(t.registry.GetOffset(Identifier(t.source.Config.ConsumerGroup, tp.topic, tp.partition)), 10, 64)
+		if err != nil {
+			offset = -1
+		}
+		if state.forwarded <= state.committed || offset >= state.forwarded {
+			offset = state.consumed
+		}
+		if offset <= state.committed {
+			continue
+		}
+		if offsets[tp.topic] == nil {
+			offsets[tp.topic] = make(map[int32]int64)
+		}
+		offsets[tp.topic][tp.partition] = offset
+	}
+	return offsets
+}
+
+// commit commits the offsets to the consumer group.
+func (t *Tailer) commit(ctx context.Context, offsets map[string]map[int32]int64) {
+	if len(offsets) == 0 {
+		return
+	}
+	if err := t.consumer.Commit(ctx, offsets); err != nil {
+		log.Warnf("Could not commit the offsets of consumer group %s: %v", t.source.Config.ConsumerGroup, err)
+		return
+	}
+
+	t.partitionsMu.Lock()
+	defer t.partitionsMu.Unlock()
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			if state, ok := t.partitions[topicPartition{topic: topic, partition: partition}]; ok {
+				state.committed = offset
+			}
+		}
+	}
+}
+
+// toMessage returns the message of the record, its origin offset is the
+// offset to commit once it is delivered.
+func (t *Tailer) toMessage(record *kgo.Record) *message.Message {
+	origin := message.NewOrigin(t.source)
+	origin.Identifier = Identifier(t.source.Config.ConsumerGroup, record.Topic, record.Partition)
+	origin.Offset = strconv.FormatInt(record.Offset+1, 10)
+	origin.SetTags(t.recordTags(record))
+	t.source.RecordBytes(int64(len(record.Value)))
+	return message.NewMessage(record.Value, origin, message.StatusInfo, time.Now().UnixNano())
+}
+
+// recordTags returns the topic, partition, key and headers of the record as
+// tags. Only the headers listed in the header_tags of the source are kept if
+// any is listed.
+func (t *Tailer) recordTags(record *kgo.Record) []string {
+	tags := []string{
+		"kafka_topic:" + record.Topic,
+		"kafka_partition:" + strconv.FormatInt(int64(record.Partition), 10),
+	}
+	if len(record.Key) > 0 {
+		tags = append(tags, "kafka_key:"+string(record.Key))
+	}
+	headerTags := t.source.Config.HeaderTags
+	for _, header := range record.Headers {
+		if len(headerTags) > 0 && !slices.Contains(headerTags, header.Key) {
+			continue
+		}
+		tags = append(tags, header.Key+":"+string(header.Value))
+	}
+	return tags
+}
@@ -0,0 +1,288 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package kafka
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// mockConsumer returns the records sent to its records channel, and records
+// the committed offsets.
+type mockConsumer struct {
+	records chan []*kgo.Record
+
+	mu        sync.Mutex
+	committed map[string]map[int32]int64
+	closed    bool
+}
+
+func newMockConsumer() *mockConsumer {
+	return &mockConsumer{
+		records:   make(chan []*kgo.Record, 10),
+		committed: make(map[string]map[int32]int64),
+	}
+}
+
+func (c *mockConsumer) Poll(ctx context.Context) ([]*kgo.Record, error) {
+	select {
+	case records := <-c.records:
+		return records, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *mockConsumer) Commit(_ context.Context, offsets map[string]map[int32]int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, partitions := range offsets {
+		if c.committed[topic] == nil {
+			c.committed[topic] = make(map[int32]int64)
+		}
+		for partition, offset := range partitions {
+			c.committed[topic][partition] = offset
+		}
+	}
+	return nil
+}
+
+func (c *mockConsumer) Close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+}
+
+func (c *mockConsumer) committedOffset(topic string, partition int32) (int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	offset, ok := c.committed[topic][partition]
+	return offset, ok
+}
+
+// registry holds an offset per identifier, as the auditor does.
+type registry struct {
+	mu      sync.Mutex
+	offsets map[string]string
+}
+
+func newRegistry() *registry {
+	return &registry{offsets: make(map[string]string)}
+}
+
+func (r *registry) GetOffset(identifier string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.offsets[identifier]
+}
+
+func (r *registry) GetTailingMode(_ string) string { return "" }
+
+func (r *registry) IsCompleted(_ string) bool { return false }
+
+func (r *registry) MarkCompleted(_ string, _ string) {}
+
+// deliver records the offset of the message as the auditor does once it is
+// sent.
+func (r *registry) deliver(msg *message.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.offsets[msg.Origin.Identifier] = msg.Origin.Offset
+}
+
+func newTestTailer(t *testing.T, cfg *config.LogsConfig) (*Tailer, *mockConsumer, *registry, chan *message.Message) {
+	consumer := newMockConsumer()
+	registry := newRegistry()
+	outputChan := make(chan *message.Message, 10)
+	factory := func(_ *config.LogsConfig, _ func(context.Context, map[string][]int32)) (Consumer, error) {
+		return consumer, nil
+	}
+	tailer := NewTailer(sources.NewLogSource("", cfg), outputChan, registry, factory)
+	require.NoError(t, tailer.Start())
+	return tailer, consumer, registry, outputChan
+}
+
+func TestTailerForwardsRecordsWithTags(t *testing.T) {
+	tailer, consumer, _, outputChan := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+		Tags:          []string{"env:test"},
+	})
+	defer tailer.Stop()
+
+	consumer.records <- []*kgo.Record{{
+		Topic:     "logs",
+		Partition: 2,
+		Offset:    41,
+		Key:       []byte("user-1"),
+		Value:     []byte("hello world"),
+		Headers:   []kgo.RecordHeader{{Key: "service", Value: []byte("web")}},
+	}}
+
+	msg := <-outputChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.Equal(t, "kafka:datadog:logs:2", msg.Origin.Identifier)
+	assert.Equal(t, "42", msg.Origin.Offset)
+	assert.Equal(t, []string{"kafka_topic:logs", "kafka_partition:2", "kafka_key:user-1", "service:web", "env:test"}, msg.Tags())
+}
+
+func TestTailerKeepsListedHeaderTags(t *testing.T) {
+	tailer, consumer, _, outputChan := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+		HeaderTags:    []string{"team"},
+	})
+	defer tailer.Stop()
+
+	consumer.records <- []*kgo.Record{{
+		Topic:   "logs",
+		Value:   []byte("hello world"),
+		Headers: []kgo.RecordHeader{{Key: "service", Value: []byte("web")}, {Key: "team", Value: []byte("logs")}},
+	}}
+
+	msg := <-outputChan
+	assert.Equal(t, []string{"kafka_topic:logs", "kafka_partition:0", "team:logs"}, msg.Tags())
+}
+
+func TestTailerCommitsDeliveredOffsets(t *testing.T) {
+	tailer, consumer, registry, outputChan := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+	})
+
+	consumer.records <- []*kgo.Record{
+		{Topic: "logs", Partition: 0, Offset: 10, Value: []byte("first")},
+		{Topic: "logs", Partition: 0, Offset: 11, Value: []byte("second")},
+	}
+	first := <-outputChan
+	<-outputChan
+
+	// nothing is committed until the auditor records a delivery
+	time.Sleep(2 * commitInterval)
+	_, committed := consumer.committedOffset("logs", 0)
+	assert.False(t, committed)
+
+	registry.deliver(first)
+	assert.Eventually(t, func() bool {
+		offset, _ := consumer.committedOffset("logs", 0)
+		return offset == 11
+	}, 5*time.Second, 10*time.Millisecond)
+
+	tailer.Stop()
+	assert.True(t, consumer.closed)
+}
+
+func TestTailerCommitsRevokedPartitions(t *testing.T) {
+	tailer, consumer, registry, outputChan := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+	})
+	defer tailer.Stop()
+
+	consumer.records <- []*kgo.Record{
+		{Topic: "logs", Partition: 0, Offset: 5, Value: []byte("first")},
+		{Topic: "logs", Partition: 1, Offset: 7, Value: []byte("second")},
+	}
+	registry.deliver(<-outputChan)
+	registry.deliver(<-outputChan)
+
+	tailer.revoke(context.Background(), map[string][]int32{"logs": {1}})
+
+	offset, committed := consumer.committedOffset("logs", 1)
+	assert.True(t, committed)
+	assert.Equal(t, int64(8), offset)
+
+	tailer.partitionsMu.Lock()
+	defer tailer.partitionsMu.Unlock()
+	assert.NotContains(t, tailer.partitions, topicPartition{topic: "logs", partition: 1})
+	assert.Contains(t, tailer.partitions, topicPartition{topic: "logs", partition: 0})
+}
+
+func TestTailerDoesNotCommitStaleOffsetsOnReassign(t *testing.T) {
+	tailer, consumer, registry, outputChan := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+	})
+	defer tailer.Stop()
+
+	// the partition was consumed up to offset 8 by the tailer before being
+	// revoked, and up to 20 by another consumer of the group since then
+	registry.offsets[Identifier("datadog", "logs", 0)] = "8"
+	consumer.records <- []*kgo.Record{
+		{Topic: "logs", Partition: 0, Offset: 20, Value: []byte("hello world")},
+	}
+	msg := <-outputChan
+
+	time.Sleep(2 * commitInterval)
+	_, committed := consumer.committedOffset("logs", 0)
+	assert.False(t, committed)
+
+	registry.deliver(msg)
+	assert.Eventually(t, func() bool {
+		offset, _ := consumer.committedOffset("logs", 0)
+		return offset == 21
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTailerSkipsEmptyRecords(t *testing.T) {
+	tailer, consumer, registry, outputChan := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+	})
+	defer tailer.Stop()
+
+	consumer.records <- []*kgo.Record{
+		{Topic: "logs", Offset: 1},
+		{Topic: "logs", Offset: 2, Value: []byte("hello world")},
+		{Topic: "logs", Offset: 3},
+		{Topic: "logs", Offset: 4},
+	}
+
+	msg := <-outputChan
+	assert.Equal(t, "3", msg.Origin.Offset)
+
+	// the empty records following the delivered message are committed too
+	registry.deliver(msg)
+	assert.Eventually(t, func() bool {
+		offset, _ := consumer.committedOffset("logs", 0)
+		return offset == 5
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestTailerCommitsEmptyRecordsWithoutMessages(t *testing.T) {
+	tailer, consumer, _, _ := newTestTailer(t, &config.LogsConfig{
+		Type:          config.KafkaType,
+		ConsumerGroup: "datadog",
+		Topics:        []string{"logs"},
+	})
+	defer tailer.Stop()
+
+	consumer.records <- []*kgo.Record{
+		{Topic: "logs", Offset: 7},
+		{Topic: "logs", Offset: 8},
+	}
+
+	assert.Eventually(t, func() bool {
+		offset, _ := consumer.committedOffset("logs", 0)
+		return offset == 9
+	}, 5*time.Second, 10*time.Millisecond)
+}
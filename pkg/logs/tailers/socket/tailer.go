@@ -12,9 +12,12 @@ import (
 	"net"
 	"strings"
 
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/framer"
 	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/noop"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers/syslog"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
 	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
@@ -40,13 +43,28 @@ func NewTailer(source *sources.LogSource, conn net.Conn, outputChan chan *messag
 		Conn:       conn,
 		outputChan: outputChan,
 		read:       read,
-		// tailer info is currently unused for this tailer type.
-		decoder: decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New(), status.NewInfoRegistry()),
-		stop:    make(chan struct{}, 1),
-		done:    make(chan struct{}, 1),
+		decoder:    buildDecoder(source),
+		stop:       make(chan struct{}, 1),
+		done:       make(chan struct{}, 1),
 	}
 }
 
+// buildDecoder returns a decoder parsing syslog messages for syslog sources,
+// and a decoder leaving the content untouched for the other ones.
+// Octet-counted frames are only expected on stream transports, each UDP
+// datagram already holds a single message.
+func buildDecoder(source *sources.LogSource) *decoder.Decoder {
+	// tailer info is currently unused for this tailer type.
+	if source.Config.Type == config.SyslogType {
+		framing := framer.UTF8Newline
+		if source.Config.SyslogProtocol() == config.TCPType {
+			framing = framer.OctetCounting
+		}
+		return decoder.NewDecoderWithFraming(sources.NewReplaceableSource(source), syslog.New(), framing, nil, status.NewInfoRegistry())
+	}
+	return decoder.InitializeDecoder(sources.NewReplaceableSource(source), noop.New(), status.NewInfoRegistry())
+}
+
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
 	go t.forwardMessages()
@@ -71,7 +89,11 @@ func (t *Tailer) forwardMessages() {
 		if len(output.GetContent()) > 0 {
 			origin := message.NewOrigin(t.source)
 			origin.SetTags(output.ParsingExtra.Tags)
-			t.outputChan <- message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			msg := message.NewMessage(output.GetContent(), origin, output.Status, output.IngestionTimestamp)
+			// timestamp and attributes parsed from the content, if any
+			msg.LogTimestamp = output.LogTimestamp
+			msg.ProcessingAttributes = output.ProcessingAttributes
+			t.outputChan <- msg
 		}
 	}
 }
@@ -6,6 +6,8 @@
 package metrics
 
 import (
+	"math"
+
 	taggertypes "github.com/DataDog/datadog-agent/pkg/tagger/types"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
 )
@@ -108,6 +110,34 @@ type MetricSample struct {
 	ListenerID      string
 	NoIndex         bool
 	Source          MetricSource
+	// Summary is set on distribution samples aggregated by the client, and
+	// is merged in the sketch of the context in place of Value.
+	Summary *DistributionSummary
+}
+
+// MaxDistributionSummaryCount is the largest count of a DistributionSummary.
+// The sketches store the counts in bins holding up to 65535 values each, and
+// the bins needed for larger counts would take too much memory.
+const MaxDistributionSummaryCount = math.MaxUint32
+
+// DistributionSummary is a distribution aggregated by the client.
+type DistributionSummary struct {
+	// Count is at most MaxDistributionSummaryCount
+	Count uint64
+	Sum   float64
+	Min   float64
+	Max   float64
+	// Buckets holds the values of the distribution with their number of
+	// occurrences, their counts add up to Count. When empty, the values are
+	// considered evenly spread between Min and Max.
+	Buckets []DistributionBucket
+}
+
+// DistributionBucket is a value of a DistributionSummary, occurring Count
+// times.
+type DistributionBucket struct {
+	Value float64
+	Count uint64
 }
 
 // Implement the MetricSampleContext interface
@@ -0,0 +1,318 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// ObfuscatedGraphQL holds an obfuscated GraphQL document.
+type ObfuscatedGraphQL struct {
+	// Query holds the obfuscated and normalized document.
+	Query string
+
+	// Signature identifies the document once obfuscated: documents which only
+	// differ in their literal values, their whitespaces or their comments have
+	// the same signature.
+	Signature string
+}
+
+// graphqlContext is the kind of the brackets the obfuscator is in.
+type graphqlContext int
+
+const (
+	// graphqlSelection is a selection set: "{ field }".
+	graphqlSelection graphqlContext = iota
+	// graphqlArguments are the arguments of a field or a directive: "(arg: value)".
+	graphqlArguments
+	// graphqlVariables are the variable definitions of an operation: "($var: Type = value)".
+	graphqlVariables
+	// graphqlObject is an input object value: "{field: value}".
+	graphqlObject
+	// graphqlList is a list value: "[value]".
+	graphqlList
+)
+
+type graphqlFrame struct {
+	ctx graphqlContext
+	// items counts the items written, to separate them with commas.
+	items int
+	// placeholder reports whether the last value written in a list was an
+	// obfuscated literal, so that consecutive ones are collapsed.
+	placeholder bool
+}
+
+// graphqlObfuscator rewrites a GraphQL document, replacing its literal values
+// with "?" and normalizing its whitespaces.
+type graphqlObfuscator struct {
+	out   strings.Builder
+	prev  string
+	stack []graphqlFrame
+	// value reports whether a value is expected next.
+	value bool
+	// typ reports whether the type of a variable definition is being written.
+	typ bool
+}
+
+var errGraphQLUnbalanced = errors.New("unbalanced brackets")
+
+// ObfuscateGraphQLString obfuscates the GraphQL document query: argument,
+// variable default and inline literal values are replaced with "?", and the
+// document is normalized to single spaces without comments. Fragment
+// definitions are removed when GraphQLConfig.RemoveFragments is set.
+func (o *Obfuscator) ObfuscateGraphQLString(query string) (*ObfuscatedGraphQL, error) {
+	var g graphqlObfuscator
+	t := newGraphQLTokenizer(query)
+	// fragmentDepth is the brace depth of the fragment definition being
+	// removed, or -1 outside of one.
+	fragmentDepth := -1
+	for {
+		tok, typ, err := t.scan()
+		if err != nil {
+			return nil, err
+		}
+		if typ == graphqlTokenEOF {
+			break
+		}
+		if fragmentDepth >= 0 {
+			switch tok {
+			case "{":
+				fragmentDepth++
+			case "}":
+				fragmentDepth--
+				if fragmentDepth == 0 {
+					fragmentDepth = -1
+				}
+			}
+			continue
+		}
+		if o.opts.GraphQL.RemoveFragments && typ == graphqlTokenName && tok == "fragment" && len(g.stack) == 0 {
+			fragmentDepth = 0
+			continue
+		}
+		if err := g.write(tok, typ); err != nil {
+			return nil, err
+		}
+	}
+	if len(g.stack) > 0 || fragmentDepth > 0 {
+		return nil, errGraphQLUnbalanced
+	}
+	q := g.out.String()
+	h := fnv.New64a()
+	h.Write([]byte(q))
+	return &ObfuscatedGraphQL{
+		Query:     q,
+		Signature: strconv.FormatUint(h.Sum64(), 16),
+	}, nil
+}
+
+func (g *graphqlObfuscator) top() *graphqlFrame {
+	if len(g.stack) == 0 {
+		return nil
+	}
+	return &g.stack[len(g.stack)-1]
+}
+
+func (g *graphqlObfuscator) push(ctx graphqlContext) {
+	g.stack = append(g.stack, graphqlFrame{ctx: ctx})
+}
+
+func (g *graphqlObfuscator) pop(ctx graphqlContext) error {
+	if f := g.top(); f == nil || f.ctx != ctx {
+		return errGraphQLUnbalanced
+	}
+	g.stack = g.stack[:len(g.stack)-1]
+	return nil
+}
+
+// item starts a new item of the arguments, variables, object or list the
+// obfuscator is in, separating it from the previous one.
+func (g *graphqlObfuscator) item() {
+	f := g.top()
+	if f == nil || f.ctx == graphqlSelection {
+		return
+	}
+	if f.items > 0 {
+		g.emit(",")
+	}
+	f.items++
+	f.placeholder = false
+}
+
+// listItem starts a new item when the obfuscator is in a list.
+func (g *graphqlObfuscator) listItem() {
+	if f := g.top(); f != nil && f.ctx == graphqlList {
+		g.item()
+	}
+}
+
+// valueDone is called once a whole value was written.
+func (g *graphqlObfuscator) valueDone() {
+	f := g.top()
+	g.value = f != nil && f.ctx == graphqlList
+}
+
+// placeholder writes "?" in place of a literal value.
+func (g *graphqlObfuscator) placeholder() {
+	if f := g.top(); f != nil && f.ctx == graphqlList {
+		if f.placeholder {
+			// consecutive literals of a list are collapsed
+			return
+		}
+		g.item()
+		f = g.top()
+		f.placeholder = true
+		g.emit("?")
+		return
+	}
+	g.emit("?")
+}
+
+func (g *graphqlObfuscator) write(tok string, typ graphqlTokenType) error {
+	if g.value {
+		return g.writeValue(tok, typ)
+	}
+	f := g.top()
+	switch typ {
+	case graphqlTokenString, graphqlTokenNumber:
+		// literals out of a value, such as descriptions
+		g.emit("?")
+		return nil
+	case graphqlTokenVariable:
+		if f != nil && f.ctx == graphqlVariables {
+			g.typ = false
+			g.item()
+		}
+		g.emit(tok)
+		return nil
+	case graphqlTokenName:
+		if f != nil && (f.ctx == graphqlArguments || f.ctx == graphqlObject) && !g.typ {
+			g.item()
+		}
+		g.emit(tok)
+		return nil
+	}
+	switch tok {
+	case ":":
+		g.emit(tok)
+		if f != nil && f.ctx == graphqlVariables && !g.typ {
+			g.typ = true
+			return nil
+		}
+		if f != nil && (f.ctx == graphqlArguments || f.ctx == graphqlObject) {
+			g.value = true
+		}
+	case "=":
+		g.emit(tok)
+		g.typ = false
+		g.value = true
+	case "@":
+		g.typ = false
+		g.emit(tok)
+	case "(":
+		g.emit(tok)
+		if f == nil {
+			g.push(graphqlVariables)
+		} else {
+			g.push(graphqlArguments)
+		}
+	case ")":
+		g.typ = false
+		if f != nil && f.ctx == graphqlVariables {
+			return g.popEmit(graphqlVariables, tok)
+		}
+		return g.popEmit(graphqlArguments, tok)
+	case "{":
+		g.emit(tok)
+		g.push(graphqlSelection)
+	case "}":
+		if f != nil && f.ctx == graphqlObject {
+			if err := g.popEmit(graphqlObject, tok); err != nil {
+				return err
+			}
+			g.valueDone()
+			return nil
+		}
+		return g.popEmit(graphqlSelection, tok)
+	default:
+		// "[", "]" and "!" of types, "...", "&" and "|"
+		g.emit(tok)
+	}
+	return nil
+}
+
+func (g *graphqlObfuscator) popEmit(ctx graphqlContext, tok string) error {
+	if err := g.pop(ctx); err != nil {
+		return err
+	}
+	g.emit(tok)
+	return nil
+}
+
+// writeValue writes the token of a value.
+func (g *graphqlObfuscator) writeValue(tok string, typ graphqlTokenType) error {
+	switch typ {
+	case graphqlTokenString, graphqlTokenNumber, graphqlTokenName:
+		// strings, numbers, booleans, null and enum values
+		g.placeholder()
+		g.valueDone()
+		return nil
+	case graphqlTokenVariable:
+		g.listItem()
+		g.emit(tok)
+		g.valueDone()
+		return nil
+	}
+	switch tok {
+	case "[":
+		g.listItem()
+		g.emit(tok)
+		g.push(graphqlList)
+		return nil
+	case "]":
+		if err := g.popEmit(graphqlList, tok); err != nil {
+			return err
+		}
+		g.valueDone()
+		return nil
+	case "{":
+		g.listItem()
+		g.emit(tok)
+		g.push(graphqlObject)
+		g.value = false
+		return nil
+	}
+	return fmt.Errorf("unexpected %q in value", tok)
+}
+
+// emit writes the token, with a space before it when needed.
+func (g *graphqlObfuscator) emit(tok string) {
+	if g.out.Len() > 0 && graphqlSpaceBetween(g.prev, tok) {
+		g.out.WriteByte(' ')
+	}
+	g.out.WriteString(tok)
+	g.prev = tok
+}
+
+func graphqlSpaceBetween(prev, tok string) bool {
+	switch prev {
+	case "(", "[", "@", "$":
+		return false
+	case "...":
+		return tok == "on" || tok == "{" || tok == "@"
+	case "{":
+		return tok != "}"
+	}
+	switch tok {
+	case "(", ")", "]", ":", "!", ",":
+		return false
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObfuscateGraphQL(t *testing.T) {
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{ user(id: 42) { name } }`,
+			`{ user(id: ?) { name } }`,
+		},
+		{
+			`query GetUser($id: ID!, $limit: Int = 10) {
+				user(id: $id, email: "jane@example.com", token: """secret""") {
+					name
+					posts(first: $limit, orderBy: CREATED_AT, published: true, tags: ["a", "b", $tag]) {
+						title
+					}
+				}
+			}`,
+			`query GetUser($id: ID!, $limit: Int = ?) { user(id: $id, email: ?, token: ?) { name posts(first: $limit, orderBy: ?, published: ?, tags: [?, $tag]) { title } } }`,
+		},
+		{
+			`mutation {
+				# create the account
+				createUser(input: {email: "jane@example.com", roles: [ADMIN], address: {zip: 75001, city: null}}) @include(if: false) {
+					id
+				}
+			}`,
+			`mutation { createUser(input: { email: ?, roles: [?], address: { zip: ?, city: ? } }) @include(if: ?) { id } }`,
+		},
+		{
+			`query Q($ids: [ID!]! = ["1", "2"], $filter: Filter = {}) { nodes(ids: $ids, filter: $filter, empty: []) { ... on User { name } ...Fields } }
+			fragment Fields on User { email(format: "short") }`,
+			`query Q($ids: [ID!]! = [?], $filter: Filter = {}) { nodes(ids: $ids, filter: $filter, empty: []) { ... on User { name } ...Fields } } fragment Fields on User { email(format: ?) }`,
+		},
+		{
+			`{ a: user(id: 1) { id } b: user(id: 2) { id } }`,
+			`{ a: user(id: ?) { id } b: user(id: ?) { id } }`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			o := NewObfuscator(Config{})
+			oq, err := o.ObfuscateGraphQLString(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.out, oq.Query)
+		})
+	}
+}
+
+func TestObfuscateGraphQLRemoveFragments(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true, RemoveFragments: true}})
+	oq, err := o.ObfuscateGraphQLString(`query { me { ...Fields } } fragment Fields on User { name friends { ...Other } } fragment Other on User { id }`)
+	require.NoError(t, err)
+	assert.Equal(t, `query { me { ...Fields } }`, oq.Query)
+}
+
+func TestObfuscateGraphQLSignature(t *testing.T) {
+	o := NewObfuscator(Config{})
+	a, err := o.ObfuscateGraphQLString(`query Q { user(id: 1, email: "a@example.com") { name } }`)
+	require.NoError(t, err)
+	b, err := o.ObfuscateGraphQLString("query Q {\n  # the user\n  user(id: 2\n email: \"b@example.com\") {\n    name\n  }\n}")
+	require.NoError(t, err)
+	c, err := o.ObfuscateGraphQLString(`query Q { user(id: 1) { name } }`)
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, a.Signature)
+	assert.Equal(t, a.Signature, b.Signature)
+	assert.NotEqual(t, a.Signature, c.Signature)
+}
+
+func TestObfuscateGraphQLErrors(t *testing.T) {
+	o := NewObfuscator(Config{})
+	for _, in := range []string{
+		`{ user(id: 1) { name }`,
+		`{ user(id: 1)) { name } }`,
+		`{ user(id: "unterminated) }`,
+		`{ user(id: :) }`,
+		`{ user(id: [1) }`,
+	} {
+		_, err := o.ObfuscateGraphQLString(in)
+		assert.Error(t, err, in)
+	}
+}
@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// graphqlTokenType specifies the token type returned by the tokenizer.
+type graphqlTokenType int
+
+const (
+	// graphqlTokenEOF is returned once the whole document was scanned.
+	graphqlTokenEOF graphqlTokenType = iota
+
+	// graphqlTokenPunct is a punctuator, such as "{", ":" or "...".
+	graphqlTokenPunct
+
+	// graphqlTokenName is a name: a keyword, a field, an argument, a type or
+	// an enum value.
+	graphqlTokenName
+
+	// graphqlTokenVariable is a variable, including its "$" prefix.
+	graphqlTokenVariable
+
+	// graphqlTokenNumber is an integer or float literal.
+	graphqlTokenNumber
+
+	// graphqlTokenString is a string or block string literal, including its
+	// quotes.
+	graphqlTokenString
+)
+
+// String implements fmt.Stringer.
+func (t graphqlTokenType) String() string {
+	return map[graphqlTokenType]string{
+		graphqlTokenEOF:      "EOF",
+		graphqlTokenPunct:    "punctuator",
+		graphqlTokenName:     "name",
+		graphqlTokenVariable: "variable",
+		graphqlTokenNumber:   "number",
+		graphqlTokenString:   "string",
+	}[t]
+}
+
+var errGraphQLUnterminatedString = errors.New("unterminated string")
+
+// graphqlTokenizer tokenizes a GraphQL document. Whitespaces, commas and
+// comments are ignored, as they are insignificant in GraphQL.
+type graphqlTokenizer struct {
+	data string
+	off  int
+}
+
+// newGraphQLTokenizer returns a new tokenizer for the given document.
+func newGraphQLTokenizer(data string) *graphqlTokenizer {
+	return &graphqlTokenizer{data: data}
+}
+
+// scan returns the next token and its type. It returns graphqlTokenEOF at
+// the end of the document.
+func (t *graphqlTokenizer) scan() (tok string, typ graphqlTokenType, err error) {
+	t.skipIgnored()
+	if t.off >= len(t.data) {
+		return "", graphqlTokenEOF, nil
+	}
+	start := t.off
+	c := t.data[t.off]
+	switch {
+	case isGraphQLNameStart(c):
+		t.scanName()
+		return t.data[start:t.off], graphqlTokenName, nil
+	case c == '$':
+		t.off++
+		if t.off >= len(t.data) || !isGraphQLNameStart(t.data[t.off]) {
+			return "", graphqlTokenEOF, fmt.Errorf("invalid variable at position %d", start)
+		}
+		t.scanName()
+		return t.data[start:t.off], graphqlTokenVariable, nil
+	case c == '-' || isDigit(rune(c)):
+		t.scanNumber()
+		return t.data[start:t.off], graphqlTokenNumber, nil
+	case c == '"':
+		if err := t.scanString(); err != nil {
+			return "", graphqlTokenEOF, err
+		}
+		return t.data[start:t.off], graphqlTokenString, nil
+	case c == '.':
+		if len(t.data)-t.off < 3 || t.data[t.off:t.off+3] != "..." {
+			return "", graphqlTokenEOF, fmt.Errorf("unexpected %q at position %d", c, start)
+		}
+		t.off += 3
+		return "...", graphqlTokenPunct, nil
+	}
+	switch c {
+	case '!', '&', '(', ')', ':', '=', '@', '[', ']', '{', '|', '}':
+		t.off++
+		return t.data[start:t.off], graphqlTokenPunct, nil
+	}
+	return "", graphqlTokenEOF, fmt.Errorf("unexpected %q at position %d", c, start)
+}
+
+// skipIgnored skips the whitespaces, commas and comments.
+func (t *graphqlTokenizer) skipIgnored() {
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case ' ', '\t', '\n', '\r', ',':
+			t.off++
+		case '#':
+			for t.off < len(t.data) && t.data[t.off] != '\n' && t.data[t.off] != '\r' {
+				t.off++
+			}
+		default:
+			if len(t.data)-t.off >= 3 && t.data[t.off:t.off+3] == "\ufeff" {
+				t.off += 3
+				continue
+			}
+			return
+		}
+	}
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (t *graphqlTokenizer) scanName() {
+	for t.off < len(t.data) {
+		c := t.data[t.off]
+		if !isGraphQLNameStart(c) && !isDigit(rune(c)) {
+			return
+		}
+		t.off++
+	}
+}
+
+// scanNumber scans an integer or a float. It is lenient, any malformed
+// number is still a literal to obfuscate.
+func (t *graphqlTokenizer) scanNumber() {
+	t.off++ // sign or first digit
+	for t.off < len(t.data) {
+		c := t.data[t.off]
+		switch {
+		case isDigit(rune(c)) || c == '.' || c == 'e' || c == 'E':
+			t.off++
+		case (c == '+' || c == '-') && (t.data[t.off-1] == 'e' || t.data[t.off-1] == 'E'):
+			t.off++
+		default:
+			return
+		}
+	}
+}
+
+// scanString scans a string or a block string.
+func (t *graphqlTokenizer) scanString() error {
+	if len(t.data)-t.off >= 3 && t.data[t.off:t.off+3] == `"""` {
+		t.off += 3
+		for t.off < len(t.data) {
+			switch {
+			case t.data[t.off] == '\\' && len(t.data)-t.off >= 4 && t.data[t.off+1:t.off+4] == `"""`:
+				// escaped triple quote
+				t.off += 4
+			case len(t.data)-t.off >= 3 && t.data[t.off:t.off+3] == `"""`:
+				t.off += 3
+				return nil
+			default:
+				t.off++
+			}
+		}
+		return errGraphQLUnterminatedString
+	}
+	t.off++
+	for t.off < len(t.data) {
+		switch t.data[t.off] {
+		case '\\':
+			t.off += 2
+		case '"':
+			t.off++
+			return nil
+		case '\n', '\r':
+			return errGraphQLUnterminatedString
+		default:
+			t.off++
+		}
+	}
+	return errGraphQLUnterminatedString
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGraphQLTokenizer(t *testing.T) {
+	type testResult struct {
+		tok string
+		typ graphqlTokenType
+	}
+	for _, tt := range []struct {
+		in  string
+		out []testResult
+	}{
+		{
+			in:  "",
+			out: nil,
+		},
+		{
+			in: "query Q($id: ID!, $n: [Int] = [1, -2.5e+3]) # comment\n{ ...F }",
+			out: []testResult{
+				{"query", graphqlTokenName},
+				{"Q", graphqlTokenName},
+				{"(", graphqlTokenPunct},
+				{"$id", graphqlTokenVariable},
+				{":", graphqlTokenPunct},
+				{"ID", graphqlTokenName},
+				{"!", graphqlTokenPunct},
+				{"$n", graphqlTokenVariable},
+				{":", graphqlTokenPunct},
+				{"[", graphqlTokenPunct},
+				{"Int", graphqlTokenName},
+				{"]", graphqlTokenPunct},
+				{"=", graphqlTokenPunct},
+				{"[", graphqlTokenPunct},
+				{"1", graphqlTokenNumber},
+				{"-2.5e+3", graphqlTokenNumber},
+				{"]", graphqlTokenPunct},
+				{")", graphqlTokenPunct},
+				{"{", graphqlTokenPunct},
+				{"...", graphqlTokenPunct},
+				{"F", graphqlTokenName},
+				{"}", graphqlTokenPunct},
+			},
+		},
+		{
+			in: `"a \"quoted\" string" """block "string" \""" here"""`,
+			out: []testResult{
+				{`"a \"quoted\" string"`, graphqlTokenString},
+				{`"""block "string" \""" here"""`, graphqlTokenString},
+			},
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			tokenizer := newGraphQLTokenizer(tt.in)
+			var out []testResult
+			for {
+				tok, typ, err := tokenizer.scan()
+				require.NoError(t, err)
+				if typ == graphqlTokenEOF {
+					break
+				}
+				out = append(out, testResult{tok, typ})
+			}
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestGraphQLTokenizerErrors(t *testing.T) {
+	for _, in := range []string{
+		`{ a(b: "unterminated) }`,
+		`{ a(b: """unterminated) }`,
+		"{ a(b: \"new\nline\") }",
+		`{ a(b: $) }`,
+		`{ a.b }`,
+		`{ a(b: ~) }`,
+	} {
+		t.Run("", func(t *testing.T) {
+			tokenizer := newGraphQLTokenizer(in)
+			for {
+				_, typ, err := tokenizer.scan()
+				if err != nil {
+					return
+				}
+				if typ == graphqlTokenEOF {
+					t.Fatalf("no error tokenizing %q", in)
+				}
+			}
+		})
+	}
+}
@@ -40,6 +40,9 @@ type Obfuscator struct {
 	// queryCache keeps a cache of already obfuscated queries.
 	queryCache *measuredCache
 	log        Logger
+	// dialectModeWarned reports whether the obfuscation mode being ignored for
+	// the CQL and PartiQL queries has been logged.
+	dialectModeWarned atomic.Bool
 }
 
 // Logger is able to log certain log messages.
@@ -48,6 +51,23 @@ type Logger interface {
 	Debugf(format string, params ...interface{})
 }
 
+// WarnLogger is a Logger also able to log warnings. The warnings are logged
+// as debug messages by the loggers which don't implement it.
+type WarnLogger interface {
+	Logger
+	// Warnf logs the given warning using the given format.
+	Warnf(format string, params ...interface{})
+}
+
+// warnf logs a warning with the logger of the obfuscator.
+func (o *Obfuscator) warnf(format string, params ...interface{}) {
+	if l, ok := o.log.(WarnLogger); ok {
+		l.Warnf(format, params...)
+		return
+	}
+	o.log.Debugf(format, params...)
+}
+
 type noopLogger struct{}
 
 func (noopLogger) Debugf(_ string, _ ...interface{}) {}
@@ -101,6 +121,9 @@ type Config struct {
 	// Memcached holds the obfuscation settings for Memcached commands.
 	Memcached MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the obfuscation settings for GraphQL queries.
+	GraphQL GraphQLConfig `mapstructure:"graphql"`
+
 	// Memcached holds the obfuscation settings for obfuscation of CC numbers in meta.
 	CreditCard CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -259,6 +282,16 @@ type MemcachedConfig struct {
 	KeepCommand bool `mapstructure:"keep_command"`
 }
 
+// GraphQLConfig holds the configuration settings for GraphQL obfuscation
+type GraphQLConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// RemoveFragments specifies whether fragment definitions should be
+	// removed from the obfuscated queries. Fragment spreads are kept.
+	RemoveFragments bool `mapstructure:"remove_fragments"`
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
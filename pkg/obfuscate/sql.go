@@ -25,6 +25,9 @@ type metadataFinderFilter struct {
 	collectCommands   bool
 	collectComments   bool
 	replaceDigits     bool
+	// quotedIDs reports whether the identifiers keep their double quotes, as
+	// they do in CQL and PartiQL.
+	quotedIDs bool
 
 	// size holds the byte size of the metadata collected by the filter.
 	size int64
@@ -59,7 +62,7 @@ func (f *metadataFinderFilter) Filter(token, lastToken TokenKind, buffer []byte)
 			// SELECT ... FROM [tableName]
 			// DELETE FROM [tableName]
 			// ... JOIN [tableName]
-			if r, _ := utf8.DecodeRune(buffer); !unicode.IsLetter(r) {
+			if r, _ := utf8.DecodeRune(buffer); !unicode.IsLetter(r) && !(f.quotedIDs && r == '"') {
 				// first character in buffer is not a letter; we might have a nested
 				// query like SELECT * FROM (SELECT ...)
 				break
@@ -74,6 +77,9 @@ func (f *metadataFinderFilter) Filter(token, lastToken TokenKind, buffer []byte)
 				copy(tableNameCopy, buffer)
 				tableName = string(replaceDigits(tableNameCopy))
 			}
+			if f.quotedIDs {
+				tableName = strings.ReplaceAll(tableName, `"`, "")
+			}
 			f.storeTableName(tableName)
 			return TableName, buffer, nil
 		}
@@ -191,7 +197,7 @@ func (f *replaceFilter) Filter(token, lastToken TokenKind, buffer []byte) (token
 		}
 	}
 	switch token {
-	case DollarQuotedString, String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence:
+	case DollarQuotedString, String, Number, Null, Variable, PreparedStatement, BooleanLiteral, EscapeSequence, CollectionLiteral:
 		return markFilteredGroupable(token), questionMark, nil
 	case '?':
 		// Cases like 'ARRAY [ ?, ? ]' should be collapsed into 'ARRAY [ ? ]'
@@ -299,7 +305,20 @@ func (o *Obfuscator) ObfuscateSQLString(in string) (*ObfuscatedQuery, error) {
 }
 
 // ObfuscateSQLStringForDBMS quantizes and obfuscates the given input SQL query string for a specific DBMS.
+// CQL (DBMSCassandra) and PartiQL (DBMSDynamoDB) queries are always obfuscated with the tokenizer aware
+// of their dialect, whatever the obfuscation mode, which is logged once.
 func (o *Obfuscator) ObfuscateSQLStringForDBMS(in string, dbms string) (*ObfuscatedQuery, error) {
+	switch dbms {
+	case DBMSCassandra, DBMSDynamoDB:
+		// go-sqllexer doesn't support these dialects
+		if isSQLLexer(o.opts.SQL.ObfuscationMode) && o.dialectModeWarned.CompareAndSwap(false, true) {
+			o.warnf("The SQL obfuscation mode %q doesn't support CQL and PartiQL queries, they are obfuscated without it", o.opts.SQL.ObfuscationMode)
+		}
+		opts := o.opts.SQL
+		opts.DBMS = dbms
+		opts.ObfuscationMode = ""
+		return o.ObfuscateSQLStringWithOptions(in, &opts)
+	}
 	if isSQLLexer(o.opts.SQL.ObfuscationMode) {
 		o.opts.SQL.DBMS = dbms
 	}
@@ -384,6 +403,7 @@ func attemptObfuscation(tokenizer *SQLTokenizer) (*ObfuscatedQuery, error) {
 			collectCommands:   tokenizer.cfg.CollectCommands,
 			collectComments:   tokenizer.cfg.CollectComments,
 			replaceDigits:     tokenizer.cfg.ReplaceDigits,
+			quotedIDs:         tokenizer.isCQLOrPartiQL(),
 		}
 		discard  = discardFilter{keepSQLAlias: tokenizer.cfg.KeepSQLAlias}
 		replace  = replaceFilter{replaceDigits: tokenizer.cfg.ReplaceDigits}
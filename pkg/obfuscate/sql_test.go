@@ -7,9 +7,13 @@ package obfuscate
 
 import (
 	"bytes"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -1941,6 +1945,97 @@ func BenchmarkQueryCacheTippingPoint(b *testing.B) {
 	}
 }
 
+// xmlSQLDialectTest is a test of the ./testdata/cql_tests.xml and ./testdata/partiql_tests.xml
+// golden corpora.
+type xmlSQLDialectTest struct {
+	Tag    string
+	In     string
+	Out    string
+	Tables string
+	Error  bool // the query is invalid
+}
+
+func loadSQLDialectTests(t *testing.T, path string) []*xmlSQLDialectTest {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var suite struct {
+		Tests []*xmlSQLDialectTest `xml:"TestSuite>Test"`
+	}
+	require.NoError(t, xml.NewDecoder(f).Decode(&suite))
+	require.NotEmpty(t, suite.Tests)
+	return suite.Tests
+}
+
+func TestObfuscateSQLDialects(t *testing.T) {
+	for dbms, path := range map[string]string{
+		DBMSCassandra: "./testdata/cql_tests.xml",
+		DBMSDynamoDB:  "./testdata/partiql_tests.xml",
+	} {
+		for _, tt := range loadSQLDialectTests(t, path) {
+			t.Run(tt.Tag, func(t *testing.T) {
+				for _, mode := range []ObfuscationMode{"", ObfuscateAndNormalize} {
+					o := NewObfuscator(Config{SQL: SQLConfig{TableNames: true, ObfuscationMode: mode}})
+					oq, err := o.ObfuscateSQLStringForDBMS(strings.TrimSpace(tt.In), dbms)
+					if tt.Error {
+						assert.Error(t, err)
+						continue
+					}
+					require.NoError(t, err)
+					assert.Equal(t, tt.Out, oq.Query)
+					assert.Equal(t, tt.Tables, oq.Metadata.TablesCSV)
+				}
+			})
+		}
+	}
+}
+
+type warnRecorder struct {
+	mu       sync.Mutex
+	warnings []string
+}
+
+func (r *warnRecorder) Debugf(_ string, _ ...interface{}) {}
+
+func (r *warnRecorder) Warnf(format string, params ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.warnings = append(r.warnings, fmt.Sprintf(format, params...))
+}
+
+func TestSQLDialectObfuscationModeWarning(t *testing.T) {
+	logger := &warnRecorder{}
+	o := NewObfuscator(Config{SQL: SQLConfig{ObfuscationMode: ObfuscateOnly}, Logger: logger})
+	for _, dbms := range []string{DBMSCassandra, DBMSDynamoDB, DBMSCassandra} {
+		_, err := o.ObfuscateSQLStringForDBMS("SELECT * FROM users WHERE id = 1", dbms)
+		require.NoError(t, err)
+	}
+	// the obfuscation mode being ignored is only logged once
+	assert.Equal(t, []string{`The SQL obfuscation mode "obfuscate_only" doesn't support CQL and PartiQL queries, they are obfuscated without it`}, logger.warnings)
+
+	logger = &warnRecorder{}
+	o = NewObfuscator(Config{Logger: logger})
+	_, err := o.ObfuscateSQLStringForDBMS("SELECT * FROM users WHERE id = 1", DBMSCassandra)
+	require.NoError(t, err)
+	assert.Empty(t, logger.warnings)
+}
+
+func TestSQLDialectObfuscationModeWarningConcurrent(t *testing.T) {
+	logger := &warnRecorder{}
+	o := NewObfuscator(Config{SQL: SQLConfig{ObfuscationMode: ObfuscateOnly}, Logger: logger})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := o.ObfuscateSQLStringForDBMS("SELECT * FROM users WHERE id = 1", DBMSDynamoDB)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, logger.warnings, 1)
+}
+
 func TestCassQuantizer(t *testing.T) {
 	assert := assert.New(t)
 
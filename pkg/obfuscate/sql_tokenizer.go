@@ -13,7 +13,7 @@ import (
 	"unicode/utf8"
 )
 
-// tokenizer.go implemenents a lexer-like iterator that tokenizes SQL and CQL
+// tokenizer.go implemenents a lexer-like iterator that tokenizes SQL, CQL and PartiQL
 // strings, so that an external component can filter or alter each token of the
 // string. This implementation can't be used as a real SQL lexer (so a parser
 // cannot build the AST) because many rules are ignored to make the tokenizer
@@ -87,6 +87,9 @@ const (
 	JSONAllKeysExist   // ?&
 	JSONDelete         // #-
 
+	// CQL and PartiQL specific
+	CollectionLiteral // a list, set, map, user-defined type, struct or bag literal, e.g. {'k': 'v'}
+
 	// FilteredGroupable specifies that the given token has been discarded by one of the
 	// token filters and that it is groupable together with consecutive FilteredGroupable
 	// tokens.
@@ -163,6 +166,7 @@ var tokenKindStrings = map[TokenKind]string{
 	JSONAnyKeysExist:             "JSONAnyKeysExist",
 	JSONAllKeysExist:             "JSONAllKeysExist",
 	JSONDelete:                   "JSONDelete",
+	CollectionLiteral:            "CollectionLiteral",
 }
 
 func (k TokenKind) String() string {
@@ -182,6 +186,10 @@ const (
 	DBMSMySQL = "mysql"
 	// DBMSOracle is an Oracle Server
 	DBMSOracle = "oracle"
+	// DBMSCassandra is an Apache Cassandra Server, queried with CQL
+	DBMSCassandra = "cassandra"
+	// DBMSDynamoDB is an Amazon DynamoDB table, queried with PartiQL
+	DBMSDynamoDB = "dynamodb"
 )
 
 const escapeCharacter = '\\'
@@ -272,6 +280,14 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 	tkn.SkipBlank()
 
 	switch ch := tkn.lastChar; {
+	case tkn.cfg.DBMS == DBMSCassandra && tkn.isUUID():
+		// CQL UUIDs are unquoted constants, e.g. 123e4567-e89b-12d3-a456-426614174000
+		return tkn.scanUUID()
+	case tkn.cfg.DBMS == DBMSCassandra && tkn.isDuration():
+		// CQL durations are unquoted constants, e.g. 1d2h
+		return tkn.scanDuration()
+	case tkn.isCQLOrPartiQL() && isLeadingLetter(ch):
+		return tkn.scanPath(false)
 	case isLeadingLetter(ch) &&
 		!(tkn.cfg.DBMS == DBMSPostgres && ch == '@'):
 		// The '@' symbol should not be considered part of an identifier in
@@ -333,6 +349,10 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			if tkn.cfg.DBMS == DBMSSQLServer {
 				return tkn.scanString(']', DoubleQuotedString)
 			}
+			if tkn.isCQLOrPartiQL() {
+				// subscripts are scanned along with what they index, so this is a list
+				return tkn.scanCollectionLiteral()
+			}
 			return TokenKind(ch), tkn.bytes()
 		case '.':
 			if isDigit(tkn.lastChar) {
@@ -406,6 +426,12 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 				return tkn.scanCommentType1("#")
 			}
 		case '<':
+			if tkn.cfg.DBMS == DBMSDynamoDB && tkn.lastChar == '<' {
+				// PartiQL bag, e.g. <<1, 2>>, unless it isn't terminated, e.g. a<<5
+				if kind, bag, ok := tkn.scanBag(); ok {
+					return kind, bag
+				}
+			}
 			switch tkn.lastChar {
 			case '>':
 				tkn.advance()
@@ -459,6 +485,10 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 		case '\'':
 			return tkn.scanString(ch, String)
 		case '"':
+			if tkn.isCQLOrPartiQL() {
+				// double quotes delimit identifiers
+				return tkn.scanPath(true)
+			}
 			return tkn.scanString(ch, DoubleQuotedString)
 		case '`':
 			return tkn.scanString(ch, ID)
@@ -524,6 +554,10 @@ func (tkn *SQLTokenizer) Scan() (TokenKind, []byte) {
 			}
 			fallthrough
 		case '{':
+			if tkn.isCQLOrPartiQL() {
+				// CQL set, map or user-defined type, PartiQL struct
+				return tkn.scanCollectionLiteral()
+			}
 			if tkn.pos == 1 || tkn.curlys > 0 {
 				// Do not fully obfuscate top-level SQL escape sequences like {{[?=]call procedure-name[([parameter][,parameter]...)]}.
 				// We want these to display a bit more context than just a plain '?'
@@ -615,6 +649,281 @@ func (tkn *SQLTokenizer) scanIdentifier() (TokenKind, []byte) {
 	return ID, t
 }
 
+// isCQLOrPartiQL reports whether the tokenizer scans CQL or PartiQL rather than SQL.
+func (tkn *SQLTokenizer) isCQLOrPartiQL() bool {
+	return tkn.cfg.DBMS == DBMSCassandra || tkn.cfg.DBMS == DBMSDynamoDB
+}
+
+// scanPath scans a CQL or PartiQL identifier, along with the fields and the subscripts
+// following it, e.g. ks.users, m['key'] or "Awards"[1].Name. The double quotes of the
+// identifiers are kept. Numeric subscripts are kept too, as they are part of document
+// paths, while the other ones are replaced with "?". The quoted argument reports whether
+// the opening double quote of the identifier was scanned.
+func (tkn *SQLTokenizer) scanPath(quoted bool) (TokenKind, []byte) {
+	var path []byte
+	bare := !quoted
+	for {
+		if quoted {
+			var ok bool
+			if path, ok = tkn.appendQuotedIdentifier(path); !ok {
+				tkn.setErr("unexpected EOF in quoted identifier")
+				return LexError, tkn.bytes()
+			}
+		} else {
+			for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '$' {
+				path = utf8.AppendRune(path, tkn.lastChar)
+				tkn.advance()
+			}
+		}
+		for tkn.lastChar == '[' {
+			bare = false
+			var ok bool
+			if path, ok = tkn.appendSubscript(path); !ok {
+				return LexError, tkn.bytes()
+			}
+		}
+		if tkn.lastChar != '.' {
+			break
+		}
+		bare = false
+		path = append(path, '.')
+		tkn.advance()
+		switch {
+		case tkn.lastChar == '"':
+			quoted = true
+			tkn.advance()
+		case tkn.lastChar == '*':
+			// e.g. SELECT t.* FROM ...
+			tkn.advance()
+			tkn.bytes()
+			return ID, append(path, '*')
+		case isLeadingLetter(tkn.lastChar):
+			quoted = false
+		default:
+			tkn.setErr(`unexpected char "%c" (%d) after "."`, tkn.lastChar, tkn.lastChar)
+			return LexError, tkn.bytes()
+		}
+	}
+	tkn.bytes()
+	if bare {
+		var space [256]byte
+		if keywordID, found := keywords[string(toUpper(path, space[:0]))]; found {
+			return keywordID, path
+		}
+	}
+	return ID, path
+}
+
+// appendQuotedIdentifier appends the double-quoted identifier whose opening quote was
+// scanned to path, with its quotes. It reports false if the identifier isn't terminated.
+func (tkn *SQLTokenizer) appendQuotedIdentifier(path []byte) ([]byte, bool) {
+	path = append(path, '"')
+	for {
+		ch := tkn.lastChar
+		if ch == EndChar {
+			return path, false
+		}
+		tkn.advance()
+		path = utf8.AppendRune(path, ch)
+		if ch == '"' {
+			if tkn.lastChar != '"' {
+				return path, true
+			}
+			// a doubled quote is embedded in the identifier
+			path = append(path, '"')
+			tkn.advance()
+		}
+	}
+}
+
+// appendSubscript appends the subscript starting at the current '[' to path. Numeric
+// subscripts are kept, strings and bind parameters are replaced with "?".
+func (tkn *SQLTokenizer) appendSubscript(path []byte) ([]byte, bool) {
+	tkn.advance()
+	tkn.skipSpaces()
+	switch ch := tkn.lastChar; {
+	case isDigit(ch):
+		path = append(path, '[')
+		for isDigit(tkn.lastChar) {
+			path = utf8.AppendRune(path, tkn.lastChar)
+			tkn.advance()
+		}
+		path = append(path, ']')
+	case ch == '\'':
+		tkn.advance()
+		if !tkn.skipQuoted('\'') {
+			tkn.setErr("unexpected EOF in string")
+			return path, false
+		}
+		path = append(path, "[?]"...)
+	case ch == '?':
+		tkn.advance()
+		path = append(path, "[?]"...)
+	case ch == ':':
+		tkn.advance()
+		for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) {
+			tkn.advance()
+		}
+		path = append(path, "[?]"...)
+	default:
+		tkn.setErr(`unexpected char "%c" (%d) in subscript`, ch, ch)
+		return path, false
+	}
+	tkn.skipSpaces()
+	if tkn.lastChar != ']' {
+		tkn.setErr(`expected "]" to end subscript, got "%c" (%d)`, tkn.lastChar, tkn.lastChar)
+		return path, false
+	}
+	tkn.advance()
+	return path, true
+}
+
+// skipSpaces moves the tokenizer forward until hitting a non-whitespace character,
+// without discarding the bytes advanced over.
+func (tkn *SQLTokenizer) skipSpaces() {
+	for unicode.IsSpace(tkn.lastChar) {
+		tkn.advance()
+	}
+}
+
+// skipQuoted moves the tokenizer past the string delimited by delim whose opening
+// delimiter was scanned. It reports false if the string isn't terminated.
+func (tkn *SQLTokenizer) skipQuoted(delim rune) bool {
+	for {
+		ch := tkn.lastChar
+		if ch == EndChar {
+			return false
+		}
+		tkn.advance()
+		if ch == delim {
+			if tkn.lastChar != delim {
+				return true
+			}
+			// doubling a delimiter is the way to embed the delimiter within a string
+			tkn.advance()
+		}
+	}
+}
+
+// scanCollectionLiteral scans a CQL or PartiQL collection literal whose opening bracket
+// was scanned, including the collections nested in it.
+func (tkn *SQLTokenizer) scanCollectionLiteral() (TokenKind, []byte) {
+	for depth := 1; depth > 0; {
+		ch := tkn.lastChar
+		if ch == EndChar {
+			tkn.setErr("unexpected EOF in collection literal")
+			return LexError, tkn.bytes()
+		}
+		tkn.advance()
+		switch ch {
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '<', '>':
+			if tkn.cfg.DBMS == DBMSDynamoDB && tkn.lastChar == ch {
+				// PartiQL bag
+				tkn.advance()
+				if ch == '<' {
+					depth++
+				} else {
+					depth--
+				}
+			}
+		case '\'', '"':
+			if !tkn.skipQuoted(ch) {
+				tkn.setErr("unexpected EOF in string")
+				return LexError, tkn.bytes()
+			}
+		}
+	}
+	return CollectionLiteral, tkn.bytes()
+}
+
+// scanBag scans the PartiQL bag whose first '<' was scanned. It reports false, without
+// moving the tokenizer forward, if the bag isn't terminated.
+func (tkn *SQLTokenizer) scanBag() (TokenKind, []byte, bool) {
+	buf, pos, off, lastChar, err := tkn.buf, tkn.pos, tkn.off, tkn.lastChar, tkn.err
+	tkn.advance()
+	kind, bag := tkn.scanCollectionLiteral()
+	if kind == LexError {
+		tkn.buf, tkn.pos, tkn.off, tkn.lastChar, tkn.err = buf, pos, off, lastChar, err
+		return kind, nil, false
+	}
+	return kind, bag, true
+}
+
+// isUUID reports whether the token starting at the current character starts with a UUID.
+func (tkn *SQLTokenizer) isUUID() bool {
+	const uuidLen = 36
+	if tkn.lastChar == EndChar {
+		return false
+	}
+	// the unread portion of the buffer starts at the current character
+	buf := tkn.buf[tkn.off-utf8.RuneLen(tkn.lastChar):]
+	if len(buf) < uuidLen {
+		return false
+	}
+	for i := 0; i < uuidLen; i++ {
+		switch i {
+		case 8, 13, 18, 23:
+			if buf[i] != '-' {
+				return false
+			}
+		default:
+			if digitVal(rune(buf[i])) >= 16 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scanUUID scans the UUID starting at the current character. The letters, digits and
+// dashes following it are scanned along, so that nothing of a value looking like a UUID
+// is left in the clear.
+func (tkn *SQLTokenizer) scanUUID() (TokenKind, []byte) {
+	for i := 0; i < 36; i++ {
+		tkn.advance()
+	}
+	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) || tkn.lastChar == '-' {
+		tkn.advance()
+	}
+	return String, tkn.bytes()
+}
+
+// isDuration reports whether the token starting at the current character is a CQL
+// duration, i.e. digits followed by a unit such as y, mo, w, d, h, m, s, ms, us or ns.
+func (tkn *SQLTokenizer) isDuration() bool {
+	if !isDigit(tkn.lastChar) {
+		return false
+	}
+	// the unread portion of the buffer starts at the current character
+	buf := tkn.buf[tkn.off-1:]
+	i := 0
+	for i < len(buf) && isDigit(rune(buf[i])) {
+		i++
+	}
+	if i == len(buf) {
+		return false
+	}
+	unit, _ := utf8.DecodeRune(buf[i:])
+	switch unicode.ToLower(unit) {
+	case 'y', 'm', 'w', 'd', 'h', 's', 'u', 'n', 'µ':
+		return true
+	}
+	return false
+}
+
+// scanDuration scans the CQL duration starting at the current character, along with
+// the letters and digits following it.
+func (tkn *SQLTokenizer) scanDuration() (TokenKind, []byte) {
+	for isLetter(tkn.lastChar) || isDigit(tkn.lastChar) {
+		tkn.advance()
+	}
+	return Number, tkn.bytes()
+}
+
 func (tkn *SQLTokenizer) scanVariableIdentifier(_ rune) (TokenKind, []byte) {
 	for tkn.advance(); tkn.lastChar != ')' && tkn.lastChar != EndChar; tkn.advance() {
 		continue
@@ -46,6 +46,53 @@ func TestTokenizeDecimalWithoutIntegerPart(t *testing.T) {
 	}
 }
 
+func TestTokenizeCQLAndPartiQL(t *testing.T) {
+	for _, tt := range []struct {
+		dbms  string
+		in    string
+		kinds []TokenKind
+		toks  []string
+	}{
+		{
+			DBMSCassandra,
+			`m['k'] = {'a': [1]}`,
+			[]TokenKind{ID, '=', CollectionLiteral},
+			[]string{"m[?]", "=", `{'a': [1]}`},
+		},
+		{
+			DBMSCassandra,
+			`ks."T" 123e4567-e89b-12d3-a456-426614174000`,
+			[]TokenKind{ID, String},
+			[]string{`ks."T"`, "123e4567-e89b-12d3-a456-426614174000"},
+		},
+		{
+			DBMSDynamoDB,
+			`"Awards"[1].Name << 'a' >> select`,
+			[]TokenKind{ID, CollectionLiteral, Select},
+			[]string{`"Awards"[1].Name`, "<< 'a' >>", "select"},
+		},
+	} {
+		t.Run(tt.dbms, func(t *testing.T) {
+			tok := NewSQLTokenizer(tt.in, false, &SQLConfig{DBMS: tt.dbms})
+			var (
+				kinds []TokenKind
+				toks  []string
+			)
+			for {
+				kind, buff := tok.Scan()
+				if kind == EndChar {
+					break
+				}
+				assert.NotEqual(t, LexError, kind, tok.Err())
+				kinds = append(kinds, kind)
+				toks = append(toks, string(buff))
+			}
+			assert.Equal(t, tt.kinds, kinds)
+			assert.Equal(t, tt.toks, toks)
+		})
+	}
+}
+
 func FuzzTokenizeIntegerStrings(f *testing.F) {
 	f.Add(int64(123456789))
 	f.Add(int64(0))
@@ -27,6 +27,9 @@ type AgentConfig struct {
 // ConfigContent contains the configurations set by remote-config
 type ConfigContent struct {
 	LogLevel string `json:"log_level"`
+	// RollupRules are the rollup rules of the metrics, left encoded as they
+	// are applied as a runtime setting
+	RollupRules json.RawMessage `json:"rollup_rules,omitempty"`
 }
 
 type agentConfigData struct {
@@ -146,12 +149,18 @@ func MergeRCAgentConfig(applyStatus func(cfgPath string, status ApplyStatus), up
 	for i := len(orderFile.Config.Order) - 1; i >= 0; i-- {
 		if layer, found := parsedLayers[orderFile.Config.Order[i]]; found {
 			mergedConfig.LogLevel = layer.Config.Config.LogLevel
+			if layer.Config.Config.RollupRules != nil {
+				mergedConfig.RollupRules = layer.Config.Config.RollupRules
+			}
 		}
 	}
 	// Same for internal config
 	for i := len(orderFile.Config.InternalOrder) - 1; i >= 0; i-- {
 		if layer, found := parsedLayers[orderFile.Config.InternalOrder[i]]; found {
 			mergedConfig.LogLevel = layer.Config.Config.LogLevel
+			if layer.Config.Config.RollupRules != nil {
+				mergedConfig.RollupRules = layer.Config.Config.RollupRules
+			}
 		}
 	}
 
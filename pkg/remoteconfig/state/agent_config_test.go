@@ -17,3 +17,17 @@ func TestMergeRCConfigWithEmptyData(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, ConfigContent{}, content)
 }
+
+func TestMergeRCConfigRollupRules(t *testing.T) {
+	emptyUpdateStatus := func(_ string, _ ApplyStatus) {}
+
+	content, err := MergeRCAgentConfig(emptyUpdateStatus, map[string]RawConfig{
+		"datadog/2/AGENT_CONFIG/layer1/configname":              {Config: []byte(`{"name": "layer1", "config": {"rollup_rules": [{"metric_name": "a"}]}}`)},
+		"datadog/2/AGENT_CONFIG/layer2/configname":              {Config: []byte(`{"name": "layer2", "config": {"log_level": "debug"}}`)},
+		"datadog/2/AGENT_CONFIG/configuration_order/configname": {Config: []byte(`{"order": ["layer2", "layer1"]}`)},
+	})
+	assert.NoError(t, err)
+	// the layers without rollup rules don't override them
+	assert.Equal(t, "debug", content.LogLevel)
+	assert.JSONEq(t, `[{"metric_name": "a"}]`, string(content.RollupRules))
+}
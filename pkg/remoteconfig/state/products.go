@@ -16,6 +16,7 @@ var validProducts = map[string]struct{}{
 	ProductAgentTask:                    {},
 	ProductAgentIntegrations:            {},
 	ProductAPMSampling:                  {},
+	ProductAPMSpanFilters:               {},
 	ProductCWSDD:                        {},
 	ProductCWSCustom:                    {},
 	ProductCWSProfiles:                  {},
@@ -58,6 +59,9 @@ const (
 	ProductAgentTask = "AGENT_TASK"
 	// ProductAPMSampling is the apm sampling product
 	ProductAPMSampling = "APM_SAMPLING"
+	// ProductAPMSpanFilters is the span filter rules product of the trace-agent.
+	// It is experimental and only requested when remote_configuration.apm_span_filters.enabled is set.
+	ProductAPMSpanFilters = "APM_SPAN_FILTERS"
 	// ProductCWSDD is the cloud workload security product managed by datadog employees
 	ProductCWSDD = "CWS_DD"
 	// ProductCWSCustom is the cloud workload security product managed by datadog customers
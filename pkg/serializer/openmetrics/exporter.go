@@ -0,0 +1,409 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package openmetrics exposes the latest series and sketches flushed to the
+// serializer in the OpenMetrics text format, to be scraped locally.
+package openmetrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+const (
+	// MetricsPath is the path of the exposition endpoint.
+	MetricsPath = "/metrics"
+	// ContentType is the content type of the exposition.
+	ContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// SummaryQuantiles are the quantiles of the sketches exposed in the summaries.
+var SummaryQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// kind is the OpenMetrics type of a metric family.
+type kind string
+
+const (
+	kindGauge   kind = "gauge"
+	kindCounter kind = "counter"
+	kindSummary kind = "summary"
+)
+
+// entry is the state of an exposed series.
+type entry struct {
+	family string
+	kind   kind
+	labels string
+	// value is the last value of the gauges, the sum of the values of the
+	// counters and the sum of the values of the summaries.
+	value float64
+	// count is the number of values of the summaries.
+	count uint64
+	// quantiles are the quantiles of the last sketch of the summaries.
+	quantiles []float64
+	lastSeen  time.Time
+}
+
+// Exporter keeps the latest state of the series and sketches flushed, and
+// exposes them in the OpenMetrics text format:
+//   - the gauges and rates as gauges,
+//   - the counts as counters, whose value is the sum of the counts flushed,
+//   - the sketches as summaries of the quantiles of the last sketch flushed,
+//     whose sum and count are the totals of the sketches flushed.
+//
+// The series which aren't flushed again within the expiry are forgotten,
+// resetting their counters.
+type Exporter struct {
+	expiry time.Duration
+	log    log.Component
+
+	mu      sync.RWMutex
+	entries map[string]*entry
+
+	httpServer *http.Server
+}
+
+// NewExporter returns an exporter forgetting the series not flushed within
+// expiry.
+func NewExporter(expiry time.Duration, log log.Component) *Exporter {
+	return &Exporter{
+		expiry:  expiry,
+		log:     log,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Listen starts serving the exposition on addr, and returns the address
+// listened on.
+func (e *Exporter) Listen(addr string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, e)
+	e.httpServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := e.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.log.Errorf("openmetrics-exporter: error serving the requests: %v", err)
+		}
+	}()
+	e.log.Infof("openmetrics-exporter: starting to listen on %s", listener.Addr())
+	return listener.Addr(), nil
+}
+
+// Stop closes the listener and the connections.
+func (e *Exporter) Stop() {
+	if e.httpServer != nil {
+		_ = e.httpServer.Close()
+	}
+}
+
+// ServeHTTP writes the exposition.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	if err := e.Write(w); err != nil {
+		e.log.Debugf("openmetrics-exporter: error writing the exposition: %v", err)
+	}
+}
+
+// Sinks returns sinks recording the series and sketches of a flush started
+// at now before appending them to seriesSink and sketchesSink. The series
+// expired at now are forgotten.
+func (e *Exporter) Sinks(now time.Time, seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) (metrics.SerieSink, metrics.SketchesSink) {
+	e.expire(now)
+	return &exportSerieSink{SerieSink: seriesSink, exporter: e, now: now},
+		&exportSketchesSink{SketchesSink: sketchesSink, exporter: e, now: now}
+}
+
+func (e *Exporter) expire(now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for key, entry := range e.entries {
+		if now.Sub(entry.lastSeen) > e.expiry {
+			delete(e.entries, key)
+		}
+	}
+}
+
+// get returns the entry of a series, creating it if needed, or nil if the
+// series was exposed with another kind. It must be called with mu held.
+func (e *Exporter) get(family string, k kind, labels string, now time.Time) *entry {
+	key := family + "{" + labels + "}"
+	en, ok := e.entries[key]
+	if !ok {
+		en = &entry{family: family, kind: k, labels: labels}
+		e.entries[key] = en
+	} else if en.kind != k {
+		return nil
+	}
+	en.lastSeen = now
+	return en
+}
+
+type exportSerieSink struct {
+	metrics.SerieSink
+	exporter *Exporter
+	now      time.Time
+}
+
+func (s *exportSerieSink) Append(serie *metrics.Serie) {
+	if len(serie.Points) > 0 {
+		s.exporter.addSerie(serie, s.now)
+	}
+	s.SerieSink.Append(serie)
+}
+
+func (e *Exporter) addSerie(serie *metrics.Serie, now time.Time) {
+	k := kindGauge
+	family := metricName(serie.Name)
+	if serie.MType == metrics.APICountType {
+		k = kindCounter
+		family = strings.TrimSuffix(family, "_total")
+	}
+	labels := formatLabels(serie.Host, serie.Tags.UnsafeToReadOnlySliceString())
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	en := e.get(family, k, labels, now)
+	if en == nil {
+		return
+	}
+	if k == kindCounter {
+		for _, p := range serie.Points {
+			en.value += p.Value
+		}
+	} else {
+		en.value = serie.Points[len(serie.Points)-1].Value
+	}
+}
+
+type exportSketchesSink struct {
+	metrics.SketchesSink
+	exporter *Exporter
+	now      time.Time
+}
+
+func (s *exportSketchesSink) Append(sketch *metrics.SketchSeries) {
+	if len(sketch.Points) > 0 {
+		s.exporter.addSketch(sketch, s.now)
+	}
+	s.SketchesSink.Append(sketch)
+}
+
+func (e *Exporter) addSketch(sketch *metrics.SketchSeries, now time.Time) {
+	family := metricName(sketch.Name)
+	labels := formatLabels(sketch.Host, sketch.Tags.UnsafeToReadOnlySliceString())
+
+	var last *quantile.Sketch
+	var count uint64
+	var sum float64
+	for _, p := range sketch.Points {
+		if p.Sketch == nil {
+			continue
+		}
+		last = p.Sketch
+		count += uint64(p.Sketch.Basic.Cnt)
+		sum += p.Sketch.Basic.Sum
+	}
+	if last == nil {
+		return
+	}
+	quantiles := make([]float64, len(SummaryQuantiles))
+	for i, q := range SummaryQuantiles {
+		quantiles[i] = last.Quantile(quantile.Default(), q)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	en := e.get(family, kindSummary, labels, now)
+	if en == nil {
+		return
+	}
+	en.count += count
+	en.value += sum
+	en.quantiles = quantiles
+}
+
+// Write writes the exposition to w.
+func (e *Exporter) Write(w io.Writer) error {
+	e.mu.RLock()
+	entries := make([]entry, 0, len(e.entries))
+	for _, en := range e.entries {
+		entries = append(entries, *en)
+	}
+	e.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].family != entries[j].family {
+			return entries[i].family < entries[j].family
+		}
+		if entries[i].kind != entries[j].kind {
+			return entries[i].kind < entries[j].kind
+		}
+		return entries[i].labels < entries[j].labels
+	})
+
+	bw := bufio.NewWriter(w)
+	var family string
+	var familyKind kind
+	for _, en := range entries {
+		if en.family == family && en.kind != familyKind {
+			// a family has a single type, keep the first one
+			continue
+		}
+		if en.family != family {
+			family, familyKind = en.family, en.kind
+			fmt.Fprintf(bw, "# TYPE %s %s\n", family, familyKind)
+		}
+
+		switch en.kind {
+		case kindGauge:
+			writeSample(bw, en.family, en.labels, "", formatFloat(en.value))
+		case kindCounter:
+			writeSample(bw, en.family+"_total", en.labels, "", formatFloat(en.value))
+		case kindSummary:
+			for i, q := range SummaryQuantiles {
+				writeSample(bw, en.family, en.labels, `quantile="`+formatFloat(q)+`"`, formatFloat(en.quantiles[i]))
+			}
+			writeSample(bw, en.family+"_sum", en.labels, "", formatFloat(en.value))
+			writeSample(bw, en.family+"_count", en.labels, "", strconv.FormatUint(en.count, 10))
+		}
+	}
+	bw.WriteString("# EOF\n")
+	return bw.Flush()
+}
+
+func writeSample(w *bufio.Writer, name, labels, extraLabel, value string) {
+	w.WriteString(name)
+	if labels != "" || extraLabel != "" {
+		w.WriteByte('{')
+		w.WriteString(labels)
+		if labels != "" && extraLabel != "" {
+			w.WriteByte(',')
+		}
+		w.WriteString(extraLabel)
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(value)
+	w.WriteByte('\n')
+}
+
+// formatLabels returns the labels of the tags and the host, sorted by name.
+// The tags without value are labels set to true, and the values of the tags
+// sharing a name are joined.
+func formatLabels(host string, tags []string) string {
+	values := make(map[string][]string, len(tags)+1)
+	for _, tag := range tags {
+		name, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			value = "true"
+		}
+		name = labelName(name)
+		values[name] = append(values[name], value)
+	}
+	if _, ok := values["host"]; !ok && host != "" {
+		values["host"] = []string{host}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		vs := values[name]
+		sort.Strings(vs)
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(strings.Join(vs, ",")))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+// metricName returns the metric name with its invalid characters replaced.
+func metricName(name string) string {
+	return sanitizeName(name, true)
+}
+
+// labelName returns the label name of a tag name, with its invalid
+// characters replaced and prefixed if it is reserved.
+func labelName(name string) string {
+	name = sanitizeName(name, false)
+	if name == "quantile" || name == "le" || strings.HasPrefix(name, "__") {
+		return "tag_" + name
+	}
+	return name
+}
+
+func sanitizeName(name string, allowColon bool) string {
+	if name == "" {
+		return "_"
+	}
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(c >= '0' && c <= '9') || (allowColon && c == ':')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
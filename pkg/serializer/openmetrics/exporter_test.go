@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package openmetrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func flush(e *Exporter, now time.Time, series []*metrics.Serie, sketches []*metrics.SketchSeries) (metrics.Series, metrics.SketchSeriesList) {
+	var seriesOut metrics.Series
+	var sketchesOut metrics.SketchSeriesList
+	seriesSink, sketchesSink := e.Sinks(now, &seriesOut, &sketchesOut)
+	for _, serie := range series {
+		seriesSink.Append(serie)
+	}
+	for _, sketch := range sketches {
+		sketchesSink.Append(sketch)
+	}
+	return seriesOut, sketchesOut
+}
+
+func exposition(t *testing.T, e *Exporter) string {
+	var b bytes.Buffer
+	require.NoError(t, e.Write(&b))
+	return b.String()
+}
+
+func newSerie(name string, mtype metrics.APIMetricType, value float64, tags ...string) *metrics.Serie {
+	return &metrics.Serie{
+		Name:   name,
+		Points: []metrics.Point{{Ts: 10, Value: value}},
+		Tags:   tagset.CompositeTagsFromSlice(tags),
+		Host:   "my-host",
+		MType:  mtype,
+	}
+}
+
+func TestExporterSeries(t *testing.T) {
+	e := NewExporter(time.Minute, logmock.New(t))
+	now := time.Now()
+
+	gauge := newSerie("system.load.1", metrics.APIGaugeType, 0.5, "env:prod", "role:db", "role:web")
+	series, _ := flush(e, now, []*metrics.Serie{
+		gauge,
+		newSerie("http.requests", metrics.APICountType, 3, "status_code:200"),
+		newSerie("bytes.rate", metrics.APIRateType, 12.5, "flag"),
+	}, nil)
+	// the series are still sent to the serializer
+	require.Len(t, series, 3)
+	assert.Equal(t, gauge, series[0])
+
+	flush(e, now.Add(10*time.Second), []*metrics.Serie{
+		newSerie("system.load.1", metrics.APIGaugeType, 0.7, "env:prod", "role:db", "role:web"),
+		newSerie("http.requests", metrics.APICountType, 2, "status_code:200"),
+	}, nil)
+
+	assert.Equal(t, `# TYPE bytes_rate gauge
+bytes_rate{flag="true",host="my-host"} 12.5
+# TYPE http_requests counter
+http_requests_total{host="my-host",status_code="200"} 5
+# TYPE system_load_1 gauge
+system_load_1{env="prod",host="my-host",role="db,web"} 0.7
+# EOF
+`, exposition(t, e))
+
+	// the series not flushed again within a minute expire
+	flush(e, now.Add(20*time.Second), []*metrics.Serie{
+		newSerie("http.requests", metrics.APICountType, 1, "status_code:200"),
+	}, nil)
+	flush(e, now.Add(75*time.Second), nil, nil)
+	assert.Equal(t, `# TYPE http_requests counter
+http_requests_total{host="my-host",status_code="200"} 6
+# EOF
+`, exposition(t, e))
+}
+
+func TestExporterSketches(t *testing.T) {
+	e := NewExporter(time.Minute, logmock.New(t))
+	now := time.Now()
+
+	newSketch := func(values ...float64) *metrics.SketchSeries {
+		sketch := &quantile.Sketch{}
+		sketch.Insert(quantile.Default(), values...)
+		return &metrics.SketchSeries{
+			Name:   "request.latency",
+			Tags:   tagset.CompositeTagsFromSlice([]string{"quantile:x"}),
+			Points: []metrics.SketchPoint{{Sketch: sketch, Ts: 10}},
+		}
+	}
+	flush(e, now, nil, []*metrics.SketchSeries{newSketch(1, 2, 3)})
+	_, sketches := flush(e, now.Add(10*time.Second), nil, []*metrics.SketchSeries{newSketch(4, 4, 4, 4)})
+	require.Len(t, sketches, 1)
+
+	text := exposition(t, e)
+	assert.Contains(t, text, "# TYPE request_latency summary\n")
+	assert.Contains(t, text, "request_latency_sum{tag_quantile=\"x\"} 22\n")
+	assert.Contains(t, text, "request_latency_count{tag_quantile=\"x\"} 7\n")
+
+	// the quantiles are the ones of the last sketch
+	require.Len(t, e.entries, 1)
+	for _, en := range e.entries {
+		require.Len(t, en.quantiles, len(SummaryQuantiles))
+		for i, q := range SummaryQuantiles {
+			assert.InDelta(t, 4, en.quantiles[i], 0.1)
+			assert.Contains(t, text, `request_latency{tag_quantile="x",quantile="`+formatFloat(q)+`"} `+formatFloat(en.quantiles[i])+"\n")
+		}
+	}
+}
+
+func TestExporterTypeConflict(t *testing.T) {
+	e := NewExporter(time.Minute, logmock.New(t))
+	flush(e, time.Now(), []*metrics.Serie{
+		newSerie("my.metric", metrics.APIGaugeType, 1, "a:1"),
+		newSerie("my.metric", metrics.APIGaugeType, 2, "a:2"),
+		newSerie("my.metric", metrics.APICountType, 3, "a:1"),
+		newSerie("my_metric", metrics.APICountType, 4, "a:3"),
+	}, nil)
+
+	// a family has a single type
+	assert.Equal(t, `# TYPE my_metric counter
+my_metric_total{a="3",host="my-host"} 4
+# EOF
+`, exposition(t, e))
+}
+
+func TestNames(t *testing.T) {
+	assert.Equal(t, "system_cpu_user", metricName("system.cpu.user"))
+	assert.Equal(t, "ns:my_metric", metricName("ns:my-metric"))
+	assert.Equal(t, "_1metric", metricName("1metric"))
+	assert.Equal(t, "_", metricName(""))
+	assert.Equal(t, "kube_pod", labelName("kube.pod"))
+	assert.Equal(t, "tag_le", labelName("le"))
+	assert.Equal(t, "tag___name__", labelName("__name__"))
+	assert.Equal(t, `env="a\"b\\c\nd"`, formatLabels("", []string{"env:a\"b\\c\nd"}))
+	assert.Equal(t, `host="tag-host"`, formatLabels("my-host", []string{"host:tag-host"}))
+	assert.Equal(t, "+Inf", formatFloat(1/zero()))
+}
+
+func zero() float64 { return 0 }
+
+func TestExporterServeHTTP(t *testing.T) {
+	e := NewExporter(time.Minute, logmock.New(t))
+	flush(e, time.Now(), []*metrics.Serie{newSerie("my.gauge", metrics.APIGaugeType, 1)}, nil)
+
+	addr, err := e.Listen("127.0.0.1:0")
+	require.NoError(t, err)
+	defer e.Stop()
+
+	resp, err := http.Get("http://" + addr.String() + MetricsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ContentType, resp.Header.Get("Content-Type"))
+	assert.Equal(t, "# TYPE my_gauge gauge\nmy_gauge{host=\"my-host\"} 1\n# EOF\n", string(body))
+
+	rr := httptest.NewRecorder()
+	e.ServeHTTP(rr, httptest.NewRequest("POST", MetricsPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
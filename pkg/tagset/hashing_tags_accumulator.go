@@ -92,6 +92,25 @@ func (h *HashingTagsAccumulator) Truncate(len int) {
 	h.hash = h.hash[0:len]
 }
 
+// Retain keeps the tags for which keep returns true, preserving their order,
+// and returns the number of tags removed and their combined hash, as returned
+// by Hash.
+func (h *HashingTagsAccumulator) Retain(keep func(tag string) bool) (removed int, removedHash uint64) {
+	n := 0
+	for i, t := range h.data {
+		if keep(t) {
+			h.data[n] = t
+			h.hash[n] = h.hash[i]
+			n++
+		} else {
+			removedHash ^= h.hash[i]
+		}
+	}
+	removed = len(h.data) - n
+	h.Truncate(n)
+	return removed, removedHash
+}
+
 // Less implements sort.Interface.Less
 func (h *HashingTagsAccumulator) Less(i, j int) bool {
 	if h.hash[i] == h.hash[j] {
@@ -57,6 +57,21 @@ func TestHashingTagsAccumulatorGet(t *testing.T) {
 	assert.Equal(t, []string{"test", "b", "c"}, tb.data)
 }
 
+func TestHashingTagsAccumulatorRetain(t *testing.T) {
+	tb := NewHashingTagsAccumulatorWithTags([]string{"a:1", "b:2", "c:3", "b:4"})
+
+	removed, removedHash := tb.Retain(func(tag string) bool { return tag[0] != 'b' })
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, NewHashingTagsAccumulatorWithTags([]string{"b:2", "b:4"}).Hash(), removedHash)
+	assert.Equal(t, []string{"a:1", "c:3"}, tb.Get())
+	assert.Equal(t, NewHashingTagsAccumulatorWithTags([]string{"a:1", "c:3"}).Hashes(), tb.Hashes())
+
+	removed, removedHash = tb.Retain(func(string) bool { return true })
+	assert.Equal(t, 0, removed)
+	assert.Zero(t, removedHash)
+	assert.Equal(t, []string{"a:1", "c:3"}, tb.Get())
+}
+
 func TestHashingTagsAccumulatorCopy(t *testing.T) {
 	tb := NewHashingTagsAccumulator()
 
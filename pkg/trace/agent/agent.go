@@ -89,6 +89,7 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	SpanFilter            *filters.SpanFilter
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
@@ -127,6 +128,12 @@ type Agent struct {
 	ctx context.Context
 
 	firstSpanMap sync.Map
+
+	// tail holds the chunks until their trace is decided, when tail sampling
+	// is enabled. tailExit and tailDone synchronize on the exit of its loop.
+	tail     *tailBuffer
+	tailExit chan struct{}
+	tailDone chan struct{}
 }
 
 // SpanModifier is an interface that allows to modify spans while they are
@@ -147,11 +154,17 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	}
 	timing := timing.New(statsd)
 	statsWriter := writer.NewStatsWriter(conf, telemetryCollector, statsd, timing)
+	spanFilter, err := filters.NewSpanFilter(conf.SpanFilters)
+	if err != nil {
+		log.Errorf("Invalid span filters, spans won't be filtered: %s", err)
+		spanFilter, _ = filters.NewSpanFilter(nil)
+	}
 	agnt := &Agent{
 		Concentrator:          stats.NewConcentrator(conf, statsWriter, time.Now(), statsd),
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsWriter, statsd),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		SpanFilter:            spanFilter,
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(conf),
@@ -168,10 +181,14 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		Statsd:                statsd,
 		Timing:                timing,
 	}
+	if agnt.tail = newTailBuffer(conf); agnt.tail != nil {
+		agnt.tailExit = make(chan struct{})
+		agnt.tailDone = make(chan struct{})
+	}
 	agnt.SamplerMetrics.Add(agnt.PrioritySampler, agnt.ErrorsSampler, agnt.NoPrioritySampler, agnt.RareSampler)
 	agnt.Receiver = api.NewHTTPReceiver(conf, dynConf, in, agnt, telemetryCollector, statsd, timing)
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
-	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
+	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler, agnt.SpanFilter)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
 	return agnt
 }
@@ -194,6 +211,9 @@ func (a *Agent) Run() {
 	}
 
 	go a.StatsWriter.Run()
+	if a.tail != nil {
+		go a.tailSamplingLoop()
+	}
 
 	// Having GOMAXPROCS processor threads is
 	// enough to keep the agent busy.
@@ -261,6 +281,12 @@ func (a *Agent) loop() {
 	if err := a.Receiver.Stop(); err != nil {
 		log.Error(err)
 	}
+	if a.tail != nil {
+		// decide the traces left in the tail sampling buffer before the
+		// writer is stopped
+		close(a.tailExit)
+		<-a.tailDone
+	}
 	for _, stopper := range []interface{ Stop() }{
 		a.Concentrator,
 		a.ClientStatsAggregator,
@@ -317,6 +343,7 @@ func (a *Agent) Process(p *api.Payload) {
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
+	var tailHeader *pb.TracerPayload
 
 	p.TracerPayload.Env = traceutil.NormalizeTagValue(p.TracerPayload.Env)
 
@@ -359,6 +386,11 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		if n := a.SpanFilter.Filter(chunk); n > 0 {
+			log.Debugf("Dropped %d spans with the span filter rules", n)
+			ts.SpansFiltered.Add(int64(n))
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range chunk.Spans {
 			for k, v := range a.conf.GlobalTags {
@@ -393,6 +425,18 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		if a.tail != nil {
+			// Stats are computed above on all the traces; the chunk is now
+			// held until its whole trace is decided.
+			tp := p.TracerPayload
+			if tailHeader == nil || tailHeader.Env != tp.Env || tailHeader.Hostname != tp.Hostname || tailHeader.AppVersion != tp.AppVersion {
+				tailHeader = tailPayload(tp)
+			}
+			a.bufferTailChunk(now, ts, tailHeader, pt)
+			p.RemoveChunk(i)
+			continue
+		}
+
 		keep, numEvents := a.sample(now, ts, pt)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
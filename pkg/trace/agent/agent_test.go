@@ -828,6 +828,42 @@ func TestClientComputedTopLevel(t *testing.T) {
 	})
 }
 
+func TestSpanFilters(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.SpanFilters = []*config.SpanFilterRule{
+		{Name: "ping", Service: "redis", Resource: "^PING$", Action: config.SpanFilterDrop},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+
+	root := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /"}
+	ping := &pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "redis", Name: "redis.command", Resource: "PING"}
+	get := &pb.Span{TraceID: 1, SpanID: 3, ParentID: 2, Service: "redis", Name: "redis.command", Resource: "GET"}
+	chunk := testutil.TraceChunkWithSpans([]*pb.Span{root, ping, get})
+	chunk.Priority = int32(sampler.PriorityUserKeep)
+	ts := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+	agnt.Process(&api.Payload{
+		TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+		Source:        ts,
+	})
+
+	// the span is dropped before stats are computed
+	inputs := agnt.Concentrator.(*mockConcentrator).Reset()
+	require.Len(t, inputs, 1)
+	require.Len(t, inputs[0].Traces, 1)
+	assert.Len(t, inputs[0].Traces[0].TraceChunk.Spans, 2)
+
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 1)
+	spans := payloads[0].TracerPayload.Chunks[0].Spans
+	require.Len(t, spans, 2)
+	assert.Equal(t, uint64(3), spans[1].SpanID)
+	assert.Equal(t, uint64(1), spans[1].ParentID)
+	assert.EqualValues(t, 1, ts.SpansFiltered.Load())
+}
+
 func TestFilteredByTags(t *testing.T) {
 	for name, tt := range map[string]*struct {
 		require      []*config.Tag
@@ -2039,6 +2075,7 @@ func TestPartialSamplingFree(t *testing.T) {
 		Concentrator:      &mockConcentrator{},
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
+		SpanFilter:        &filters.SpanFilter{},
 		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
 		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
 		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
@@ -18,6 +18,7 @@ import (
 const (
 	tagRedisRawCommand  = transform.TagRedisRawCommand
 	tagMemcachedCommand = transform.TagMemcachedCommand
+	tagGraphQLQuery     = transform.TagGraphQLQuery
 	tagMongoDBQuery     = transform.TagMongoDBQuery
 	tagElasticBody      = transform.TagElasticBody
 	tagOpenSearchBody   = transform.TagOpenSearchBody
@@ -52,7 +53,7 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		if span.Resource == "" {
 			return
 		}
-		oq, err := transform.ObfuscateSQLSpan(o, span)
+		oq, err := transform.ObfuscateSQLSpanForDBMS(o, span, transform.SQLDBMS(span.Type, span.Meta[tagDBMS], a.conf.Obfuscation.CQL.Enabled))
 		if err != nil {
 			// we have an error, discard the SQL to avoid polluting user resources.
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -80,6 +81,13 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagMemcachedCommand] = o.ObfuscateMemcachedString(span.Meta[tagMemcachedCommand])
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled {
+			return
+		}
+		if _, err := transform.ObfuscateGraphQLSpan(o, span); err != nil {
+			log.Debugf("Error parsing GraphQL query: %v. Query: %q", err, span.Meta[tagGraphQLQuery])
+		}
 	case "web", "http":
 		if span.Meta == nil || span.Meta[tagHTTPURL] == "" {
 			return
@@ -161,7 +169,8 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 
 	switch b.Type {
 	case "sql", "cassandra":
-		oq, err := o.ObfuscateSQLStringForDBMS(b.Resource, b.DBType)
+		dbms := transform.SQLDBMS(b.Type, b.DBType, a.conf.Obfuscation != nil && a.conf.Obfuscation.CQL.Enabled)
+		oq, err := o.ObfuscateSQLStringForDBMS(b.Resource, dbms)
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
 			b.Resource = textNonParsable
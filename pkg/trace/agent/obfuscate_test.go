@@ -43,6 +43,7 @@ func TestObfuscateStatsGroup(t *testing.T) {
 	}{
 		{statsGroup("sql", "SELECT 1 FROM db"), "SELECT ? FROM db"},
 		{statsGroup("sql", "SELECT 1\nFROM Blogs AS [b\nORDER BY [b]"), textNonParsable},
+		{statsGroup("cassandra", "UPDATE users SET tags = {'a', 'b'} WHERE id = 1"), "UPDATE users SET tags = ? WHERE id = ?"},
 		{statsGroup("redis", "ADD 1, 2"), "ADD"},
 		{statsGroup("valkey", "ADD 1, 2"), "ADD"},
 		{statsGroup("other", "ADD 1, 2"), "ADD 1, 2"},
@@ -52,6 +53,20 @@ func TestObfuscateStatsGroup(t *testing.T) {
 		agnt.obfuscateStatsGroup(tt.in)
 		assert.Equal(t, tt.in.Resource, tt.out)
 	}
+
+	t.Run("cql", func(t *testing.T) {
+		resource := "SELECT * FROM users WHERE id = 123e4567-e89b-12d3-a456-426614174000"
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		in := statsGroup("cassandra", resource)
+		agnt.obfuscateStatsGroup(in)
+		assert.Equal(t, "SELECT * FROM users WHERE id = ? - e89b ? d3 - a456 ?", in.Resource)
+
+		agnt.conf.Obfuscation.CQL.Enabled = true
+		in = statsGroup("cassandra", resource)
+		agnt.obfuscateStatsGroup(in)
+		assert.Equal(t, "SELECT * FROM users WHERE id = ?", in.Resource)
+	})
 }
 
 // TestObfuscateDefaults ensures that running the obfuscator with no config continues to obfuscate/quantize
@@ -296,6 +311,41 @@ func TestObfuscateConfig(t *testing.T) {
 		&config.ObfuscationConfig{},
 	))
 
+	t.Run("graphql", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(email: "jane@example.com") { id } }`,
+		"query { user(email: ?) { id } }",
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/remove_fragments", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(id: 1) { ...F } } fragment F on User { name }`,
+		"query { user(id: ?) { ...F } }",
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{
+			Enabled:         true,
+			RemoveFragments: true,
+		}},
+	))
+
+	t.Run("graphql/invalid", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(token: "abc) { id } }`,
+		"Non-parsable GraphQL query",
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.query",
+		`query { user(id: 1) { id } }`,
+		`query { user(id: 1) { id } }`,
+		&config.ObfuscationConfig{},
+	))
+
 	t.Run("creditcard", func(t *testing.T) {
 		for _, tt := range []struct {
 			k, v string
@@ -363,6 +413,60 @@ func TestSQLResourceQuery(t *testing.T) {
 	}
 }
 
+func TestSQLDialectResourceQuery(t *testing.T) {
+	for _, tt := range []struct {
+		span *pb.Span
+		out  string
+	}{
+		{
+			&pb.Span{
+				Resource: "INSERT INTO users (id, emails) VALUES (1, {'jane@example.com'})",
+				Type:     "cassandra",
+			},
+			"INSERT INTO users ( id, emails ) VALUES ( ? )",
+		},
+		{
+			&pb.Span{
+				Resource: `SELECT * FROM "Music" WHERE "Awards"[0].Name = 'Grammy'`,
+				Type:     "sql",
+				Meta:     map[string]string{"db.type": "dynamodb"},
+			},
+			`SELECT * FROM "Music" WHERE "Awards"[0].Name = ?`,
+		},
+	} {
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.conf.Obfuscation.CQL.Enabled = true
+		agnt.obfuscateSpan(tt.span)
+		assert.Equal(t, tt.out, tt.span.Resource)
+		assert.Equal(t, tt.out, tt.span.Meta["sql.query"])
+	}
+}
+
+func TestSQLCassandraResourceQuery(t *testing.T) {
+	resource := "UPDATE users SET d = 1h30m WHERE id = 123e4567-e89b-12d3-a456-426614174000"
+	for _, tt := range []struct {
+		cql bool
+		out string
+	}{
+		// the queries of Cassandra spans are obfuscated as SQL by default
+		{false, "UPDATE users SET d = ? h30m WHERE id = ? - e89b ? d3 - a456 ?"},
+		{true, "UPDATE users SET d = ? WHERE id = ?"},
+	} {
+		agnt, stop := agentWithDefaults()
+		defer stop()
+		agnt.conf.Obfuscation.CQL.Enabled = tt.cql
+		span := &pb.Span{Resource: resource, Type: "cassandra"}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, tt.out, span.Resource)
+
+		// the spans reporting their DBMS are obfuscated for it
+		span = &pb.Span{Resource: resource, Type: "cassandra", Meta: map[string]string{"db.type": "cassandra"}}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "UPDATE users SET d = ? WHERE id = ?", span.Resource)
+	}
+}
+
 func TestSQLResourceWithError(t *testing.T) {
 	assert := assert.New(t)
 	testCases := []*struct {
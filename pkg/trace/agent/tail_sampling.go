@@ -0,0 +1,393 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"sync"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+const (
+	// tagTailSamplingPolicy holds the name of the tail sampling policy which
+	// kept the trace.
+	tagTailSamplingPolicy = "_dd.tail_sampling.policy"
+
+	// tailSamplingMemoryShare is the share of the MaxMemory of the agent the
+	// buffer may use when its maximum memory isn't configured.
+	tailSamplingMemoryShare = 0.25
+
+	// tailSamplingTick is the interval at which the buffered traces whose
+	// decision wait elapsed are decided.
+	tailSamplingTick = time.Second
+
+	// tailSamplingDecisions is the number of recent decisions remembered to
+	// decide the chunks received after their trace.
+	tailSamplingDecisions = 100000
+)
+
+// tailChunk is a chunk held by the tail sampling buffer, with what is needed
+// to sample and write it once its trace is decided.
+type tailChunk struct {
+	pt *traceutil.ProcessedTrace
+	ts *info.TagStats
+	// payload is the tracer payload the chunk was received in, without its
+	// chunks.
+	payload *pb.TracerPayload
+	size    int64
+}
+
+// tailTrace holds the buffered chunks of a trace.
+type tailTrace struct {
+	id     uint64
+	first  time.Time
+	chunks []*tailChunk
+	size   int64
+	// policy is the policy matching the trace once decided, if any.
+	policy *sampler.TailPolicy
+	// late reports whether the chunks were received after the decision on
+	// their trace, and follow it.
+	late bool
+}
+
+// tailBuffer holds the chunks of the traces for a decision wait, so that the
+// tail sampling policies decide on whole traces, whatever the payloads their
+// chunks were received in.
+type tailBuffer struct {
+	wait      time.Duration
+	maxTraces int
+	budget    *watchdog.MemBudget
+	policies  []*sampler.TailPolicy
+
+	mu     sync.Mutex
+	traces map[uint64]*tailTrace
+	// queue holds the traces by arrival of their first chunk.
+	queue []*tailTrace
+	// decisions maps the IDs of the traces recently decided to the policy
+	// they matched, if any. decisionIDs holds these IDs in a ring, the oldest
+	// at nextDecision, to bound their number.
+	decisions    map[uint64]*sampler.TailPolicy
+	decisionIDs  []uint64
+	nextDecision int
+}
+
+// newTailBuffer returns the tail sampling buffer configured in conf, or nil
+// if tail sampling is disabled.
+func newTailBuffer(conf *config.AgentConfig) *tailBuffer {
+	tc := conf.TailSampling
+	if !tc.Enabled {
+		return nil
+	}
+	limit := tc.MaxMemory
+	if limit <= 0 {
+		limit = watchdog.BudgetFromMaxMemory(conf.MaxMemory, tailSamplingMemoryShare)
+	}
+	log.Infof("Tail sampling enabled with a decision wait of %s, %d policies", tc.DecisionWait, len(tc.Policies))
+	return &tailBuffer{
+		wait:      tc.DecisionWait,
+		maxTraces: tc.MaxTraces,
+		budget:    watchdog.NewMemBudget(limit),
+		policies:  sampler.NewTailPolicies(tc.Policies),
+		traces:    make(map[uint64]*tailTrace),
+		decisions: make(map[uint64]*sampler.TailPolicy, tailSamplingDecisions),
+	}
+}
+
+// add buffers the chunk, and returns the traces to decide now: the oldest
+// ones evicted to make room for it, or the trace of the chunk if it can't fit
+// in the buffer at all or was already decided.
+func (b *tailBuffer) add(now time.Time, c *tailChunk) (decided []*tailTrace) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := c.pt.TraceChunk.Spans[0].TraceID
+	if late := b.late(now, c); late != nil {
+		return []*tailTrace{late}
+	}
+	if limit := b.budget.Limit(); limit > 0 && c.size > limit {
+		// the chunk can't fit in the buffer, its trace is decided now
+		t, ok := b.traces[id]
+		if ok {
+			b.unqueue(t)
+			b.remove(t)
+		} else {
+			t = &tailTrace{id: id, first: now}
+		}
+		t.chunks = append(t.chunks, c)
+		t.size += c.size
+		return []*tailTrace{b.decide(t)}
+	}
+	for !b.budget.Reserve(c.size) {
+		decided = append(decided, b.pop())
+	}
+
+	t, ok := b.traces[id]
+	if !ok {
+		if late := b.late(now, c); late != nil {
+			// the trace of the chunk was evicted to make room for it
+			b.budget.Release(c.size)
+			return append(decided, late)
+		}
+		if b.maxTraces > 0 && len(b.traces) >= b.maxTraces {
+			decided = append(decided, b.pop())
+		}
+		t = &tailTrace{id: id, first: now}
+		b.traces[id] = t
+		b.queue = append(b.queue, t)
+	}
+	t.chunks = append(t.chunks, c)
+	t.size += c.size
+	return decided
+}
+
+// late returns the trace of the chunk with the decision on it, if it was
+// already decided.
+func (b *tailBuffer) late(now time.Time, c *tailChunk) *tailTrace {
+	id := c.pt.TraceChunk.Spans[0].TraceID
+	policy, ok := b.decisions[id]
+	if !ok {
+		return nil
+	}
+	return &tailTrace{id: id, first: now, chunks: []*tailChunk{c}, size: c.size, policy: policy, late: true}
+}
+
+// pop removes and decides the oldest trace.
+func (b *tailBuffer) pop() *tailTrace {
+	t := b.queue[0]
+	b.queue[0] = nil
+	b.queue = b.queue[1:]
+	return b.decide(b.remove(t))
+}
+
+// decide matches the trace against the policies, and remembers the decision
+// for its late chunks.
+func (b *tailBuffer) decide(t *tailTrace) *tailTrace {
+	chunks := make([]*pb.TraceChunk, 0, len(t.chunks))
+	for _, c := range t.chunks {
+		chunks = append(chunks, c.pt.TraceChunk)
+	}
+	t.policy = sampler.MatchTailPolicies(b.policies, chunks)
+
+	if len(b.decisionIDs) < tailSamplingDecisions {
+		b.decisionIDs = append(b.decisionIDs, t.id)
+	} else {
+		delete(b.decisions, b.decisionIDs[b.nextDecision])
+		b.decisionIDs[b.nextDecision] = t.id
+		b.nextDecision = (b.nextDecision + 1) % tailSamplingDecisions
+	}
+	b.decisions[t.id] = t.policy
+	return t
+}
+
+// unqueue removes the trace from the queue.
+func (b *tailBuffer) unqueue(t *tailTrace) {
+	for i, qt := range b.queue {
+		if qt == t {
+			b.queue = append(b.queue[:i], b.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// remove removes the trace from the traces and releases its memory. The
+// caller removes it from the queue.
+func (b *tailBuffer) remove(t *tailTrace) *tailTrace {
+	delete(b.traces, t.id)
+	b.budget.Release(t.size)
+	return t
+}
+
+// expired removes and decides the traces whose decision wait elapsed at now.
+func (b *tailBuffer) expired(now time.Time) []*tailTrace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var decided []*tailTrace
+	for len(b.queue) > 0 && now.Sub(b.queue[0].first) >= b.wait {
+		decided = append(decided, b.pop())
+	}
+	return decided
+}
+
+// flush removes and decides all the traces.
+func (b *tailBuffer) flush() []*tailTrace {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	decided := make([]*tailTrace, 0, len(b.queue))
+	for len(b.queue) > 0 {
+		decided = append(decided, b.pop())
+	}
+	return decided
+}
+
+// len returns the number of traces buffered.
+func (b *tailBuffer) len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.traces)
+}
+
+// tailPayload returns a copy of the tracer payload without its chunks, for
+// the chunks of p held by the tail sampling buffer.
+func tailPayload(p *pb.TracerPayload) *pb.TracerPayload {
+	return &pb.TracerPayload{
+		ContainerID:     p.ContainerID,
+		LanguageName:    p.LanguageName,
+		LanguageVersion: p.LanguageVersion,
+		TracerVersion:   p.TracerVersion,
+		RuntimeID:       p.RuntimeID,
+		Env:             p.Env,
+		Hostname:        p.Hostname,
+		AppVersion:      p.AppVersion,
+		Tags:            p.Tags,
+	}
+}
+
+// bufferTailChunk holds the chunk in the tail sampling buffer until its trace
+// is decided, deciding the traces evicted to make room for it. The chunk is
+// decided now if its trace already was.
+func (a *Agent) bufferTailChunk(now time.Time, ts *info.TagStats, payload *pb.TracerPayload, pt *traceutil.ProcessedTrace) {
+	c := &tailChunk{
+		pt:      pt,
+		ts:      ts,
+		payload: payload,
+		size:    int64(pt.TraceChunk.Msgsize()),
+	}
+	decided := a.tail.add(now, c)
+	var evicted int64
+	for _, t := range decided {
+		if !t.late {
+			evicted++
+		}
+	}
+	if evicted > 0 {
+		_ = a.Statsd.Count("datadog.trace_agent.tail_sampling.evicted", evicted, nil, 1)
+	}
+	a.decideTailTraces(now, decided)
+}
+
+// tailSamplingLoop decides the buffered traces whose decision wait elapsed,
+// and all of them when the agent uses more than its MaxMemory.
+func (a *Agent) tailSamplingLoop() {
+	defer close(a.tailDone)
+	defer watchdog.LogOnPanic(a.Statsd)
+
+	tick := time.NewTicker(tailSamplingTick)
+	defer tick.Stop()
+	wd := time.NewTicker(a.conf.WatchdogInterval)
+	defer wd.Stop()
+	wi := watchdog.NewCurrentInfo()
+	for {
+		select {
+		case now := <-tick.C:
+			a.decideTailTraces(now, a.tail.expired(now))
+		case now := <-wd.C:
+			if wi.OverMaxMemory(a.conf.MaxMemory) {
+				log.Warnf("Memory above %.2fM, deciding the traces held by the tail sampling buffer", a.conf.MaxMemory/1024/1024)
+				flushed := a.tail.flush()
+				_ = a.Statsd.Count("datadog.trace_agent.tail_sampling.evicted", int64(len(flushed)), nil, 1)
+				a.decideTailTraces(now, flushed)
+			}
+			_ = a.Statsd.Gauge("datadog.trace_agent.tail_sampling.traces", float64(a.tail.len()), nil, 1)
+			_ = a.Statsd.Gauge("datadog.trace_agent.tail_sampling.memory_bytes", float64(a.tail.budget.Used()), nil, 1)
+		case <-a.tailExit:
+			a.decideTailTraces(time.Now(), a.tail.flush())
+			return
+		}
+	}
+}
+
+// decideTailTraces keeps the whole traces which matched a tail sampling
+// policy, and samples the chunks of the other ones with the samplers, then
+// writes the chunks kept.
+func (a *Agent) decideTailTraces(now time.Time, traces []*tailTrace) {
+	if len(traces) == 0 {
+		return
+	}
+	// the kept chunks are grouped by the payload they were received in
+	pending := make(map[*pb.TracerPayload]*writer.SampledChunks)
+	write := func(payload *pb.TracerPayload, sc *writer.SampledChunks) {
+		sc.TracerPayload.Chunks = newChunksArray(sc.TracerPayload.Chunks)
+		a.TraceWriter.WriteChunks(sc)
+		delete(pending, payload)
+	}
+
+	for _, t := range traces {
+		policy := t.policy
+		if t.late {
+			_ = a.Statsd.Count("datadog.trace_agent.tail_sampling.late_chunks", int64(len(t.chunks)), nil, 1)
+		} else if policy != nil {
+			_ = a.Statsd.Count("datadog.trace_agent.tail_sampling.kept", 1, []string{"policy:" + policy.Name()}, 1)
+		}
+
+		for _, c := range t.chunks {
+			var keep bool
+			var numEvents int
+			if policy != nil && !isUserDrop(c.pt) {
+				keep = a.keepTailChunk(c.pt, policy)
+			} else {
+				keep, numEvents = a.sample(now, c.ts, c.pt)
+			}
+			if !keep && len(c.pt.TraceChunk.Spans) == 0 {
+				continue
+			}
+
+			sc, ok := pending[c.payload]
+			if !ok {
+				sc = &writer.SampledChunks{TracerPayload: tailPayload(c.payload)}
+				pending[c.payload] = sc
+			}
+			if !c.pt.TraceChunk.DroppedTrace {
+				a.setFirstTraceTags(c.pt.Root)
+				sc.SpanCount += int64(len(c.pt.TraceChunk.Spans))
+			}
+			sc.EventCount += int64(numEvents)
+			sc.Size += c.pt.TraceChunk.Msgsize()
+			sc.TracerPayload.Chunks = append(sc.TracerPayload.Chunks, c.pt.TraceChunk)
+			if sc.Size > writer.MaxPayloadSize {
+				// payload size is getting big; flush what we have so far
+				write(c.payload, sc)
+			}
+		}
+	}
+	for payload, sc := range pending {
+		write(payload, sc)
+	}
+}
+
+// keepTailChunk keeps the chunk of a trace matching the policy. The
+// auto-drop and unset priorities are raised to auto-keep.
+func (a *Agent) keepTailChunk(pt *traceutil.ProcessedTrace, policy *sampler.TailPolicy) bool {
+	chunk := pt.TraceChunk
+	if chunk.Tags == nil {
+		chunk.Tags = make(map[string]string)
+	}
+	chunk.Tags[tagTailSamplingPolicy] = policy.Name()
+	priority, ok := sampler.GetSamplingPriority(chunk)
+	if !ok || priority == sampler.PriorityAutoDrop {
+		chunk.Priority = int32(sampler.PriorityAutoKeep)
+		priority = sampler.PriorityAutoKeep
+	}
+	chunk.DroppedTrace = false
+	a.SamplerMetrics.RecordMetricsKey(true, sampler.NewMetricsKey(pt.Root.Service, pt.TracerEnv, sampler.NameTail, priority))
+	return true
+}
+
+// isUserDrop reports whether the chunk was dropped by the user, such chunks
+// are sampled as usual whatever the tail sampling policy.
+func isUserDrop(pt *traceutil.ProcessedTrace) bool {
+	priority, _ := sampler.GetSamplingPriority(pt.TraceChunk)
+	return priority == sampler.PriorityUserDrop
+}
@@ -0,0 +1,196 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+func testTailChunk(traceID uint64, size int64) *tailChunk {
+	span := &pb.Span{TraceID: traceID, SpanID: 1}
+	return &tailChunk{
+		pt: &traceutil.ProcessedTrace{
+			TraceChunk: &pb.TraceChunk{Spans: []*pb.Span{span}},
+			Root:       span,
+		},
+		size: size,
+	}
+}
+
+func tailTraceIDs(traces []*tailTrace) []uint64 {
+	ids := make([]uint64, 0, len(traces))
+	for _, t := range traces {
+		ids = append(ids, t.id)
+	}
+	return ids
+}
+
+func TestTailBuffer(t *testing.T) {
+	newBuffer := func(maxTraces int, maxMemory int64) *tailBuffer {
+		conf := config.New()
+		conf.TailSampling.Enabled = true
+		conf.TailSampling.MaxTraces = maxTraces
+		conf.TailSampling.MaxMemory = maxMemory
+		return newTailBuffer(conf)
+	}
+	now := time.Now()
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Nil(t, newTailBuffer(config.New()))
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		b := newBuffer(10, 1000)
+		assert.Empty(t, b.add(now, testTailChunk(1, 10)))
+		assert.Empty(t, b.add(now.Add(time.Second), testTailChunk(2, 10)))
+		assert.Empty(t, b.add(now.Add(2*time.Second), testTailChunk(1, 10)))
+		assert.Equal(t, 2, b.len())
+		assert.EqualValues(t, 30, b.budget.Used())
+
+		decided := b.expired(now.Add(b.wait))
+		require.Len(t, decided, 1)
+		assert.EqualValues(t, 1, decided[0].id)
+		assert.Len(t, decided[0].chunks, 2)
+		assert.EqualValues(t, 10, b.budget.Used())
+
+		assert.Equal(t, []uint64{2}, tailTraceIDs(b.flush()))
+		assert.Zero(t, b.len())
+		assert.Zero(t, b.budget.Used())
+	})
+
+	t.Run("max-traces", func(t *testing.T) {
+		b := newBuffer(2, 1000)
+		assert.Empty(t, b.add(now, testTailChunk(1, 10)))
+		assert.Empty(t, b.add(now, testTailChunk(2, 10)))
+		assert.Empty(t, b.add(now, testTailChunk(2, 10)))
+		assert.Equal(t, []uint64{1}, tailTraceIDs(b.add(now, testTailChunk(3, 10))))
+		assert.Equal(t, 2, b.len())
+	})
+
+	t.Run("max-memory", func(t *testing.T) {
+		b := newBuffer(10, 100)
+		assert.Empty(t, b.add(now, testTailChunk(1, 40)))
+		assert.Empty(t, b.add(now, testTailChunk(2, 40)))
+		assert.Equal(t, []uint64{1}, tailTraceIDs(b.add(now, testTailChunk(3, 40))))
+		assert.EqualValues(t, 80, b.budget.Used())
+
+		// a chunk larger than the budget is decided with its trace
+		decided := b.add(now, testTailChunk(2, 200))
+		assert.Equal(t, []uint64{2}, tailTraceIDs(decided))
+		assert.Len(t, decided[0].chunks, 2)
+		assert.Equal(t, 1, b.len())
+		assert.EqualValues(t, 40, b.budget.Used())
+	})
+
+	t.Run("late", func(t *testing.T) {
+		b := newBuffer(10, 100)
+		b.policies = sampler.NewTailPolicies([]*config.TailSamplingPolicy{{Name: "errors", Error: true}})
+		c := testTailChunk(1, 10)
+		c.pt.TraceChunk.Spans[0].Error = 1
+		assert.Empty(t, b.add(now, c))
+		assert.Empty(t, b.add(now, testTailChunk(2, 10)))
+		decided := b.flush()
+		require.Len(t, decided, 2)
+		assert.Equal(t, "errors", decided[0].policy.Name())
+		assert.Nil(t, decided[1].policy)
+
+		// the late chunks follow the decision on their trace, without being buffered
+		for id, policy := range map[uint64]*sampler.TailPolicy{1: b.policies[0], 2: nil} {
+			decided = b.add(now.Add(b.wait), testTailChunk(id, 10))
+			require.Len(t, decided, 1)
+			assert.True(t, decided[0].late)
+			assert.Equal(t, id, decided[0].id)
+			assert.Equal(t, policy, decided[0].policy)
+		}
+		assert.Zero(t, b.len())
+		assert.Zero(t, b.budget.Used())
+
+		// the chunk of a trace evicted to make room for it is late too
+		assert.Empty(t, b.add(now, testTailChunk(3, 60)))
+		decided = b.add(now, testTailChunk(3, 60))
+		assert.Equal(t, []uint64{3, 3}, tailTraceIDs(decided))
+		assert.True(t, decided[1].late)
+		assert.Zero(t, b.budget.Used())
+	})
+
+	t.Run("bounded-decisions", func(t *testing.T) {
+		b := newBuffer(0, 0)
+		for id := uint64(0); id <= tailSamplingDecisions; id++ {
+			b.decide(&tailTrace{id: id})
+		}
+		assert.Len(t, b.decisions, tailSamplingDecisions)
+		assert.NotContains(t, b.decisions, uint64(0))
+		assert.Contains(t, b.decisions, uint64(tailSamplingDecisions))
+	})
+}
+
+func TestTailSampling(t *testing.T) {
+	conf := config.New()
+	conf.Endpoints[0].APIKey = "test"
+	conf.TailSampling.Enabled = true
+	conf.TailSampling.Policies = []*config.TailSamplingPolicy{{Name: "errors", Error: true}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, conf, telemetry.NewNoopCollector())
+
+	processWithPriority := func(span *pb.Span, priority sampler.SamplingPriority) {
+		chunk := testutil.TraceChunkWithSpan(span)
+		chunk.Priority = int32(priority)
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+	}
+	process := func(span *pb.Span) { processWithPriority(span, sampler.PriorityAutoDrop) }
+	// the error is in the second chunk of the trace
+	process(&pb.Span{TraceID: 1, SpanID: 1, Service: "front", Name: "request", Start: 0, Duration: 100})
+	process(&pb.Span{TraceID: 1, SpanID: 2, ParentID: 1, Service: "back", Name: "query", Start: 10, Duration: 10, Error: 1})
+	process(&pb.Span{TraceID: 2, SpanID: 3, Service: "front", Name: "request", Start: 0, Duration: 100})
+	// the chunk dropped by the user stays dropped, whatever the policy
+	processWithPriority(&pb.Span{TraceID: 1, SpanID: 5, ParentID: 1, Service: "auth", Name: "check", Start: 5, Duration: 5}, sampler.PriorityUserDrop)
+
+	// stats are computed on all the traces before they are decided
+	assert.Len(t, agnt.Concentrator.(*mockConcentrator).Reset(), 4)
+	assert.Empty(t, agnt.TraceWriter.(*mockTraceWriter).payloads)
+	assert.Equal(t, 2, agnt.tail.len())
+
+	agnt.decideTailTraces(time.Now(), agnt.tail.flush())
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	var kept []*pb.TraceChunk
+	for _, p := range payloads {
+		kept = append(kept, p.TracerPayload.Chunks...)
+	}
+	require.Len(t, kept, 2)
+	for _, chunk := range kept {
+		assert.EqualValues(t, 1, chunk.Spans[0].TraceID)
+		assert.EqualValues(t, sampler.PriorityAutoKeep, chunk.Priority)
+		assert.False(t, chunk.DroppedTrace)
+		assert.Equal(t, "errors", chunk.Tags[tagTailSamplingPolicy])
+	}
+
+	// a late chunk of the kept trace is kept as well, without being buffered
+	process(&pb.Span{TraceID: 1, SpanID: 4, ParentID: 1, Service: "back", Name: "cache", Start: 20, Duration: 10})
+	assert.Zero(t, agnt.tail.len())
+	payloads = agnt.TraceWriter.(*mockTraceWriter).payloads
+	late := payloads[len(payloads)-1].TracerPayload.Chunks
+	require.Len(t, late, 1)
+	assert.EqualValues(t, 4, late[0].Spans[0].SpanID)
+	assert.Equal(t, "errors", late[0].Tags[tagTailSamplingPolicy])
+}
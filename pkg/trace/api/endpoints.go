@@ -94,6 +94,18 @@ var endpoints = []Endpoint{
 		Pattern: "/v0.7/traces",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.handleWithVersion(V07, r.handleTraces) },
 	},
+	{
+		Pattern: "/api/v2/spans",
+		Handler: func(r *HTTPReceiver) http.Handler {
+			return r.handleWithVersion(zipkinV2, r.handleTranslatedTraces(decodeZipkinSpans))
+		},
+	},
+	{
+		Pattern: "/api/traces",
+		Handler: func(r *HTTPReceiver) http.Handler {
+			return r.handleWithVersion(jaeger, r.handleTranslatedTraces(decodeJaegerBatch))
+		},
+	},
 	{
 		Pattern: "/profiling/v1/input",
 		Handler: func(r *HTTPReceiver) http.Handler { return r.profileProxyHandler() },
@@ -0,0 +1,767 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	// jaegerFlagDebug is the flag of the Jaeger spans forced to be sampled.
+	jaegerFlagDebug = 0x2
+	// jaegerRefChildOf is the type of the references to the parent span.
+	jaegerRefChildOf = 0
+)
+
+type jaegerTag struct {
+	key   string
+	value pcommon.Value
+}
+
+type jaegerLog struct {
+	timestamp pcommon.Timestamp
+	fields    []jaegerTag
+}
+
+type jaegerRef struct {
+	refType int64
+	traceID pcommon.TraceID
+	spanID  pcommon.SpanID
+}
+
+type jaegerProcess struct {
+	serviceName string
+	tags        []jaegerTag
+}
+
+// jaegerSpan is a span of the Jaeger model, decoded from Thrift or Protobuf.
+type jaegerSpan struct {
+	traceID       pcommon.TraceID
+	spanID        pcommon.SpanID
+	parentSpanID  pcommon.SpanID
+	operationName string
+	references    []jaegerRef
+	flags         int64
+	start         pcommon.Timestamp
+	// duration is in nanoseconds.
+	duration int64
+	tags     []jaegerTag
+	logs     []jaegerLog
+	// process is only set when it differs from the one of the batch.
+	process *jaegerProcess
+}
+
+type jaegerBatch struct {
+	process *jaegerProcess
+	spans   []*jaegerSpan
+}
+
+// decodeJaegerBatch decodes a Jaeger batch, encoded as a jaeger.thrift Batch
+// in the binary protocol or as a jaeger.api_v2.Batch, into OTLP traces.
+func decodeJaegerBatch(mediaType string, body []byte) (ptrace.Traces, error) {
+	var batch *jaegerBatch
+	var err error
+	switch mediaType {
+	case "application/x-thrift", "application/vnd.apache.thrift.binary":
+		batch, err = decodeJaegerThrift(body)
+	case "application/x-protobuf", "application/protobuf":
+		batch, err = decodeJaegerProto(body)
+	default:
+		return ptrace.Traces{}, fmt.Errorf("unsupported media type: %q", mediaType)
+	}
+	if err != nil {
+		return ptrace.Traces{}, err
+	}
+	return batch.translate(), nil
+}
+
+// translate returns the spans of the batch, grouped by the services of their
+// process.
+func (b *jaegerBatch) translate() ptrace.Traces {
+	translated := newTranslatedSpans()
+	for _, s := range b.spans {
+		process := s.process
+		if process == nil {
+			process = b.process
+		}
+		if process == nil {
+			process = &jaegerProcess{}
+		}
+		span := translated.appendSpan(process.serviceName, func(attrs pcommon.Map) {
+			for _, tag := range process.tags {
+				key := tag.key
+				if key == "hostname" {
+					key = semconv.AttributeHostName
+				}
+				tag.value.CopyTo(attrs.PutEmpty(key))
+			}
+		})
+		s.translate(span)
+	}
+	return translated.traces
+}
+
+func (s *jaegerSpan) translate(span ptrace.Span) {
+	span.SetTraceID(s.traceID)
+	span.SetSpanID(s.spanID)
+	span.SetName(s.operationName)
+	span.SetStartTimestamp(s.start)
+	span.SetEndTimestamp(s.start + pcommon.Timestamp(s.duration))
+
+	parentID := s.parentSpanID
+	for _, ref := range s.references {
+		if ref.spanID == parentID {
+			continue
+		}
+		if parentID.IsEmpty() && ref.refType == jaegerRefChildOf && ref.traceID == s.traceID {
+			parentID = ref.spanID
+			continue
+		}
+		link := span.Links().AppendEmpty()
+		link.SetTraceID(ref.traceID)
+		link.SetSpanID(ref.spanID)
+	}
+	span.SetParentSpanID(parentID)
+
+	for _, tag := range s.tags {
+		setSpanTag(span, tag.key, tag.value)
+	}
+	if s.flags&jaegerFlagDebug != 0 {
+		if _, ok := span.Attributes().Get("sampling.priority"); !ok {
+			// debug spans are always kept
+			span.Attributes().PutInt("sampling.priority", 2)
+		}
+	}
+	for _, l := range s.logs {
+		event := span.Events().AppendEmpty()
+		event.SetTimestamp(l.timestamp)
+		for _, field := range l.fields {
+			if field.key == "event" && field.value.Type() == pcommon.ValueTypeStr {
+				event.SetName(field.value.Str())
+				continue
+			}
+			field.value.CopyTo(event.Attributes().PutEmpty(field.key))
+		}
+	}
+}
+
+// jaegerTraceID returns the trace ID of the high and low 64 bits of a
+// Jaeger trace ID.
+func jaegerTraceID(high, low int64) pcommon.TraceID {
+	var id pcommon.TraceID
+	binary.BigEndian.PutUint64(id[:8], uint64(high))
+	binary.BigEndian.PutUint64(id[8:], uint64(low))
+	return id
+}
+
+func jaegerSpanID(id int64) pcommon.SpanID {
+	var spanID pcommon.SpanID
+	binary.BigEndian.PutUint64(spanID[:], uint64(id))
+	return spanID
+}
+
+// Thrift types of the binary protocol.
+const (
+	thriftStop   = 0
+	thriftBool   = 2
+	thriftByte   = 3
+	thriftDouble = 4
+	thriftI16    = 6
+	thriftI32    = 8
+	thriftI64    = 10
+	thriftString = 11
+	thriftStruct = 12
+	thriftMap    = 13
+	thriftSet    = 14
+	thriftList   = 15
+)
+
+// thriftMaxDepth is the maximum nesting of the skipped thrift values.
+const thriftMaxDepth = 64
+
+var errThriftTruncated = errors.New("thrift: truncated payload")
+
+// thriftReader reads the values of a message in the Thrift binary protocol.
+type thriftReader struct {
+	b     []byte
+	depth int
+}
+
+func (r *thriftReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.b) {
+		return nil, errThriftTruncated
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b, nil
+}
+
+func (r *thriftReader) readByte() (byte, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (r *thriftReader) readI16() (int16, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *thriftReader) readI32() (int32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *thriftReader) readI64() (int64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(b)), nil
+}
+
+func (r *thriftReader) readDouble() (float64, error) {
+	v, err := r.readI64()
+	return math.Float64frombits(uint64(v)), err
+}
+
+func (r *thriftReader) readBinary() ([]byte, error) {
+	n, err := r.readI32()
+	if err != nil {
+		return nil, err
+	}
+	return r.next(int(n))
+}
+
+func (r *thriftReader) readString() (string, error) {
+	b, err := r.readBinary()
+	return string(b), err
+}
+
+// readStruct calls f with the type and the identifier of each field of a
+// struct, f having to read or skip its value.
+func (r *thriftReader) readStruct(f func(typ byte, id int16) error) error {
+	for {
+		typ, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		if typ == thriftStop {
+			return nil
+		}
+		id, err := r.readI16()
+		if err != nil {
+			return err
+		}
+		if err := f(typ, id); err != nil {
+			return err
+		}
+	}
+}
+
+// readList calls f for each element of a list whose elements are of type
+// typ, f having to read its value.
+func (r *thriftReader) readList(typ byte, f func() error) error {
+	elemType, err := r.readByte()
+	if err != nil {
+		return err
+	}
+	size, err := r.readI32()
+	if err != nil {
+		return err
+	}
+	if size < 0 || int(size) > len(r.b) {
+		return errThriftTruncated
+	}
+	if elemType != typ {
+		return fmt.Errorf("thrift: unexpected list of type %d", elemType)
+	}
+	for i := int32(0); i < size; i++ {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skip skips a value of type typ.
+func (r *thriftReader) skip(typ byte) error {
+	var err error
+	switch typ {
+	case thriftBool, thriftByte:
+		_, err = r.next(1)
+	case thriftI16:
+		_, err = r.next(2)
+	case thriftI32:
+		_, err = r.next(4)
+	case thriftDouble, thriftI64:
+		_, err = r.next(8)
+	case thriftString:
+		_, err = r.readBinary()
+	case thriftStruct, thriftMap, thriftSet, thriftList:
+		if r.depth++; r.depth > thriftMaxDepth {
+			return errors.New("thrift: maximum depth exceeded")
+		}
+		defer func() { r.depth-- }()
+		err = r.skipContainer(typ)
+	default:
+		err = fmt.Errorf("thrift: unknown type %d", typ)
+	}
+	return err
+}
+
+func (r *thriftReader) skipContainer(typ byte) error {
+	switch typ {
+	case thriftStruct:
+		return r.readStruct(func(typ byte, _ int16) error { return r.skip(typ) })
+	case thriftMap:
+		keyType, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		valueType, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		size, err := r.readI32()
+		if err != nil {
+			return err
+		}
+		if size < 0 || int(size) > len(r.b) {
+			return errThriftTruncated
+		}
+		for i := int32(0); i < size; i++ {
+			if err := r.skip(keyType); err != nil {
+				return err
+			}
+			if err := r.skip(valueType); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		elemType, err := r.readByte()
+		if err != nil {
+			return err
+		}
+		size, err := r.readI32()
+		if err != nil {
+			return err
+		}
+		if size < 0 || int(size) > len(r.b) {
+			return errThriftTruncated
+		}
+		for i := int32(0); i < size; i++ {
+			if err := r.skip(elemType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// decodeJaegerThrift decodes a jaeger.thrift Batch.
+func decodeJaegerThrift(b []byte) (*jaegerBatch, error) {
+	r := &thriftReader{b: b}
+	batch := &jaegerBatch{}
+	err := r.readStruct(func(typ byte, id int16) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftStruct:
+			batch.process, err = r.readJaegerProcess()
+		case id == 2 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				span, err := r.readJaegerSpan()
+				batch.spans = append(batch.spans, span)
+				return err
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	return batch, err
+}
+
+func (r *thriftReader) readJaegerProcess() (*jaegerProcess, error) {
+	p := &jaegerProcess{}
+	err := r.readStruct(func(typ byte, id int16) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftString:
+			p.serviceName, err = r.readString()
+		case id == 2 && typ == thriftList:
+			p.tags, err = r.readJaegerTags()
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	return p, err
+}
+
+func (r *thriftReader) readJaegerSpan() (*jaegerSpan, error) {
+	s := &jaegerSpan{}
+	var traceIDLow, traceIDHigh, spanID, parentSpanID, start, duration int64
+	err := r.readStruct(func(typ byte, id int16) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			traceIDLow, err = r.readI64()
+		case id == 2 && typ == thriftI64:
+			traceIDHigh, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			spanID, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			parentSpanID, err = r.readI64()
+		case id == 5 && typ == thriftString:
+			s.operationName, err = r.readString()
+		case id == 6 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				ref, err := r.readJaegerRef()
+				s.references = append(s.references, ref)
+				return err
+			})
+		case id == 7 && typ == thriftI32:
+			var flags int32
+			flags, err = r.readI32()
+			s.flags = int64(flags)
+		case id == 8 && typ == thriftI64:
+			start, err = r.readI64()
+		case id == 9 && typ == thriftI64:
+			duration, err = r.readI64()
+		case id == 10 && typ == thriftList:
+			s.tags, err = r.readJaegerTags()
+		case id == 11 && typ == thriftList:
+			err = r.readList(thriftStruct, func() error {
+				l, err := r.readJaegerLog()
+				s.logs = append(s.logs, l)
+				return err
+			})
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	s.traceID = jaegerTraceID(traceIDHigh, traceIDLow)
+	s.spanID = jaegerSpanID(spanID)
+	if parentSpanID != 0 {
+		s.parentSpanID = jaegerSpanID(parentSpanID)
+	}
+	s.start = microsToTimestamp(start)
+	s.duration = int64(microsToTimestamp(duration))
+	return s, err
+}
+
+func (r *thriftReader) readJaegerRef() (jaegerRef, error) {
+	var ref jaegerRef
+	var traceIDLow, traceIDHigh, spanID int64
+	err := r.readStruct(func(typ byte, id int16) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI32:
+			var refType int32
+			refType, err = r.readI32()
+			ref.refType = int64(refType)
+		case id == 2 && typ == thriftI64:
+			traceIDLow, err = r.readI64()
+		case id == 3 && typ == thriftI64:
+			traceIDHigh, err = r.readI64()
+		case id == 4 && typ == thriftI64:
+			spanID, err = r.readI64()
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	ref.traceID = jaegerTraceID(traceIDHigh, traceIDLow)
+	ref.spanID = jaegerSpanID(spanID)
+	return ref, err
+}
+
+func (r *thriftReader) readJaegerLog() (jaegerLog, error) {
+	var l jaegerLog
+	err := r.readStruct(func(typ byte, id int16) error {
+		var err error
+		switch {
+		case id == 1 && typ == thriftI64:
+			var ts int64
+			ts, err = r.readI64()
+			l.timestamp = microsToTimestamp(ts)
+		case id == 2 && typ == thriftList:
+			l.fields, err = r.readJaegerTags()
+		default:
+			err = r.skip(typ)
+		}
+		return err
+	})
+	return l, err
+}
+
+// Jaeger thrift tag types.
+const (
+	jaegerThriftString = 0
+	jaegerThriftDouble = 1
+	jaegerThriftBool   = 2
+	jaegerThriftLong   = 3
+	jaegerThriftBinary = 4
+)
+
+func (r *thriftReader) readJaegerTags() ([]jaegerTag, error) {
+	var tags []jaegerTag
+	err := r.readList(thriftStruct, func() error {
+		var key, str string
+		var vType int32
+		var double float64
+		var boolean byte
+		var long int64
+		var bin []byte
+		err := r.readStruct(func(typ byte, id int16) error {
+			var err error
+			switch {
+			case id == 1 && typ == thriftString:
+				key, err = r.readString()
+			case id == 2 && typ == thriftI32:
+				vType, err = r.readI32()
+			case id == 3 && typ == thriftString:
+				str, err = r.readString()
+			case id == 4 && typ == thriftDouble:
+				double, err = r.readDouble()
+			case id == 5 && typ == thriftBool:
+				boolean, err = r.readByte()
+			case id == 6 && typ == thriftI64:
+				long, err = r.readI64()
+			case id == 7 && typ == thriftString:
+				bin, err = r.readBinary()
+			default:
+				err = r.skip(typ)
+			}
+			return err
+		})
+		tag := jaegerTag{key: key}
+		switch vType {
+		case jaegerThriftDouble:
+			tag.value = pcommon.NewValueDouble(double)
+		case jaegerThriftBool:
+			tag.value = pcommon.NewValueBool(boolean != 0)
+		case jaegerThriftLong:
+			tag.value = pcommon.NewValueInt(long)
+		case jaegerThriftBinary:
+			tag.value = pcommon.NewValueBytes()
+			tag.value.Bytes().FromRaw(bin)
+		default:
+			tag.value = pcommon.NewValueStr(str)
+		}
+		tags = append(tags, tag)
+		return err
+	})
+	return tags, err
+}
+
+// decodeJaegerProto decodes a jaeger.api_v2.Batch.
+func decodeJaegerProto(b []byte) (*jaegerBatch, error) {
+	batch := &jaegerBatch{}
+	err := rangeProtoFields(b, func(f protoField) error {
+		var err error
+		switch {
+		case f.num == 1 && f.typ == protowire.BytesType:
+			var span *jaegerSpan
+			span, err = decodeJaegerProtoSpan(f.b)
+			batch.spans = append(batch.spans, span)
+		case f.num == 2 && f.typ == protowire.BytesType:
+			batch.process, err = decodeJaegerProtoProcess(f.b)
+		}
+		return err
+	})
+	return batch, err
+}
+
+func decodeJaegerProtoProcess(b []byte) (*jaegerProcess, error) {
+	p := &jaegerProcess{}
+	err := rangeProtoFields(b, func(f protoField) error {
+		switch {
+		case f.num == 1 && f.typ == protowire.BytesType:
+			p.serviceName = string(f.b)
+		case f.num == 2 && f.typ == protowire.BytesType:
+			tag, err := decodeJaegerProtoKeyValue(f.b)
+			if err != nil {
+				return err
+			}
+			p.tags = append(p.tags, tag)
+		}
+		return nil
+	})
+	return p, err
+}
+
+func decodeJaegerProtoSpan(b []byte) (*jaegerSpan, error) {
+	s := &jaegerSpan{}
+	err := rangeProtoFields(b, func(f protoField) error {
+		if f.typ == protowire.VarintType {
+			if f.num == 5 {
+				s.flags = int64(f.v)
+			}
+			return nil
+		}
+		if f.typ != protowire.BytesType {
+			return nil
+		}
+		var err error
+		switch f.num {
+		case 1:
+			s.traceID, err = jaegerProtoTraceID(f.b)
+		case 2:
+			s.spanID, err = jaegerProtoSpanID(f.b)
+		case 3:
+			s.operationName = string(f.b)
+		case 4:
+			var ref jaegerRef
+			err = rangeProtoFields(f.b, func(f protoField) error {
+				var err error
+				switch f.num {
+				case 1:
+					ref.traceID, err = jaegerProtoTraceID(f.b)
+				case 2:
+					ref.spanID, err = jaegerProtoSpanID(f.b)
+				case 3:
+					ref.refType = int64(f.v)
+				}
+				return err
+			})
+			s.references = append(s.references, ref)
+		case 6:
+			var ns int64
+			ns, err = decodeProtoDuration(f.b)
+			s.start = pcommon.Timestamp(ns)
+		case 7:
+			s.duration, err = decodeProtoDuration(f.b)
+		case 8:
+			var tag jaegerTag
+			tag, err = decodeJaegerProtoKeyValue(f.b)
+			s.tags = append(s.tags, tag)
+		case 9:
+			var l jaegerLog
+			err = rangeProtoFields(f.b, func(f protoField) error {
+				var err error
+				switch f.num {
+				case 1:
+					var ns int64
+					ns, err = decodeProtoDuration(f.b)
+					l.timestamp = pcommon.Timestamp(ns)
+				case 2:
+					var tag jaegerTag
+					tag, err = decodeJaegerProtoKeyValue(f.b)
+					l.fields = append(l.fields, tag)
+				}
+				return err
+			})
+			s.logs = append(s.logs, l)
+		case 10:
+			s.process, err = decodeJaegerProtoProcess(f.b)
+		}
+		return err
+	})
+	return s, err
+}
+
+// Jaeger api_v2 value types.
+const (
+	jaegerProtoString  = 0
+	jaegerProtoBool    = 1
+	jaegerProtoInt64   = 2
+	jaegerProtoFloat64 = 3
+	jaegerProtoBinary  = 4
+)
+
+func decodeJaegerProtoKeyValue(b []byte) (jaegerTag, error) {
+	var key, str string
+	var vType, boolean, long, double uint64
+	var bin []byte
+	err := rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			key = string(f.b)
+		case 2:
+			vType = f.v
+		case 3:
+			str = string(f.b)
+		case 4:
+			boolean = f.v
+		case 5:
+			long = f.v
+		case 6:
+			double = f.v
+		case 7:
+			bin = f.b
+		}
+		return nil
+	})
+	tag := jaegerTag{key: key}
+	switch vType {
+	case jaegerProtoBool:
+		tag.value = pcommon.NewValueBool(boolean != 0)
+	case jaegerProtoInt64:
+		tag.value = pcommon.NewValueInt(int64(long))
+	case jaegerProtoFloat64:
+		tag.value = pcommon.NewValueDouble(math.Float64frombits(double))
+	case jaegerProtoBinary:
+		tag.value = pcommon.NewValueBytes()
+		tag.value.Bytes().FromRaw(bin)
+	default:
+		tag.value = pcommon.NewValueStr(str)
+	}
+	return tag, err
+}
+
+// decodeProtoDuration decodes a google.protobuf.Timestamp or Duration, which
+// share their fields, into nanoseconds.
+func decodeProtoDuration(b []byte) (int64, error) {
+	var seconds, nanos int64
+	err := rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			seconds = int64(f.v)
+		case 2:
+			nanos = int64(int32(f.v))
+		}
+		return nil
+	})
+	return seconds*1e9 + nanos, err
+}
+
+func jaegerProtoTraceID(b []byte) (pcommon.TraceID, error) {
+	var id pcommon.TraceID
+	if len(b) > len(id) {
+		return id, fmt.Errorf("invalid trace ID of %d bytes", len(b))
+	}
+	copy(id[len(id)-len(b):], b)
+	return id, nil
+}
+
+func jaegerProtoSpanID(b []byte) (pcommon.SpanID, error) {
+	var id pcommon.SpanID
+	if len(b) > len(id) {
+		return id, fmt.Errorf("invalid span ID of %d bytes", len(b))
+	}
+	copy(id[len(id)-len(b):], b)
+	return id, nil
+}
@@ -0,0 +1,250 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+// thriftWriter writes the values of a message in the Thrift binary protocol.
+type thriftWriter struct {
+	bytes.Buffer
+}
+
+func (w *thriftWriter) field(typ byte, id int16) {
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, id)
+}
+
+func (w *thriftWriter) stop() { w.WriteByte(thriftStop) }
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(thriftI32, id)
+	binary.Write(w, binary.BigEndian, v)
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(thriftI64, id)
+	binary.Write(w, binary.BigEndian, v)
+}
+
+func (w *thriftWriter) str(id int16, s string) {
+	w.field(thriftString, id)
+	binary.Write(w, binary.BigEndian, int32(len(s)))
+	w.WriteString(s)
+}
+
+func (w *thriftWriter) list(id int16, typ byte, n int) {
+	w.field(thriftList, id)
+	w.WriteByte(typ)
+	binary.Write(w, binary.BigEndian, int32(n))
+}
+
+func (w *thriftWriter) strTag(key, value string) {
+	w.str(1, key)
+	w.i32(2, jaegerThriftString)
+	w.str(3, value)
+	w.stop()
+}
+
+func TestJaegerThrift(t *testing.T) {
+	var w thriftWriter
+	// process
+	w.field(thriftStruct, 1)
+	w.str(1, "inventory")
+	w.list(2, thriftStruct, 2)
+	w.strTag("hostname", "host-1")
+	w.strTag("jaeger.version", "Go-2.30.0")
+	w.stop()
+	// spans
+	w.list(2, thriftStruct, 2)
+	// the root span
+	w.i64(1, 0x48485a3953bb6124)
+	w.i64(2, 0x463ac35c9f6413ad)
+	w.i64(3, 1)
+	w.i64(4, 0)
+	w.str(5, "GET /items")
+	w.i32(7, jaegerFlagDebug)
+	w.i64(8, 1556604172355737)
+	w.i64(9, 1431)
+	w.list(10, thriftStruct, 3)
+	w.strTag("span.kind", "server")
+	w.str(1, "error")
+	w.i32(2, jaegerThriftBool)
+	w.field(thriftBool, 5)
+	w.WriteByte(1)
+	w.stop()
+	w.str(1, "http.status_code")
+	w.i32(2, jaegerThriftLong)
+	w.i64(6, 500)
+	w.stop()
+	w.list(11, thriftStruct, 1)
+	w.i64(1, 1556604172355800)
+	w.list(2, thriftStruct, 2)
+	w.strTag("event", "exception")
+	w.strTag("exception.message", "out of stock")
+	w.stop()
+	// unknown fields are skipped
+	w.field(thriftMap, 99)
+	w.WriteByte(thriftString)
+	w.WriteByte(thriftI32)
+	binary.Write(&w, binary.BigEndian, int32(1))
+	binary.Write(&w, binary.BigEndian, int32(1))
+	w.WriteString("k")
+	binary.Write(&w, binary.BigEndian, int32(1))
+	w.stop()
+	// the child span, following from another trace
+	w.i64(1, 0x48485a3953bb6124)
+	w.i64(2, 0x463ac35c9f6413ad)
+	w.i64(3, 2)
+	w.i64(4, 1)
+	w.str(5, "SELECT")
+	w.list(6, thriftStruct, 1)
+	w.i32(1, 1)
+	w.i64(2, 42)
+	w.i64(3, 0)
+	w.i64(4, 7)
+	w.stop()
+	w.i64(8, 1556604172355900)
+	w.i64(9, 100)
+	w.list(10, thriftStruct, 2)
+	w.strTag("span.kind", "client")
+	w.strTag("peer.service", "postgres")
+	w.stop()
+	w.stop()
+
+	conf := newTestReceiverConfig()
+	r := newTestReceiverFromConfig(conf)
+	req := httptest.NewRequest(http.MethodPost, "/api/traces", bytes.NewReader(w.Bytes()))
+	req.Header.Set("Content-Type", "application/x-thrift")
+	rr, payloads := serveTranslated(t, r, jaeger, req)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	require.Len(t, payloads, 1)
+
+	tp := payloads[0].TracerPayload
+	assert.Equal(t, "host-1", tp.Hostname)
+	require.Len(t, tp.Chunks, 1)
+	assert.EqualValues(t, sampler.PriorityUserKeep, tp.Chunks[0].Priority)
+	require.Len(t, tp.Chunks[0].Spans, 2)
+
+	root, child := tp.Chunks[0].Spans[0], tp.Chunks[0].Spans[1]
+	assert.Equal(t, "inventory", root.Service)
+	assert.Equal(t, "GET /items", root.Resource)
+	assert.Equal(t, uint64(0x48485a3953bb6124), root.TraceID)
+	assert.Equal(t, "463ac35c9f6413ad48485a3953bb6124", root.Meta["otel.trace_id"])
+	assert.Zero(t, root.ParentID)
+	assert.Equal(t, int64(1431000), root.Duration)
+	assert.Equal(t, "server", root.Meta["span.kind"])
+	assert.Equal(t, int32(1), root.Error)
+	assert.Equal(t, "out of stock", root.Meta["error.msg"])
+	assert.Equal(t, float64(500), root.Metrics["http.status_code"])
+	assert.Equal(t, "Go-2.30.0", root.Meta["jaeger.version"])
+	assert.Contains(t, root.Meta["events"], `"name":"exception"`)
+
+	assert.Equal(t, root.SpanID, child.ParentID)
+	assert.Equal(t, uint64(2), child.SpanID)
+	assert.Equal(t, "client", child.Meta["span.kind"])
+	assert.Equal(t, "postgres", child.Meta["peer.service"])
+	assert.Zero(t, child.Error)
+	assert.Contains(t, child.Meta["_dd.span_links"], `"span_id":"0000000000000007"`)
+}
+
+func TestJaegerThriftInvalid(t *testing.T) {
+	var w thriftWriter
+	w.list(2, thriftStruct, 1)
+	w.i64(1, 1)
+	w.str(5, "truncated")
+
+	_, err := decodeJaegerBatch("application/x-thrift", w.Bytes()[:w.Len()-2])
+	assert.Error(t, err)
+
+	// a list larger than the payload
+	w.Reset()
+	w.list(2, thriftStruct, 1<<30)
+	_, err = decodeJaegerBatch("application/x-thrift", w.Bytes())
+	assert.Error(t, err)
+
+	// deeply nested values
+	w.Reset()
+	for i := 0; i < 2*thriftMaxDepth; i++ {
+		w.field(thriftStruct, 99)
+	}
+	_, err = decodeJaegerBatch("application/x-thrift", w.Bytes())
+	assert.Error(t, err)
+}
+
+func TestJaegerProto(t *testing.T) {
+	appendMessage := func(b []byte, num protowire.Number, m []byte) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendBytes(b, m)
+	}
+	appendString := func(b []byte, num protowire.Number, s string) []byte {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		return protowire.AppendString(b, s)
+	}
+	appendVarint := func(b []byte, num protowire.Number, v uint64) []byte {
+		b = protowire.AppendTag(b, num, protowire.VarintType)
+		return protowire.AppendVarint(b, v)
+	}
+	traceID := []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 5}
+
+	var ref []byte
+	ref = appendMessage(ref, 1, traceID)
+	ref = appendMessage(ref, 2, []byte{0, 0, 0, 0, 0, 0, 0, 3})
+	ref = appendVarint(ref, 3, jaegerRefChildOf)
+
+	var kv []byte
+	kv = appendString(kv, 1, "retries")
+	kv = appendVarint(kv, 2, jaegerProtoInt64)
+	kv = appendVarint(kv, 5, 2)
+
+	var start, duration []byte
+	start = appendVarint(start, 1, 1556604172)
+	start = appendVarint(start, 2, 355737000)
+	duration = appendVarint(duration, 2, 2000)
+
+	var span []byte
+	span = appendMessage(span, 1, traceID)
+	span = appendMessage(span, 2, []byte{0, 0, 0, 0, 0, 0, 0, 4})
+	span = appendString(span, 3, "charge")
+	span = appendMessage(span, 4, ref)
+	span = appendMessage(span, 6, start)
+	span = appendMessage(span, 7, duration)
+	span = appendMessage(span, 8, kv)
+
+	var process []byte
+	process = appendString(process, 1, "billing")
+
+	var batch []byte
+	batch = appendMessage(batch, 1, span)
+	batch = appendMessage(batch, 2, process)
+
+	traces, err := decodeJaegerBatch("application/x-protobuf", batch)
+	require.NoError(t, err)
+	require.Equal(t, 1, traces.SpanCount())
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	tp := r.translatedTracerPayload(traces.ResourceSpans().At(0), "")
+	assert.EqualValues(t, sampler.PriorityNone, tp.Chunks[0].Priority)
+
+	ddspan := tp.Chunks[0].Spans[0]
+	assert.Equal(t, "billing", ddspan.Service)
+	assert.Equal(t, uint64(5), ddspan.TraceID)
+	assert.Equal(t, uint64(4), ddspan.SpanID)
+	assert.Equal(t, uint64(3), ddspan.ParentID)
+	assert.Equal(t, int64(1556604172355737000), ddspan.Start)
+	assert.Equal(t, int64(2000), ddspan.Duration)
+	assert.Equal(t, float64(2), ddspan.Metrics["retries"])
+}
@@ -0,0 +1,305 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/transform"
+)
+
+// tracesDecoder decodes a payload of spans in another tracing format, whose
+// media type is mediaType, into OTLP traces.
+type tracesDecoder func(mediaType string, body []byte) (ptrace.Traces, error)
+
+// handleTranslatedTraces returns the handler of the requests whose spans are
+// decoded by decode, then converted the way the OTLP spans are, so that they
+// go through the same normalization, sampling and stats.
+func (r *HTTPReceiver) handleTranslatedTraces(decode tracesDecoder) func(Version, http.ResponseWriter, *http.Request) {
+	return func(v Version, w http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		if req.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		select {
+		// Wait for the semaphore to become available, allowing the handler to
+		// decode its payload.
+		case r.recvsem <- struct{}{}:
+		case <-time.After(time.Duration(r.conf.DecoderTimeout) * time.Millisecond):
+			log.Debugf("trace-agent is overwhelmed, a %s payload has been rejected", v)
+			io.Copy(io.Discard, req.Body) //nolint:errcheck
+			w.WriteHeader(http.StatusTooManyRequests)
+			r.tagStats(v, req.Header, "").PayloadRefused.Inc()
+			return
+		}
+		defer func() { <-r.recvsem }()
+
+		start := time.Now()
+		traces, err := r.decodeTranslatedRequest(req, decode)
+		defer func(err error) {
+			tags := []string{"endpoint_version:" + string(v), fmt.Sprintf("success:%v", err == nil)}
+			_ = r.statsd.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), tags, 1)
+		}(err)
+		if err != nil {
+			httpDecodingError(err, []string{"handler:traces", fmt.Sprintf("v:%s", v)}, w, r.statsd)
+			ts := r.tagStats(v, req.Header, "")
+			switch err {
+			case apiutil.ErrLimitedReaderLimitReached:
+				ts.TracesDropped.PayloadTooLarge.Inc()
+			case io.EOF, io.ErrUnexpectedEOF:
+				ts.TracesDropped.EOF.Inc()
+			default:
+				ts.TracesDropped.DecodingError.Inc()
+			}
+			log.Errorf("Cannot decode %s traces payload: %v", v, err)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+
+		containerID := r.containerIDProvider.GetContainerID(req.Context(), req.Header)
+		bytes := req.Body.(*apiutil.LimitedReader).Count
+		rspans := traces.ResourceSpans()
+		for i := 0; i < rspans.Len(); i++ {
+			tp := r.translatedTracerPayload(rspans.At(i), containerID)
+			if len(tp.Chunks) == 0 {
+				continue
+			}
+			ts := r.tagStats(v, req.Header, tp.Chunks[0].Spans[0].Service)
+			ts.TracesReceived.Add(int64(len(tp.Chunks)))
+			ts.TracesBytes.Add(bytes)
+			ts.PayloadAccepted.Inc()
+			// the bytes of the request are only counted once
+			bytes = 0
+			r.out <- &Payload{
+				Source:                 ts,
+				TracerPayload:          tp,
+				ClientComputedTopLevel: r.conf.HasFeature("enable_otlp_compute_top_level_by_span_kind"),
+			}
+		}
+	}
+}
+
+// decodeTranslatedRequest reads the body of the request, decompressing it if
+// it is gzipped, and decodes it with decode.
+func (r *HTTPReceiver) decodeTranslatedRequest(req *http.Request, decode tracesDecoder) (ptrace.Traces, error) {
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return ptrace.Traces{}, err
+		}
+		defer gz.Close()
+		// the decompressed size is limited as well
+		body = apiutil.NewLimitedReader(gz, r.conf.MaxRequestBytes)
+	}
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return ptrace.Traces{}, err
+	}
+	return decode(getMediaType(req), buf)
+}
+
+// translatedTracerPayload converts the spans of a service into a tracer
+// payload, with a chunk per trace.
+func (r *HTTPReceiver) translatedTracerPayload(rspans ptrace.ResourceSpans, containerID string) *pb.TracerPayload {
+	otelres := rspans.Resource()
+	var env string
+	tracesByID := make(map[uint64]pb.Trace)
+	priorityByID := make(map[uint64]sampler.SamplingPriority)
+	var traceIDs []uint64
+	for i := 0; i < rspans.ScopeSpans().Len(); i++ {
+		libspans := rspans.ScopeSpans().At(i)
+		for j := 0; j < libspans.Spans().Len(); j++ {
+			otelspan := libspans.Spans().At(j)
+			traceID := traceutil.OTelTraceIDToUint64(otelspan.TraceID())
+			ddspan := transform.OtelSpanToDDSpan(otelspan, otelres, libspans.Scope(), r.conf)
+			if env == "" {
+				env = ddspan.Meta["env"]
+			}
+			if p, ok := ddspan.Metrics["_sampling_priority_v1"]; ok {
+				priorityByID[traceID] = sampler.SamplingPriority(p)
+			}
+			if _, ok := tracesByID[traceID]; !ok {
+				traceIDs = append(traceIDs, traceID)
+			}
+			tracesByID[traceID] = append(tracesByID[traceID], ddspan)
+		}
+	}
+
+	chunks := make([]*pb.TraceChunk, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		spans := tracesByID[traceID]
+		// without a decision by the user, the samplers of the agent decide
+		chunk := &pb.TraceChunk{Spans: spans, Priority: int32(sampler.PriorityNone)}
+		if p, ok := priorityByID[traceID]; ok && !r.conf.ProbabilisticSamplerEnabled {
+			chunk.Priority = int32(p)
+			if p.IsKeep() {
+				traceutil.SetMeta(spans[0], "_dd.p.dm", "-4")
+			}
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	hostname := traceutil.GetOTelAttrVal(otelres.Attributes(), true, transform.KeyDatadogHost, semconv.AttributeHostName)
+	if hostname == "" {
+		hostname = r.conf.Hostname
+	}
+	if env == "" {
+		env = r.conf.DefaultEnv
+	}
+	tp := &pb.TracerPayload{
+		Hostname:    hostname,
+		Env:         env,
+		ContainerID: containerID,
+		Chunks:      chunks,
+	}
+	if ctags := getContainerTags(r.conf.ContainerTags, containerID); ctags != "" {
+		tp.Tags = map[string]string{tagContainersTags: ctags}
+	}
+	return tp
+}
+
+// translatedSpans groups the translated spans by service, each service
+// being a resource of the traces.
+type translatedSpans struct {
+	traces    ptrace.Traces
+	byService map[string]ptrace.SpanSlice
+}
+
+func newTranslatedSpans() *translatedSpans {
+	return &translatedSpans{
+		traces:    ptrace.NewTraces(),
+		byService: make(map[string]ptrace.SpanSlice),
+	}
+}
+
+// appendSpan returns a new span of the service, calling setResource to fill
+// the attributes of its resource when the service is first seen.
+func (t *translatedSpans) appendSpan(service string, setResource func(pcommon.Map)) ptrace.Span {
+	spans, ok := t.byService[service]
+	if !ok {
+		rspans := t.traces.ResourceSpans().AppendEmpty()
+		attrs := rspans.Resource().Attributes()
+		if setResource != nil {
+			setResource(attrs)
+		}
+		attrs.PutStr(semconv.AttributeServiceName, service)
+		spans = rspans.ScopeSpans().AppendEmpty().Spans()
+		t.byService[service] = spans
+	}
+	return spans.AppendEmpty()
+}
+
+// setSpanTag sets a tag of a Zipkin or Jaeger span, mapping the ones that
+// have an OTLP counterpart to it.
+func setSpanTag(span ptrace.Span, key string, value pcommon.Value) {
+	switch key {
+	case "span.kind":
+		span.SetKind(spanKindFromTag(value.AsString()))
+	case "error":
+		if value.Type() == pcommon.ValueTypeBool && !value.Bool() || value.AsString() == "false" {
+			return
+		}
+		span.Status().SetCode(ptrace.StatusCodeError)
+		if msg := value.AsString(); value.Type() == pcommon.ValueTypeStr && msg != "true" && msg != "" && span.Status().Message() == "" {
+			// the Zipkin error tag holds the error message
+			span.Status().SetMessage(msg)
+		}
+	case semconv.OtelStatusCode:
+		switch value.AsString() {
+		case "ERROR":
+			span.Status().SetCode(ptrace.StatusCodeError)
+		case "OK":
+			span.Status().SetCode(ptrace.StatusCodeOk)
+		}
+	case semconv.OtelStatusDescription:
+		span.Status().SetMessage(value.AsString())
+	default:
+		value.CopyTo(span.Attributes().PutEmpty(key))
+	}
+}
+
+func spanKindFromTag(kind string) ptrace.SpanKind {
+	switch kind {
+	case "client", "CLIENT":
+		return ptrace.SpanKindClient
+	case "server", "SERVER":
+		return ptrace.SpanKindServer
+	case "producer", "PRODUCER":
+		return ptrace.SpanKindProducer
+	case "consumer", "CONSUMER":
+		return ptrace.SpanKindConsumer
+	case "internal", "INTERNAL":
+		return ptrace.SpanKindInternal
+	}
+	return ptrace.SpanKindUnspecified
+}
+
+// microsToTimestamp converts microseconds since the epoch, as used by Zipkin
+// and Jaeger, to a timestamp.
+func microsToTimestamp(us int64) pcommon.Timestamp {
+	return pcommon.Timestamp(us * int64(time.Microsecond))
+}
+
+// protoField is a field of a protobuf message. Its value is in v for the
+// varint and fixed size fields, and in b for the length-delimited ones.
+type protoField struct {
+	num protowire.Number
+	typ protowire.Type
+	v   uint64
+	b   []byte
+}
+
+// rangeProtoFields calls f with each field of the protobuf message b, to
+// decode the messages of the formats whose types aren't compiled in.
+func rangeProtoFields(b []byte, f func(protoField) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		field := protoField{num: num, typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			field.v, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, n = protowire.ConsumeFixed32(b)
+			field.v = uint64(v)
+		case protowire.Fixed64Type:
+			field.v, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			field.b, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := f(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
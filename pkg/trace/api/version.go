@@ -127,4 +127,26 @@ const (
 	// Response: Service sampling rates (see description in v04).
 	//
 	V07 Version = "v0.7"
+
+	// zipkinV2 API
+	//
+	// Request: Zipkin v2 spans.
+	// 	Content-Type: application/json or application/x-protobuf
+	// 	Payload: list of spans (https://zipkin.io/zipkin-api/zipkin2-api.yaml)
+	//
+	// Response: empty, with the 202 Accepted status.
+	//
+	zipkinV2 Version = "zipkin_v2"
+
+	// jaeger API
+	//
+	// Request: Jaeger batch of spans.
+	// 	Content-Type: application/x-thrift
+	// 	Payload: Batch (jaeger-idl/thrift/jaeger.thrift), in the binary protocol
+	// 	Content-Type: application/x-protobuf
+	// 	Payload: jaeger.api_v2.Batch (jaeger-idl/proto/api_v2/model.proto)
+	//
+	// Response: empty, with the 202 Accepted status.
+	//
+	jaeger Version = "jaeger"
 )
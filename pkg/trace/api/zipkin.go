@@ -0,0 +1,273 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// zipkinEndpoint is the network context of a Zipkin span.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int64  `json:"port"`
+}
+
+// ip returns the IP address of the endpoint, preferring IPv4.
+func (e *zipkinEndpoint) ip() string {
+	if e.IPv4 != "" {
+		return e.IPv4
+	}
+	return e.IPv6
+}
+
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// zipkinSpan is a span of the Zipkin v2 API. Its identifiers are hex-encoded,
+// and its timestamp and duration are in microseconds.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId"`
+	ID             string             `json:"id"`
+	Kind           string             `json:"kind"`
+	Name           string             `json:"name"`
+	Timestamp      int64              `json:"timestamp"`
+	Duration       int64              `json:"duration"`
+	Debug          bool               `json:"debug"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+// decodeZipkinSpans decodes a list of Zipkin v2 spans, encoded in JSON or as
+// a zipkin.proto3.ListOfSpans, into OTLP traces.
+func decodeZipkinSpans(mediaType string, body []byte) (ptrace.Traces, error) {
+	var spans []zipkinSpan
+	switch mediaType {
+	case "application/json", "text/plain":
+		if err := json.Unmarshal(body, &spans); err != nil {
+			return ptrace.Traces{}, err
+		}
+	case "application/x-protobuf", "application/protobuf":
+		var err error
+		if spans, err = decodeZipkinProto(body); err != nil {
+			return ptrace.Traces{}, err
+		}
+	default:
+		return ptrace.Traces{}, fmt.Errorf("unsupported media type: %q", mediaType)
+	}
+
+	translated := newTranslatedSpans()
+	for i := range spans {
+		if err := spans[i].translate(translated); err != nil {
+			return ptrace.Traces{}, err
+		}
+	}
+	return translated.traces, nil
+}
+
+// translate appends the span to the spans of its local service.
+func (s *zipkinSpan) translate(translated *translatedSpans) error {
+	traceID, err := parseHexTraceID(s.TraceID)
+	if err != nil {
+		return fmt.Errorf("invalid trace ID %q: %v", s.TraceID, err)
+	}
+	spanID, err := parseHexSpanID(s.ID)
+	if err != nil {
+		return fmt.Errorf("invalid span ID %q: %v", s.ID, err)
+	}
+	var parentID pcommon.SpanID
+	if s.ParentID != "" {
+		if parentID, err = parseHexSpanID(s.ParentID); err != nil {
+			return fmt.Errorf("invalid parent ID %q: %v", s.ParentID, err)
+		}
+	}
+
+	var service string
+	if s.LocalEndpoint != nil {
+		service = s.LocalEndpoint.ServiceName
+	}
+	span := translated.appendSpan(service, nil)
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	span.SetParentSpanID(parentID)
+	span.SetName(s.Name)
+	span.SetKind(spanKindFromTag(s.Kind))
+	span.SetStartTimestamp(microsToTimestamp(s.Timestamp))
+	span.SetEndTimestamp(microsToTimestamp(s.Timestamp + s.Duration))
+
+	attrs := span.Attributes()
+	if e := s.LocalEndpoint; e != nil {
+		if ip := e.ip(); ip != "" {
+			attrs.PutStr(semconv.AttributeNetHostIP, ip)
+		}
+		if e.Port != 0 {
+			attrs.PutInt(semconv.AttributeNetHostPort, e.Port)
+		}
+	}
+	if e := s.RemoteEndpoint; e != nil {
+		if e.ServiceName != "" {
+			attrs.PutStr(semconv.AttributePeerService, e.ServiceName)
+		}
+		if ip := e.ip(); ip != "" {
+			attrs.PutStr(semconv.AttributeNetPeerIP, ip)
+		}
+		if e.Port != 0 {
+			attrs.PutInt(semconv.AttributeNetPeerPort, e.Port)
+		}
+	}
+	for k, v := range s.Tags {
+		setSpanTag(span, k, pcommon.NewValueStr(v))
+	}
+	if s.Debug {
+		// debug spans are always kept
+		attrs.PutInt("sampling.priority", 2)
+	}
+	for _, a := range s.Annotations {
+		event := span.Events().AppendEmpty()
+		event.SetName(a.Value)
+		event.SetTimestamp(microsToTimestamp(a.Timestamp))
+	}
+	return nil
+}
+
+// decodeZipkinProto decodes a zipkin.proto3.ListOfSpans.
+func decodeZipkinProto(b []byte) ([]zipkinSpan, error) {
+	var spans []zipkinSpan
+	err := rangeProtoFields(b, func(f protoField) error {
+		if f.num != 1 || f.typ != protowire.BytesType {
+			return nil
+		}
+		span, err := decodeZipkinProtoSpan(f.b)
+		spans = append(spans, span)
+		return err
+	})
+	return spans, err
+}
+
+func decodeZipkinProtoSpan(b []byte) (zipkinSpan, error) {
+	var s zipkinSpan
+	err := rangeProtoFields(b, func(f protoField) error {
+		var err error
+		switch f.num {
+		case 1:
+			s.TraceID = hex.EncodeToString(f.b)
+		case 2:
+			s.ParentID = hex.EncodeToString(f.b)
+		case 3:
+			s.ID = hex.EncodeToString(f.b)
+		case 4:
+			s.Kind = zipkinProtoKinds[f.v]
+		case 5:
+			s.Name = string(f.b)
+		case 6:
+			s.Timestamp = int64(f.v)
+		case 7:
+			s.Duration = int64(f.v)
+		case 8:
+			s.LocalEndpoint, err = decodeZipkinProtoEndpoint(f.b)
+		case 9:
+			s.RemoteEndpoint, err = decodeZipkinProtoEndpoint(f.b)
+		case 10:
+			var a zipkinAnnotation
+			err = rangeProtoFields(f.b, func(f protoField) error {
+				switch f.num {
+				case 1:
+					a.Timestamp = int64(f.v)
+				case 2:
+					a.Value = string(f.b)
+				}
+				return nil
+			})
+			s.Annotations = append(s.Annotations, a)
+		case 11:
+			var k, v string
+			err = rangeProtoFields(f.b, func(f protoField) error {
+				switch f.num {
+				case 1:
+					k = string(f.b)
+				case 2:
+					v = string(f.b)
+				}
+				return nil
+			})
+			if s.Tags == nil {
+				s.Tags = make(map[string]string)
+			}
+			s.Tags[k] = v
+		case 12:
+			s.Debug = f.v != 0
+		}
+		return err
+	})
+	return s, err
+}
+
+// zipkinProtoKinds are the span kinds of the zipkin.proto3.Span.Kind values.
+var zipkinProtoKinds = map[uint64]string{1: "CLIENT", 2: "SERVER", 3: "PRODUCER", 4: "CONSUMER"}
+
+func decodeZipkinProtoEndpoint(b []byte) (*zipkinEndpoint, error) {
+	e := &zipkinEndpoint{}
+	err := rangeProtoFields(b, func(f protoField) error {
+		switch f.num {
+		case 1:
+			e.ServiceName = string(f.b)
+		case 2:
+			if len(f.b) == net.IPv4len {
+				e.IPv4 = net.IP(f.b).String()
+			}
+		case 3:
+			if len(f.b) == net.IPv6len {
+				e.IPv6 = net.IP(f.b).String()
+			}
+		case 4:
+			e.Port = int64(int32(f.v))
+		}
+		return nil
+	})
+	return e, err
+}
+
+// parseHexTraceID parses a trace ID of 64 or 128 bits encoded in hex.
+func parseHexTraceID(s string) (pcommon.TraceID, error) {
+	var id pcommon.TraceID
+	err := parseHexID(id[:], s)
+	return id, err
+}
+
+// parseHexSpanID parses a span ID of 64 bits encoded in hex.
+func parseHexSpanID(s string) (pcommon.SpanID, error) {
+	var id pcommon.SpanID
+	err := parseHexID(id[:], s)
+	return id, err
+}
+
+// parseHexID parses the hex-encoded s into id, right-aligned.
+func parseHexID(id []byte, s string) error {
+	if s == "" || len(s) > 2*len(id) {
+		return fmt.Errorf("expected 1 to %d hex characters", 2*len(id))
+	}
+	b, err := hex.DecodeString(strings.Repeat("0", 2*len(id)-len(s)) + s)
+	if err != nil {
+		return err
+	}
+	copy(id, b)
+	return nil
+}
@@ -0,0 +1,241 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+)
+
+const zipkinJSONSpans = `[
+  {
+    "traceId": "5af7183fb1d4cf5f",
+    "id": "352bff9a74ca9ad2",
+    "kind": "SERVER",
+    "name": "get /api",
+    "timestamp": 1556604172355737,
+    "duration": 1431,
+    "localEndpoint": {"serviceName": "frontend", "ipv4": "192.168.99.1", "port": 3306},
+    "tags": {"http.method": "GET", "http.path": "/api", "env": "prod"}
+  },
+  {
+    "traceId": "5af7183fb1d4cf5f",
+    "parentId": "352bff9a74ca9ad2",
+    "id": "6b221d5bc9e6496c",
+    "kind": "CLIENT",
+    "name": "get",
+    "timestamp": 1556604172355800,
+    "duration": 1000,
+    "debug": true,
+    "localEndpoint": {"serviceName": "frontend"},
+    "remoteEndpoint": {"serviceName": "backend", "ipv4": "172.17.0.13", "port": 8080},
+    "annotations": [{"timestamp": 1556604172355900, "value": "ws"}],
+    "tags": {"error": "connection refused"}
+  },
+  {
+    "traceId": "463ac35c9f6413ad48485a3953bb6124",
+    "id": "a2fb4a1d1a96d312",
+    "name": "query",
+    "timestamp": 1556604172356000,
+    "duration": 200,
+    "localEndpoint": {"serviceName": "backend"}
+  }
+]`
+
+// serveTranslated serves a request on the endpoint of version v, returning
+// the response and the payloads sent by the receiver.
+func serveTranslated(t *testing.T, r *HTTPReceiver, v Version, req *http.Request) (*httptest.ResponseRecorder, []*Payload) {
+	decode := decodeZipkinSpans
+	if v == jaeger {
+		decode = decodeJaegerBatch
+	}
+	rr := httptest.NewRecorder()
+	r.handleWithVersion(v, r.handleTranslatedTraces(decode)).ServeHTTP(rr, req)
+
+	var payloads []*Payload
+	for {
+		select {
+		case p := <-r.out:
+			payloads = append(payloads, p)
+		default:
+			return rr, payloads
+		}
+	}
+}
+
+// payloadByService returns the payload of the spans of service.
+func payloadByService(t *testing.T, payloads []*Payload, service string) *pb.TracerPayload {
+	for _, p := range payloads {
+		if p.TracerPayload.Chunks[0].Spans[0].Service == service {
+			return p.TracerPayload
+		}
+	}
+	require.Failf(t, "no payload", "service %s", service)
+	return nil
+}
+
+func TestZipkinSpans(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.Hostname = "agent-host"
+	conf.DefaultEnv = "none"
+	r := newTestReceiverFromConfig(conf)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(zipkinJSONSpans))
+	req.Header.Set("Content-Type", "application/json")
+	rr, payloads := serveTranslated(t, r, zipkinV2, req)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	require.Len(t, payloads, 2)
+
+	frontend := payloadByService(t, payloads, "frontend")
+	assert.Equal(t, "agent-host", frontend.Hostname)
+	assert.Equal(t, "prod", frontend.Env)
+	require.Len(t, frontend.Chunks, 1)
+	chunk := frontend.Chunks[0]
+	// the client span is in debug mode
+	assert.EqualValues(t, sampler.PriorityUserKeep, chunk.Priority)
+	require.Len(t, chunk.Spans, 2)
+	assert.Equal(t, "-4", chunk.Spans[0].Meta["_dd.p.dm"])
+
+	server, client := chunk.Spans[0], chunk.Spans[1]
+	assert.Equal(t, uint64(0x5af7183fb1d4cf5f), server.TraceID)
+	assert.Equal(t, uint64(0x352bff9a74ca9ad2), server.SpanID)
+	assert.Zero(t, server.ParentID)
+	assert.Equal(t, int64(1556604172355737000), server.Start)
+	assert.Equal(t, int64(1431000), server.Duration)
+	assert.Equal(t, "server", server.Meta["span.kind"])
+	assert.Equal(t, "192.168.99.1", server.Meta["net.host.ip"])
+	assert.Equal(t, float64(3306), server.Metrics["net.host.port"])
+	assert.Equal(t, "GET", server.Meta["http.method"])
+	assert.Equal(t, "Unset", server.Meta["otel.status_code"])
+	assert.Zero(t, server.Error)
+
+	assert.Equal(t, server.SpanID, client.ParentID)
+	assert.Equal(t, "client", client.Meta["span.kind"])
+	assert.Equal(t, "backend", client.Meta["peer.service"])
+	assert.Equal(t, "172.17.0.13", client.Meta["net.peer.ip"])
+	assert.Equal(t, float64(8080), client.Metrics["net.peer.port"])
+	assert.Equal(t, int32(1), client.Error)
+	assert.Equal(t, "connection refused", client.Meta["error.msg"])
+	assert.Equal(t, float64(2), client.Metrics["_sampling_priority_v1"])
+	assert.Contains(t, client.Meta["events"], `"name":"ws"`)
+
+	backend := payloadByService(t, payloads, "backend")
+	assert.Equal(t, "none", backend.Env)
+	require.Len(t, backend.Chunks, 1)
+	// without a decision, the samplers of the agent decide
+	assert.EqualValues(t, sampler.PriorityNone, backend.Chunks[0].Priority)
+	span := backend.Chunks[0].Spans[0]
+	assert.Equal(t, uint64(0x48485a3953bb6124), span.TraceID)
+	assert.Equal(t, "463ac35c9f6413ad48485a3953bb6124", span.Meta["otel.trace_id"])
+
+	ts := r.Stats.GetTagStats(info.Tags{EndpointVersion: string(zipkinV2), Service: "frontend"})
+	assert.Equal(t, int64(1), ts.TracesReceived.Load())
+	assert.Equal(t, int64(len(zipkinJSONSpans)), ts.TracesBytes.Load())
+}
+
+func TestZipkinSpansProto(t *testing.T) {
+	endpoint := protowire.AppendTag(nil, 1, protowire.BytesType)
+	endpoint = protowire.AppendString(endpoint, "frontend")
+	remote := protowire.AppendTag(nil, 2, protowire.BytesType)
+	remote = protowire.AppendBytes(remote, []byte{10, 0, 0, 1})
+
+	var span []byte
+	span = protowire.AppendTag(span, 1, protowire.BytesType)
+	span = protowire.AppendBytes(span, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 7})
+	span = protowire.AppendTag(span, 3, protowire.BytesType)
+	span = protowire.AppendBytes(span, []byte{0, 0, 0, 0, 0, 0, 0, 9})
+	span = protowire.AppendTag(span, 4, protowire.VarintType)
+	span = protowire.AppendVarint(span, 3) // PRODUCER
+	span = protowire.AppendTag(span, 5, protowire.BytesType)
+	span = protowire.AppendString(span, "send")
+	span = protowire.AppendTag(span, 6, protowire.Fixed64Type)
+	span = protowire.AppendFixed64(span, 1556604172355737)
+	span = protowire.AppendTag(span, 7, protowire.VarintType)
+	span = protowire.AppendVarint(span, 50)
+	span = protowire.AppendTag(span, 8, protowire.BytesType)
+	span = protowire.AppendBytes(span, endpoint)
+	span = protowire.AppendTag(span, 9, protowire.BytesType)
+	span = protowire.AppendBytes(span, remote)
+	tag := protowire.AppendTag(nil, 1, protowire.BytesType)
+	tag = protowire.AppendString(tag, "messaging.system")
+	tag = protowire.AppendTag(tag, 2, protowire.BytesType)
+	tag = protowire.AppendString(tag, "kafka")
+	span = protowire.AppendTag(span, 11, protowire.BytesType)
+	span = protowire.AppendBytes(span, tag)
+	list := protowire.AppendTag(nil, 1, protowire.BytesType)
+	list = protowire.AppendBytes(list, span)
+
+	traces, err := decodeZipkinSpans("application/x-protobuf", list)
+	require.NoError(t, err)
+	require.Equal(t, 1, traces.SpanCount())
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	ddspan := r.translatedTracerPayload(traces.ResourceSpans().At(0), "").Chunks[0].Spans[0]
+
+	assert.Equal(t, "frontend", ddspan.Service)
+	assert.Equal(t, uint64(7), ddspan.TraceID)
+	assert.Equal(t, uint64(9), ddspan.SpanID)
+	assert.Equal(t, int64(50000), ddspan.Duration)
+	assert.Equal(t, "producer", ddspan.Meta["span.kind"])
+	assert.Equal(t, "10.0.0.1", ddspan.Meta["net.peer.ip"])
+	assert.Equal(t, "kafka", ddspan.Meta["messaging.system"])
+}
+
+func TestZipkinSpansRequests(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+
+	t.Run("gzip", func(t *testing.T) {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write([]byte(zipkinJSONSpans))
+		gz.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", &buf)
+		req.Header.Set("Content-Encoding", "gzip")
+		rr, payloads := serveTranslated(t, r, zipkinV2, req)
+		assert.Equal(t, http.StatusAccepted, rr.Code)
+		assert.Len(t, payloads, 2)
+	})
+
+	t.Run("method", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v2/spans", nil)
+		rr, _ := serveTranslated(t, r, zipkinV2, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, body := range []string{`{"traceId": "1"}`, `[{"traceId": "xyz", "id": "1"}]`, `[{"traceId": "1"}]`} {
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(body))
+			rr, payloads := serveTranslated(t, r, zipkinV2, req)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+			assert.Empty(t, payloads)
+		}
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(zipkinJSONSpans))
+		req.Header.Set("Content-Type", "application/x-thrift")
+		rr, _ := serveTranslated(t, r, zipkinV2, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("overwhelmed", func(t *testing.T) {
+		conf := newTestReceiverConfig()
+		conf.DecoderTimeout = 1
+		r := newTestReceiverFromConfig(conf)
+		r.recvsem = make(chan struct{})
+		req := httptest.NewRequest(http.MethodPost, "/api/v2/spans", bytes.NewBufferString(zipkinJSONSpans))
+		rr, payloads := serveTranslated(t, r, zipkinV2, req)
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.Empty(t, payloads)
+	})
+}
@@ -128,6 +128,14 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached obfuscate.MemcachedConfig `mapstructure:"memcached"`
 
+	// GraphQL holds the configuration for obfuscating the "graphql.query" tag
+	// for spans of type "graphql".
+	GraphQL obfuscate.GraphQLConfig `mapstructure:"graphql"`
+
+	// CQL holds the configuration for obfuscating the queries of the spans of
+	// type "cassandra" which don't have a "db.type" tag as CQL rather than SQL.
+	CQL Enablable `mapstructure:"cql"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards obfuscate.CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -168,6 +176,7 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 		Redis:                o.Redis,
 		Valkey:               o.Valkey,
 		Memcached:            o.Memcached,
+		GraphQL:              o.GraphQL,
 		CreditCard:           o.CreditCards,
 		Logger:               new(debugLogger),
 		Cache:                o.Cache,
@@ -180,6 +189,10 @@ func (debugLogger) Debugf(format string, params ...interface{}) {
 	log.Debugf(format, params...)
 }
 
+func (debugLogger) Warnf(format string, params ...interface{}) {
+	log.Warnf(format, params...)
+}
+
 // Enablable can represent any option that has an "enabled" boolean sub-field.
 type Enablable struct {
 	Enabled bool `mapstructure:"enabled"`
@@ -209,6 +222,89 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// TailSamplingConfig holds the configuration of the tail-based sampling buffer,
+// which holds the chunks of the traces for a decision wait before sampling
+// them, so that the decision takes all the chunks of a trace into account.
+type TailSamplingConfig struct {
+	// Enabled reports whether the chunks are buffered until their trace is decided.
+	Enabled bool
+
+	// DecisionWait is the time a trace is buffered, from the arrival of its
+	// first chunk, before it is decided.
+	DecisionWait time.Duration
+
+	// MaxTraces is the maximum number of traces buffered. The oldest traces
+	// are decided early when it is reached.
+	MaxTraces int
+
+	// MaxMemory is the maximum size, in bytes, of the chunks buffered. The oldest
+	// traces are decided early when it is reached. When 0, it is a quarter of
+	// the MaxMemory of the agent.
+	MaxMemory int64
+
+	// Policies keep the traces matching one of them. The chunks of the traces
+	// matching none are sampled by the other samplers.
+	Policies []*TailSamplingPolicy
+}
+
+// TailSamplingPolicy keeps the traces matching all its conditions. The conditions
+// which aren't set are ignored, and a policy without any condition is ignored.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the chunks it keeps.
+	Name string `mapstructure:"name" json:"name"`
+
+	// MinDurationMs keeps the traces lasting at least this many milliseconds,
+	// from the start of their first span to the end of their last one.
+	MinDurationMs int64 `mapstructure:"min_duration_ms" json:"min_duration_ms"`
+
+	// Error keeps the traces with an error span.
+	Error bool `mapstructure:"error" json:"error"`
+
+	// Tags keeps the traces with a span having one of these tags, either as
+	// "key:value", or as "key" to match any value.
+	Tags []string `mapstructure:"tags" json:"tags"`
+
+	// MinSpans keeps the traces with at least this many spans.
+	MinSpans int `mapstructure:"min_spans" json:"min_spans"`
+}
+
+// Span filter actions, see SpanFilterRule.
+const (
+	// SpanFilterDrop drops the matching spans, re-parenting their children.
+	SpanFilterDrop = "drop"
+	// SpanFilterRemoveTags removes the listed keys from the meta and metrics
+	// of the matching spans.
+	SpanFilterRemoveTags = "remove_tags"
+	// SpanFilterTruncate truncates the listed meta values of the matching spans.
+	SpanFilterTruncate = "truncate"
+)
+
+// SpanFilterRule matches spans on their properties, and applies an action to
+// them. A span matches when it matches all the patterns set in the rule.
+type SpanFilterRule struct {
+	// Name identifies the rule in logs.
+	Name string `mapstructure:"name" json:"name"`
+
+	// Service, SpanName and Resource are regular expressions matching the
+	// service, name and resource of the spans.
+	Service  string `mapstructure:"service" json:"service"`
+	SpanName string `mapstructure:"span_name" json:"span_name"`
+	Resource string `mapstructure:"resource" json:"resource"`
+
+	// Meta maps meta keys to regular expressions matching their values.
+	Meta map[string]string `mapstructure:"meta" json:"meta"`
+
+	// Action is one of SpanFilterDrop, SpanFilterRemoveTags or SpanFilterTruncate.
+	Action string `mapstructure:"action" json:"action"`
+
+	// Keys lists the tags removed or truncated. With SpanFilterTruncate, "*"
+	// targets all the meta values but the internal ones.
+	Keys []string `mapstructure:"keys" json:"keys"`
+
+	// MaxLength is the length in bytes the values are truncated to.
+	MaxLength int `mapstructure:"max_length" json:"max_length"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -358,6 +454,9 @@ type AgentConfig struct {
 	// Error Tracking Standalone
 	ErrorTrackingStandalone bool
 
+	// Tail Sampling configuration
+	TailSampling TailSamplingConfig
+
 	// Receiver
 	ReceiverEnabled bool // specifies whether Receiver listeners are enabled. Unless OTLPReceiver is used, this should always be true.
 	ReceiverHost    string
@@ -419,6 +518,14 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// SpanFilters drops individual spans or scrubs their tags, before stats are
+	// computed.
+	SpanFilters []*SpanFilterRule
+
+	// SpanFiltersRemoteConfig enables the span filter rules sent with remote
+	// configuration, which are applied after SpanFilters.
+	SpanFiltersRemoteConfig bool
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -561,6 +668,11 @@ func New() *AgentConfig {
 
 		ErrorTrackingStandalone: false,
 
+		TailSampling: TailSamplingConfig{
+			DecisionWait: 10 * time.Second,
+			MaxTraces:    50000,
+		},
+
 		ReceiverEnabled:        true,
 		ReceiverHost:           "localhost",
 		ReceiverPort:           8126,
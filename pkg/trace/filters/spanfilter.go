@@ -0,0 +1,279 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+)
+
+// SpanFilter is a filter which drops individual spans of the traces, or
+// scrubs their tags, based on its rules. The rules of the configuration are
+// set once, the remote ones are applied after them and can be updated while
+// the filter is used.
+type SpanFilter struct {
+	local []*spanFilterRule
+
+	mu     sync.RWMutex
+	remote []*spanFilterRule
+	// drops reports whether one of the rules drops spans.
+	drops bool
+}
+
+// spanFilterRule is a compiled config.SpanFilterRule.
+type spanFilterRule struct {
+	service   *regexp.Regexp
+	spanName  *regexp.Regexp
+	resource  *regexp.Regexp
+	meta      map[string]*regexp.Regexp
+	action    string
+	keys      []string
+	allKeys   bool
+	maxLength int
+}
+
+// NewSpanFilter returns a new SpanFilter which will use the given set of rules.
+// It returns an error if one of the rules is invalid.
+func NewSpanFilter(rules []*config.SpanFilterRule) (*SpanFilter, error) {
+	local, err := compileSpanFilterRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return &SpanFilter{local: local, drops: dropsSpans(local)}, nil
+}
+
+// UpdateRemoteRules replaces the remote rules of the filter, which are
+// applied after the ones it was created with. The rules are left unchanged if
+// one of the new ones is invalid.
+func (f *SpanFilter) UpdateRemoteRules(rules []*config.SpanFilterRule) error {
+	remote, err := compileSpanFilterRules(rules)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	f.remote = remote
+	f.drops = dropsSpans(f.local) || dropsSpans(remote)
+	f.mu.Unlock()
+	return nil
+}
+
+func compileSpanFilterRules(rules []*config.SpanFilterRule) ([]*spanFilterRule, error) {
+	compiled := make([]*spanFilterRule, 0, len(rules))
+	for i, r := range rules {
+		cr, err := compileSpanFilterRule(r)
+		if err != nil {
+			name := fmt.Sprintf("#%d", i)
+			if r != nil && r.Name != "" {
+				name = r.Name
+			}
+			return nil, fmt.Errorf("span filter rule %s: %s", name, err)
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+// dropsSpans reports whether one of the rules drops spans.
+func dropsSpans(rules []*spanFilterRule) bool {
+	for _, r := range rules {
+		if r.action == config.SpanFilterDrop {
+			return true
+		}
+	}
+	return false
+}
+
+func compileSpanFilterRule(r *config.SpanFilterRule) (*spanFilterRule, error) {
+	if r == nil {
+		return nil, errors.New("empty rule")
+	}
+	cr := &spanFilterRule{
+		action:    r.Action,
+		maxLength: r.MaxLength,
+	}
+	var err error
+	if cr.service, err = compileOptional(r.Service); err != nil {
+		return nil, fmt.Errorf("service: %s", err)
+	}
+	if cr.spanName, err = compileOptional(r.SpanName); err != nil {
+		return nil, fmt.Errorf("span_name: %s", err)
+	}
+	if cr.resource, err = compileOptional(r.Resource); err != nil {
+		return nil, fmt.Errorf("resource: %s", err)
+	}
+	if len(r.Meta) > 0 {
+		cr.meta = make(map[string]*regexp.Regexp, len(r.Meta))
+		for k, pattern := range r.Meta {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("meta %q: %s", k, err)
+			}
+			cr.meta[k] = re
+		}
+	}
+	if cr.service == nil && cr.spanName == nil && cr.resource == nil && cr.meta == nil {
+		return nil, errors.New(`at least one of "service", "span_name", "resource" or "meta" must be set`)
+	}
+	switch r.Action {
+	case config.SpanFilterDrop:
+	case config.SpanFilterRemoveTags:
+		if len(r.Keys) == 0 {
+			return nil, errors.New(`"keys" must be set to remove tags`)
+		}
+		cr.keys = r.Keys
+	case config.SpanFilterTruncate:
+		if len(r.Keys) == 0 {
+			return nil, errors.New(`"keys" must be set to truncate tags`)
+		}
+		if r.MaxLength <= 0 {
+			return nil, errors.New(`"max_length" must be positive to truncate tags`)
+		}
+		for _, k := range r.Keys {
+			if k == "*" {
+				cr.allKeys = true
+			}
+		}
+		cr.keys = r.Keys
+	default:
+		return nil, fmt.Errorf("unknown action %q, it must be one of %q, %q or %q", r.Action, config.SpanFilterDrop, config.SpanFilterRemoveTags, config.SpanFilterTruncate)
+	}
+	return cr, nil
+}
+
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+func (r *spanFilterRule) match(s *pb.Span) bool {
+	if r.service != nil && !r.service.MatchString(s.Service) {
+		return false
+	}
+	if r.spanName != nil && !r.spanName.MatchString(s.Name) {
+		return false
+	}
+	if r.resource != nil && !r.resource.MatchString(s.Resource) {
+		return false
+	}
+	for k, re := range r.meta {
+		v, ok := s.Meta[k]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// apply removes or truncates the tags of the span. The internal tags, e.g.
+// _dd.* or _sampling_priority_v1, are never modified.
+func (r *spanFilterRule) apply(s *pb.Span) {
+	switch r.action {
+	case config.SpanFilterRemoveTags:
+		for _, k := range r.keys {
+			if strings.HasPrefix(k, hiddenTagPrefix) {
+				continue
+			}
+			delete(s.Meta, k)
+			delete(s.Metrics, k)
+		}
+	case config.SpanFilterTruncate:
+		if r.allKeys {
+			for k, v := range s.Meta {
+				if !strings.HasPrefix(k, hiddenTagPrefix) {
+					s.Meta[k] = traceutil.TruncateUTF8(v, r.maxLength)
+				}
+			}
+			return
+		}
+		for _, k := range r.keys {
+			if strings.HasPrefix(k, hiddenTagPrefix) {
+				continue
+			}
+			if v, ok := s.Meta[k]; ok {
+				s.Meta[k] = traceutil.TruncateUTF8(v, r.maxLength)
+			}
+		}
+	}
+}
+
+// Filter applies the rules to the spans of the chunk, and returns the number
+// of spans dropped. The children of the dropped spans are re-parented to
+// their closest ancestor left. The root span of the chunk is never dropped.
+func (f *SpanFilter) Filter(chunk *pb.TraceChunk) (dropped int) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if len(f.local) == 0 && len(f.remote) == 0 {
+		return 0
+	}
+
+	var root *pb.Span
+	if f.drops {
+		root = traceutil.GetRoot(chunk.Spans)
+	}
+	// parents maps the IDs of the dropped spans to the IDs of their parents.
+	var parents map[uint64]uint64
+	kept := chunk.Spans[:0]
+	for _, s := range chunk.Spans {
+		if f.filterSpan(s, root) {
+			if parents == nil {
+				parents = make(map[uint64]uint64)
+			}
+			parents[s.SpanID] = s.ParentID
+			dropped++
+			continue
+		}
+		kept = append(kept, s)
+	}
+	for i := len(kept); i < len(chunk.Spans); i++ {
+		chunk.Spans[i] = nil
+	}
+	chunk.Spans = kept
+	if dropped == 0 {
+		return 0
+	}
+	for _, s := range kept {
+		// the length of the chain of dropped ancestors is bounded by the
+		// number of dropped spans, even if their IDs loop
+		for n := 0; n < len(parents); n++ {
+			parentID, ok := parents[s.ParentID]
+			if !ok {
+				break
+			}
+			s.ParentID = parentID
+		}
+	}
+	return dropped
+}
+
+// filterSpan applies the rules to the span and reports whether it is dropped.
+func (f *SpanFilter) filterSpan(s *pb.Span, root *pb.Span) bool {
+	return filterSpan(f.local, s, root) || filterSpan(f.remote, s, root)
+}
+
+func filterSpan(rules []*spanFilterRule, s *pb.Span, root *pb.Span) bool {
+	for _, r := range rules {
+		if !r.match(s) {
+			continue
+		}
+		if r.action == config.SpanFilterDrop {
+			if s != root {
+				return true
+			}
+			continue
+		}
+		r.apply(s)
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestSpanFilterDrop(t *testing.T) {
+	f, err := NewSpanFilter([]*config.SpanFilterRule{
+		{Name: "health", Meta: map[string]string{"http.route": "^/health$"}, Action: config.SpanFilterDrop},
+		{Name: "ping", Service: "redis", Resource: "^PING$", Action: config.SpanFilterDrop},
+	})
+	require.NoError(t, err)
+
+	chunk := &pb.TraceChunk{Spans: []*pb.Span{
+		{SpanID: 1, Service: "web", Meta: map[string]string{"http.route": "/health"}},
+		{SpanID: 2, ParentID: 1, Service: "web", Meta: map[string]string{"http.route": "/health"}},
+		{SpanID: 3, ParentID: 2, Service: "redis", Resource: "PING"},
+		{SpanID: 4, ParentID: 3, Service: "redis", Resource: "GET"},
+		{SpanID: 5, ParentID: 2, Service: "redis", Resource: "GET"},
+		{SpanID: 6, ParentID: 1, Service: "redis", Resource: "PING"},
+	}}
+	assert.Equal(t, 3, f.Filter(chunk))
+
+	parents := make(map[uint64]uint64)
+	for _, s := range chunk.Spans {
+		parents[s.SpanID] = s.ParentID
+	}
+	// the root is kept even if it matches
+	assert.Equal(t, map[uint64]uint64{1: 0, 4: 1, 5: 1}, parents)
+}
+
+func TestSpanFilterTags(t *testing.T) {
+	f, err := NewSpanFilter([]*config.SpanFilterRule{
+		// the internal tags are never removed or truncated
+		{SpanName: "^http\\.", Keys: []string{"http.request.headers.cookie", "http.request.body.size", "_dd.p.tid", "_sampling_priority_v1"}, Action: config.SpanFilterRemoveTags},
+		{Service: "web", Keys: []string{"*"}, MaxLength: 4, Action: config.SpanFilterTruncate},
+		{Service: "db", Keys: []string{"sql.query", "_dd.base_service"}, MaxLength: 6, Action: config.SpanFilterTruncate},
+	})
+	require.NoError(t, err)
+
+	web := &pb.Span{
+		Service: "web",
+		Name:    "http.request",
+		Meta: map[string]string{
+			"http.request.headers.cookie": "session=secret",
+			"http.url":                    "https://example.com",
+			"_dd.p.tid":                   "64b8f3c200000000",
+		},
+		Metrics: map[string]float64{"http.request.body.size": 42, "_sampling_priority_v1": 1},
+	}
+	db := &pb.Span{
+		Service: "db",
+		Name:    "postgres.query",
+		Meta:    map[string]string{"sql.query": "SELECT * FROM users", "db.name": "users", "_dd.base_service": "backend"},
+	}
+	assert.Zero(t, f.Filter(&pb.TraceChunk{Spans: []*pb.Span{web, db}}))
+
+	assert.Equal(t, map[string]string{"http.url": "http", "_dd.p.tid": "64b8f3c200000000"}, web.Meta)
+	assert.Equal(t, map[string]float64{"_sampling_priority_v1": 1}, web.Metrics)
+	assert.Equal(t, map[string]string{"sql.query": "SELECT", "db.name": "users", "_dd.base_service": "backend"}, db.Meta)
+}
+
+func TestSpanFilterInvalid(t *testing.T) {
+	for name, rule := range map[string]*config.SpanFilterRule{
+		"no-match":      {Action: config.SpanFilterDrop},
+		"bad-regexp":    {Service: "(", Action: config.SpanFilterDrop},
+		"bad-meta":      {Meta: map[string]string{"k": "["}, Action: config.SpanFilterDrop},
+		"no-action":     {Service: "web"},
+		"no-keys":       {Service: "web", Action: config.SpanFilterRemoveTags},
+		"no-max-length": {Service: "web", Keys: []string{"*"}, Action: config.SpanFilterTruncate},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSpanFilter([]*config.SpanFilterRule{rule})
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("update", func(t *testing.T) {
+		f, err := NewSpanFilter(nil)
+		require.NoError(t, err)
+		require.NoError(t, f.UpdateRemoteRules([]*config.SpanFilterRule{{Service: "web", Action: config.SpanFilterDrop}}))
+		assert.Error(t, f.UpdateRemoteRules([]*config.SpanFilterRule{{Service: "("}}))
+		assert.Error(t, f.UpdateRemoteRules([]*config.SpanFilterRule{nil}))
+
+		// the previous rules are kept
+		chunk := &pb.TraceChunk{Spans: []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1, Service: "web"}}}
+		assert.Equal(t, 1, f.Filter(chunk))
+
+		require.NoError(t, f.UpdateRemoteRules(nil))
+		chunk = &pb.TraceChunk{Spans: []*pb.Span{{SpanID: 1}, {SpanID: 2, ParentID: 1, Service: "web"}}}
+		assert.Zero(t, f.Filter(chunk))
+	})
+}
+
+func TestSpanFilterRemoteRules(t *testing.T) {
+	f, err := NewSpanFilter([]*config.SpanFilterRule{{Service: "web", Keys: []string{"http.url"}, Action: config.SpanFilterRemoveTags}})
+	require.NoError(t, err)
+	require.NoError(t, f.UpdateRemoteRules([]*config.SpanFilterRule{{Service: "redis", Action: config.SpanFilterDrop}}))
+
+	// the remote rules are applied along with the local ones
+	web := &pb.Span{SpanID: 1, Service: "web", Meta: map[string]string{"http.url": "/"}}
+	chunk := &pb.TraceChunk{Spans: []*pb.Span{web, {SpanID: 2, ParentID: 1, Service: "redis"}}}
+	assert.Equal(t, 1, f.Filter(chunk))
+	assert.Empty(t, web.Meta)
+
+	// the local rules are kept when the remote ones are removed
+	require.NoError(t, f.UpdateRemoteRules(nil))
+	web = &pb.Span{SpanID: 1, Service: "web", Meta: map[string]string{"http.url": "/"}}
+	chunk = &pb.TraceChunk{Spans: []*pb.Span{web, {SpanID: 2, ParentID: 1, Service: "redis"}}}
+	assert.Zero(t, f.Filter(chunk))
+	assert.Empty(t, web.Meta)
+}
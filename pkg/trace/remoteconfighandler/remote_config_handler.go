@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 
 	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
@@ -34,12 +35,22 @@ type rareSampler interface {
 	SetEnabled(enabled bool)
 }
 
+type spanFilter interface {
+	UpdateRemoteRules(rules []*config.SpanFilterRule) error
+}
+
+// spanFiltersConfig is the payload of the APM_SPAN_FILTERS product.
+type spanFiltersConfig struct {
+	Rules []*config.SpanFilterRule `json:"rules"`
+}
+
 // RemoteConfigHandler holds pointers to samplers that need to be updated when APM remote config changes
 type RemoteConfigHandler struct {
 	remoteClient                  config.RemoteClient
 	prioritySampler               prioritySampler
 	errorsSampler                 errorsSampler
 	rareSampler                   rareSampler
+	spanFilter                    spanFilter
 	agentConfig                   *config.AgentConfig
 	configState                   *state.AgentConfigState
 	configHTTPClient              *http.Client
@@ -47,7 +58,7 @@ type RemoteConfigHandler struct {
 }
 
 // New creates a new RemoteConfigHandler
-func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler) *RemoteConfigHandler {
+func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler rareSampler, errorsSampler errorsSampler, spanFilter spanFilter) *RemoteConfigHandler {
 	if conf.RemoteConfigClient == nil {
 		return nil
 	}
@@ -68,6 +79,7 @@ func New(conf *config.AgentConfig, prioritySampler prioritySampler, rareSampler
 		prioritySampler: prioritySampler,
 		rareSampler:     rareSampler,
 		errorsSampler:   errorsSampler,
+		spanFilter:      spanFilter,
 		agentConfig:     conf,
 		configState: &state.AgentConfigState{
 			FallbackLogLevel: level.String(),
@@ -91,6 +103,9 @@ func (h *RemoteConfigHandler) Start() {
 
 	h.remoteClient.Start()
 	h.remoteClient.Subscribe(state.ProductAPMSampling, h.onUpdate)
+	if h.agentConfig.SpanFiltersRemoteConfig {
+		h.remoteClient.Subscribe(state.ProductAPMSpanFilters, h.onSpanFiltersUpdate)
+	}
 	h.remoteClient.Subscribe(state.ProductAgentConfig, h.onAgentConfigUpdate)
 }
 
@@ -231,3 +246,43 @@ func (h *RemoteConfigHandler) updateSamplers(config apmsampling.SamplerConfig) {
 	}
 	h.rareSampler.SetEnabled(rareSamplerEnabled)
 }
+
+// onSpanFiltersUpdate applies the span filter rules of all the remote configs,
+// in the order of their paths, after the ones of the configuration file. The
+// rules are left unchanged if one of the remote configs is invalid.
+func (h *RemoteConfigHandler) onSpanFiltersUpdate(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) {
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var rules []*config.SpanFilterRule
+	var err error
+	for _, path := range paths {
+		var payload spanFiltersConfig
+		if err = json.Unmarshal(updates[path].Config, &payload); err != nil {
+			err = fmt.Errorf("%s: %s", path, err)
+			break
+		}
+		rules = append(rules, payload.Rules...)
+	}
+	if err == nil {
+		log.Debugf("updating span filters with remote configuration: %v", spew.Sdump(rules))
+		err = h.spanFilter.UpdateRemoteRules(rules)
+	}
+	if err != nil {
+		log.Errorf("couldn't apply the remote configuration span filters: %s", err)
+	}
+
+	for _, path := range paths {
+		if err == nil {
+			applyStateCallback(path, state.ApplyStatus{State: state.ApplyStateAcknowledged})
+		} else {
+			applyStateCallback(path, state.ApplyStatus{
+				State: state.ApplyStateError,
+				Error: err.Error(),
+			})
+		}
+	}
+}
@@ -7,6 +7,7 @@ package remoteconfighandler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -33,9 +34,10 @@ func TestStart(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
 
 	remoteClient.EXPECT().Subscribe(state.ProductAPMSampling, gomock.Any()).Times(1)
 	remoteClient.EXPECT().Subscribe(state.ProductAgentConfig, gomock.Any()).Times(1)
@@ -46,6 +48,28 @@ func TestStart(t *testing.T) {
 	ctrl.Finish()
 }
 
+func TestStartSpanFilters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, DebugServerPort: 1, SpanFiltersRemoteConfig: true}
+	prioritySampler := NewMockprioritySampler(ctrl)
+	errorsSampler := NewMockerrorsSampler(ctrl)
+	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
+	pkglog.SetupLogger(pkglog.Default(), "debug")
+
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
+
+	remoteClient.EXPECT().Subscribe(state.ProductAPMSampling, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Subscribe(state.ProductAPMSpanFilters, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Subscribe(state.ProductAgentConfig, gomock.Any()).Times(1)
+	remoteClient.EXPECT().Start().Times(1)
+
+	h.Start()
+
+	ctrl.Finish()
+}
+
 func TestStartNoRemoteClient(t *testing.T) {
 	var h *RemoteConfigHandler
 	assert.NotPanics(t, h.Start)
@@ -57,10 +81,11 @@ func TestPrioritySampler(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -88,10 +113,11 @@ func TestErrorsSampler(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -119,10 +145,11 @@ func TestRareSampler(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -150,10 +177,11 @@ func TestEnvPrecedence(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 
 	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, TargetTPS: 41, ErrorTPS: 41, RareSamplerEnabled: true, DefaultEnv: "agent-env", DebugServerPort: 1}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
 
 	payload := apmsampling.SamplerConfig{
 		AllEnvs: apmsampling.SamplerEnvConfig{
@@ -191,6 +219,7 @@ func TestLogLevel(t *testing.T) {
 	prioritySampler := NewMockprioritySampler(ctrl)
 	errorsSampler := NewMockerrorsSampler(ctrl)
 	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
 
 	pkglog.SetupLogger(pkglog.Default(), "debug")
 	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -208,7 +237,7 @@ func TestLogLevel(t *testing.T) {
 			return "fakeToken"
 		},
 	}
-	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler)
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
 
 	layer := state.RawConfig{Config: []byte(`{"name": "layer1", "config": {"log_level": "debug"}}`)}
 	configOrder := state.RawConfig{Config: []byte(`{"internal_order": ["layer1", "layer2"]}`)}
@@ -229,3 +258,43 @@ func TestLogLevel(t *testing.T) {
 
 	ctrl.Finish()
 }
+
+func TestSpanFilters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	remoteClient := NewMockRemoteClient(ctrl)
+	prioritySampler := NewMockprioritySampler(ctrl)
+	errorsSampler := NewMockerrorsSampler(ctrl)
+	rareSampler := NewMockrareSampler(ctrl)
+	spanFilter := NewMockspanFilter(ctrl)
+	pkglog.SetupLogger(pkglog.Default(), "debug")
+
+	local := &config.SpanFilterRule{Name: "local", Service: "web", Action: config.SpanFilterDrop}
+	agentConfig := config.AgentConfig{RemoteConfigClient: remoteClient, DebugServerPort: 1, SpanFilters: []*config.SpanFilterRule{local}}
+	h := New(&agentConfig, prioritySampler, rareSampler, errorsSampler, spanFilter)
+
+	ping := state.RawConfig{Config: []byte(`{"rules": [{"name": "ping", "span_name": "redis.command", "resource": "^PING$", "action": "drop"}]}`)}
+	health := state.RawConfig{Config: []byte(`{"rules": [{"name": "health", "meta": {"http.route": "/health"}, "action": "drop"}]}`)}
+
+	// the local rules are applied by the filter itself, they aren't part of the updates
+	spanFilter.EXPECT().UpdateRemoteRules([]*config.SpanFilterRule{
+		{Name: "health", Meta: map[string]string{"http.route": "/health"}, Action: config.SpanFilterDrop},
+		{Name: "ping", SpanName: "redis.command", Resource: "^PING$", Action: config.SpanFilterDrop},
+	}).Return(nil).Times(1)
+	remoteClient.EXPECT().UpdateApplyStatus("datadog/2/APM_SPAN_FILTERS/a/config", state.ApplyStatus{State: state.ApplyStateAcknowledged})
+	remoteClient.EXPECT().UpdateApplyStatus("datadog/2/APM_SPAN_FILTERS/b/config", state.ApplyStatus{State: state.ApplyStateAcknowledged})
+	h.onSpanFiltersUpdate(map[string]state.RawConfig{
+		"datadog/2/APM_SPAN_FILTERS/b/config": ping,
+		"datadog/2/APM_SPAN_FILTERS/a/config": health,
+	}, remoteClient.UpdateApplyStatus)
+
+	// the remote rules are removed along with the remote configs
+	spanFilter.EXPECT().UpdateRemoteRules([]*config.SpanFilterRule(nil)).Return(nil).Times(1)
+	h.onSpanFiltersUpdate(map[string]state.RawConfig{}, remoteClient.UpdateApplyStatus)
+
+	// invalid configs are reported
+	spanFilter.EXPECT().UpdateRemoteRules(gomock.Any()).Return(errors.New("invalid rule")).Times(1)
+	remoteClient.EXPECT().UpdateApplyStatus("datadog/2/APM_SPAN_FILTERS/a/config", state.ApplyStatus{State: state.ApplyStateError, Error: "invalid rule"})
+	h.onSpanFiltersUpdate(map[string]state.RawConfig{"datadog/2/APM_SPAN_FILTERS/a/config": ping}, remoteClient.UpdateApplyStatus)
+
+	ctrl.Finish()
+}
@@ -0,0 +1,160 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+)
+
+// TailPolicy is a compiled tail sampling policy, deciding on a trace once
+// all its chunks have been buffered.
+type TailPolicy struct {
+	name        string
+	minDuration int64
+	error       bool
+	// tags maps the keys of the tags to their values, or to nil to match any value.
+	tags     map[string][]string
+	minSpans int
+}
+
+// NewTailPolicies compiles the tail sampling policies. The policies without
+// a name are named after their index. The policies without any condition,
+// which would keep all the traces, are ignored.
+func NewTailPolicies(policies []*config.TailSamplingPolicy) []*TailPolicy {
+	compiled := make([]*TailPolicy, 0, len(policies))
+	for i, p := range policies {
+		if p == nil {
+			continue
+		}
+		if p.MinDurationMs <= 0 && !p.Error && len(p.Tags) == 0 && p.MinSpans <= 0 {
+			log.Warnf("Ignoring the tail sampling policy %d (%q) without any condition, it would keep all the traces", i, p.Name)
+			continue
+		}
+		tp := &TailPolicy{
+			name:        p.Name,
+			minDuration: int64(time.Duration(p.MinDurationMs) * time.Millisecond),
+			error:       p.Error,
+			minSpans:    p.MinSpans,
+		}
+		if tp.name == "" {
+			tp.name = fmt.Sprintf("policy_%d", i)
+		}
+		if len(p.Tags) > 0 {
+			tp.tags = make(map[string][]string, len(p.Tags))
+			for _, tag := range p.Tags {
+				k, v, hasValue := strings.Cut(tag, ":")
+				if !hasValue {
+					tp.tags[k] = nil
+					continue
+				}
+				if values, ok := tp.tags[k]; !ok || values != nil {
+					tp.tags[k] = append(values, v)
+				}
+			}
+		}
+		compiled = append(compiled, tp)
+	}
+	return compiled
+}
+
+// Name returns the name of the policy.
+func (p *TailPolicy) Name() string {
+	return p.name
+}
+
+// MatchTailPolicies returns the first policy matching the trace made of the
+// chunks, or nil if none does.
+func MatchTailPolicies(policies []*TailPolicy, chunks []*pb.TraceChunk) *TailPolicy {
+	if len(policies) == 0 {
+		return nil
+	}
+	t := newTailTraceSummary(chunks)
+	for _, p := range policies {
+		if p.match(chunks, &t) {
+			return p
+		}
+	}
+	return nil
+}
+
+// tailTraceSummary holds the properties of a trace shared by the policies.
+type tailTraceSummary struct {
+	spans    int
+	duration int64
+	error    bool
+}
+
+func newTailTraceSummary(chunks []*pb.TraceChunk) tailTraceSummary {
+	var t tailTraceSummary
+	var start, end int64
+	for _, chunk := range chunks {
+		for _, span := range chunk.Spans {
+			if t.spans == 0 || span.Start < start {
+				start = span.Start
+			}
+			if t.spans == 0 || span.Start+span.Duration > end {
+				end = span.Start + span.Duration
+			}
+			t.spans++
+			t.error = t.error || span.Error != 0
+		}
+	}
+	t.duration = end - start
+	return t
+}
+
+func (p *TailPolicy) match(chunks []*pb.TraceChunk, t *tailTraceSummary) bool {
+	if p.minSpans > 0 && t.spans < p.minSpans {
+		return false
+	}
+	if p.minDuration > 0 && t.duration < p.minDuration {
+		return false
+	}
+	if p.error && !t.error {
+		return false
+	}
+	if len(p.tags) > 0 && !p.matchTags(chunks) {
+		return false
+	}
+	return true
+}
+
+// matchTags reports whether a span of the chunks has one of the tags of the
+// policy, in its meta or its metrics.
+func (p *TailPolicy) matchTags(chunks []*pb.TraceChunk) bool {
+	for _, chunk := range chunks {
+		for _, span := range chunk.Spans {
+			for k, values := range p.tags {
+				if v, ok := span.Meta[k]; ok && matchTagValue(values, v) {
+					return true
+				}
+				if v, ok := span.Metrics[k]; ok && matchTagValue(values, strconv.FormatFloat(v, 'f', -1, 64)) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func matchTagValue(values []string, v string) bool {
+	if values == nil {
+		return true
+	}
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
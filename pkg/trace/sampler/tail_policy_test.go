@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+)
+
+func TestMatchTailPolicies(t *testing.T) {
+	policies := NewTailPolicies([]*config.TailSamplingPolicy{
+		{Name: "slow", MinDurationMs: 500},
+		{Error: true, MinSpans: 2},
+		{Name: "checkout", Tags: []string{"http.route:/checkout", "http.status_code:503"}},
+		{Name: "canary", Tags: []string{"canary"}},
+		{Name: "all"},
+	})
+	// the policy without any condition is ignored
+	require.Len(t, policies, 4)
+	assert.Equal(t, "policy_1", policies[1].Name())
+
+	for _, tt := range []struct {
+		name   string
+		chunks []*pb.TraceChunk
+		want   string
+	}{
+		{
+			name: "none",
+			chunks: []*pb.TraceChunk{{Spans: []*pb.Span{
+				{Start: 0, Duration: 1e6, Meta: map[string]string{"http.route": "/cart"}},
+			}}},
+		},
+		{
+			name: "duration-across-chunks",
+			chunks: []*pb.TraceChunk{
+				{Spans: []*pb.Span{{Start: 0, Duration: 1e6}}},
+				{Spans: []*pb.Span{{Start: 499e6, Duration: 2e6}}},
+			},
+			want: "slow",
+		},
+		{
+			name: "error-too-few-spans",
+			chunks: []*pb.TraceChunk{{Spans: []*pb.Span{
+				{Error: 1},
+			}}},
+		},
+		{
+			name: "error",
+			chunks: []*pb.TraceChunk{
+				{Spans: []*pb.Span{{}}},
+				{Spans: []*pb.Span{{Error: 1}}},
+			},
+			want: "policy_1",
+		},
+		{
+			name: "tag-value",
+			chunks: []*pb.TraceChunk{{Spans: []*pb.Span{
+				{Meta: map[string]string{"http.route": "/checkout"}},
+			}}},
+			want: "checkout",
+		},
+		{
+			name: "metric-value",
+			chunks: []*pb.TraceChunk{{Spans: []*pb.Span{
+				{Metrics: map[string]float64{"http.status_code": 503}},
+			}}},
+			want: "checkout",
+		},
+		{
+			name: "tag-any-value",
+			chunks: []*pb.TraceChunk{{Spans: []*pb.Span{
+				{Meta: map[string]string{"canary": "v2"}},
+			}}},
+			want: "canary",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			p := MatchTailPolicies(policies, tt.chunks)
+			if tt.want == "" {
+				assert.Nil(t, p)
+				return
+			}
+			if assert.NotNil(t, p) {
+				assert.Equal(t, tt.want, p.Name())
+			}
+		})
+	}
+}
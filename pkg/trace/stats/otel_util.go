@@ -132,7 +132,7 @@ func obfuscateSpanForConcentrator(o *obfuscate.Obfuscator, span *pb.Span, conf *
 	}
 	switch span.Type {
 	case "sql", "cassandra":
-		_, err := transform.ObfuscateSQLSpan(o, span)
+		_, err := transform.ObfuscateSQLSpanForDBMS(o, span, transform.SQLDBMS(span.Type, span.Meta[transform.TagDBMS], conf.Obfuscation.CQL.Enabled))
 		if err != nil {
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
 		}
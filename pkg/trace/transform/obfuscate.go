@@ -18,6 +18,10 @@ const (
 	TagValkeyRawCommand = "valkey.raw_command"
 	// TagMemcachedCommand represents a memcached command tag
 	TagMemcachedCommand = "memcached.command"
+	// TagGraphQLQuery represents a GraphQL query tag
+	TagGraphQLQuery = "graphql.query"
+	// TagGraphQLSignature represents a GraphQL query signature tag
+	TagGraphQLSignature = "graphql.signature"
 	// TagMongoDBQuery represents a MongoDB query tag
 	TagMongoDBQuery = "mongodb.query"
 	// TagElasticBody represents an Elasticsearch body tag
@@ -35,14 +39,32 @@ const (
 const (
 	// TextNonParsable is the error text used when a query is non-parsable
 	TextNonParsable = "Non-parsable SQL query"
+	// TextNonParsableGraphQL is the error text used when a GraphQL query is non-parsable
+	TextNonParsableGraphQL = "Non-parsable GraphQL query"
 )
 
+// SQLDBMS returns the DBMS whose dialect the queries of spans of type spanType
+// reporting dbms are obfuscated with. When cql is set, the queries of the
+// Cassandra spans which don't report their DBMS are obfuscated as CQL.
+func SQLDBMS(spanType string, dbms string, cql bool) string {
+	if dbms == "" && spanType == "cassandra" && cql {
+		return obfuscate.DBMSCassandra
+	}
+	return dbms
+}
+
 // ObfuscateSQLSpan obfuscates a SQL span using pkg/obfuscate logic
 func ObfuscateSQLSpan(o *obfuscate.Obfuscator, span *pb.Span) (*obfuscate.ObfuscatedQuery, error) {
+	return ObfuscateSQLSpanForDBMS(o, span, span.Meta[TagDBMS])
+}
+
+// ObfuscateSQLSpanForDBMS obfuscates a SQL span whose query is written for dbms
+// using pkg/obfuscate logic
+func ObfuscateSQLSpanForDBMS(o *obfuscate.Obfuscator, span *pb.Span, dbms string) (*obfuscate.ObfuscatedQuery, error) {
 	if span.Resource == "" {
 		return nil, nil
 	}
-	oq, err := o.ObfuscateSQLStringForDBMS(span.Resource, span.Meta[TagDBMS])
+	oq, err := o.ObfuscateSQLStringForDBMS(span.Resource, dbms)
 	if err != nil {
 		// we have an error, discard the SQL to avoid polluting user resources.
 		span.Resource = TextNonParsable
@@ -80,3 +102,27 @@ func ObfuscateValkeySpan(o *obfuscate.Obfuscator, span *pb.Span, removeAllArgs b
 	}
 	span.Meta[TagValkeyRawCommand] = o.ObfuscateRedisString(span.Meta[TagValkeyRawCommand])
 }
+
+// ObfuscateGraphQLSpan obfuscates the query of a GraphQL span using pkg/obfuscate logic,
+// and sets its signature. The resource is obfuscated too when it holds the query.
+func ObfuscateGraphQLSpan(o *obfuscate.Obfuscator, span *pb.Span) (*obfuscate.ObfuscatedGraphQL, error) {
+	if span.Meta == nil || span.Meta[TagGraphQLQuery] == "" {
+		return nil, nil
+	}
+	query := span.Meta[TagGraphQLQuery]
+	oq, err := o.ObfuscateGraphQLString(query)
+	if err != nil {
+		// we have an error, discard the query to avoid leaking its values.
+		span.Meta[TagGraphQLQuery] = TextNonParsableGraphQL
+		if span.Resource == query {
+			span.Resource = TextNonParsableGraphQL
+		}
+		return nil, err
+	}
+	span.Meta[TagGraphQLQuery] = oq.Query
+	span.Meta[TagGraphQLSignature] = oq.Signature
+	if span.Resource == query {
+		span.Resource = oq.Query
+	}
+	return oq, nil
+}
@@ -0,0 +1,76 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package watchdog
+
+import (
+	"go.uber.org/atomic"
+)
+
+// MemBudget accounts for the memory held by a buffer of the trace-agent,
+// so that the buffer sheds its data before it makes the process use more
+// than the MaxMemory the watchdog allows.
+type MemBudget struct {
+	limit int64
+	used  *atomic.Int64
+}
+
+// NewMemBudget returns a budget of limit bytes. A limit of 0 or less means
+// the budget is unbounded.
+func NewMemBudget(limit int64) *MemBudget {
+	return &MemBudget{
+		limit: limit,
+		used:  atomic.NewInt64(0),
+	}
+}
+
+// BudgetFromMaxMemory returns the limit of a budget taking the given share
+// of maxMemory, the maximum memory of the process. It is unbounded when
+// maxMemory is.
+func BudgetFromMaxMemory(maxMemory float64, share float64) int64 {
+	if maxMemory <= 0 {
+		return 0
+	}
+	return int64(maxMemory * share)
+}
+
+// Reserve accounts for n more bytes, and reports whether they fit in the
+// budget. Nothing is accounted for when they don't.
+func (b *MemBudget) Reserve(n int64) bool {
+	for {
+		used := b.used.Load()
+		if b.limit > 0 && used+n > b.limit {
+			return false
+		}
+		if b.used.CompareAndSwap(used, used+n) {
+			return true
+		}
+	}
+}
+
+// Release gives back n bytes previously reserved.
+func (b *MemBudget) Release(n int64) {
+	b.used.Sub(n)
+}
+
+// Used returns the number of bytes reserved.
+func (b *MemBudget) Used() int64 {
+	return b.used.Load()
+}
+
+// Limit returns the limit of the budget, 0 if it is unbounded.
+func (b *MemBudget) Limit() int64 {
+	if b.limit <= 0 {
+		return 0
+	}
+	return b.limit
+}
+
+// OverMaxMemory reports whether the heap of the process is above maxMemory,
+// in which case the buffers should shed their data. It is always false when
+// maxMemory is 0 or less.
+func (pi *CurrentInfo) OverMaxMemory(maxMemory float64) bool {
+	return maxMemory > 0 && float64(pi.Mem().Alloc) > maxMemory
+}
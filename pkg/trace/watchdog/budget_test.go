@@ -0,0 +1,30 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package watchdog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemBudget(t *testing.T) {
+	b := NewMemBudget(100)
+	assert.True(t, b.Reserve(60))
+	assert.False(t, b.Reserve(50))
+	assert.EqualValues(t, 60, b.Used())
+	assert.True(t, b.Reserve(40))
+	b.Release(60)
+	assert.EqualValues(t, 40, b.Used())
+	assert.EqualValues(t, 100, b.Limit())
+
+	unbounded := NewMemBudget(0)
+	assert.True(t, unbounded.Reserve(1<<40))
+	assert.Zero(t, unbounded.Limit())
+
+	assert.EqualValues(t, 250, BudgetFromMaxMemory(1000, 0.25))
+	assert.Zero(t, BudgetFromMaxMemory(0, 0.25))
+}